@@ -17,8 +17,13 @@ func main() {
 	openaiModel := flag.String("openai_model", "gpt-3.5-turbo", "Modelo OpenAI a ser usado")
 	googleKey := flag.String("google_key", "", "Chave API do Google (opcional, pode usar GOOGLE_API_KEY)")
 	googleModel := flag.String("google_model", "gemini-pro", "Modelo Google a ser usado")
+	anthropicKey := flag.String("anthropic_key", "", "Chave API da Anthropic (opcional, pode usar ANTHROPIC_API_KEY)")
+	anthropicModel := flag.String("anthropic_model", "claude-3-5-haiku-latest", "Modelo Anthropic a ser usado")
+	compatibleURL := flag.String("compatible_url", "http://localhost:1234/v1", "URL base do servidor compatível com a API da OpenAI")
+	compatibleKey := flag.String("compatible_key", "", "Chave API do servidor compatível (opcional)")
+	compatibleModel := flag.String("compatible_model", "", "Modelo do servidor compatível")
 	testAll := flag.Bool("all", false, "Testar todos os provedores LLM disponíveis")
-	testProvider := flag.String("provider", "", "Provedor específico a testar (ollama, openai, google)")
+	testProvider := flag.String("provider", "", "Provedor específico a testar (ollama, openai, google, anthropic, openai-compatible)")
 	prompt := flag.String("prompt", "Explique de forma breve como funciona a linguagem Go.", "Prompt para o LLM")
 	sysPrompt := flag.String("sysprompt", "Você é um assistente especializado em programação.", "System Prompt")
 	
@@ -30,6 +35,8 @@ func main() {
 	testOllama := *testAll || *testProvider == "ollama" || *testProvider == ""
 	testOpenAI := *testAll || *testProvider == "openai"
 	testGoogle := *testAll || *testProvider == "google"
+	testAnthropic := *testAll || *testProvider == "anthropic"
+	testCompatible := *testAll || *testProvider == "openai-compatible"
 	
 	// Teste Ollama
 	if testOllama {
@@ -99,6 +106,49 @@ func main() {
 			testProviderWithConfig("google", config, *prompt, *sysPrompt)
 		}
 	}
+	
+	// Teste Anthropic
+	if testAnthropic {
+		fmt.Println("\n=== Testando Anthropic ===")
+		
+		// Prioriza a flag sobre a variável de ambiente
+		apiKey := *anthropicKey
+		if apiKey == "" {
+			apiKey = os.Getenv("ANTHROPIC_API_KEY")
+		}
+		
+		if apiKey == "" {
+			fmt.Println("API Key da Anthropic não configurada. Configure a variável de ambiente ANTHROPIC_API_KEY ou use a flag -anthropic_key")
+		} else {
+			config := map[string]string{
+				"api_key": apiKey,
+				"model":   *anthropicModel,
+			}
+			
+			fmt.Printf("Modelo: %s\n", *anthropicModel)
+			fmt.Printf("API Key: %s...%s (primeiros/últimos 4 caracteres)\n", 
+				apiKey[0:min(4, len(apiKey))], 
+				apiKey[max(0, len(apiKey)-4):])
+			
+			testProviderWithConfig("anthropic", config, *prompt, *sysPrompt)
+		}
+	}
+	
+	// Teste de servidor compatível com a API da OpenAI
+	if testCompatible {
+		fmt.Println("\n=== Testando servidor compatível com OpenAI ===")
+		
+		config := map[string]string{
+			"base_url": *compatibleURL,
+			"api_key":  *compatibleKey,
+			"model":    *compatibleModel,
+		}
+		
+		fmt.Printf("URL: %s\n", *compatibleURL)
+		fmt.Printf("Modelo: %s\n", *compatibleModel)
+		
+		testProviderWithConfig("openai-compatible", config, *prompt, *sysPrompt)
+	}
 }
 
 func testProviderWithConfig(providerName string, config map[string]string, prompt string, systemPrompt string) {
@@ -111,6 +161,15 @@ func testProviderWithConfig(providerName string, config map[string]string, promp
 	
 	fmt.Printf("Cliente %s criado com sucesso\n", providerName)
 	
+	// Lista os modelos quando o provedor oferece essa consulta
+	if lister, ok := provider.(llm.ModelLister); ok {
+		if models, err := lister.ListModels(); err != nil {
+			fmt.Printf("Não foi possível listar os modelos: %v\n", err)
+		} else {
+			fmt.Printf("Modelos disponíveis: %v\n", models)
+		}
+	}
+	
 	// Testa geração de resposta
 	fmt.Printf("Enviando prompt: '%s'\n", prompt)
 	fmt.Printf("System prompt: '%s'\n", systemPrompt)
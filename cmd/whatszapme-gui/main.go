@@ -9,6 +9,8 @@ import (
 	"image/color"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"text/template"
 	"time"
@@ -24,8 +26,12 @@ import (
 	"fyne.io/fyne/v2/widget"
 
 	"github.com/peder/whatszapme/internal/auth"
+	"github.com/peder/whatszapme/internal/conversation"
 	"github.com/peder/whatszapme/internal/db"
+	"github.com/peder/whatszapme/internal/knowledge"
 	"github.com/peder/whatszapme/internal/llm"
+	"github.com/peder/whatszapme/internal/speech"
+	"github.com/peder/whatszapme/internal/token"
 	"github.com/peder/whatszapme/internal/ui"
 	"github.com/peder/whatszapme/internal/whatsapp"
 )
@@ -36,7 +42,14 @@ var (
 	client               *whatsapp.Client
 	database             *db.DB
 	llmClient            llm.Provider
+	budgetFallbackClient llm.Provider // Provedor usado com o orçamento excedido (nil quando não configurado)
 	gerenciadorHistorico *ui.GerenciadorHistorico
+	tokenCounter         *token.Counter
+	gerenciadorTokens    *ui.GerenciadorTokens
+	knowledgeIndex       *knowledge.Index // Base de conhecimento (nil quando desativada)
+	knowledgeCancel      context.CancelFunc
+	gerenciadorConhecimento *ui.GerenciadorConhecimento
+	knowledgeTab         *fyne.Container
 	statusLabel          *widget.Label // Label para exibir mensagens de status
 	statusMu             sync.Mutex    // Mutex para proteger acesso às variáveis de estado
 	connected            bool = false  // Estado de conexão do WhatsApp
@@ -57,6 +70,13 @@ type appConfig struct {
 	googleKey            string
 	googleModel          string
 	useGoogleOAuth       bool
+	anthropicKey         string
+	anthropicModel       string
+	// Servidor compatível com a API da OpenAI (LM Studio, vLLM, llama.cpp server, Groq, Grok)
+	compatibleURL        string
+	compatibleKey        string
+	compatibleModel      string
+	compatibleHeaders    string // Cabeçalhos extras, um "Nome: valor" por linha
 	googleClientID       string // ID do cliente OAuth do Google
 	googleClientSecret   string // Segredo do cliente OAuth do Google
 	systemPromptTemplate string
@@ -68,6 +88,55 @@ type appConfig struct {
 	// Configurações de grupos
 	respondToGroups        bool // Se deve responder a grupos
 	respondOnlyIfMentioned bool // Se deve responder apenas quando mencionado
+	// Configurações do contexto de conversa
+	historyExchanges   int // Quantidade de trocas anteriores enviadas ao LLM
+	historyTokenBudget int // Limite aproximado de tokens do histórico
+	// Parâmetros de geração do LLM
+	llmTemperature    float64 // Temperatura usada nas respostas
+	llmMaxTokens      int     // Limite de tokens de cada resposta
+	llmTimeoutSeconds int     // Tempo máximo de uma geração, em segundos
+	// Orçamento mensal de gastos com os provedores
+	budgetMonthlyLimit     float64 // Limite mensal (0 = sem limite)
+	budgetWarnPercent      int     // Percentual do limite que dispara o aviso
+	budgetAction           string  // Ação ao exceder o limite (token.BudgetAction)
+	budgetFallbackProvider string  // Provedor usado quando a ação é "fallback"
+	// Cadeia de provedores usada quando o provedor principal falha
+	failoverEnabled bool   // Se deve tentar outros provedores em caso de falha
+	failoverOrder   string // Provedores alternativos, em ordem, separados por vírgula
+	// Base de conhecimento usada para responder com informações do negócio
+	knowledgeEnabled       bool   // Se deve buscar trechos da base para cada mensagem
+	knowledgeDir           string // Pasta com os arquivos Markdown, TXT ou PDF
+	knowledgeEmbedProvider string // Provedor dos embeddings (ollama, openai ou openai-compatible)
+	knowledgeEmbedModel    string // Modelo de embeddings
+	knowledgeTopK          int    // Quantidade de trechos enviados ao modelo
+	// Transcrição das mensagens de voz por um servidor compatível com a API da OpenAI
+	sttEnabled    bool   // Se deve transcrever os áudios recebidos e respondê-los
+	sttURL        string // URL base do servidor (whisper.cpp server, faster-whisper)
+	sttModel      string // Modelo de transcrição
+	sttAPIKey     string // API Key, quando o servidor exigir
+	sttLanguage   string // Dica de idioma (ISO-639-1, ex: pt); vazio detecta automaticamente
+	sttMaxSeconds int    // Duração máxima dos áudios transcritos (0 = sem limite)
+	// Imagens enviadas pelos contatos, respondidas por modelos multimodais
+	visionEnabled bool   // Se deve enviar as imagens recebidas ao modelo e respondê-las
+	visionModels  string // Modelos extras que aceitam imagens, separados por vírgula
+	// Respostas em mensagens de voz geradas por um servidor local de síntese (Piper)
+	replyMode  string // Modo padrão de resposta (speech.ReplyMode); cada contato pode ter o seu
+	ttsURL     string // URL do servidor HTTP do Piper
+	ttsVoice   string // Voz carregada no servidor; vazio usa a padrão
+	ffmpegPath string // Executável do ffmpeg usado na conversão para OGG/Opus
+	// Comportamento das respostas no WhatsApp
+	quoteReplies       bool   // Se a resposta deve citar a mensagem que a originou
+	reactionsEnabled   bool   // Se deve reagir à mensagem enquanto processa e ao concluir
+	reactionProcessing string // Reação enquanto a resposta é gerada
+	reactionDone       string // Reação quando a resposta é enviada
+	readReceipts       bool   // Se deve confirmar a leitura (tiques azuis) das mensagens respondidas
+	regenerateOnEdit   bool   // Se deve responder de novo quando o contato edita uma pergunta já respondida
+	// Ritmo de envio: o assistente "digita" por um tempo proporcional ao tamanho da resposta
+	pacingEnabled bool
+	pacing        whatsapp.Pacing
+	// Importação das conversas antigas enviadas pelo WhatsApp depois do pareamento
+	historyImport bool // Se deve gravar no histórico as conversas sincronizadas
+	historyDays   int  // Importa apenas as mensagens dos últimos N dias; 0 importa todas
 }
 
 // Implementação da interface SyncStore do pacote whatsapp
@@ -78,13 +147,102 @@ func (cfg *appConfig) GetRespondToGroupsConfig(respondToGroups, respondOnlyIfMen
 }
 
 // SincronizarContato implementa a interface whatsapp.SyncStore
-func (c *appConfig) SincronizarContato(jid, nome, telefone string) error {
+func (c *appConfig) SincronizarContato(contato whatsapp.Contact) error {
 	// Usar a instância global do banco de dados para sincronizar contatos
 	if database == nil {
 		return fmt.Errorf("banco de dados não inicializado")
 	}
 	
-	return database.SincronizarContato(jid, nome, telefone)
+	return database.AtualizarContato(db.Contato{
+		JID:           contato.JID,
+		Telefone:      contato.Phone,
+		NomeAgenda:    contato.FullName,
+		NomePublico:   contato.PushName,
+		NomeComercial: contato.BusinessName,
+	})
+}
+
+// GetGroupPolicy implementa a interface whatsapp.SyncStore com as políticas salvas no banco de dados
+func (c *appConfig) GetGroupPolicy(groupJID string) (*whatsapp.GroupPolicy, error) {
+	if database == nil {
+		return nil, nil
+	}
+	
+	politica, err := database.ObterPoliticaGrupo(groupJID)
+	if err != nil || politica == nil {
+		return nil, err
+	}
+	return &whatsapp.GroupPolicy{
+		Enabled:           politica.Ativo,
+		MentionOnly:       politica.SomenteMencao,
+		Keywords:          politica.PalavrasChave,
+		Persona:           politica.Persona,
+		AdminOnlyCommands: politica.ComandosSomenteAdmin,
+		Cooldown:          politica.Intervalo,
+	}, nil
+}
+
+// GetModerationRules implementa a interface whatsapp.SyncStore com a moderação salva no banco de dados
+func (c *appConfig) GetModerationRules(groupJID string) (*whatsapp.ModerationRules, error) {
+	if database == nil {
+		return nil, nil
+	}
+	
+	moderacao, err := database.ObterModeracaoGrupo(groupJID)
+	if err != nil || moderacao == nil {
+		return nil, err
+	}
+	return &whatsapp.ModerationRules{
+		WelcomeTemplate: moderacao.BoasVindas,
+		GoodbyeTemplate: moderacao.Despedida,
+		BlockLinks:      moderacao.BloquearLinks,
+		FloodLimit:      moderacao.LimiteFlood,
+		FloodWindow:     moderacao.JanelaFlood,
+		BannedWords:     moderacao.PalavrasProibidas,
+		Action:          whatsapp.ModerationAction(moderacao.Acao),
+	}, nil
+}
+
+// RegistrarModeracao implementa a interface whatsapp.SyncStore, gravando a ação no log de moderação
+func (c *appConfig) RegistrarModeracao(evento whatsapp.ModerationEvent) error {
+	if database == nil {
+		return fmt.Errorf("banco de dados não inicializado")
+	}
+	
+	registro := db.RegistroModeracao{
+		GrupoJID:        evento.GroupJID,
+		ParticipanteJID: evento.ParticipantJID,
+		Acao:            string(evento.Action),
+		Motivo:          evento.Reason,
+		MensagemID:      evento.MessageID,
+		Conteudo:        evento.Text,
+	}
+	if evento.Err != nil {
+		registro.Erro = evento.Err.Error()
+	}
+	fmt.Printf("[INFO] Moderação no grupo %s: %s %s %s\n", evento.GroupJID, evento.Action, evento.ParticipantJID, evento.Reason)
+	_, err := database.RegistrarModeracao(registro)
+	return err
+}
+
+// SincronizarGrupo implementa a interface whatsapp.SyncStore
+func (c *appConfig) SincronizarGrupo(grupo whatsapp.Group) error {
+	if database == nil {
+		return fmt.Errorf("banco de dados não inicializado")
+	}
+	
+	g := db.Grupo{
+		JID:           grupo.JID,
+		Nome:          grupo.Name,
+		Descricao:     grupo.Topic,
+		Dono:          grupo.OwnerJID,
+		CriadoEm:      grupo.Created,
+		Participantes: make([]db.Participante, len(grupo.Participants)),
+	}
+	for i, p := range grupo.Participants {
+		g.Participantes[i] = db.Participante{JID: p.JID, Telefone: p.Phone, Admin: p.IsAdmin, SuperAdmin: p.IsSuperAdmin}
+	}
+	return database.SincronizarGrupo(g)
 }
 
 // Configuração padrão
@@ -94,6 +252,8 @@ var config = appConfig{
 	ollamaModel:         "llama2",
 	openAIModel:         "gpt-3.5-turbo",
 	googleModel:         "gemini-pro",
+	anthropicModel:      "claude-3-5-haiku-latest",
+	compatibleURL:       llm.OpenAICompatiblePresets["lmstudio"],
 	userPromptTemplate:  "Mensagem de {{.SenderName}}: {{.Message}}\n\nResponda de forma concisa e útil.",
 	systemPromptTemplate: "Você é um assistente virtual via WhatsApp. Seu objetivo é fornecer respostas úteis, precisas e concisas. Mantenha um tom educado e profissional. Não mencione que é uma IA a menos que seja perguntado diretamente.",
 	allowAllContacts:     true,
@@ -103,6 +263,34 @@ var config = appConfig{
 	// Valores padrão para configurações de grupos
 	respondToGroups:      true,  // Por padrão, responde a todas as mensagens de grupos
 	respondOnlyIfMentioned: false, // Por padrão, responde a todas as mensagens de grupos, mesmo sem menção
+	historyExchanges:       conversation.DefaultMaxExchanges,
+	historyTokenBudget:     conversation.DefaultTokenBudget,
+	llmTemperature:         0.7,
+	llmMaxTokens:           1024,
+	llmTimeoutSeconds:      90,
+	budgetWarnPercent:      80,
+	budgetAction:           string(token.BudgetActionNone),
+	budgetFallbackProvider: "ollama",
+	failoverOrder:          "ollama,openai,google",
+	knowledgeDir:           filepath.Join(os.Getenv("HOME"), ".whatszapme", "conhecimento"),
+	knowledgeEmbedProvider: "ollama",
+	knowledgeEmbedModel:    "nomic-embed-text",
+	knowledgeTopK:          knowledge.DefaultTopK,
+	sttURL:                 speech.DefaultWhisperURL,
+	sttModel:               speech.DefaultWhisperModel,
+	sttLanguage:            "pt",
+	sttMaxSeconds:          180,
+	visionEnabled:          true,
+	replyMode:              string(speech.ReplyText),
+	ttsURL:                 speech.DefaultPiperURL,
+	ffmpegPath:             "ffmpeg",
+	quoteReplies:           true,
+	reactionProcessing:     "👀",
+	reactionDone:           "✅",
+	readReceipts:           true,
+	pacingEnabled:          true,
+	pacing:                 whatsapp.DefaultPacing(),
+	historyImport:          true,
 }
 
 func main() {
@@ -137,6 +325,15 @@ func main() {
 	// Inicializa o banco de dados
 	initDB()
 	
+	// Inicializa a base de conhecimento, que depende do banco de dados
+	initKnowledge()
+	
+	// Inicializa o contador de tokens, alimentado pelas respostas dos provedores
+	tokenCounter, err = token.NewCounter(filepath.Join(dataDir, "config"))
+	if err != nil {
+		fmt.Printf("Erro ao inicializar contador de tokens: %v\n", err)
+	}
+	
 	// Tenta reconectar automaticamente ao WhatsApp
 	autoReconnectWhatsApp()
 	
@@ -144,6 +341,8 @@ func main() {
 	tabs := container.NewAppTabs(
 		container.NewTabItemWithIcon("Conexão", theme.ComputerIcon(), createConnectionTab()),
 		container.NewTabItemWithIcon("Histórico", theme.DocumentIcon(), createHistoryTab()),
+		container.NewTabItemWithIcon("Tokens", theme.StorageIcon(), createTokensTab()),
+		container.NewTabItemWithIcon("Conhecimento", theme.FolderIcon(), createKnowledgeTab()),
 		container.NewTabItemWithIcon("Configurações", theme.SettingsIcon(), createSettingsTab()),
 		container.NewTabItemWithIcon("Sobre", theme.InfoIcon(), createAboutTab()),
 	)
@@ -201,7 +400,7 @@ func createConnectionTab() fyne.CanvasObject {
 // Cria a aba de configurações
 func createSettingsTab() fyne.CanvasObject {
 	// Seleção do provedor LLM
-	providerOptions := []string{"Ollama (local)", "OpenAI", "Google"}
+	providerOptions := []string{"Ollama (local)", "OpenAI", "Google", "Anthropic (Claude)", "Compatível com OpenAI"}
 	providerSelect := widget.NewSelect(providerOptions, func(value string) {
 		switch value {
 		case "Ollama (local)":
@@ -210,6 +409,10 @@ func createSettingsTab() fyne.CanvasObject {
 			config.llmProvider = "openai"
 		case "Google":
 			config.llmProvider = "google"
+		case "Anthropic (Claude)":
+			config.llmProvider = "anthropic"
+		case "Compatível com OpenAI":
+			config.llmProvider = "openai-compatible"
 		}
 	})
 	providerSelect.SetSelected("Ollama (local)")
@@ -246,7 +449,9 @@ func createSettingsTab() fyne.CanvasObject {
 	promptHelpLabel := widget.NewLabel("Variáveis disponíveis para templates de prompt:\n" + 
 		"{{.SenderName}} - Nome do remetente\n" + 
 		"{{.Message}} - Conteúdo da mensagem\n" + 
-		"{{.JID}} - ID do remetente no WhatsApp")
+		"{{.JID}} - ID do remetente no WhatsApp\n" + 
+		"{{.History}} - Conversa anterior com o contato (sem a variável, ela é enviada ao modelo como turnos)\n" + 
+		"{{.Context}} - Trechos da base de conhecimento relacionados à mensagem")
 	promptHelpLabel.Wrapping = fyne.TextWrapWord
 	
 	// Botão para restaurar prompts padrão
@@ -257,6 +462,504 @@ func createSettingsTab() fyne.CanvasObject {
 		config.systemPromptTemplate = systemPromptEntry.Text
 	})
 	
+	// Configurações do contexto de conversa
+	historyExchangesEntry := widget.NewEntry()
+	historyExchangesEntry.SetText(strconv.Itoa(config.historyExchanges))
+	historyExchangesEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			config.historyExchanges = n
+		}
+	}
+	
+	historyBudgetEntry := widget.NewEntry()
+	historyBudgetEntry.SetText(strconv.Itoa(config.historyTokenBudget))
+	historyBudgetEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			config.historyTokenBudget = n
+		}
+	}
+	
+	historySettings := container.NewVBox(
+		widget.NewLabel("Memória da Conversa"),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Trocas anteriores (0 = desativado):"),
+			historyExchangesEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Limite de tokens do histórico:"),
+			historyBudgetEntry,
+		),
+	)
+	
+	// Parâmetros de geração do LLM
+	temperatureEntry := widget.NewEntry()
+	temperatureEntry.SetText(strconv.FormatFloat(config.llmTemperature, 'f', -1, 64))
+	temperatureEntry.OnChanged = func(value string) {
+		if t, err := strconv.ParseFloat(value, 64); err == nil && t >= 0 && t <= 2 {
+			config.llmTemperature = t
+		}
+	}
+	
+	maxTokensEntry := widget.NewEntry()
+	maxTokensEntry.SetText(strconv.Itoa(config.llmMaxTokens))
+	maxTokensEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			config.llmMaxTokens = n
+		}
+	}
+	
+	timeoutEntry := widget.NewEntry()
+	timeoutEntry.SetText(strconv.Itoa(config.llmTimeoutSeconds))
+	timeoutEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			config.llmTimeoutSeconds = n
+		}
+	}
+	
+	generationSettings := container.NewVBox(
+		widget.NewLabel("Parâmetros de Geração"),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Temperatura (0 a 2):"),
+			temperatureEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Máximo de tokens por resposta:"),
+			maxTokensEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Tempo limite (segundos):"),
+			timeoutEntry,
+		),
+	)
+	
+	// Provedores alternativos em caso de falha
+	failoverOrderEntry := widget.NewEntry()
+	failoverOrderEntry.SetText(config.failoverOrder)
+	failoverOrderEntry.OnChanged = func(value string) {
+		config.failoverOrder = value
+	}
+	
+	failoverCheck := widget.NewCheck("Usar outros provedores quando o principal falhar", func(value bool) {
+		config.failoverEnabled = value
+	})
+	failoverCheck.SetChecked(config.failoverEnabled)
+	
+	failoverSettings := container.NewVBox(
+		widget.NewLabel("Failover"),
+		failoverCheck,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Ordem dos alternativos (ex.: ollama,openai,google):"),
+			failoverOrderEntry,
+		),
+	)
+	
+	// Orçamento mensal
+	budgetLimitEntry := widget.NewEntry()
+	budgetLimitEntry.SetPlaceHolder("0 = sem limite")
+	budgetLimitEntry.SetText(strconv.FormatFloat(config.budgetMonthlyLimit, 'f', -1, 64))
+	budgetLimitEntry.OnChanged = func(value string) {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 {
+			config.budgetMonthlyLimit = v
+		}
+	}
+	
+	budgetWarnEntry := widget.NewEntry()
+	budgetWarnEntry.SetText(strconv.Itoa(config.budgetWarnPercent))
+	budgetWarnEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 && n <= 100 {
+			config.budgetWarnPercent = n
+		}
+	}
+	
+	budgetActions := map[string]token.BudgetAction{
+		"Apenas avisar":             token.BudgetActionNone,
+		"Usar provedor alternativo": token.BudgetActionFallback,
+		"Pausar respostas":          token.BudgetActionPause,
+	}
+	budgetActionSelect := widget.NewSelect([]string{"Apenas avisar", "Usar provedor alternativo", "Pausar respostas"}, func(value string) {
+		config.budgetAction = string(budgetActions[value])
+	})
+	for label, action := range budgetActions {
+		if string(action) == config.budgetAction {
+			budgetActionSelect.SetSelected(label)
+		}
+	}
+	
+	budgetFallbackSelect := widget.NewSelect([]string{"ollama", "openai", "google", "anthropic", "openai-compatible"}, func(value string) {
+		config.budgetFallbackProvider = value
+	})
+	budgetFallbackSelect.SetSelected(config.budgetFallbackProvider)
+	
+	budgetSettings := container.NewVBox(
+		widget.NewCard("Orçamento Mensal", "Limite os gastos com os provedores pagos", nil),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Limite mensal (moeda do catálogo de preços):"),
+			budgetLimitEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Avisar ao atingir (%):"),
+			budgetWarnEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Ao exceder o limite:"),
+			budgetActionSelect,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Provedor alternativo:"),
+			budgetFallbackSelect,
+		),
+	)
+	
+	// Base de conhecimento
+	knowledgeCheck := widget.NewCheck("Responder usando a base de conhecimento", func(value bool) {
+		config.knowledgeEnabled = value
+	})
+	knowledgeCheck.SetChecked(config.knowledgeEnabled)
+	
+	knowledgeDirEntry := widget.NewEntry()
+	knowledgeDirEntry.SetText(config.knowledgeDir)
+	knowledgeDirEntry.OnChanged = func(value string) {
+		config.knowledgeDir = value
+	}
+	
+	knowledgeProviderSelect := widget.NewSelect([]string{"ollama", "openai", "openai-compatible"}, func(value string) {
+		config.knowledgeEmbedProvider = value
+	})
+	knowledgeProviderSelect.SetSelected(config.knowledgeEmbedProvider)
+	
+	knowledgeModelEntry := widget.NewEntry()
+	knowledgeModelEntry.SetText(config.knowledgeEmbedModel)
+	knowledgeModelEntry.OnChanged = func(value string) {
+		config.knowledgeEmbedModel = value
+	}
+	
+	knowledgeTopKEntry := widget.NewEntry()
+	knowledgeTopKEntry.SetText(strconv.Itoa(config.knowledgeTopK))
+	knowledgeTopKEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			config.knowledgeTopK = n
+		}
+	}
+	
+	knowledgeSettings := container.NewVBox(
+		widget.NewCard("Base de Conhecimento", "Arquivos com preços, horários e outras informações do negócio", nil),
+		knowledgeCheck,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Pasta dos arquivos (.md, .txt, .pdf):"),
+			knowledgeDirEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Provedor de embeddings:"),
+			knowledgeProviderSelect,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Modelo de embeddings:"),
+			knowledgeModelEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Trechos por resposta:"),
+			knowledgeTopKEntry,
+		),
+	)
+	
+	// Transcrição de mensagens de voz
+	sttCheck := widget.NewCheck("Transcrever e responder mensagens de voz", func(value bool) {
+		config.sttEnabled = value
+	})
+	sttCheck.SetChecked(config.sttEnabled)
+	
+	sttURLEntry := widget.NewEntry()
+	sttURLEntry.SetText(config.sttURL)
+	sttURLEntry.SetPlaceHolder(speech.DefaultWhisperURL)
+	sttURLEntry.OnChanged = func(value string) {
+		config.sttURL = value
+	}
+	
+	sttModelEntry := widget.NewEntry()
+	sttModelEntry.SetText(config.sttModel)
+	sttModelEntry.OnChanged = func(value string) {
+		config.sttModel = value
+	}
+	
+	sttKeyEntry := widget.NewPasswordEntry()
+	sttKeyEntry.SetText(config.sttAPIKey)
+	sttKeyEntry.OnChanged = func(value string) {
+		config.sttAPIKey = value
+	}
+	
+	sttLanguageEntry := widget.NewEntry()
+	sttLanguageEntry.SetText(config.sttLanguage)
+	sttLanguageEntry.SetPlaceHolder("automático")
+	sttLanguageEntry.OnChanged = func(value string) {
+		config.sttLanguage = strings.TrimSpace(value)
+	}
+	
+	sttMaxSecondsEntry := widget.NewEntry()
+	sttMaxSecondsEntry.SetText(strconv.Itoa(config.sttMaxSeconds))
+	sttMaxSecondsEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			config.sttMaxSeconds = n
+		}
+	}
+	
+	sttSettings := container.NewVBox(
+		widget.NewCard("Mensagens de Voz", "Transcrição por um servidor local (whisper.cpp server, faster-whisper) com o endpoint /v1/audio/transcriptions", nil),
+		sttCheck,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("URL do servidor:"),
+			sttURLEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Modelo:"),
+			sttModelEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("API Key (opcional):"),
+			sttKeyEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Idioma:"),
+			sttLanguageEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Duração máxima (segundos):"),
+			sttMaxSecondsEntry,
+		),
+	)
+	
+	// Respostas em mensagens de voz
+	replyModeOptions := make([]string, 0, len(speech.ReplyModes))
+	for _, mode := range speech.ReplyModes {
+		replyModeOptions = append(replyModeOptions, string(mode))
+	}
+	
+	replyModeSelect := widget.NewSelect(replyModeOptions, func(value string) {
+		config.replyMode = value
+	})
+	replyModeSelect.SetSelected(config.replyMode)
+	
+	ttsURLEntry := widget.NewEntry()
+	ttsURLEntry.SetText(config.ttsURL)
+	ttsURLEntry.SetPlaceHolder(speech.DefaultPiperURL)
+	ttsURLEntry.OnChanged = func(value string) {
+		config.ttsURL = value
+	}
+	
+	ttsVoiceEntry := widget.NewEntry()
+	ttsVoiceEntry.SetText(config.ttsVoice)
+	ttsVoiceEntry.SetPlaceHolder("ex: pt_BR-faber-medium")
+	ttsVoiceEntry.OnChanged = func(value string) {
+		config.ttsVoice = strings.TrimSpace(value)
+	}
+	
+	ffmpegEntry := widget.NewEntry()
+	ffmpegEntry.SetText(config.ffmpegPath)
+	ffmpegEntry.OnChanged = func(value string) {
+		config.ffmpegPath = strings.TrimSpace(value)
+	}
+	
+	// Modo de resposta de um contato específico, salvo no banco de dados
+	contactModeEntry := widget.NewEntry()
+	contactModeEntry.SetPlaceHolder("ID do contato (ex: 551199999999@s.whatsapp.net)")
+	contactModeSelect := widget.NewSelect(append([]string{"padrão"}, replyModeOptions...), nil)
+	contactModeSelect.SetSelected("padrão")
+	contactModeEntry.OnChanged = func(value string) {
+		modo := ""
+		if database != nil {
+			modo, _ = database.ObterModoResposta(strings.TrimSpace(value))
+		}
+		if modo == "" {
+			modo = "padrão"
+		}
+		contactModeSelect.SetSelected(modo)
+	}
+	contactModeButton := widget.NewButton("Definir", func() {
+		jid := strings.TrimSpace(contactModeEntry.Text)
+		if jid == "" || database == nil {
+			return
+		}
+		modo := contactModeSelect.Selected
+		if modo == "padrão" {
+			modo = ""
+		}
+		if err := database.DefinirModoResposta(jid, modo); err != nil {
+			showErrorDialog(fmt.Sprintf("Erro ao definir modo de resposta: %v", err))
+			return
+		}
+		updateStatusBar(fmt.Sprintf("Modo de resposta de %s atualizado", jid))
+	})
+	
+	ttsSettings := container.NewVBox(
+		widget.NewCard("Respostas em Voz", "Síntese pelo servidor HTTP do Piper e conversão para OGG/Opus com o ffmpeg. No modo auto, áudios recebem resposta em áudio", nil),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Modo de resposta padrão:"),
+			replyModeSelect,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("URL do Piper:"),
+			ttsURLEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Voz:"),
+			ttsVoiceEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Executável do ffmpeg:"),
+			ffmpegEntry,
+		),
+		widget.NewLabel("Modo de resposta por contato:"),
+		container.NewBorder(nil, nil, nil, container.NewHBox(contactModeSelect, contactModeButton), contactModeEntry),
+	)
+	
+	// Imagens recebidas
+	visionCheck := widget.NewCheck("Responder a imagens (a legenda é usada como pergunta)", func(value bool) {
+		config.visionEnabled = value
+	})
+	visionCheck.SetChecked(config.visionEnabled)
+	
+	visionModelsEntry := widget.NewEntry()
+	visionModelsEntry.SetText(config.visionModels)
+	visionModelsEntry.SetPlaceHolder("ex: meu-modelo-vl, qwen2.5vl:7b")
+	// A lista é aplicada ao salvar, e não a cada tecla, para não marcar nomes incompletos como multimodais
+	visionModelsEntry.OnChanged = func(value string) {
+		config.visionModels = value
+	}
+	
+	visionSettings := container.NewVBox(
+		widget.NewCard("Imagens", "Modelos como llava, GPT-4o, Gemini e Claude descrevem fotos e capturas de tela; os demais recebem apenas a legenda", nil),
+		visionCheck,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Outros modelos com visão:"),
+			visionModelsEntry,
+		),
+	)
+	
+	// Comportamento das respostas
+	quoteRepliesCheck := widget.NewCheck("Citar a mensagem respondida", func(value bool) {
+		config.quoteReplies = value
+	})
+	quoteRepliesCheck.SetChecked(config.quoteReplies)
+	
+	reactionsCheck := widget.NewCheck("Reagir às mensagens durante o processamento", func(value bool) {
+		config.reactionsEnabled = value
+	})
+	reactionsCheck.SetChecked(config.reactionsEnabled)
+	
+	reactionProcessingEntry := widget.NewEntry()
+	reactionProcessingEntry.SetText(config.reactionProcessing)
+	reactionProcessingEntry.OnChanged = func(value string) {
+		config.reactionProcessing = strings.TrimSpace(value)
+	}
+	
+	readReceiptsCheck := widget.NewCheck("Confirmar a leitura das mensagens respondidas", func(value bool) {
+		config.readReceipts = value
+	})
+	readReceiptsCheck.SetChecked(config.readReceipts)
+	
+	regenerateOnEditCheck := widget.NewCheck("Responder de novo quando o contato editar a pergunta", func(value bool) {
+		config.regenerateOnEdit = value
+	})
+	regenerateOnEditCheck.SetChecked(config.regenerateOnEdit)
+	
+	reactionDoneEntry := widget.NewEntry()
+	reactionDoneEntry.SetText(config.reactionDone)
+	reactionDoneEntry.OnChanged = func(value string) {
+		config.reactionDone = strings.TrimSpace(value)
+	}
+	
+	// Ritmo de envio das respostas
+	pacingCheck := widget.NewCheck("Simular o tempo de digitação antes de enviar", func(value bool) {
+		config.pacingEnabled = value
+	})
+	pacingCheck.SetChecked(config.pacingEnabled)
+	
+	typingSpeedEntry := widget.NewEntry()
+	typingSpeedEntry.SetText(strconv.FormatFloat(config.pacing.CharsPerSecond, 'f', -1, 64))
+	typingSpeedEntry.OnChanged = func(value string) {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v > 0 {
+			config.pacing.CharsPerSecond = v
+		}
+	}
+	
+	pacingMinEntry := widget.NewEntry()
+	pacingMinEntry.SetText(strconv.FormatFloat(config.pacing.MinDelay.Seconds(), 'f', -1, 64))
+	pacingMinEntry.OnChanged = func(value string) {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 {
+			config.pacing.MinDelay = time.Duration(v * float64(time.Second))
+		}
+	}
+	
+	pacingMaxEntry := widget.NewEntry()
+	pacingMaxEntry.SetText(strconv.FormatFloat(config.pacing.MaxDelay.Seconds(), 'f', -1, 64))
+	pacingMaxEntry.OnChanged = func(value string) {
+		if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 {
+			config.pacing.MaxDelay = time.Duration(v * float64(time.Second))
+		}
+	}
+	
+	pacingJitterEntry := widget.NewEntry()
+	pacingJitterEntry.SetText(strconv.Itoa(int(config.pacing.Jitter * 100)))
+	pacingJitterEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 100 {
+			config.pacing.Jitter = float64(n) / 100
+		}
+	}
+	
+	// Importação do histórico sincronizado pelo WhatsApp
+	historyImportCheck := widget.NewCheck("Importar as conversas antigas ao parear o aparelho", func(value bool) {
+		config.historyImport = value
+	})
+	historyImportCheck.SetChecked(config.historyImport)
+	
+	historyDaysEntry := widget.NewEntry()
+	historyDaysEntry.SetPlaceHolder("0 = todas")
+	historyDaysEntry.SetText(strconv.Itoa(config.historyDays))
+	historyDaysEntry.OnChanged = func(value string) {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			config.historyDays = n
+		}
+	}
+	
+	behaviorSettings := container.NewVBox(
+		widget.NewCard("Respostas", "Como o assistente sinaliza e entrega as respostas no WhatsApp", nil),
+		quoteRepliesCheck,
+		readReceiptsCheck,
+		regenerateOnEditCheck,
+		reactionsCheck,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Reação ao processar:"),
+			reactionProcessingEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Reação ao concluir:"),
+			reactionDoneEntry,
+		),
+		widget.NewCard("Ritmo de Envio", "Respostas instantâneas parecem automáticas e aumentam o risco de bloqueio", nil),
+		pacingCheck,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Caracteres digitados por segundo:"),
+			typingSpeedEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Atraso mínimo (segundos):"),
+			pacingMinEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Atraso máximo (segundos):"),
+			pacingMaxEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Variação aleatória (%):"),
+			pacingJitterEntry,
+		),
+		widget.NewCard("Histórico do WhatsApp", "Conversas anteriores dão contexto às respostas e aparecem na aba Histórico", nil),
+		historyImportCheck,
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Importar apenas os últimos dias:"),
+			historyDaysEntry,
+		),
+	)
+	
 	// Container de configurações de prompts
 	promptSettings := container.NewVBox(
 		widget.NewCard("Personalização de Prompts", "Configure como o assistente responderá às mensagens", nil),
@@ -345,43 +1048,138 @@ func createSettingsTab() fyne.CanvasObject {
 		googleOAuthButton,
 	)
 	
-	// Opções de resposta a mensagens de grupos
-	groupsCard := widget.NewCard(
-		"Configurações de Grupos",
-		"Defina como o assistente responde a mensagens de grupos",
-		nil)
+	// Configurações Anthropic
+	anthropicKeyEntry := widget.NewPasswordEntry()
+	anthropicKeyEntry.Bind(binding.BindString(&config.anthropicKey))
 	
-	respondToGroupsCheck := widget.NewCheck("Responder a mensagens de grupos", func(value bool) {
-		config.respondToGroups = value
+	anthropicModelOptions := []string{"claude-3-5-haiku-latest", "claude-3-5-sonnet-latest", "claude-3-opus-latest", "claude-3-haiku-20240307"}
+	anthropicModelSelect := widget.NewSelect(anthropicModelOptions, func(value string) {
+		config.anthropicModel = value
 	})
-	respondToGroupsCheck.SetChecked(config.respondToGroups)
+	anthropicModelSelect.SetSelected(config.anthropicModel)
 	
-	respondOnlyIfMentionedCheck := widget.NewCheck("Responder apenas quando for mencionado em grupos", func(value bool) {
-		config.respondOnlyIfMentioned = value
-	})
-	respondOnlyIfMentionedCheck.SetChecked(config.respondOnlyIfMentioned)
+	anthropicSettings := container.NewVBox(
+		widget.NewLabel("Configurações Anthropic"),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("API Key:"),
+			anthropicKeyEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Modelo:"),
+			anthropicModelSelect,
+		),
+	)
 	
-	// Desabilita a opção "responder apenas quando mencionado" se não estiver respondendo a grupos
-	if !config.respondToGroups {
-		respondOnlyIfMentionedCheck.Disable()
+	// Configurações de servidor compatível com a API da OpenAI
+	compatibleURLEntry := widget.NewEntry()
+	compatibleURLEntry.SetText(config.compatibleURL)
+	compatibleURLEntry.OnChanged = func(value string) {
+		config.compatibleURL = value
 	}
 	
-	// Atualiza o estado do checkbox de responder apenas quando mencionado quando a opção de responder a grupos mudar
-	respondToGroupsCheck.OnChanged = func(value bool) {
-		config.respondToGroups = value
-		if value {
-			respondOnlyIfMentionedCheck.Enable()
-		} else {
-			respondOnlyIfMentionedCheck.Disable()
-		}
+	compatibleModelSelect := widget.NewSelectEntry(nil)
+	compatibleModelSelect.SetText(config.compatibleModel)
+	compatibleModelSelect.OnChanged = func(value string) {
+		config.compatibleModel = value
 	}
 	
-	groupsSettingsContainer := container.NewVBox(
-		groupsCard,
-		respondToGroupsCheck,
+	compatiblePresetSelect := widget.NewSelect([]string{"lmstudio", "vllm", "llamacpp", "groq", "grok"}, func(value string) {
+		compatibleURLEntry.SetText(llm.OpenAICompatiblePresets[value])
+		// Serviços hospedados exigem o nome do modelo
+		if model := llm.OpenAICompatibleDefaultModels[value]; model != "" && config.compatibleModel == "" {
+			compatibleModelSelect.SetText(model)
+		}
+	})
+	compatiblePresetSelect.PlaceHolder = "Servidor conhecido"
+	
+	compatibleKeyEntry := widget.NewPasswordEntry()
+	compatibleKeyEntry.Bind(binding.BindString(&config.compatibleKey))
+	
+	compatibleHeadersEntry := widget.NewMultiLineEntry()
+	compatibleHeadersEntry.SetPlaceHolder("Nome: valor (um por linha)")
+	compatibleHeadersEntry.SetText(config.compatibleHeaders)
+	compatibleHeadersEntry.SetMinRowsVisible(2)
+	compatibleHeadersEntry.OnChanged = func(value string) {
+		config.compatibleHeaders = value
+	}
+	
+	// Consulta o endpoint /models do servidor para preencher a lista de modelos
+	listCompatibleModelsButton := widget.NewButton("Listar modelos", func() {
+		client := llm.NewOpenAICompatibleClient(config.compatibleURL, config.compatibleKey, config.compatibleModel, llm.ParseHeaders(config.compatibleHeaders))
+		go func() {
+			models, err := client.ListModels()
+			if err != nil {
+				showErrorDialog(fmt.Sprintf("Erro ao listar modelos de %s: %v", config.compatibleURL, err))
+				return
+			}
+			compatibleModelSelect.SetOptions(models)
+			if config.compatibleModel == "" && len(models) > 0 {
+				compatibleModelSelect.SetText(models[0])
+			}
+			updateStatusBar(fmt.Sprintf("%d modelos encontrados em %s", len(models), config.compatibleURL))
+		}()
+	})
+	
+	compatibleSettings := container.NewVBox(
+		widget.NewLabel("Servidor compatível com a API da OpenAI"),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("URL base:"),
+			container.NewBorder(nil, nil, nil, compatiblePresetSelect, compatibleURLEntry),
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("API Key (opcional):"),
+			compatibleKeyEntry,
+		),
+		container.NewGridWithColumns(2,
+			widget.NewLabel("Modelo:"),
+			container.NewBorder(nil, nil, nil, listCompatibleModelsButton, compatibleModelSelect),
+		),
+		widget.NewLabel("Cabeçalhos extras:"),
+		compatibleHeadersEntry,
+	)
+	
+	// Opções de resposta a mensagens de grupos
+	groupsCard := widget.NewCard(
+		"Configurações de Grupos",
+		"Defina como o assistente responde a mensagens de grupos",
+		nil)
+	
+	respondToGroupsCheck := widget.NewCheck("Responder a mensagens de grupos", func(value bool) {
+		config.respondToGroups = value
+	})
+	respondToGroupsCheck.SetChecked(config.respondToGroups)
+	
+	respondOnlyIfMentionedCheck := widget.NewCheck("Responder apenas quando for mencionado em grupos", func(value bool) {
+		config.respondOnlyIfMentioned = value
+	})
+	respondOnlyIfMentionedCheck.SetChecked(config.respondOnlyIfMentioned)
+	
+	// Desabilita a opção "responder apenas quando mencionado" se não estiver respondendo a grupos
+	if !config.respondToGroups {
+		respondOnlyIfMentionedCheck.Disable()
+	}
+	
+	// Atualiza o estado do checkbox de responder apenas quando mencionado quando a opção de responder a grupos mudar
+	respondToGroupsCheck.OnChanged = func(value bool) {
+		config.respondToGroups = value
+		if value {
+			respondOnlyIfMentionedCheck.Enable()
+		} else {
+			respondOnlyIfMentionedCheck.Disable()
+		}
+	}
+	
+	groupsSettingsContainer := container.NewVBox(
+		groupsCard,
+		respondToGroupsCheck,
 		respondOnlyIfMentionedCheck,
 	)
 	
+	// Cada grupo pode ter uma política própria, que substitui as opções acima
+	if database != nil {
+		groupsSettingsContainer.Add(ui.NewGerenciadorGrupos(database, mainWindow).Container())
+	}
+	
 	// Configurações de diretórios
 	dbPathEntry := widget.NewEntry()
 	dbPathEntry.SetText(config.dbPath)
@@ -425,6 +1223,10 @@ func createSettingsTab() fyne.CanvasObject {
 		if client != nil {
 			client.SetSyncStore(&config)
 		}
+		
+		// Recria os provedores LLM e reinicia a base de conhecimento com a pasta e o modelo configurados
+		initLLMClient()
+		initKnowledge()
 	})
 	
 	// Configurações de Prompts
@@ -520,17 +1322,36 @@ func createSettingsTab() fyne.CanvasObject {
 				widget.NewAccordionItem("Ollama", ollamaSettings),
 				widget.NewAccordionItem("OpenAI", openAISettings),
 				widget.NewAccordionItem("Google", googleSettings),
+				widget.NewAccordionItem("Anthropic", anthropicSettings),
+				widget.NewAccordionItem("Compatível com OpenAI", compatibleSettings),
 			),
+			generationSettings,
+			failoverSettings,
 		)),
 		container.NewTabItem("Personalização", container.NewVBox(
 			promptSettings,
+			historySettings,
 		)),
 		container.NewTabItem("Grupos", container.NewVBox(
 			groupsSettingsContainer,
 		)),
+		container.NewTabItem("Orçamento", container.NewVBox(
+			budgetSettings,
+		)),
+		container.NewTabItem("Conhecimento", container.NewVBox(
+			knowledgeSettings,
+		)),
+		container.NewTabItem("Mídia", container.NewVBox(
+			sttSettings,
+			ttsSettings,
+			visionSettings,
+		)),
 		container.NewTabItem("Contatos", container.NewVBox(
 			contactsSettings,
 		)),
+		container.NewTabItem("Comportamento", container.NewVBox(
+			behaviorSettings,
+		)),
 	)
 	
 	// Layout final
@@ -547,6 +1368,41 @@ func createSettingsTab() fyne.CanvasObject {
 // Variável global para armazenar o gerenciador de histórico
 // gerenciadorHistorico já foi declarado globalmente
 
+// Cria a aba de uso de tokens e custos
+func createTokensTab() fyne.CanvasObject {
+	if tokenCounter == nil {
+		return widget.NewLabel("Estatísticas de tokens indisponíveis. Contador não inicializado.")
+	}
+	
+	gerenciadorTokens = ui.NewGerenciadorTokens(tokenCounter, mainWindow)
+	return container.NewVScroll(gerenciadorTokens.Container())
+}
+
+// Cria a aba com os arquivos da base de conhecimento
+func createKnowledgeTab() fyne.CanvasObject {
+	knowledgeTab = container.NewStack()
+	refreshKnowledgeTab()
+	return knowledgeTab
+}
+
+// Atualiza a aba da base de conhecimento após a base ser ativada, desativada ou reconfigurada
+func refreshKnowledgeTab() {
+	if knowledgeTab == nil {
+		return
+	}
+	
+	if knowledgeIndex == nil {
+		gerenciadorConhecimento = nil
+		knowledgeTab.Objects = []fyne.CanvasObject{
+			widget.NewLabel("Base de conhecimento desativada. Ative em Configurações > Conhecimento."),
+		}
+	} else {
+		gerenciadorConhecimento = ui.NewGerenciadorConhecimento(knowledgeIndex, mainWindow)
+		knowledgeTab.Objects = []fyne.CanvasObject{gerenciadorConhecimento.Container()}
+	}
+	knowledgeTab.Refresh()
+}
+
 // Cria a aba de histórico de conversas
 func createHistoryTab() fyne.CanvasObject {
 	// Utilizamos o componente de gerenciador de histórico já implementado
@@ -559,14 +1415,28 @@ func createHistoryTab() fyne.CanvasObject {
 	gerenciadorHistorico = ui.NewGerenciadorHistorico(database, mainWindow)
 	
 	// Configura o callback para envio de mensagens manuais
-	gerenciadorHistorico.ConfigurarEnvioCallback(func(destinatario, texto string) error {
+	gerenciadorHistorico.ConfigurarEnvioCallback(func(destinatario, texto string) (string, error) {
 		// Verifica se o cliente WhatsApp está conectado e logado
 		if client == nil || !client.IsLoggedIn() {
-			return fmt.Errorf("cliente WhatsApp não está conectado ou logado")
+			return "", fmt.Errorf("cliente WhatsApp não está conectado ou logado")
 		}
 		
 		// Usa o cliente WhatsApp para enviar a mensagem
-		return client.SendMessage(destinatario, texto)
+		return client.SendText(destinatario, texto, nil)
+	})
+	
+	// Respostas e envios manuais podem ser editados ou apagados pelo operador
+	gerenciadorHistorico.ConfigurarEdicaoCallback(func(destinatario, whatsappID, texto string) error {
+		if client == nil || !client.IsLoggedIn() {
+			return fmt.Errorf("cliente WhatsApp não está conectado ou logado")
+		}
+		return client.EditText(destinatario, whatsappID, texto)
+	})
+	gerenciadorHistorico.ConfigurarExclusaoCallback(func(destinatario, whatsappID string) error {
+		if client == nil || !client.IsLoggedIn() {
+			return fmt.Errorf("cliente WhatsApp não está conectado ou logado")
+		}
+		return client.Revoke(destinatario, whatsappID)
 	})
 	
 	// Função de sincronização de contatos que será usada pelo botão
@@ -700,6 +1570,7 @@ func connectToWhatsApp(statusLabel *canvas.Text, qrCodeGenerator *ui.QRCodeGener
 		DBPath: config.dbPath,
 		LogLevel: "INFO",
 		AutoReconnect: true,
+		MediaDir: filepath.Join(dataDir, "midia"),
 	}
 	var err error
 	client, err = whatsapp.NewClient(whatsappConfig)
@@ -779,6 +1650,7 @@ func connectToWhatsApp(statusLabel *canvas.Text, qrCodeGenerator *ui.QRCodeGener
 				statusMu.Lock()
 				loggedIn = false
 				statusMu.Unlock()
+				cancelPendingGenerations()
 			default:
 				qrCodeGenerator.UpdateProgress(0.0, fmt.Sprintf("Estado da conexão: %s", state))
 				updateStatus(statusLabel, fmt.Sprintf("Estado: %s", state), color.NRGBA{R: 100, G: 100, B: 100, A: 255})
@@ -791,6 +1663,21 @@ func connectToWhatsApp(statusLabel *canvas.Text, qrCodeGenerator *ui.QRCodeGener
 	
 	// Configurar handler de mensagens
 	client.SetMessageHandler(handleIncomingMessage)
+	client.SetReactionHandler(handleReaction)
+	client.SetReceiptHandler(handleReceipt)
+	client.SetUpdateHandler(handleMessageUpdate)
+	client.SetHistoryHandler(func(batch *whatsapp.HistoryBatch) {
+		importadas := handleHistorySync(batch)
+		
+		// A barra de progresso da conexão acompanha a sincronização, que continua depois do login
+		mensagem := fmt.Sprintf("Importando histórico do WhatsApp: %d%% (%d mensagens novas)", batch.Progress, importadas)
+		if batch.Progress >= 100 {
+			qrCodeGenerator.StopProgress("Histórico importado. Pronto para receber mensagens.")
+		} else if batch.Progress > 0 {
+			qrCodeGenerator.StartProgress(mensagem)
+			qrCodeGenerator.UpdateProgress(float64(batch.Progress)/100, mensagem)
+		}
+	})
 	
 	// Iniciar processo de login para exibir o QR Code
 	go func() {
@@ -829,6 +1716,9 @@ func disconnectFromWhatsApp(statusLabel *canvas.Text, qrCodeGenerator *ui.QRCode
 	loggedIn = false
 	statusMu.Unlock()
 	
+	// Respostas em geração não teriam para onde ser enviadas
+	cancelPendingGenerations()
+	
 	updateStatus(statusLabel, "Desconectado", color.NRGBA{R: 255, G: 100, B: 100, A: 255})
 	qrCodeGenerator.ClearQRCode("Desconectado do WhatsApp")
 }
@@ -940,108 +1830,757 @@ func loadConfig() error {
 		dataDir = filepath.Join(homeDir, ".config", appName)
 	}
 	
-	// Define o diretório de configurações
-	saveDir = filepath.Join(dataDir, "config")
-	
-	// Caminho completo do arquivo de configuração
-	confPath := filepath.Join(saveDir, configFile)
-	
-	// Verifica se o arquivo existe
-	_, err := os.Stat(confPath)
-	if os.IsNotExist(err) {
-		fmt.Printf("Arquivo de configuração não encontrado, usando valores padrão: %s\n", confPath)
-		return nil // Usa configurações padrão
+	// Define o diretório de configurações
+	saveDir = filepath.Join(dataDir, "config")
+	
+	// Caminho completo do arquivo de configuração
+	confPath := filepath.Join(saveDir, configFile)
+	
+	// Verifica se o arquivo existe
+	_, err := os.Stat(confPath)
+	if os.IsNotExist(err) {
+		fmt.Printf("Arquivo de configuração não encontrado, usando valores padrão: %s\n", confPath)
+		return nil // Usa configurações padrão
+	}
+	
+	// Lê o arquivo de configuração
+	data, err := os.ReadFile(confPath)
+	if err != nil {
+		return fmt.Errorf("erro ao ler arquivo de configuração: %w", err)
+	}
+	
+	// Faz o parsing do JSON para a estrutura
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return fmt.Errorf("erro ao deserializar configurações: %w", err)
+	}
+	
+	updateStatusBar("Configurações carregadas de " + confPath)
+	fmt.Printf("Configurações carregadas de: %s\n", confPath)
+	
+	return nil
+}
+
+// Inicializa o cliente LLM de acordo com as configurações
+func initLLMClient() {
+	applyVisionModels()
+	initBudgetFallbackClient()
+	
+	if config.failoverEnabled {
+		llmClient = newFailoverChain()
+		return
+	}
+	
+	provider, err := newLLMProvider(config.llmProvider)
+	if err != nil {
+		fmt.Printf("Erro ao inicializar cliente LLM: %v\n", err)
+		return
+	}
+	
+	llmClient = provider
+}
+
+// Cria o provedor usado quando o orçamento mensal é excedido, uma única vez para que as conexões
+// sejam reaproveitadas entre as mensagens
+func initBudgetFallbackClient() {
+	budgetFallbackClient = nil
+	nome := config.budgetFallbackProvider
+	if nome == "" || nome == config.llmProvider {
+		return
+	}
+	
+	provider, err := newLLMProvider(nome)
+	if err != nil {
+		fmt.Printf("[ERRO] Falha ao criar provedor alternativo %s: %v\n", nome, err)
+		return
+	}
+	budgetFallbackClient = provider
+}
+
+// Monta a cadeia de fallback: o provedor principal primeiro, seguido dos alternativos na ordem configurada
+func newFailoverChain() llm.Provider {
+	nomes := []string{config.llmProvider}
+	for _, nome := range strings.Split(config.failoverOrder, ",") {
+		nomes = append(nomes, strings.TrimSpace(strings.ToLower(nome)))
+	}
+	
+	var backends []llm.Backend
+	usados := make(map[string]bool)
+	for _, nome := range nomes {
+		if nome == "" || usados[nome] || !providerConfigured(nome) {
+			continue
+		}
+		usados[nome] = true
+		
+		provider, err := newLLMProvider(nome)
+		if err != nil {
+			fmt.Printf("[ALERTA] Provedor %s ignorado na cadeia de fallback: %v\n", nome, err)
+			continue
+		}
+		backends = append(backends, llm.Backend{Name: nome, Provider: provider})
+	}
+	
+	chain := llm.NewFallbackProvider(backends...)
+	chain.OnFailover = func(failed string, err error) {
+		fmt.Printf("[ALERTA] Provedor %s falhou, tentando o próximo: %v\n", failed, err)
+		updateStatusBar(fmt.Sprintf("Provedor %s indisponível, usando alternativo", failed))
+	}
+	
+	return chain
+}
+
+// Indica se o provedor tem as credenciais necessárias para ser usado
+func providerConfigured(name string) bool {
+	switch name {
+	case "ollama":
+		return config.ollamaURL != ""
+	case "openai":
+		return config.openAIKey != ""
+	case "google":
+		return config.googleKey != "" || config.useGoogleOAuth
+	case "anthropic":
+		return config.anthropicKey != ""
+	case "openai-compatible":
+		return config.compatibleURL != ""
+	default:
+		return false
+	}
+}
+
+// Cria o provedor LLM informado usando as credenciais e modelos configurados
+func newLLMProvider(name string) (llm.Provider, error) {
+	var provider llm.Provider
+	
+	switch name {
+	case "ollama":
+		provider = llm.NewOllamaClient(config.ollamaURL, config.ollamaModel)
+	case "openai":
+		provider = llm.NewOpenAIClient(config.openAIKey, config.openAIModel)
+	case "google":
+		if config.useGoogleOAuth {
+			// Usar autenticação OAuth para Google
+			// Cria o objeto de autenticação OAuth
+			oauthOptions := auth.GoogleOAuthOptions{
+				ClientID:     config.googleClientID,
+				ClientSecret: config.googleClientSecret,
+			}
+			
+			googleOAuth, err := auth.NewGoogleOAuth(oauthOptions)
+			if err != nil {
+				return nil, fmt.Errorf("erro ao criar cliente OAuth do Google: %w", err)
+			}
+			
+			// Verifica se já está autenticado
+			if !googleOAuth.IsAuthenticated() {
+				return nil, fmt.Errorf("autenticação OAuth do Google necessária. Abra a URL e siga as instruções:\n%s", googleOAuth.GetAuthURL())
+			}
+			
+			// Cria o cliente usando o objeto OAuth e o contexto
+			oauthClient, err := llm.NewGoogleOAuthClient(googleOAuth, config.googleModel, context.Background())
+			if err != nil {
+				return nil, fmt.Errorf("erro ao criar cliente Google OAuth: %w", err)
+			}
+			provider = oauthClient
+		} else {
+			// Usar API Key para Google
+			provider = llm.NewGoogleClient(config.googleKey, config.googleModel)
+		}
+	case "anthropic":
+		provider = llm.NewAnthropicClient(config.anthropicKey, config.anthropicModel)
+	case "openai-compatible":
+		if config.compatibleURL == "" {
+			return nil, fmt.Errorf("URL base do servidor compatível com OpenAI não configurada")
+		}
+		provider = llm.NewOpenAICompatibleClient(config.compatibleURL, config.compatibleKey, config.compatibleModel, llm.ParseHeaders(config.compatibleHeaders))
+	default:
+		return nil, fmt.Errorf("provedor LLM desconhecido: %s", name)
+	}
+	
+	// Registra os tokens de cada resposta no contador exibido na aba "Tokens"
+	if tokenCounter != nil {
+		provider = llm.NewMeteredProvider(provider, name, modelForProvider(name), tokenCounter)
+	}
+	
+	return provider, nil
+}
+
+// Retorna o modelo configurado para o provedor informado
+func modelForProvider(name string) string {
+	switch name {
+	case "ollama":
+		return config.ollamaModel
+	case "openai":
+		return config.openAIModel
+	case "google":
+		return config.googleModel
+	case "anthropic":
+		return config.anthropicModel
+	case "openai-compatible":
+		return config.compatibleModel
+	default:
+		return ""
+	}
+}
+
+// Orçamento mensal montado a partir das configurações
+func currentBudget() token.Budget {
+	return token.Budget{
+		MonthlyLimit:  config.budgetMonthlyLimit,
+		WarnThreshold: float64(config.budgetWarnPercent) / 100,
+		Action:        token.BudgetAction(config.budgetAction),
+	}
+}
+
+// Avisos de orçamento já exibidos, por mês e situação, para não repetir o diálogo a cada mensagem
+var (
+	budgetNotified   = make(map[string]bool)
+	budgetNotifiedMu sync.Mutex
+)
+
+// Exibe um aviso de orçamento uma única vez por mês e situação
+func notifyBudget(status token.BudgetStatus, message string) {
+	key := fmt.Sprintf("%s|%d", time.Now().Format("2006-01"), status)
+	
+	budgetNotifiedMu.Lock()
+	jaAvisado := budgetNotified[key]
+	budgetNotified[key] = true
+	budgetNotifiedMu.Unlock()
+	
+	fmt.Printf("[ALERTA] %s\n", message)
+	updateStatusBar(message)
+	if !jaAvisado && mainWindow != nil {
+		showInfoDialog("Orçamento mensal", message)
+	}
+}
+
+// Verifica o orçamento mensal e retorna o provedor que deve responder à próxima mensagem.
+// Retorna false quando o orçamento foi excedido e a ação configurada é pausar as respostas
+func providerWithinBudget() (llm.Provider, bool) {
+	budget := currentBudget()
+	if tokenCounter == nil || budget.MonthlyLimit <= 0 {
+		return llmClient, true
+	}
+	
+	status, gasto := tokenCounter.CheckBudget(budget, time.Now())
+	moeda := tokenCounter.Pricing().Currency
+	
+	switch status {
+	case token.BudgetWarning:
+		notifyBudget(status, fmt.Sprintf("Atenção: %.2f %s de %.2f %s do orçamento mensal já foram gastos",
+			gasto, moeda, budget.MonthlyLimit, moeda))
+	case token.BudgetExceeded:
+		switch budget.Action {
+		case token.BudgetActionPause:
+			notifyBudget(status, "Orçamento mensal excedido. As respostas automáticas estão pausadas até o próximo mês.")
+			return nil, false
+		case token.BudgetActionFallback:
+			if fallback := budgetFallbackClient; fallback != nil {
+				notifyBudget(status, fmt.Sprintf("Orçamento mensal excedido. Respondendo com o provedor %s.", config.budgetFallbackProvider))
+				return fallback, true
+			}
+			notifyBudget(status, "Orçamento mensal excedido e nenhum provedor alternativo disponível.")
+		default:
+			notifyBudget(status, fmt.Sprintf("Orçamento mensal excedido: %.2f %s gastos de %.2f %s",
+				gasto, moeda, budget.MonthlyLimit, moeda))
+		}
+	}
+	
+	return llmClient, true
+}
+
+// Estrutura para dados de mensagem que serão usados nos templates
+type MessageData struct {
+	SenderName string
+	Message    string
+	JID        string
+	History    string
+	Context    string // Trechos da base de conhecimento relacionados à mensagem
+}
+
+// Processa um template com dados de mensagem
+func processTemplate(templateText string, data MessageData) (string, error) {
+	tmpl, err := template.New("prompt").Parse(templateText)
+	if err != nil {
+		return "", fmt.Errorf("erro ao analisar template: %w", err)
+	}
+	
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", fmt.Errorf("erro ao executar template: %w", err)
+	}
+	
+	return buf.String(), nil
+}
+
+// Inicializa a base de conhecimento e passa a reindexar a pasta sempre que os arquivos mudarem
+func initKnowledge() {
+	// Interrompe a observação da pasta anterior
+	if knowledgeCancel != nil {
+		knowledgeCancel()
+		knowledgeCancel = nil
+	}
+	knowledgeIndex = nil
+	defer refreshKnowledgeTab()
+	
+	if !config.knowledgeEnabled || database == nil {
+		return
+	}
+	
+	var embedder llm.Embedder
+	switch config.knowledgeEmbedProvider {
+	case "openai":
+		embedder = llm.NewOpenAIEmbedder(config.openAIKey, config.knowledgeEmbedModel)
+	case "openai-compatible":
+		compatible := llm.NewOpenAIEmbedder(config.compatibleKey, config.knowledgeEmbedModel)
+		compatible.BaseURL = strings.TrimRight(config.compatibleURL, "/")
+		embedder = compatible
+	default:
+		embedder = llm.NewOllamaEmbedder(config.ollamaURL, config.knowledgeEmbedModel)
+	}
+	
+	if err := os.MkdirAll(config.knowledgeDir, 0755); err != nil {
+		fmt.Printf("Erro ao criar pasta da base de conhecimento: %v\n", err)
+		return
+	}
+	
+	index := knowledge.NewIndex(database, embedder, config.knowledgeDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	knowledgeIndex = index
+	knowledgeCancel = cancel
+	
+	go func() {
+		result, err := index.Reindex(ctx)
+		reportKnowledgeIndex(result, err)
+		index.Watch(ctx, knowledge.DefaultWatchInterval, reportKnowledgeIndex)
+	}()
+}
+
+// Registra o resultado de uma indexação da base de conhecimento
+func reportKnowledgeIndex(result knowledge.IndexResult, err error) {
+	if err != nil {
+		fmt.Printf("[ALERTA] Erro ao indexar a base de conhecimento: %v\n", err)
+		updateStatusBar("Erro ao indexar a base de conhecimento")
+		return
+	}
+	
+	fmt.Printf("[INFO] Base de conhecimento: %d arquivos indexados, %d sem alteração, %d removidos\n",
+		len(result.Indexed), result.Unchanged, len(result.Removed))
+	for arquivo, erro := range result.Errors {
+		fmt.Printf("[ALERTA] Arquivo %s não indexado: %v\n", arquivo, erro)
+	}
+	
+	if gerenciadorConhecimento != nil {
+		gerenciadorConhecimento.MostrarResultado(result, nil)
+	}
+}
+
+// Busca os trechos da base de conhecimento relacionados à mensagem
+func knowledgeContext(message string) string {
+	index := knowledgeIndex
+	if index == nil {
+		return ""
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	
+	passages, err := index.Search(ctx, message, config.knowledgeTopK)
+	if err != nil {
+		fmt.Printf("[ALERTA] Erro ao buscar na base de conhecimento: %v. Continuando sem contexto.\n", err)
+		return ""
+	}
+	
+	return knowledge.FormatContext(passages)
+}
+
+// Retorna o modo de resposta do contato, ou o modo padrão quando ele não tem um definido
+func replyModeFor(jid string) speech.ReplyMode {
+	if database != nil {
+		modo, err := database.ObterModoResposta(jid)
+		if err != nil {
+			fmt.Printf("[ALERTA] Erro ao obter modo de resposta de %s: %v\n", jid, err)
+		} else if modo != "" {
+			return speech.ReplyMode(modo)
+		}
+	}
+	return speech.ReplyMode(config.replyMode)
+}
+
+// Gera a resposta em voz com o Piper e a envia como mensagem de voz, citando a mensagem recebida
+// quando quoted não é nil. Retorna o ID da mensagem enviada
+func sendVoiceReply(jid, resposta string, quoted *whatsapp.IncomingMessage) (string, error) {
+	synth := speech.NewPiperClient(config.ttsURL, config.ttsVoice)
+	encoder := speech.NewOpusEncoder()
+	if config.ffmpegPath != "" {
+		encoder.FFmpegPath = config.ffmpegPath
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	
+	note, err := speech.SynthesizeVoiceNote(ctx, synth, encoder, resposta)
+	if err != nil {
+		return "", fmt.Errorf("erro ao gerar mensagem de voz: %w", err)
+	}
+	return client.SendVoiceReply(jid, note.Data, note.MimeType, note.Seconds, quoted)
+}
+
+// Reage à mensagem recebida com o emoji informado, quando as reações estão habilitadas.
+// Um emoji vazio remove a reação anterior
+func reagir(incoming *whatsapp.IncomingMessage, emoji string) {
+	if !config.reactionsEnabled || client == nil || !client.IsLoggedIn() {
+		return
+	}
+	if emoji == "" && config.reactionProcessing == "" {
+		return
+	}
+	if err := client.SendReaction(incoming.ChatJID, incoming.SenderJID, incoming.ID, emoji); err != nil {
+		fmt.Printf("[ALERTA] Não foi possível reagir à mensagem: %v\n", err)
+	}
+}
+
+// Avança o status das mensagens enviadas conforme os recibos de entrega e leitura do WhatsApp
+func handleReceipt(receipt *whatsapp.Receipt) {
+	if database == nil {
+		return
+	}
+	
+	status := map[whatsapp.ReceiptStatus]string{
+		whatsapp.ReceiptDelivered: db.StatusEntregue,
+		whatsapp.ReceiptRead:      db.StatusLida,
+		whatsapp.ReceiptFailed:    db.StatusFalhou,
+	}[receipt.Status]
+	
+	// O histórico é organizado pelo JID salvo com a mensagem, que pode diferir do JID do recibo
+	var jid string
+	for _, id := range receipt.MessageIDs {
+		atualizada, err := database.AtualizarStatusPorWhatsAppID(id, status)
+		if err != nil {
+			fmt.Printf("[ERRO] Falha ao atualizar status da mensagem %s: %v\n", id, err)
+			continue
+		}
+		if atualizada && jid == "" {
+			if msg, err := database.BuscarMensagemPorWhatsAppID(id); err == nil && msg != nil {
+				jid = msg.JID
+			}
+		}
+	}
+	if jid != "" {
+		atualizarInterfaceHistorico(jid)
+	}
+}
+
+// Registra no histórico as mensagens editadas ou apagadas pelos contatos, mantendo o conteúdo original.
+// Com a opção habilitada, uma pergunta já respondida que foi editada recebe uma nova resposta
+func handleMessageUpdate(update *whatsapp.MessageUpdate) {
+	if update.FromMe || database == nil {
+		return
+	}
+	
+	var encontrada bool
+	var err error
+	switch update.Type {
+	case whatsapp.MessageEdited:
+		encontrada, err = database.RegistrarEdicao(update.TargetID, update.Message.Summary())
+	case whatsapp.MessageRevoked:
+		encontrada, err = database.RegistrarExclusao(update.TargetID)
+	}
+	if err != nil {
+		fmt.Printf("[ERRO] Falha ao registrar alteração da mensagem %s: %v\n", update.TargetID, err)
+		return
+	}
+	if !encontrada {
+		return
+	}
+	
+	msg, err := database.BuscarMensagemPorWhatsAppID(update.TargetID)
+	if err != nil || msg == nil {
+		return
+	}
+	fmt.Printf("[INFO] Mensagem %s de %s: %s\n", update.TargetID, msg.Nome, update.Type)
+	atualizarInterfaceHistorico(msg.JID)
+	
+	// A nova resposta é salva como outra mensagem, preservando a resposta original no histórico
+	if update.Type == whatsapp.MessageEdited && config.regenerateOnEdit && msg.Resposta != "" &&
+		update.Message.Type == whatsapp.MessageTypeText {
+		fmt.Printf("[INFO] Pergunta editada por %s; gerando nova resposta\n", msg.Nome)
+		respondToMessage(update.Message, 0, msg.ID)
+	}
+}
+
+// Registra no histórico as reações dos contatos às mensagens da conversa
+func handleReaction(reaction *whatsapp.Reaction) {
+	if reaction.FromMe || database == nil {
+		return
+	}
+	
+	atualizada, err := database.AtualizarReacao(reaction.TargetID, reaction.Emoji)
+	if err != nil {
+		fmt.Printf("[ERRO] Falha ao salvar reação no histórico: %v\n", err)
+		return
+	}
+	if atualizada {
+		fmt.Printf("[INFO] Reação %q de %s registrada\n", reaction.Emoji, reaction.SenderJID)
+		atualizarInterfaceHistorico(reaction.ChatJID)
+	}
+}
+
+// Grava no histórico as conversas antigas e os nomes dos contatos enviados pela sincronização
+// do WhatsApp. Mensagens já registradas são ignoradas. Retorna quantas mensagens foram importadas
+func handleHistorySync(batch *whatsapp.HistoryBatch) int {
+	if !config.historyImport || database == nil {
+		return 0
+	}
+	
+	for jid, nome := range batch.Names {
+		telefone := strings.SplitN(jid, "@", 2)[0]
+		if err := database.ImportarNomeContato(jid, nome, telefone); err != nil {
+			fmt.Printf("[ERRO] Falha ao importar contato %s: %v\n", jid, err)
+		}
+	}
+	
+	var limite time.Time
+	if config.historyDays > 0 {
+		limite = time.Now().AddDate(0, 0, -config.historyDays)
+	}
+	
+	// As conversas são organizadas como as mensagens em tempo real: as recebidas pelo remetente,
+	// as enviadas pelo aparelho pelo destinatário e as de grupos pelo grupo, para que não se
+	// misturem às conversas particulares de cada participante
+	mensagens := make([]db.Mensagem, 0, len(batch.Messages))
+	for _, msg := range batch.Messages {
+		if msg.Timestamp.Before(limite) {
+			continue
+		}
+		m := db.Mensagem{
+			JID:        msg.SenderJID,
+			Nome:       msg.SenderName,
+			Conteudo:   msg.Summary(),
+			Timestamp:  msg.Timestamp,
+			Entrada:    !msg.FromMe,
+			Tipo:       string(msg.Type),
+			WhatsAppID: msg.ID,
+			Status:     db.StatusRecebida,
+		}
+		if msg.IsGroup {
+			m.JID = msg.ChatJID
+		}
+		if msg.FromMe {
+			m.JID = msg.ChatJID
+			m.Nome = batch.Names[msg.ChatJID]
+			m.Status = db.StatusEnviada
+		}
+		mensagens = append(mensagens, m)
+	}
+	
+	importadas, err := database.ImportarMensagens(mensagens)
+	if err != nil {
+		fmt.Printf("[ERRO] Falha ao importar histórico: %v\n", err)
+		return 0
 	}
 	
-	// Lê o arquivo de configuração
-	data, err := os.ReadFile(confPath)
+	fmt.Printf("[INFO] Histórico do WhatsApp: %d de %d mensagens importadas, %d contatos (%d%%)\n",
+		importadas, len(batch.Messages), len(batch.Names), batch.Progress)
+	if importadas > 0 || len(batch.Names) > 0 {
+		updateStatusBar(fmt.Sprintf("Histórico do WhatsApp: %d mensagens importadas", importadas))
+		atualizarInterfaceHistorico("")
+	}
+	return importadas
+}
+
+// Retorna a persona definida na política do grupo, ou vazio para usar o system prompt padrão
+func personaDoGrupo(grupoJID string) string {
+	if database == nil {
+		return ""
+	}
+	politica, err := database.ObterPoliticaGrupo(grupoJID)
 	if err != nil {
-		return fmt.Errorf("erro ao ler arquivo de configuração: %w", err)
+		fmt.Printf("[ALERTA] Erro ao ler a política do grupo %s: %v\n", grupoJID, err)
+		return ""
+	}
+	if politica == nil {
+		return ""
+	}
+	return politica.Persona
+}
+
+// Ajuda exibida pelo comando "/bot ajuda"
+const ajudaComandosGrupo = `Comandos do assistente:
+/bot status — mostra a configuração do grupo
+/bot ativar | desativar — liga ou desliga as respostas
+/bot mencao sim | nao — responde apenas quando mencionado
+/bot palavras preço, entrega — palavras que acionam o assistente (vazio remove)
+/bot intervalo 60 — segundos mínimos entre respostas
+/bot persona Você é... — instruções do assistente neste grupo (vazio remove)`
+
+// Executa os comandos /bot enviados em grupos, que ajustam a política do grupo. Com a política
+// padrão, ou se o grupo assim definir, apenas administradores podem alterá-la
+func handleGroupCommand(incoming *whatsapp.IncomingMessage) {
+	if database == nil || client == nil || !client.IsLoggedIn() {
+		return
+	}
+	comando, args, _ := whatsapp.ParseGroupCommand(incoming.Text)
+	responder := func(texto string) {
+		if _, err := client.SendText(incoming.ChatJID, texto, incoming); err != nil {
+			fmt.Printf("[ERRO] Falha ao responder comando do grupo: %v\n", err)
+		}
 	}
 	
-	// Faz o parsing do JSON para a estrutura
-	err = json.Unmarshal(data, &config)
+	politica, err := database.ObterPoliticaGrupo(incoming.ChatJID)
 	if err != nil {
-		return fmt.Errorf("erro ao deserializar configurações: %w", err)
+		fmt.Printf("[ERRO] Falha ao ler a política do grupo: %v\n", err)
+		return
+	}
+	if politica == nil {
+		politica = &db.PoliticaGrupo{Ativo: config.respondToGroups, SomenteMencao: config.respondOnlyIfMentioned, ComandosSomenteAdmin: true}
 	}
 	
-	updateStatusBar("Configurações carregadas de " + confPath)
-	fmt.Printf("Configurações carregadas de: %s\n", confPath)
+	if comando == "ajuda" || comando == "status" {
+		if comando == "status" {
+			responder(descreverPoliticaGrupo(politica))
+		} else {
+			responder(ajudaComandosGrupo)
+		}
+		return
+	}
 	
-	return nil
-}
-
-// Inicializa o cliente LLM de acordo com as configurações
-func initLLMClient() {
-	var provider llm.Provider
+	if politica.ComandosSomenteAdmin {
+		admin, err := client.IsGroupAdmin(incoming.ChatJID, incoming.SenderJID)
+		if err != nil {
+			fmt.Printf("[ERRO] Falha ao verificar administrador do grupo: %v\n", err)
+			return
+		}
+		if !admin {
+			responder("Apenas administradores do grupo podem alterar o assistente.")
+			return
+		}
+	}
 	
-	switch config.llmProvider {
-	case "ollama":
-		provider = llm.NewOllamaClient(config.ollamaURL, config.ollamaModel)
-	case "openai":
-		provider = llm.NewOpenAIClient(config.openAIKey, config.openAIModel)
-	case "google":
-		if config.useGoogleOAuth {
-			// Usar autenticação OAuth para Google
-			// Cria o objeto de autenticação OAuth
-			oauthOptions := auth.GoogleOAuthOptions{
-				ClientID:     config.googleClientID,
-				ClientSecret: config.googleClientSecret,
-			}
-			
-			googleOAuth, err := auth.NewGoogleOAuth(oauthOptions)
-			if err != nil {
-				fmt.Printf("Erro ao criar cliente OAuth do Google: %v\n", err)
-				return
-			}
-			
-			// Verifica se já está autenticado
-			if !googleOAuth.IsAuthenticated() {
-				fmt.Println("Autenticação OAuth do Google necessária. Abra a URL e siga as instruções.")
-				fmt.Println(googleOAuth.GetAuthURL())
-				return
-			}
-			
-			// Cria o cliente usando o objeto OAuth e o contexto
-			oauthClient, err := llm.NewGoogleOAuthClient(googleOAuth, config.googleModel, context.Background())
-			if err != nil {
-				fmt.Printf("Erro ao criar cliente Google OAuth: %v\n", err)
-				return
+	argumento := strings.TrimSpace(strings.Join(args, " "))
+	switch comando {
+	case "ativar":
+		politica.Ativo = true
+	case "desativar":
+		politica.Ativo = false
+	case "mencao", "menção":
+		switch strings.ToLower(argumento) {
+		case "sim", "on":
+			politica.SomenteMencao = true
+		case "nao", "não", "off":
+			politica.SomenteMencao = false
+		default:
+			responder("Use /bot mencao sim ou /bot mencao nao.")
+			return
+		}
+	case "palavras":
+		politica.PalavrasChave = nil
+		for _, palavra := range strings.Split(argumento, ",") {
+			if palavra = strings.TrimSpace(palavra); palavra != "" {
+				politica.PalavrasChave = append(politica.PalavrasChave, palavra)
 			}
-			provider = oauthClient
-		} else {
-			// Usar API Key para Google
-			provider = llm.NewGoogleClient(config.googleKey, config.googleModel)
 		}
+	case "intervalo":
+		segundos, err := strconv.Atoi(argumento)
+		if err != nil || segundos < 0 {
+			responder("Use /bot intervalo seguido do número de segundos, por exemplo /bot intervalo 60.")
+			return
+		}
+		politica.Intervalo = time.Duration(segundos) * time.Second
+	case "persona":
+		politica.Persona = argumento
 	default:
-		// Caso padrão: usar Ollama com modelo llama2
-		provider = llm.NewOllamaClient(config.ollamaURL, "llama2")
+		responder(ajudaComandosGrupo)
+		return
 	}
 	
-	llmClient = provider
+	if err := database.DefinirPoliticaGrupo(incoming.ChatJID, politica); err != nil {
+		fmt.Printf("[ERRO] Falha ao salvar a política do grupo: %v\n", err)
+		responder("Não foi possível salvar a configuração do grupo.")
+		return
+	}
+	fmt.Printf("[INFO] Política do grupo %s alterada por %s: %s\n", incoming.ChatJID, incoming.SenderName, comando)
+	responder(descreverPoliticaGrupo(politica))
 }
 
-// Estrutura para dados de mensagem que serão usados nos templates
-type MessageData struct {
-	SenderName string
-	Message    string
-	JID        string
+// Descreve a política do grupo para a resposta dos comandos
+func descreverPoliticaGrupo(politica *db.PoliticaGrupo) string {
+	simNao := map[bool]string{true: "sim", false: "não"}
+	linhas := []string{
+		"Assistente ativo: " + simNao[politica.Ativo],
+		"Apenas quando mencionado: " + simNao[politica.SomenteMencao],
+	}
+	if len(politica.PalavrasChave) > 0 {
+		linhas = append(linhas, "Palavras-chave: "+strings.Join(politica.PalavrasChave, ", "))
+	}
+	if politica.Intervalo > 0 {
+		linhas = append(linhas, fmt.Sprintf("Intervalo entre respostas: %d segundos", int(politica.Intervalo/time.Second)))
+	}
+	if politica.Persona != "" {
+		linhas = append(linhas, "Persona: "+truncateString(politica.Persona, 80))
+	}
+	linhas = append(linhas, "Comandos apenas para administradores: "+simNao[politica.ComandosSomenteAdmin])
+	return strings.Join(linhas, "\n")
 }
 
-// Processa um template com dados de mensagem
-func processTemplate(templateText string, data MessageData) (string, error) {
-	tmpl, err := template.New("prompt").Parse(templateText)
-	if err != nil {
-		return "", fmt.Errorf("erro ao analisar template: %w", err)
+// Pergunta enviada ao modelo no lugar da legenda de uma imagem sem legenda. Sem visão, o modelo
+// recebe só um aviso no lugar da imagem e não deve tentar descrevê-la
+func promptImagemSemLegenda(visao bool) string {
+	if visao {
+		return "O contato enviou uma imagem sem legenda. Descreva o que ela mostra e pergunte como pode ajudar."
+	}
+	return "O contato enviou uma imagem sem legenda, que você não consegue ver. Não tente descrevê-la: " +
+		"explique que não é possível ver imagens e peça que ele conte por texto do que se trata."
+}
+
+// Marca como multimodais os modelos informados pelo usuário, além dos conhecidos pelo pacote llm.
+// A lista substitui a anterior, então modelos removidos dela voltam à lista de modelos conhecidos
+func applyVisionModels() {
+	llm.ResetVisionSupport()
+	for _, model := range strings.Split(config.visionModels, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			llm.SetVisionSupport(model, true)
+		}
+	}
+}
+
+// Lê a imagem já baixada de uma mensagem recebida para enviá-la ao modelo
+func loadImage(incoming *whatsapp.IncomingMessage) (llm.Image, error) {
+	att := incoming.Attachment
+	if att == nil || att.Path == "" {
+		if att != nil && att.Err != nil {
+			return llm.Image{}, fmt.Errorf("imagem não baixada: %w", att.Err)
+		}
+		return llm.Image{}, fmt.Errorf("imagem não baixada")
 	}
 	
-	var buf bytes.Buffer
-	err = tmpl.Execute(&buf, data)
+	data, err := os.ReadFile(att.Path)
 	if err != nil {
-		return "", fmt.Errorf("erro ao executar template: %w", err)
+		return llm.Image{}, fmt.Errorf("erro ao ler imagem: %w", err)
+	}
+	return llm.Image{MimeType: att.MimeType, Data: data}, nil
+}
+
+// Transcreve uma mensagem de voz já baixada, respeitando a duração máxima configurada
+func transcribeAudio(incoming *whatsapp.IncomingMessage) (string, error) {
+	att := incoming.Attachment
+	if att == nil || att.Path == "" {
+		if att != nil && att.Err != nil {
+			return "", fmt.Errorf("áudio não baixado: %w", att.Err)
+		}
+		return "", fmt.Errorf("áudio não baixado")
+	}
+	if config.sttMaxSeconds > 0 && int(att.Seconds) > config.sttMaxSeconds {
+		return "", fmt.Errorf("áudio de %d segundos passa do limite de %d segundos", att.Seconds, config.sttMaxSeconds)
 	}
 	
-	return buf.String(), nil
+	transcriber := speech.NewWhisperClient(config.sttURL, config.sttModel, config.sttLanguage)
+	transcriber.APIKey = config.sttAPIKey
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	
+	return transcriber.TranscribeFile(ctx, att.Path)
 }
 
 // Inicializa o banco de dados
@@ -1081,6 +2620,7 @@ func autoReconnectWhatsApp() {
 		DBPath: config.dbPath,
 		LogLevel: "INFO",
 		AutoReconnect: true,
+		MediaDir: filepath.Join(dataDir, "midia"),
 	}
 	client, err = whatsapp.NewClient(whatsappConfig)
 	if err != nil {
@@ -1130,6 +2670,7 @@ func autoReconnectWhatsApp() {
 				connected = false
 				loggedIn = false
 				statusMu.Unlock()
+				cancelPendingGenerations()
 			default:
 				fmt.Printf("Reconexão automática: Estado da conexão: %s\n", state)
 			}
@@ -1138,6 +2679,12 @@ func autoReconnectWhatsApp() {
 	
 	// Configurar handler de mensagens
 	client.SetMessageHandler(handleIncomingMessage)
+	client.SetReactionHandler(handleReaction)
+	client.SetReceiptHandler(handleReceipt)
+	client.SetUpdateHandler(handleMessageUpdate)
+	client.SetHistoryHandler(func(batch *whatsapp.HistoryBatch) {
+		handleHistorySync(batch)
+	})
 	
 	// Configura o SyncStore para permitir acesso às configurações
 	client.SetSyncStore(&config)
@@ -1161,7 +2708,11 @@ func autoReconnectWhatsApp() {
 }
 
 // Handler de mensagens recebidas
-func handleIncomingMessage(jid string, senderName string, message string) {
+func handleIncomingMessage(incoming *whatsapp.IncomingMessage) {
+	jid := incoming.SenderJID
+	senderName := incoming.SenderName
+	message := incoming.Summary()
+	
 	// Adicionado log detalhado para rastrear fluxo de mensagens
 	fmt.Printf("[DEBUG] Recebida mensagem de %s (%s): %s\n", senderName, jid, truncateString(message, 50))
 	
@@ -1174,6 +2725,12 @@ func handleIncomingMessage(jid string, senderName string, message string) {
 		}
 	}
 	
+	// Comandos /bot ajustam a política do grupo e não são respondidos pelo modelo
+	if incoming.IsGroup && whatsapp.IsGroupCommand(incoming.Text) {
+		go handleGroupCommand(incoming)
+		return
+	}
+	
 	// Verifica se o LLM está disponível
 	if llmClient == nil {
 		fmt.Println("[ERRO] Cliente LLM não inicializado, tentando reinicializar...")
@@ -1196,22 +2753,33 @@ func handleIncomingMessage(jid string, senderName string, message string) {
 	var msgID int64
 	var err error
 	
-	// Salva a mensagem no histórico
+	// Salva a mensagem no histórico. Mensagens de grupo ficam na conversa do grupo, separadas
+	// da conversa particular com o remetente
+	conversa := jid
+	if incoming.IsGroup {
+		conversa = incoming.ChatJID
+	}
 	if database != nil {
 		msg := db.Mensagem{
-			JID:       jid,
-			Nome:      senderName,
-			Conteudo:  message,
-			Timestamp: time.Now(),
-			Entrada:   true, // mensagem recebida
-			// Status:    "recebida", // Status inicial (se implementarmos este campo)
+			JID:        conversa,
+			Nome:       senderName,
+			Conteudo:   message,
+			Timestamp:  time.Now(),
+			Entrada:    true, // mensagem recebida
+			Tipo:       string(incoming.Type),
+			WhatsAppID: incoming.ID,
+			Status:     db.StatusRecebida,
+		}
+		if incoming.Attachment != nil {
+			msg.Anexo = incoming.Attachment.Path
+			msg.AnexoMime = incoming.Attachment.MimeType
 		}
 		
 		msgID, err = database.SalvarMensagem(msg)
 		
 		// Força atualização imediata da interface do histórico
-		fmt.Printf("[DEBUG] Atualizando interface para JID: %s após receber mensagem\n", jid)
-		atualizarInterfaceHistorico(jid)
+		fmt.Printf("[DEBUG] Atualizando interface para JID: %s após receber mensagem\n", conversa)
+		atualizarInterfaceHistorico(conversa)
 		
 		if err != nil {
 			fmt.Printf("[ERRO] Falha ao salvar mensagem no histórico: %v\n", err)
@@ -1223,19 +2791,132 @@ func handleIncomingMessage(jid string, senderName string, message string) {
 		fmt.Println("[ALERTA] Banco de dados não disponível, mensagem não será salva no histórico")
 	}
 	
+	respondToMessage(incoming, msgID, msgID)
+}
+
+// Gera e envia a resposta a uma mensagem recebida. msgID é o ID da mensagem no histórico,
+// ou 0 se ela não foi salva; nesse caso a resposta é salva como uma nova mensagem.
+// A mensagem ignorarID fica fora do histórico enviado ao modelo
+func respondToMessage(incoming *whatsapp.IncomingMessage, msgID, ignorarID int64) {
+	jid := incoming.SenderJID
+	senderName := incoming.SenderName
+	message := incoming.Summary()
+	var err error
+	
+	// Mídias ficam registradas no histórico, mas apenas mensagens de texto, de voz e imagens são respondidas
+	transcrever := incoming.Type == whatsapp.MessageTypeAudio && config.sttEnabled
+	verImagem := incoming.Type == whatsapp.MessageTypeImage && config.visionEnabled
+	if incoming.Type != whatsapp.MessageTypeText && !transcrever && !verImagem {
+		fmt.Printf("[INFO] Mensagem do tipo %s de %s registrada sem resposta\n", incoming.Type, senderName)
+		return
+	}
+	
+	// O ritmo de envio conta o tempo a partir do recebimento, incluindo o gasto na geração
+	recebida := time.Now()
+	
+	// Em grupos, a resposta vai para o grupo e menciona quem perguntou. O histórico também é o do
+	// grupo, para que a conversa particular com o remetente não vá parar em uma resposta pública
+	destino := jid
+	var mencoes []string
+	var persona string
+	if incoming.IsGroup {
+		destino = incoming.ChatJID
+		mencoes = []string{incoming.SenderJID}
+		persona = personaDoGrupo(incoming.ChatJID)
+	}
+	
 	// Processa a mensagem com o LLM escolhido em uma goroutine separada
 	go func() {
+		// A confirmação de leitura e a reação mostram ao contato que a mensagem está sendo processada
+		if config.readReceipts && client != nil && client.IsLoggedIn() {
+			if err := client.MarkRead(incoming); err != nil {
+				fmt.Printf("[ALERTA] Não foi possível confirmar a leitura: %v\n", err)
+			}
+		}
+		reagir(incoming, config.reactionProcessing)
+		
+		// Mensagens de voz seguem o fluxo normal com o texto transcrito
+		if transcrever {
+			updateStatusBar(fmt.Sprintf("Transcrevendo áudio de %s...", senderName))
+			transcricao, err := transcribeAudio(incoming)
+			if err != nil {
+				fmt.Printf("[ALERTA] Áudio de %s não transcrito: %v\n", senderName, err)
+				updateStatusBar(fmt.Sprintf("Áudio de %s não transcrito", senderName))
+				reagir(incoming, "")
+				return
+			}
+			fmt.Printf("[INFO] Áudio de %s transcrito: %s\n", senderName, truncateString(transcricao, 50))
+			
+			message = transcricao
+			if database != nil && msgID > 0 {
+				if err := database.AtualizarTranscricao(msgID, transcricao); err != nil {
+					fmt.Printf("[ERRO] Falha ao salvar transcrição no histórico: %v\n", err)
+				}
+				atualizarInterfaceHistorico(destino)
+			}
+		}
+		
+		// Imagens seguem para o modelo junto com a legenda, que faz o papel da pergunta
+		var imagens []llm.Image
+		if verImagem {
+			imagem, err := loadImage(incoming)
+			if err != nil {
+				fmt.Printf("[ALERTA] Imagem de %s não lida: %v\n", senderName, err)
+				updateStatusBar(fmt.Sprintf("Imagem de %s não lida", senderName))
+				return
+			}
+			imagens = []llm.Image{imagem}
+			message = incoming.Text
+		}
+		
 		// Informação para log e depuração
 		fmt.Printf("[INFO] Processando mensagem de %s (%s): %s\n", senderName, jid, message)
 		
 		// Atualiza o status visual se possível (indicador de processamento)
 		updateStatusBar(fmt.Sprintf("Processando mensagem de %s...", senderName))
 		
-		// Prepara os dados para o template
+		// Recupera as trocas anteriores da conversa para que o modelo mantenha o contexto
+		var historico []llm.Message
+		if database != nil {
+			builder := conversation.NewBuilder(database)
+			builder.MaxExchanges = config.historyExchanges
+			builder.TokenBudget = config.historyTokenBudget
+			historico, err = builder.Build(destino, ignorarID)
+			if err != nil {
+				fmt.Printf("[ALERTA] Erro ao montar histórico da conversa: %v. Continuando sem contexto.\n", err)
+				historico = nil
+			}
+		}
+		
+		// Respeita o orçamento mensal: pode trocar de provedor ou pausar as respostas
+		provider, permitido := providerWithinBudget()
+		if !permitido {
+			fmt.Printf("[INFO] Mensagem de %s não respondida: orçamento mensal excedido\n", senderName)
+			return
+		}
+		
+		// Modelos sem visão recebem um aviso no lugar da imagem e respondem apenas à legenda
+		if len(imagens) > 0 {
+			visao := llm.ImagesSupported(provider)
+			if !visao {
+				fmt.Printf("[INFO] O modelo atual não aceita imagens; respondendo apenas à legenda de %s\n", senderName)
+			}
+			if message == "" {
+				message = promptImagemSemLegenda(visao)
+			}
+		}
+		
+		// Prepara os dados para o template. Nos grupos o histórico já traz o nome de cada participante
+		nomeHistorico := senderName
+		if incoming.IsGroup {
+			nomeHistorico = ""
+		}
 		msgData := MessageData{
 			SenderName: senderName,
 			Message:    message,
 			JID:        jid,
+			History:    conversation.FormatHistory(historico, nomeHistorico),
+			Context:    knowledgeContext(message),
 		}
 		
 		// Processa os templates de prompt
@@ -1245,22 +2926,123 @@ func handleIncomingMessage(jid string, senderName string, message string) {
 			userPrompt = fmt.Sprintf("Mensagem de %s: %s\n\nResponda de forma concisa e útil.", senderName, message)
 		}
 		
-		systemPrompt, err := processTemplate(config.systemPromptTemplate, msgData)
+		// A persona do grupo, quando definida, substitui o system prompt padrão
+		systemTemplate := config.systemPromptTemplate
+		if persona != "" {
+			systemTemplate = persona
+		}
+		systemPrompt, err := processTemplate(systemTemplate, msgData)
 		if err != nil {
 			fmt.Printf("[ALERTA] Erro ao processar template de system prompt: %v. Usando fallback.\n", err)
 			systemPrompt = "Você é um assistente virtual via WhatsApp. Seja conciso e útil."
 		}
 		
+		// Templates que não usam {{.Context}} ainda recebem os trechos da base no system prompt
+		if msgData.Context != "" && !strings.Contains(systemTemplate, ".Context") && !strings.Contains(config.userPromptTemplate, ".Context") {
+			systemPrompt += "\n\nUse as informações abaixo, da base de conhecimento, para responder. " +
+				"Se a resposta não estiver nelas, diga que não sabe em vez de inventar.\n\n" + msgData.Context
+		}
+		
+		// Templates que usam {{.History}} já levam a conversa no prompt; os turnos nativos a repetiriam
+		if strings.Contains(systemTemplate, ".History") || strings.Contains(config.userPromptTemplate, ".History") {
+			historico = nil
+		}
+		
 		// Informa que a requisição para o LLM foi iniciada
 		fmt.Println("[INFO] Enviando requisição para o LLM...")
 		llmStartTime := time.Now()
 		
-		// Envia para o LLM processar
-		resposta, err := llmClient.GenerateCompletion(userPrompt, systemPrompt)
+		// Monta a solicitação com o histórico como turnos nativos da conversa. O contexto
+		// limita a duração da geração e é cancelado se o WhatsApp desconectar
+		ctx, cancel := newGenerationContext()
+		defer cancel()
+		
+		request := llm.Request{
+			Context:      ctx,
+			SystemPrompt: systemPrompt,
+			Messages:     append(historico, llm.Message{Role: llm.RoleUser, Content: userPrompt, Images: imagens}),
+			Options: &llm.GenerationOptions{
+				Temperature: config.llmTemperature,
+				MaxTokens:   config.llmMaxTokens,
+			},
+			User: jid,
+		}
+		generate := func(onChunk llm.StreamHandler) (string, error) {
+			request.OnChunk = onChunk
+			resp, err := provider.Generate(request)
+			if err != nil {
+				return "", err
+			}
+			if resp.Backend != "" {
+				fmt.Printf("[INFO] Resposta gerada pelo provedor %s\n", resp.Backend)
+			}
+			if resp.FinishReason == llm.FinishLength {
+				fmt.Printf("[ALERTA] Resposta interrompida pelo limite de %d tokens\n", config.llmMaxTokens)
+			}
+			return resp.Text, nil
+		}
+		
+		// O modo de resposta define se o contato recebe texto, voz ou ambos
+		enviarTexto, enviarVoz := replyModeFor(jid).Resolve(incoming.Type == whatsapp.MessageTypeAudio)
+		
+		// A partir daqui a resposta está em preparo; o histórico mostra o relógio até o envio
+		if database != nil && msgID > 0 {
+			if err := database.AtualizarStatus(msgID, db.StatusPendente); err != nil {
+				fmt.Printf("[ERRO] Falha ao atualizar status da mensagem: %v\n", err)
+			}
+		}
+		
+		// Envia para o LLM processar. A resposta em texto é entregue ao contato em frases
+		// enquanto o modelo ainda está gerando
+		var resposta, respID string
+		enviadaEmPartes := false
+		
+		// A resposta cita a mensagem recebida, para que o contato saiba a qual pergunta ela se refere.
+		// Só a primeira mensagem enviada traz a citação
+		var citada *whatsapp.IncomingMessage
+		if config.quoteReplies {
+			citada = incoming
+		}
+		citar := func(enviadaID string) *whatsapp.IncomingMessage {
+			if enviadaID != "" {
+				return nil
+			}
+			return citada
+		}
+		mencionar := func(enviadaID string) []string {
+			if enviadaID != "" {
+				return nil
+			}
+			return mencoes
+		}
+		if enviarTexto && client != nil && client.IsLoggedIn() {
+			// O ID da primeira parte é salvo assim que ela sai, para que os recibos que chegam durante a
+			// geração encontrem a mensagem no histórico
+			salvarID := func(id string) {
+				if database != nil && msgID > 0 {
+					if err := database.AtualizarRespostaWhatsAppID(msgID, id); err != nil {
+						fmt.Printf("[ERRO] Falha ao salvar o ID da resposta no histórico: %v\n", err)
+					}
+				}
+			}
+			resposta, respID, err = streamResponse(destino, citada, mencoes, recebida, salvarID, generate)
+			enviadaEmPartes = err == nil
+		} else {
+			// Sem streaming, o "digitando..." fica ativo durante toda a geração
+			if client != nil && client.IsLoggedIn() {
+				client.SendTyping(destino, true)
+			}
+			resposta, err = generate(nil)
+		}
 		
 		// Calcula o tempo de resposta
 		llmDuration := time.Since(llmStartTime)
 		
+		// Mostra o uso de tokens registrado por esta resposta
+		if gerenciadorTokens != nil {
+			gerenciadorTokens.Atualizar()
+		}
+		
 		if err != nil {
 			// Tratamento de erro melhorado
 			fmt.Printf("[ERRO] Falha ao gerar resposta via LLM após %.2f segundos: %v\n", 
@@ -1268,11 +3050,15 @@ func handleIncomingMessage(jid string, senderName string, message string) {
 			
 			// Atualiza interface se houver erro
 			updateStatusBar(fmt.Sprintf("Erro ao processar mensagem de %s", senderName))
+			if database != nil && msgID > 0 {
+				database.AvancarStatus(msgID, db.StatusFalhou)
+			}
 			
 			// Envia mensagem de erro para o usuário do WhatsApp
 			errorMsg := "Desculpe, tive um problema ao processar sua mensagem. Por favor, tente novamente mais tarde."
 			if client != nil && client.IsLoggedIn() {
-				err := client.SendMessage(jid, errorMsg)
+				reagir(incoming, "")
+				_, err := client.SendTextMentioning(destino, errorMsg, citada, mencoes)
 				if err != nil {
 					fmt.Printf("[ERRO] Falha ao enviar mensagem de erro: %v\n", err)
 				}
@@ -1296,10 +3082,37 @@ func handleIncomingMessage(jid string, senderName string, message string) {
 		
 		// Envia a resposta de volta pelo WhatsApp
 		if client != nil && client.IsLoggedIn() {
-			fmt.Printf("[DEBUG] Enviando resposta para %s: %s\n", jid, truncateString(resposta, 50))
-			err := client.SendMessage(jid, resposta)
+			var err error
+			pacingCtx, cancelPacing := newPacingContext()
+			defer cancelPacing()
+			if enviarVoz {
+				paceReply(pacingCtx, destino, resposta, recebida)
+				fmt.Printf("[DEBUG] Enviando resposta em voz para %s\n", destino)
+				var id string
+				if id, err = sendVoiceReply(destino, resposta, citar(respID)); respID == "" {
+					respID = id
+				}
+				if err != nil {
+					fmt.Printf("[ALERTA] Falha ao enviar resposta em voz: %v\n", err)
+					// Sem o áudio, o contato ainda recebe a resposta em texto
+					if !enviarTexto {
+						enviarTexto, err = true, nil
+					}
+				}
+			}
+			if enviarTexto && !enviadaEmPartes {
+				if !enviarVoz {
+					paceReply(pacingCtx, destino, resposta, recebida)
+				}
+				fmt.Printf("[DEBUG] Enviando resposta para %s: %s\n", destino, truncateString(resposta, 50))
+				var id string
+				if id, err = client.SendTextMentioning(destino, resposta, citar(respID), mencionar(respID)); respID == "" {
+					respID = id
+				}
+			}
 			if err != nil {
 				fmt.Printf("[ERRO] Falha ao enviar mensagem: %v\n", err)
+				reagir(incoming, "")
 				
 				// Notifica o usuário via interface
 				go func() {
@@ -1310,29 +3123,45 @@ func handleIncomingMessage(jid string, senderName string, message string) {
 			} else {
 				fmt.Printf("[INFO] Resposta enviada com sucesso para %s\n", senderName)
 				updateStatusBar(fmt.Sprintf("Resposta enviada para %s", senderName))
+				reagir(incoming, config.reactionDone)
 			}
 			
-			// Salva a resposta no histórico
+			// Salva a resposta no histórico. Os recibos do WhatsApp avançam o status para entregue e lida
+			status := db.StatusEnviada
+			if err != nil {
+				status = db.StatusFalhou
+			}
 			if database != nil {
 				if msgID > 0 {
 					// Atualiza a resposta da mensagem já salva
 					if err := database.AtualizarResposta(msgID, resposta); err != nil {
 						fmt.Printf("[ERRO] Falha ao atualizar resposta no histórico: %v\n", err)
 					} else {
+						if respID != "" {
+							if err := database.AtualizarRespostaWhatsAppID(msgID, respID); err != nil {
+								fmt.Printf("[ERRO] Falha ao salvar o ID da resposta no histórico: %v\n", err)
+							}
+						}
+						// Os recibos das partes já entregues prevalecem sobre o fim do envio
+						if _, err := database.AvancarStatus(msgID, status); err != nil {
+							fmt.Printf("[ERRO] Falha ao atualizar status da resposta: %v\n", err)
+						}
 						fmt.Println("[INFO] Resposta atualizada no histórico com sucesso")
 						// Atualiza a interface novamente para mostrar a resposta
-						atualizarInterfaceHistorico(jid)
+						atualizarInterfaceHistorico(destino)
 					}
 				} else {
 					// Salva como nova mensagem de saída
 					msg := db.Mensagem{
-						JID:       jid,
+						JID:       destino,
 						Nome:      senderName,
 						Conteudo:  message,
 						Resposta:  resposta,
 						Timestamp: time.Now(),
 						Entrada:   false, // mensagem enviada
 					}
+					msg.RespostaWhatsAppID = respID
+					msg.Status = status
 					
 					respID, err := database.SalvarMensagem(msg)
 					if err != nil {
@@ -1340,7 +3169,7 @@ func handleIncomingMessage(jid string, senderName string, message string) {
 					} else {
 						fmt.Printf("[INFO] Resposta salva no histórico com ID: %d\n", respID)
 						// Atualiza a interface para mostrar a nova mensagem
-						atualizarInterfaceHistorico(jid)
+						atualizarInterfaceHistorico(destino)
 					}
 				}
 			}
@@ -1358,3 +3187,131 @@ func handleIncomingMessage(jid string, senderName string, message string) {
 		}
 	}()
 }
+
+// Contexto compartilhado pelas gerações em andamento, cancelado quando o WhatsApp desconecta
+var (
+	generationCtx    context.Context
+	cancelGeneration context.CancelFunc
+	generationMu     sync.Mutex
+)
+
+// Cria o contexto de uma geração, limitado pelo tempo configurado
+func newGenerationContext() (context.Context, context.CancelFunc) {
+	generationMu.Lock()
+	defer generationMu.Unlock()
+	
+	if generationCtx == nil {
+		generationCtx, cancelGeneration = context.WithCancel(context.Background())
+	}
+	
+	timeout := time.Duration(config.llmTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		return context.WithCancel(generationCtx)
+	}
+	return context.WithTimeout(generationCtx, timeout)
+}
+
+// Cria o contexto do ritmo de envio, sem o limite de tempo da geração mas também cancelado
+// quando o WhatsApp desconecta
+func newPacingContext() (context.Context, context.CancelFunc) {
+	generationMu.Lock()
+	defer generationMu.Unlock()
+	
+	if generationCtx == nil {
+		generationCtx, cancelGeneration = context.WithCancel(context.Background())
+	}
+	return context.WithCancel(generationCtx)
+}
+
+// Cancela todas as gerações em andamento
+func cancelPendingGenerations() {
+	generationMu.Lock()
+	defer generationMu.Unlock()
+	
+	if cancelGeneration != nil {
+		cancelGeneration()
+		generationCtx = nil
+		cancelGeneration = nil
+	}
+}
+
+// Gera a resposta em streaming, enviando cada frase ao contato assim que ela fica pronta
+// e mantendo o indicador "digitando..." enquanto o modelo gera o restante.
+// As frases são enviadas em uma goroutine própria, para que o ritmo de envio não atrase a geração.
+// Apenas a primeira parte cita a mensagem recebida e menciona os contatos de mentions (nas respostas em grupos);
+// o ID dela é passado a onSent assim que ela é enviada e retornado junto com a resposta
+func streamResponse(jid string, quoted *whatsapp.IncomingMessage, mentions []string, recebida time.Time, onSent func(id string), generate func(onChunk llm.StreamHandler) (string, error)) (string, string, error) {
+	splitter := llm.NewSentenceSplitter()
+	var sendErr error
+	var primeiroID string
+
+	ctx, cancel := newPacingContext()
+	defer cancel()
+
+	partes := make(chan string, 64)
+	enviadas := make(chan struct{})
+	go func() {
+		defer close(enviadas)
+		ultimoEnvio := recebida
+		for parte := range partes {
+			if sendErr != nil {
+				continue
+			}
+			paceReply(ctx, jid, parte, ultimoEnvio)
+			fmt.Printf("[DEBUG] Enviando parte da resposta para %s: %s\n", jid, truncateString(parte, 50))
+			id, err := client.SendTextMentioning(jid, parte, quoted, mentions)
+			if err != nil {
+				sendErr = err
+				continue
+			}
+			if primeiroID == "" {
+				primeiroID = id
+				if onSent != nil {
+					onSent(id)
+				}
+			}
+			quoted, mentions = nil, nil
+			ultimoEnvio = time.Now()
+			// Enviar uma mensagem encerra o "digitando...", então o estado é renovado
+			client.SendTyping(jid, true)
+		}
+	}()
+
+	if err := client.SendTyping(jid, true); err != nil {
+		fmt.Printf("[ALERTA] Não foi possível enviar o estado 'digitando': %v\n", err)
+	}
+	defer client.SendTyping(jid, false)
+
+	resposta, err := generate(func(chunk string) {
+		for _, parte := range splitter.Push(chunk) {
+			partes <- parte
+		}
+	})
+	if err == nil {
+		if resto := splitter.Flush(); resto != "" {
+			partes <- resto
+		}
+	}
+	close(partes)
+	<-enviadas
+
+	if err != nil {
+		return resposta, primeiroID, err
+	}
+	if sendErr != nil {
+		return resposta, primeiroID, fmt.Errorf("erro ao enviar resposta: %w", sendErr)
+	}
+
+	return resposta, primeiroID, nil
+}
+
+// Aguarda o tempo de digitação do texto, descontado o tempo decorrido desde a mensagem anterior,
+// mantendo o "digitando..." no chat. Não faz nada se o ritmo de envio estiver desativado
+func paceReply(ctx context.Context, jid, texto string, desde time.Time) {
+	if !config.pacingEnabled || client == nil {
+		return
+	}
+	if err := client.Pace(ctx, jid, texto, config.pacing, time.Since(desde)); err != nil && ctx.Err() == nil {
+		fmt.Printf("[ALERTA] Não foi possível simular a digitação: %v\n", err)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/peder/whatszapme/internal/api"
+	"github.com/peder/whatszapme/internal/whatsapp"
+)
+
+// apiService expõe o cliente WhatsApp à API REST e publica no QRHub o andamento do login,
+// para que um servidor sem tela possa ser pareado pelo navegador
+type apiService struct {
+	client *whatsapp.Client
+	qr     *api.QRHub
+	server *api.APIServer
+
+	mu    sync.Mutex
+	state whatsapp.ConnectionState
+}
+
+// apiOptions são as opções de linha de comando da API REST
+type apiOptions struct {
+	host  string
+	port  int
+	token string
+}
+
+// apiFlags registra as opções da API REST no conjunto de flags
+func apiFlags(flags *flag.FlagSet) *apiOptions {
+	opts := &apiOptions{}
+	flags.IntVar(&opts.port, "api-port", 0, "Porta da API REST local, que também exibe o QR Code de login em /api/whatsapp/qr (0 desativa)")
+	flags.StringVar(&opts.host, "api-host", "127.0.0.1", "Endereço da API REST; use 0.0.0.0 para aceitar conexões de outras máquinas")
+	flags.StringVar(&opts.token, "api-token", os.Getenv("WHATSZAPME_API_TOKEN"), "Token exigido pela API REST (padrão: WHATSZAPME_API_TOKEN ou um token aleatório)")
+	return opts
+}
+
+// startAPI inicia a API REST, que exige o token em todas as rotas. Os QR Codes de login passam a
+// ser publicados em /api/whatsapp/qr.png e no stream, além de continuarem no terminal
+func startAPI(opts *apiOptions, client *whatsapp.Client) (*apiService, error) {
+	token := opts.token
+	if token == "" {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			return nil, fmt.Errorf("erro ao gerar token da API: %w", err)
+		}
+		token = hex.EncodeToString(random)
+	}
+
+	// Sem CORS: a página do QR Code é servida pela própria API, e outras origens não devem usá-la
+	config := api.DefaultAPIConfig()
+	config.Host = opts.host
+	config.Port = opts.port
+	server := api.NewAPIServer(config)
+	server.RegisterMiddleware(api.LoggingMiddleware())
+	server.RegisterMiddleware(api.AuthMiddleware(token))
+
+	service := &apiService{client: client, server: server, state: whatsapp.StateDisconnected}
+	handler := api.NewWhatsAppHandler(service)
+	handler.RegisterRoutes(server)
+	service.qr = handler.QR()
+
+	terminal := whatsapp.NewTerminalQR(os.Stdout)
+	client.SetQRCallback(func(code string) {
+		terminal.Show(code)
+		service.qr.PublishCode(code)
+	})
+
+	if err := server.Start(); err != nil {
+		return nil, fmt.Errorf("erro ao iniciar API: %w", err)
+	}
+	host := opts.host
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(opts.port))
+	fmt.Printf("QR Code de login disponível em http://%s/api/whatsapp/qr?token=%s\n", address, token)
+	if opts.token == "" {
+		fmt.Println("Nas demais rotas, envie o cabeçalho \"Authorization: Bearer <token>\" com o token acima")
+	}
+	return service, nil
+}
+
+// stateChanged registra o estado da conexão e publica no stream o fim do login
+func (s *apiService) stateChanged(state whatsapp.ConnectionState, err error) {
+	s.mu.Lock()
+	s.state = state
+	s.mu.Unlock()
+
+	switch state {
+	case whatsapp.StateLoggedIn:
+		s.qr.Publish(api.QREvent{State: api.QRStateSuccess})
+	case whatsapp.StateError:
+		evt := api.QREvent{State: api.QRStateError}
+		if err != nil {
+			evt.Error = err.Error()
+		}
+		s.qr.Publish(evt)
+	}
+}
+
+// stop encerra a API REST
+func (s *apiService) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Stop(ctx); err != nil {
+		log.Printf("Erro ao parar API: %v", err)
+	}
+}
+
+func (s *apiService) IsConnected() bool {
+	return s.client.IsLoggedIn()
+}
+
+func (s *apiService) GetStatus() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return string(s.state)
+}
+
+// GetQRCode não tem nada a acrescentar ao QRHub, onde os códigos já são publicados
+func (s *apiService) GetQRCode() (string, error) {
+	return "", nil
+}
+
+// Connect inicia o login em segundo plano; o QR Code e o resultado chegam pelo QRHub
+func (s *apiService) Connect() error {
+	go func() {
+		if err := s.client.Login(); err != nil {
+			log.Printf("Erro ao fazer login: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *apiService) Disconnect() error {
+	s.client.Disconnect()
+	return nil
+}
+
+func (s *apiService) PairPhone(phone string) (string, error) {
+	return s.client.PairPhone(phone)
+}
+
+func (s *apiService) SendTextMessage(to, message string) (string, error) {
+	return s.client.SendText(to, message, nil)
+}
+
+func (s *apiService) SendImageMessage(to string, data []byte, mimeType, caption string) (string, error) {
+	return s.client.SendImage(to, data, mimeType, caption)
+}
+
+func (s *apiService) SendDocumentMessage(to string, data []byte, mimeType, fileName, caption string) (string, error) {
+	return s.client.SendDocument(to, data, mimeType, fileName, caption)
+}
+
+func (s *apiService) SendAudioMessage(to string, data []byte, mimeType string, ptt bool) (string, error) {
+	return s.client.SendAudio(to, data, mimeType, 0, ptt)
+}
+
+func (s *apiService) SendLocationMessage(to string, latitude, longitude float64, name, address string) (string, error) {
+	return s.client.SendLocation(to, latitude, longitude, name, address)
+}
+
+func (s *apiService) SendContactMessage(to, displayName, vcard string) (string, error) {
+	return s.client.SendContact(to, displayName, vcard)
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/peder/whatszapme/internal/config"
 	"github.com/peder/whatszapme/internal/llm"
+	"github.com/peder/whatszapme/internal/token"
 	"github.com/peder/whatszapme/internal/whatsapp"
 )
 
@@ -32,8 +36,17 @@ func main() {
 		log.Fatalf("Erro ao criar diretório de configuração: %v", err)
 	}
 
+	// "whatszapme login" apenas conecta o aparelho, sem iniciar o atendimento
+	if len(os.Args) > 1 && os.Args[1] == "login" {
+		if err := login(configDir, os.Args[2:]); err != nil {
+			log.Fatalf("Erro ao fazer login: %v", err)
+		}
+		return
+	}
+
 	// Parâmetros de linha de comando
 	configPath := flag.String("config", filepath.Join(configDir, "config.json"), "Caminho para o arquivo de configuração")
+	apiOpts := apiFlags(flag.CommandLine)
 	flag.Parse()
 
 	// Carrega configuração
@@ -43,29 +56,70 @@ func main() {
 	}
 
 	// Inicializa cliente WhatsApp
+	var service *apiService
 	config := &whatsapp.ClientConfig{
 		DBPath: filepath.Join(configDir, "store.db"),
 		LogLevel: "INFO",
 		AutoReconnect: true,
+		MediaDir: filepath.Join(configDir, "midia"),
+		OnQRCode: whatsapp.NewTerminalQR(os.Stdout).Show,
+		OnStateChange: func(state whatsapp.ConnectionState, err error) {
+			if service != nil {
+				service.stateChanged(state, err)
+			}
+		},
 	}
 	whatsappClient, err := whatsapp.NewClient(config)
 	if err != nil {
 		log.Fatalf("Erro ao criar cliente WhatsApp: %v", err)
 	}
 
+	// A API permite acompanhar o login e enviar mensagens de outra máquina
+	if apiOpts.port != 0 {
+		service, err = startAPI(apiOpts, whatsappClient)
+		if err != nil {
+			log.Fatalf("Erro ao iniciar API: %v", err)
+		}
+		defer service.stop()
+	}
+
 	// Inicializa provedor LLM
-	llmProvider, err := llm.Factory(cfg.LLMProvider, map[string]string{
+	providerConfig := map[string]string{
 		"ollama_url":   cfg.OllamaURL,
 		"ollama_model": cfg.OllamaModel,
 		"api_key":      cfg.APIKeys[cfg.LLMProvider],
-	})
+	}
+	if cfg.LLMProvider == "openai-compatible" {
+		var headers []string
+		for name, value := range cfg.OpenAICompatible.Headers {
+			headers = append(headers, name+": "+value)
+		}
+		providerConfig["base_url"] = cfg.OpenAICompatible.BaseURL
+		providerConfig["model"] = cfg.OpenAICompatible.Model
+		providerConfig["headers"] = strings.Join(headers, "\n")
+	}
+	llmProvider, err := llm.Factory(cfg.LLMProvider, providerConfig)
 	if err != nil {
 		log.Fatalf("Erro ao criar provedor LLM: %v", err)
 	}
 
+	// Registra os tokens usados em cada resposta
+	tokenCounter, err := token.NewCounter(configDir)
+	if err != nil {
+		log.Printf("Erro ao inicializar contador de tokens: %v", err)
+	} else {
+		llmProvider = llm.NewMeteredProvider(llmProvider, cfg.LLMProvider, "", tokenCounter)
+	}
+
 	// Define o handler de mensagens
-	whatsappClient.SetMessageHandler(func(jid, sender, message string) {
+	whatsappClient.SetMessageHandler(func(msg *whatsapp.IncomingMessage) {
+		jid, sender, message := msg.SenderJID, msg.SenderName, msg.Summary()
 		log.Printf("Mensagem recebida de %s: %s", sender, message)
+
+		// Por enquanto só as mensagens de texto são respondidas
+		if msg.Type != whatsapp.MessageTypeText {
+			return
+		}
 		
 		// Gera resposta utilizando o LLM
 		response, err := llmProvider.GenerateCompletion(message, systemPrompt)
@@ -108,3 +162,88 @@ func main() {
 	whatsappClient.Close()
 	fmt.Println("Sessão encerrada com sucesso!")
 }
+
+// login conecta o aparelho ao WhatsApp pelo código de pareamento (--phone) ou pelo QR Code
+// e encerra assim que o login é concluído, mantendo a sessão para as próximas execuções
+func login(configDir string, args []string) error {
+	flags := flag.NewFlagSet("login", flag.ExitOnError)
+	phone := flags.String("phone", "", "Número no formato internacional (ex.: +5511999999999) para entrar com o código de pareamento em vez do QR Code")
+	timeout := flags.Duration("timeout", 3*time.Minute, "Tempo máximo para concluir o login")
+	apiOpts := apiFlags(flags)
+	flags.Parse(args)
+
+	// O resultado do pareamento chega pelo callback de estado
+	done := make(chan error, 1)
+	finish := func(err error) {
+		select {
+		case done <- err:
+		default:
+		}
+	}
+	var service *apiService
+	config := &whatsapp.ClientConfig{
+		DBPath:   filepath.Join(configDir, "store.db"),
+		LogLevel: "WARN",
+		OnQRCode: whatsapp.NewTerminalQR(os.Stdout).Show,
+		OnStateChange: func(state whatsapp.ConnectionState, err error) {
+			if service != nil {
+				service.stateChanged(state, err)
+			}
+			switch state {
+			case whatsapp.StateConnecting:
+				fmt.Println("Conectando ao WhatsApp...")
+			case whatsapp.StatePairing:
+				fmt.Println("Aguardando a confirmação no celular...")
+			case whatsapp.StateLoggedIn, whatsapp.StateConnected:
+				// Após o pareamento, o WhatsApp reconecta já com a sessão
+				finish(nil)
+			case whatsapp.StateError:
+				finish(err)
+			}
+		},
+	}
+	whatsappClient, err := whatsapp.NewClient(config)
+	if err != nil {
+		return fmt.Errorf("erro ao criar cliente WhatsApp: %w", err)
+	}
+	defer whatsappClient.Close()
+
+	if apiOpts.port != 0 {
+		service, err = startAPI(apiOpts, whatsappClient)
+		if err != nil {
+			return err
+		}
+		defer service.stop()
+	}
+
+	if *phone == "" {
+		fmt.Println("Realizando login via QR Code...")
+		if err := whatsappClient.Login(); err != nil {
+			return err
+		}
+	} else {
+		code, err := whatsappClient.PairPhone(*phone)
+		if errors.Is(err, whatsapp.ErrAlreadyLoggedIn) {
+			fmt.Println("Este aparelho já está conectado ao WhatsApp.")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nCódigo de pareamento: %s\n\n", code)
+		fmt.Println("No celular, abra o WhatsApp > Aparelhos conectados > Conectar aparelho >")
+		fmt.Println("Conectar com número de telefone e digite o código acima.")
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	case <-time.After(*timeout):
+		return whatsapp.ErrLoginTimeout
+	}
+
+	fmt.Println("Login concluído! Execute whatszapme para iniciar o atendimento.")
+	return nil
+}
@@ -23,9 +23,31 @@ func (s *ExampleSyncStore) GetRespondToGroupsConfig(respondToGroups, respondOnly
 }
 
 // SincronizarContato implementa a interface SyncStore
-func (s *ExampleSyncStore) SincronizarContato(jid, nome, telefone string) error {
+func (s *ExampleSyncStore) SincronizarContato(contato whatsapp.Contact) error {
 	// Aqui você implementaria a lógica para salvar o contato no banco de dados
-	fmt.Printf("Sincronizando contato: %s (%s) - %s\n", nome, telefone, jid)
+	fmt.Printf("Sincronizando contato: %s (%s) - %s\n", contato.DisplayName(), contato.Phone, contato.JID)
+	return nil
+}
+
+// SincronizarGrupo implementa a interface SyncStore
+func (s *ExampleSyncStore) SincronizarGrupo(grupo whatsapp.Group) error {
+	fmt.Printf("Sincronizando grupo: %s (%d participantes) - %s\n", grupo.Name, len(grupo.Participants), grupo.JID)
+	return nil
+}
+
+// GetGroupPolicy implementa a interface SyncStore; sem política própria, os grupos seguem a configuração geral
+func (s *ExampleSyncStore) GetGroupPolicy(groupJID string) (*whatsapp.GroupPolicy, error) {
+	return nil, nil
+}
+
+// GetModerationRules implementa a interface SyncStore; os grupos do exemplo não são moderados
+func (s *ExampleSyncStore) GetModerationRules(groupJID string) (*whatsapp.ModerationRules, error) {
+	return nil, nil
+}
+
+// RegistrarModeracao implementa a interface SyncStore
+func (s *ExampleSyncStore) RegistrarModeracao(evento whatsapp.ModerationEvent) error {
+	fmt.Printf("Moderação em %s: %s %s (%s)\n", evento.GroupJID, evento.Action, evento.ParticipantJID, evento.Reason)
 	return nil
 }
 
@@ -71,12 +93,15 @@ func useRefactoredClient(dbPath string) {
 		fmt.Printf("Estado da conexão alterado: %s\n", state)
 	})
 
-	client.SetMessageHandler(func(jid, sender, message string) {
-		fmt.Printf("Mensagem recebida de %s: %s\n", sender, message)
+	client.SetMessageHandler(func(msg *whatsapp.IncomingMessage) {
+		fmt.Printf("Mensagem recebida de %s: %s\n", msg.SenderName, msg.Summary())
+		if msg.Attachment != nil && msg.Attachment.Path != "" {
+			fmt.Printf("Anexo salvo em %s\n", msg.Attachment.Path)
+		}
 
 		// Envia uma resposta
-		response := fmt.Sprintf("Recebi sua mensagem: %s", message)
-		err := client.SendMessage(jid, response)
+		response := fmt.Sprintf("Recebi sua mensagem: %s", msg.Summary())
+		err := client.SendMessage(msg.SenderJID, response)
 		if err != nil {
 			fmt.Printf("Erro ao enviar resposta: %v\n", err)
 		}
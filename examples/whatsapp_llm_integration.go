@@ -47,8 +47,14 @@ func (i *LLMIntegration) SetPrompts(systemPrompt, userPrompt string) {
 }
 
 // HandleMessage processa mensagens recebidas do WhatsApp
-func (i *LLMIntegration) HandleMessage(jid, sender, message string) {
-	fmt.Printf("Mensagem recebida de %s: %s\n", sender, message)
+func (i *LLMIntegration) HandleMessage(msg *whatsapp.IncomingMessage) {
+	jid, sender, message := msg.SenderJID, msg.SenderName, msg.Text
+	fmt.Printf("Mensagem recebida de %s: %s\n", sender, msg.Summary())
+
+	// Apenas mensagens de texto são enviadas ao LLM
+	if msg.Type != whatsapp.MessageTypeText {
+		return
+	}
 
 	// Prepara o prompt para o LLM
 	prompt := strings.ReplaceAll(i.userPrompt, "{message}", message)
@@ -135,8 +141,30 @@ func (s *LLMExampleSyncStore) GetRespondToGroupsConfig(respondToGroups, respondO
 }
 
 // SincronizarContato implementa a interface SyncStore
-func (s *LLMExampleSyncStore) SincronizarContato(jid, nome, telefone string) error {
-	fmt.Printf("Sincronizando contato: %s (%s) - %s\n", nome, telefone, jid)
+func (s *LLMExampleSyncStore) SincronizarContato(contato whatsapp.Contact) error {
+	fmt.Printf("Sincronizando contato: %s (%s) - %s\n", contato.DisplayName(), contato.Phone, contato.JID)
+	return nil
+}
+
+// SincronizarGrupo implementa a interface SyncStore
+func (s *LLMExampleSyncStore) SincronizarGrupo(grupo whatsapp.Group) error {
+	fmt.Printf("Sincronizando grupo: %s (%d participantes) - %s\n", grupo.Name, len(grupo.Participants), grupo.JID)
+	return nil
+}
+
+// GetGroupPolicy implementa a interface SyncStore; sem política própria, os grupos seguem a configuração geral
+func (s *LLMExampleSyncStore) GetGroupPolicy(groupJID string) (*whatsapp.GroupPolicy, error) {
+	return nil, nil
+}
+
+// GetModerationRules implementa a interface SyncStore; os grupos do exemplo não são moderados
+func (s *LLMExampleSyncStore) GetModerationRules(groupJID string) (*whatsapp.ModerationRules, error) {
+	return nil, nil
+}
+
+// RegistrarModeracao implementa a interface SyncStore
+func (s *LLMExampleSyncStore) RegistrarModeracao(evento whatsapp.ModerationEvent) error {
+	fmt.Printf("Moderação em %s: %s %s (%s)\n", evento.GroupJID, evento.Action, evento.ParticipantJID, evento.Reason)
 	return nil
 }
 
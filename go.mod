@@ -13,6 +13,7 @@ require (
 	go.mau.fi/whatsmeow v0.0.0-20250717084138-aecc878ab213
 	golang.org/x/oauth2 v0.30.0
 	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -60,6 +61,5 @@ require (
 	golang.org/x/net v0.42.0 // indirect
 	golang.org/x/sys v0.34.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	rsc.io/qr v0.2.0 // indirect
 )
@@ -1,8 +1,13 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -19,11 +24,43 @@ type StatusResponse struct {
 	Connected bool   `json:"connected"`
 }
 
-// MessageRequest é a requisição para enviar uma mensagem
+// MaxMediaUploadSize é o tamanho máximo de um anexo enviado pela API
+const MaxMediaUploadSize = 16 << 20
+
+// Tipos de mensagem aceitos pelo endpoint de envio
+const (
+	MessageTypeText     = "text"
+	MessageTypeImage    = "image"
+	MessageTypeDocument = "document"
+	MessageTypeAudio    = "audio"
+	MessageTypeLocation = "location"
+	MessageTypeContact  = "contact"
+)
+
+// MessageRequest é a requisição para enviar uma mensagem. Em JSON, a mídia vai em base64
+// no campo media; em multipart/form-data, no arquivo do campo file
 type MessageRequest struct {
 	To      string `json:"to"`
 	Message string `json:"message"`
 	Type    string `json:"type"`
+
+	// Mídia (image, document, audio). Em image e document, message é a legenda
+	Media    string `json:"media,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	PTT      bool   `json:"ptt,omitempty"`
+
+	// Localização (location). As coordenadas são ponteiros para distinguir 0 de ausentes
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Address   string   `json:"address,omitempty"`
+
+	// Contato (contact): vcard completo ou nome e telefone para montar um
+	Name  string `json:"name,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	VCard string `json:"vcard,omitempty"`
+
+	data []byte
 }
 
 // MessageResponse é a resposta para o envio de mensagem
@@ -36,13 +73,20 @@ type MessageResponse struct {
 // WhatsAppHandler lida com endpoints relacionados ao WhatsApp
 type WhatsAppHandler struct {
 	whatsappService WhatsAppService
+	qr              *QRHub
 }
 
 // WhatsAppService é uma interface para o serviço de WhatsApp
 type WhatsAppService interface {
 	IsConnected() bool
 	SendTextMessage(to, message string) (string, error)
+	SendImageMessage(to string, data []byte, mimeType, caption string) (string, error)
+	SendDocumentMessage(to string, data []byte, mimeType, fileName, caption string) (string, error)
+	SendAudioMessage(to string, data []byte, mimeType string, ptt bool) (string, error)
+	SendLocationMessage(to string, latitude, longitude float64, name, address string) (string, error)
+	SendContactMessage(to, displayName, vcard string) (string, error)
 	GetQRCode() (string, error)
+	PairPhone(phone string) (string, error)
 	Disconnect() error
 	Connect() error
 	GetStatus() string
@@ -52,16 +96,27 @@ type WhatsAppService interface {
 func NewWhatsAppHandler(service WhatsAppService) *WhatsAppHandler {
 	return &WhatsAppHandler{
 		whatsappService: service,
+		qr:              NewQRHub(),
 	}
 }
 
+// QR retorna o QRHub em que devem ser publicados os QR Codes de login e o fim do pareamento
+func (h *WhatsAppHandler) QR() *QRHub {
+	return h.qr
+}
+
 // RegisterRoutes registra as rotas do handler
 func (h *WhatsAppHandler) RegisterRoutes(server *APIServer) {
 	server.RegisterHandler("GET", "/api/whatsapp/status", h.GetStatus)
 	server.RegisterHandler("GET", "/api/whatsapp/qrcode", h.GetQRCode)
+	server.RegisterHandler("GET", "/api/whatsapp/qr", h.GetQRPage)
+	server.RegisterHandler("GET", "/api/whatsapp/qr.png", h.GetQRImage)
+	server.RegisterHandler("GET", "/api/whatsapp/qr/stream", h.StreamQR)
 	server.RegisterHandler("POST", "/api/whatsapp/connect", h.Connect)
+	server.RegisterHandler("POST", "/api/whatsapp/pair", h.PairPhone)
 	server.RegisterHandler("POST", "/api/whatsapp/disconnect", h.Disconnect)
 	server.RegisterHandler("POST", "/api/whatsapp/message", h.SendMessage)
+	server.RegisterHandler("POST", "/api/whatsapp/send", h.SendMessage)
 }
 
 // GetStatus retorna o status da conexão com o WhatsApp
@@ -77,7 +132,7 @@ func (h *WhatsAppHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
 
 // GetQRCode retorna o QR Code para autenticação
 func (h *WhatsAppHandler) GetQRCode(w http.ResponseWriter, r *http.Request) {
-	qrCode, err := h.whatsappService.GetQRCode()
+	qrCode, err := h.currentQRCode()
 	if err != nil {
 		RespondError(w, http.StatusInternalServerError, "Erro ao obter QR Code: "+err.Error())
 		return
@@ -102,6 +157,38 @@ func (h *WhatsAppHandler) Connect(w http.ResponseWriter, r *http.Request) {
 	RespondJSON(w, http.StatusOK, map[string]bool{"success": true})
 }
 
+// PairRequest é a requisição para login pelo código de pareamento
+type PairRequest struct {
+	Phone string `json:"phone"` // número no formato internacional, como +55 11 99999-9999
+}
+
+// PairPhone inicia o login pelo código de pareamento e retorna o código a ser digitado no celular,
+// em Aparelhos conectados > Conectar com número de telefone. O resultado do pareamento aparece em /api/whatsapp/status
+func (h *WhatsAppHandler) PairPhone(w http.ResponseWriter, r *http.Request) {
+	if h.whatsappService.IsConnected() {
+		RespondError(w, http.StatusBadRequest, "Já está conectado ao WhatsApp")
+		return
+	}
+	
+	var req PairRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		RespondError(w, http.StatusBadRequest, "Requisição inválida: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Phone) == "" {
+		RespondError(w, http.StatusBadRequest, "Informe o número de telefone em phone")
+		return
+	}
+	
+	code, err := h.whatsappService.PairPhone(req.Phone)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Erro ao gerar código de pareamento: "+err.Error())
+		return
+	}
+	
+	RespondJSON(w, http.StatusOK, map[string]string{"code": code})
+}
+
 // Disconnect encerra a conexão com o WhatsApp
 func (h *WhatsAppHandler) Disconnect(w http.ResponseWriter, r *http.Request) {
 	if !h.whatsappService.IsConnected() {
@@ -118,33 +205,41 @@ func (h *WhatsAppHandler) Disconnect(w http.ResponseWriter, r *http.Request) {
 	RespondJSON(w, http.StatusOK, map[string]bool{"success": true})
 }
 
-// SendMessage envia uma mensagem pelo WhatsApp
+// SendMessage envia uma mensagem pelo WhatsApp. Aceita JSON, com a mídia em base64,
+// ou multipart/form-data, com a mídia no campo file
 func (h *WhatsAppHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	if !h.whatsappService.IsConnected() {
 		RespondError(w, http.StatusBadRequest, "Não está conectado ao WhatsApp")
 		return
 	}
 	
-	var req MessageRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	req, err := parseMessageRequest(w, r)
+	if err != nil {
 		RespondError(w, http.StatusBadRequest, "Requisição inválida: "+err.Error())
 		return
 	}
 	
-	// Validar campos obrigatórios
-	if req.To == "" || req.Message == "" {
-		RespondError(w, http.StatusBadRequest, "Destinatário e mensagem são obrigatórios")
-		return
-	}
-	
-	// Por enquanto, apenas mensagens de texto são suportadas
-	if req.Type != "" && req.Type != "text" {
-		RespondError(w, http.StatusBadRequest, "Tipo de mensagem não suportado: "+req.Type)
+	if err := req.validate(); err != nil {
+		RespondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	
 	// Enviar mensagem
-	id, err := h.whatsappService.SendTextMessage(req.To, req.Message)
+	var id string
+	switch req.Type {
+	case MessageTypeText:
+		id, err = h.whatsappService.SendTextMessage(req.To, req.Message)
+	case MessageTypeImage:
+		id, err = h.whatsappService.SendImageMessage(req.To, req.data, req.MimeType, req.Message)
+	case MessageTypeDocument:
+		id, err = h.whatsappService.SendDocumentMessage(req.To, req.data, req.MimeType, req.FileName, req.Message)
+	case MessageTypeAudio:
+		id, err = h.whatsappService.SendAudioMessage(req.To, req.data, req.MimeType, req.PTT)
+	case MessageTypeLocation:
+		id, err = h.whatsappService.SendLocationMessage(req.To, *req.Latitude, *req.Longitude, req.Name, req.Address)
+	case MessageTypeContact:
+		id, err = h.whatsappService.SendContactMessage(req.To, req.Name, req.VCard)
+	}
 	if err != nil {
 		RespondError(w, http.StatusInternalServerError, "Erro ao enviar mensagem: "+err.Error())
 		return
@@ -158,6 +253,163 @@ func (h *WhatsAppHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	RespondJSON(w, http.StatusOK, response)
 }
 
+// parseMessageRequest lê a requisição de envio em JSON ou multipart/form-data
+func parseMessageRequest(w http.ResponseWriter, r *http.Request) (*MessageRequest, error) {
+	// A mídia em base64 ocupa cerca de 4/3 do tamanho original
+	r.Body = http.MaxBytesReader(w, r.Body, MaxMediaUploadSize*4/3+64<<10)
+	
+	var req MessageRequest
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, err
+		}
+		if req.Media != "" {
+			data, err := decodeBase64Media(req.Media, &req.MimeType)
+			if err != nil {
+				return nil, err
+			}
+			req.data = data
+		}
+		return &req, nil
+	}
+	
+	if err := r.ParseMultipartForm(MaxMediaUploadSize); err != nil {
+		return nil, err
+	}
+	req.To = r.FormValue("to")
+	req.Message = r.FormValue("message")
+	req.Type = r.FormValue("type")
+	req.MimeType = r.FormValue("mime_type")
+	req.FileName = r.FormValue("file_name")
+	req.PTT, _ = strconv.ParseBool(r.FormValue("ptt"))
+	req.Address = r.FormValue("address")
+	req.Name = r.FormValue("name")
+	req.Phone = r.FormValue("phone")
+	req.VCard = r.FormValue("vcard")
+	for field, dest := range map[string]**float64{"latitude": &req.Latitude, "longitude": &req.Longitude} {
+		if value := r.FormValue(field); value != "" {
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s inválida: %s", field, value)
+			}
+			*dest = &n
+		}
+	}
+	
+	file, header, err := r.FormFile("file")
+	if err == http.ErrMissingFile {
+		return &req, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	
+	req.data, err = io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	if req.FileName == "" {
+		req.FileName = header.Filename
+	}
+	if req.MimeType == "" {
+		req.MimeType = header.Header.Get("Content-Type")
+	}
+	return &req, nil
+}
+
+// decodeBase64Media decodifica a mídia em base64, aceitando também o formato data:<tipo>;base64,<conteúdo>.
+// O tipo de uma data URL é usado quando mimeType está vazio
+func decodeBase64Media(media string, mimeType *string) ([]byte, error) {
+	if strings.HasPrefix(media, "data:") {
+		header, content, ok := strings.Cut(media, ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, fmt.Errorf("data URL inválida")
+		}
+		if *mimeType == "" {
+			*mimeType = strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+		}
+		media = content
+	}
+	
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(media))
+	if err != nil {
+		return nil, fmt.Errorf("mídia em base64 inválida: %w", err)
+	}
+	return data, nil
+}
+
+// validate confere os campos obrigatórios de cada tipo de mensagem
+func (req *MessageRequest) validate() error {
+	if req.To == "" {
+		return fmt.Errorf("destinatário é obrigatório")
+	}
+	if req.Type == "" {
+		req.Type = MessageTypeText
+	}
+	
+	switch req.Type {
+	case MessageTypeText:
+		if req.Message == "" {
+			return fmt.Errorf("destinatário e mensagem são obrigatórios")
+		}
+	case MessageTypeImage, MessageTypeDocument, MessageTypeAudio:
+		if len(req.data) == 0 {
+			return fmt.Errorf("mídia é obrigatória para mensagens do tipo %s", req.Type)
+		}
+		if len(req.data) > MaxMediaUploadSize {
+			return fmt.Errorf("mídia maior que o limite de %d MB", MaxMediaUploadSize>>20)
+		}
+	case MessageTypeLocation:
+		if req.Latitude == nil || req.Longitude == nil {
+			return fmt.Errorf("latitude e longitude são obrigatórias")
+		}
+		if *req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180 {
+			return fmt.Errorf("coordenadas inválidas")
+		}
+	case MessageTypeContact:
+		if req.VCard == "" {
+			if req.Name == "" || req.Phone == "" {
+				return fmt.Errorf("informe o vcard ou o nome e o telefone do contato")
+			}
+			req.VCard = buildVCard(req.Name, req.Phone)
+		}
+		if req.Name == "" {
+			req.Name = vcardName(req.VCard)
+		}
+	default:
+		return fmt.Errorf("tipo de mensagem não suportado: %s", req.Type)
+	}
+	return nil
+}
+
+// buildVCard monta um vCard simples com o nome e o telefone, incluindo o waid usado
+// pelo WhatsApp para exibir o botão de conversa
+func buildVCard(name, phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	
+	return "BEGIN:VCARD\nVERSION:3.0\n" +
+		"FN:" + name + "\n" +
+		"TEL;type=CELL;waid=" + digits + ":+" + digits + "\n" +
+		"END:VCARD"
+}
+
+// vcardName extrai o nome de exibição (FN) de um vCard
+func vcardName(vcard string) string {
+	for _, line := range strings.Split(vcard, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "FN:") {
+			return strings.TrimPrefix(line, "FN:")
+		}
+	}
+	return ""
+}
+
 // LLMHandler lida com endpoints relacionados aos modelos de linguagem
 type LLMHandler struct {
 	llmService LLMService
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// envioFalso registra o último envio feito pelo handler
+type envioFalso struct {
+	tipo, para, mime, nome, texto string
+	dados                         []byte
+	ptt                           bool
+	lat, long                     float64
+}
+
+type servicoFalso struct {
+	ultimo       envioFalso
+	desconectado bool
+	telefone     string
+}
+
+func (s *servicoFalso) IsConnected() bool          { return !s.desconectado }
+func (s *servicoFalso) GetQRCode() (string, error) { return "", nil }
+func (s *servicoFalso) Disconnect() error          { return nil }
+func (s *servicoFalso) Connect() error             { return nil }
+func (s *servicoFalso) GetStatus() string          { return "conectado" }
+
+func (s *servicoFalso) PairPhone(phone string) (string, error) {
+	s.telefone = phone
+	return "ABCD-EFGH", nil
+}
+
+func (s *servicoFalso) SendTextMessage(to, message string) (string, error) {
+	s.ultimo = envioFalso{tipo: "text", para: to, texto: message}
+	return "ID1", nil
+}
+
+func (s *servicoFalso) SendImageMessage(to string, data []byte, mimeType, caption string) (string, error) {
+	s.ultimo = envioFalso{tipo: "image", para: to, dados: data, mime: mimeType, texto: caption}
+	return "ID2", nil
+}
+
+func (s *servicoFalso) SendDocumentMessage(to string, data []byte, mimeType, fileName, caption string) (string, error) {
+	s.ultimo = envioFalso{tipo: "document", para: to, dados: data, mime: mimeType, nome: fileName, texto: caption}
+	return "ID3", nil
+}
+
+func (s *servicoFalso) SendAudioMessage(to string, data []byte, mimeType string, ptt bool) (string, error) {
+	s.ultimo = envioFalso{tipo: "audio", para: to, dados: data, mime: mimeType, ptt: ptt}
+	return "ID4", nil
+}
+
+func (s *servicoFalso) SendLocationMessage(to string, latitude, longitude float64, name, address string) (string, error) {
+	s.ultimo = envioFalso{tipo: "location", para: to, lat: latitude, long: longitude, nome: name, texto: address}
+	return "ID5", nil
+}
+
+func (s *servicoFalso) SendContactMessage(to, displayName, vcard string) (string, error) {
+	s.ultimo = envioFalso{tipo: "contact", para: to, nome: displayName, texto: vcard}
+	return "ID6", nil
+}
+
+func TestSendMessage(t *testing.T) {
+	servico := &servicoFalso{}
+	handler := NewWhatsAppHandler(servico)
+	to := "5511999999999@s.whatsapp.net"
+
+	enviarJSON := func(corpo map[string]interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(corpo)
+		req := httptest.NewRequest(http.MethodPost, "/api/whatsapp/send", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.SendMessage(rec, req)
+		return rec
+	}
+
+	t.Run("Texto", func(t *testing.T) {
+		rec := enviarJSON(map[string]interface{}{"to": to, "message": "Olá"})
+		if rec.Code != http.StatusOK || servico.ultimo.tipo != "text" || servico.ultimo.texto != "Olá" {
+			t.Errorf("Envio incorreto (%d): %+v", rec.Code, servico.ultimo)
+		}
+	})
+
+	t.Run("DocumentoBase64", func(t *testing.T) {
+		rec := enviarJSON(map[string]interface{}{
+			"to":        to,
+			"type":      "document",
+			"media":     "data:application/pdf;base64,JVBERi0xLjQ=",
+			"file_name": "nota-fiscal.pdf",
+			"message":   "Segue a nota fiscal",
+		})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Status incorreto: %d %s", rec.Code, rec.Body.String())
+		}
+		ultimo := servico.ultimo
+		if ultimo.tipo != "document" || string(ultimo.dados) != "%PDF-1.4" || ultimo.mime != "application/pdf" || ultimo.nome != "nota-fiscal.pdf" {
+			t.Errorf("Documento incorreto: %+v", ultimo)
+		}
+	})
+
+	t.Run("ImagemMultipart", func(t *testing.T) {
+		var corpo bytes.Buffer
+		writer := multipart.NewWriter(&corpo)
+		writer.WriteField("to", to)
+		writer.WriteField("type", "image")
+		writer.WriteField("message", "Produto")
+		part, _ := writer.CreateFormFile("file", "foto.jpg")
+		part.Write([]byte("jpeg"))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/whatsapp/send", &corpo)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		handler.SendMessage(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Status incorreto: %d %s", rec.Code, rec.Body.String())
+		}
+		if servico.ultimo.tipo != "image" || string(servico.ultimo.dados) != "jpeg" || servico.ultimo.texto != "Produto" {
+			t.Errorf("Imagem incorreta: %+v", servico.ultimo)
+		}
+	})
+
+	t.Run("Localizacao", func(t *testing.T) {
+		rec := enviarJSON(map[string]interface{}{"to": to, "type": "location", "latitude": -23.55, "longitude": -46.63, "name": "Loja"})
+		if rec.Code != http.StatusOK || servico.ultimo.lat != -23.55 || servico.ultimo.nome != "Loja" {
+			t.Errorf("Localização incorreta (%d): %+v", rec.Code, servico.ultimo)
+		}
+	})
+
+	t.Run("ContatoSemVCard", func(t *testing.T) {
+		rec := enviarJSON(map[string]interface{}{"to": to, "type": "contact", "name": "Suporte", "phone": "+55 (11) 98888-7777"})
+		if rec.Code != http.StatusOK || servico.ultimo.nome != "Suporte" || !strings.Contains(servico.ultimo.texto, "waid=5511988887777") {
+			t.Errorf("Contato incorreto (%d): %+v", rec.Code, servico.ultimo)
+		}
+	})
+
+	t.Run("Invalidas", func(t *testing.T) {
+		invalidas := []map[string]interface{}{
+			{"to": to, "type": "video", "message": "x"},
+			{"to": to, "type": "image"},
+			{"to": to, "type": "image", "media": "não é base64"},
+			{"to": to, "type": "location", "latitude": 100.0, "longitude": 0.0},
+			{"to": to, "type": "location", "name": "Sem coordenadas"},
+			{"to": to, "type": "location", "latitude": -23.55},
+			{"to": to, "type": "contact", "name": "Sem telefone"},
+			{"message": "sem destinatário"},
+		}
+		for _, corpo := range invalidas {
+			if rec := enviarJSON(corpo); rec.Code != http.StatusBadRequest {
+				t.Errorf("Esperava erro para %v, obteve %d", corpo, rec.Code)
+			}
+		}
+	})
+}
+
+func TestPairPhone(t *testing.T) {
+	servico := &servicoFalso{desconectado: true}
+	handler := NewWhatsAppHandler(servico)
+
+	parear := func(corpo string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/whatsapp/pair", strings.NewReader(corpo))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.PairPhone(rec, req)
+		return rec
+	}
+
+	rec := parear(`{"phone": "+55 11 99999-9999"}`)
+	var resposta map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &resposta)
+	if rec.Code != http.StatusOK || resposta["code"] != "ABCD-EFGH" || servico.telefone != "+55 11 99999-9999" {
+		t.Errorf("Pareamento incorreto (%d): %v, telefone %q", rec.Code, resposta, servico.telefone)
+	}
+
+	for _, corpo := range []string{`{}`, `{"phone": " "}`, `não é json`} {
+		if rec := parear(corpo); rec.Code != http.StatusBadRequest {
+			t.Errorf("Esperava erro para %s, obteve %d", corpo, rec.Code)
+		}
+	}
+
+	// Com uma sessão ativa não há o que parear
+	servico.desconectado = false
+	if rec := parear(`{"phone": "+55 11 99999-9999"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Esperava erro com o WhatsApp conectado, obteve %d", rec.Code)
+	}
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/peder/whatszapme/internal/llm"
+)
+
+// DefaultGenerateTimeout é o tempo máximo padrão de uma geração feita pela API
+const DefaultGenerateTimeout = 60 * time.Second
+
+// ProviderLLMService implementa LLMService usando os provedores do pacote llm
+type ProviderLLMService struct {
+	providers map[string]llm.Provider
+	mutex     sync.RWMutex
+	// Timeout limita a duração de cada geração quando a requisição não informa "timeout"
+	Timeout time.Duration
+}
+
+// NewProviderLLMService cria um novo serviço de LLM sem modelos registrados
+func NewProviderLLMService() *ProviderLLMService {
+	return &ProviderLLMService{
+		providers: make(map[string]llm.Provider),
+		Timeout:   DefaultGenerateTimeout,
+	}
+}
+
+// RegisterModel associa um nome de modelo ao provedor que o atende
+func (s *ProviderLLMService) RegisterModel(model string, provider llm.Provider) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.providers[model] = provider
+}
+
+// GetAvailableModels retorna os modelos registrados em ordem alfabética
+func (s *ProviderLLMService) GetAvailableModels() ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	models := make([]string, 0, len(s.providers))
+	for model := range s.providers {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	return models, nil
+}
+
+// IsModelAvailable verifica se o modelo foi registrado
+func (s *ProviderLLMService) IsModelAvailable(model string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	_, ok := s.providers[model]
+	return ok
+}
+
+// GenerateResponse gera uma resposta com o modelo informado.
+// As opções aceitas são temperature, top_p, max_tokens, stop, seed, system e timeout (em segundos)
+func (s *ProviderLLMService) GenerateResponse(model, prompt string, options map[string]interface{}) (string, error) {
+	s.mutex.RLock()
+	provider, ok := s.providers[model]
+	s.mutex.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("modelo não disponível: %s", model)
+	}
+
+	genOptions, err := ParseGenerationOptions(options)
+	if err != nil {
+		return "", err
+	}
+
+	timeout := s.Timeout
+	if value, ok := options["timeout"]; ok {
+		seconds, err := toFloat(value)
+		if err != nil || seconds <= 0 {
+			return "", fmt.Errorf("opção timeout inválida: %v", value)
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	systemPrompt, _ := options["system"].(string)
+
+	resp, err := provider.Generate(llm.Request{
+		Context:      ctx,
+		SystemPrompt: systemPrompt,
+		Messages:     []llm.Message{{Role: llm.RoleUser, Content: prompt}},
+		Options:      genOptions,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Text, nil
+}
+
+// ParseGenerationOptions converte as opções recebidas em JSON para llm.GenerationOptions.
+// Retorna nil quando nenhuma opção de geração foi informada, mantendo os padrões do provedor
+func ParseGenerationOptions(options map[string]interface{}) (*llm.GenerationOptions, error) {
+	genOptions := &llm.GenerationOptions{Temperature: 0.7}
+	found := false
+
+	for key, value := range options {
+		switch key {
+		case "temperature":
+			temperature, err := toFloat(value)
+			if err != nil || temperature < 0 || temperature > 2 {
+				return nil, fmt.Errorf("opção temperature inválida: %v", value)
+			}
+			genOptions.Temperature = temperature
+		case "top_p":
+			topP, err := toFloat(value)
+			if err != nil || topP < 0 || topP > 1 {
+				return nil, fmt.Errorf("opção top_p inválida: %v", value)
+			}
+			genOptions.TopP = topP
+		case "max_tokens":
+			maxTokens, err := toFloat(value)
+			if err != nil || maxTokens < 1 {
+				return nil, fmt.Errorf("opção max_tokens inválida: %v", value)
+			}
+			genOptions.MaxTokens = int(maxTokens)
+		case "seed":
+			seed, err := toFloat(value)
+			if err != nil {
+				return nil, fmt.Errorf("opção seed inválida: %v", value)
+			}
+			s := int(seed)
+			genOptions.Seed = &s
+		case "stop":
+			stop, err := toStringSlice(value)
+			if err != nil {
+				return nil, fmt.Errorf("opção stop inválida: %v", value)
+			}
+			genOptions.Stop = stop
+		default:
+			// system, timeout e opções desconhecidas não afetam a geração
+			continue
+		}
+		found = true
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return genOptions, nil
+}
+
+// toFloat converte um número decodificado de JSON para float64
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("valor não numérico: %v", value)
+	}
+}
+
+// toStringSlice aceita uma string única ou uma lista de strings
+func toStringSlice(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("valor não textual: %v", item)
+			}
+			result = append(result, text)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("valor não textual: %v", value)
+	}
+}
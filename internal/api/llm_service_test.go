@@ -0,0 +1,76 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGenerationOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     map[string]interface{}
+		expectNil   bool
+		expectError bool
+	}{
+		{
+			name:      "Sem opções",
+			options:   nil,
+			expectNil: true,
+		},
+		{
+			name:      "Apenas system e timeout",
+			options:   map[string]interface{}{"system": "Seja breve", "timeout": 10.0},
+			expectNil: true,
+		},
+		{
+			name: "Opções completas",
+			options: map[string]interface{}{
+				"temperature": 0.2,
+				"top_p":       0.9,
+				"max_tokens":  256.0,
+				"stop":        []interface{}{"FIM"},
+				"seed":        42.0,
+			},
+		},
+		{
+			name:        "Temperatura inválida",
+			options:     map[string]interface{}{"temperature": "quente"},
+			expectError: true,
+		},
+		{
+			name:        "Stop inválido",
+			options:     map[string]interface{}{"stop": 3.0},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := ParseGenerationOptions(tt.options)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Esperava erro, mas não ocorreu")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Erro inesperado: %v", err)
+			}
+
+			if tt.expectNil {
+				if opts != nil {
+					t.Errorf("Esperava opções nulas, obteve %+v", opts)
+				}
+				return
+			}
+
+			if opts.Temperature != 0.2 || opts.TopP != 0.9 || opts.MaxTokens != 256 {
+				t.Errorf("Opções numéricas incorretas: %+v", opts)
+			}
+			if !reflect.DeepEqual(opts.Stop, []string{"FIM"}) || opts.Seed == nil || *opts.Seed != 42 {
+				t.Errorf("Stop ou seed incorretos: %+v", opts)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Estados do login por QR Code enviados no stream
+const (
+	QRStateCode    = "code"    // novo QR Code disponível
+	QRStateSuccess = "success" // login concluído
+	QRStateError   = "error"   // o login falhou ou os códigos expiraram
+)
+
+// Limites do tamanho, em pixels, da imagem do QR Code
+const (
+	defaultQRSize = 256
+	minQRSize     = 128
+	maxQRSize     = 1024
+)
+
+// qrPingInterval é o intervalo dos comentários que mantêm o stream aberto em proxies
+const qrPingInterval = 15 * time.Second
+
+// QREvent é uma atualização do login por QR Code
+type QREvent struct {
+	State string `json:"state"`
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// QRHub guarda o último QR Code de login e o repassa aos clientes do stream. O binário publica
+// nele os códigos recebidos do callback de QR Code e as mudanças de estado do login
+type QRHub struct {
+	mu          sync.Mutex
+	last        QREvent
+	subscribers map[chan QREvent]struct{}
+}
+
+// NewQRHub cria um QRHub vazio
+func NewQRHub() *QRHub {
+	return &QRHub{subscribers: make(map[chan QREvent]struct{})}
+}
+
+// PublishCode publica um novo QR Code
+func (h *QRHub) PublishCode(code string) {
+	h.Publish(QREvent{State: QRStateCode, Code: code})
+}
+
+// Publish registra o evento como o estado atual e o envia aos inscritos. Inscritos que não
+// acompanham o ritmo perdem eventos, mas sempre recebem o mais recente ao se inscrever
+func (h *QRHub) Publish(evt QREvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last = evt
+	for ch := range h.subscribers {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
+
+// Latest retorna o último evento publicado
+func (h *QRHub) Latest() QREvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.last
+}
+
+// Subscribe inscreve um cliente nas atualizações. A função retornada cancela a inscrição
+func (h *QRHub) Subscribe() (<-chan QREvent, func()) {
+	ch := make(chan QREvent, 4)
+
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subscribers, ch)
+		h.mu.Unlock()
+	}
+}
+
+// currentQRCode retorna o QR Code publicado no QRHub ou, na falta dele, o informado pelo serviço
+func (h *WhatsAppHandler) currentQRCode() (string, error) {
+	if last := h.qr.Latest(); last.State == QRStateCode {
+		return last.Code, nil
+	}
+	return h.whatsappService.GetQRCode()
+}
+
+// GetQRImage retorna o QR Code de login como imagem PNG. O parâmetro size define o lado da imagem em pixels
+func (h *WhatsAppHandler) GetQRImage(w http.ResponseWriter, r *http.Request) {
+	size := defaultQRSize
+	if s := r.URL.Query().Get("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < minQRSize || n > maxQRSize {
+			RespondError(w, http.StatusBadRequest, fmt.Sprintf("size deve estar entre %d e %d", minQRSize, maxQRSize))
+			return
+		}
+		size = n
+	}
+
+	code, err := h.currentQRCode()
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Erro ao obter QR Code: "+err.Error())
+		return
+	}
+	if code == "" {
+		RespondError(w, http.StatusNotFound, "Nenhum QR Code disponível; o WhatsApp já está conectado ou o login não foi iniciado")
+		return
+	}
+
+	png, err := qrcode.Encode(code, qrcode.Medium, size)
+	if err != nil {
+		RespondError(w, http.StatusInternalServerError, "Erro ao gerar imagem do QR Code: "+err.Error())
+		return
+	}
+
+	// Cada código vale poucos segundos e não deve ficar em cache
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
+
+// StreamQR envia as atualizações do login por QR Code como Server-Sent Events. O primeiro evento é
+// o estado atual; os seguintes chegam a cada novo código e ao fim do login
+func (h *WhatsAppHandler) StreamQR(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	// O stream dura enquanto o navegador estiver conectado, além do WriteTimeout do servidor
+	rc.SetWriteDeadline(time.Time{})
+
+	events, cancel := h.qr.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if last := h.qr.Latest(); last.State != "" {
+		writeQREvent(w, last)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(qrPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case evt := <-events:
+			writeQREvent(w, evt)
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeQREvent escreve o evento no formato do Server-Sent Events, com o estado como nome do evento
+func writeQREvent(w http.ResponseWriter, evt QREvent) {
+	data, _ := json.Marshal(evt)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.State, data)
+}
+
+// GetQRPage retorna uma página que exibe o QR Code e o atualiza pelo stream, para parear
+// pelo navegador um servidor sem tela. O token recebido na URL é repassado ao stream e à imagem
+func (h *WhatsAppHandler) GetQRPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, qrPage)
+}
+
+const qrPage = `<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+<meta charset="utf-8">
+<title>WhatsZapMe - Conectar WhatsApp</title>
+<style>body{font-family:sans-serif;text-align:center;margin-top:3em}img{width:320px;height:320px}</style>
+</head>
+<body>
+<h1>Conectar WhatsApp</h1>
+<p id="status">Aguardando QR Code...</p>
+<img id="qr" alt="" hidden>
+<p>No celular, abra o WhatsApp &gt; Aparelhos conectados &gt; Conectar aparelho e escaneie o código.</p>
+<script>
+const status = document.getElementById("status");
+const qr = document.getElementById("qr");
+const token = new URLSearchParams(location.search).get("token");
+const auth = token ? "token=" + encodeURIComponent(token) + "&" : "";
+const stream = new EventSource("qr/stream?" + auth);
+stream.addEventListener("code", () => {
+  qr.src = "qr.png?" + auth + "size=320&t=" + Date.now();
+  qr.hidden = false;
+  status.textContent = "Escaneie o QR Code abaixo";
+});
+stream.addEventListener("success", () => {
+  qr.hidden = true;
+  status.textContent = "WhatsApp conectado!";
+  stream.close();
+});
+stream.addEventListener("error", (e) => {
+  if (e.data) {
+    qr.hidden = true;
+    status.textContent = "Falha no login: " + JSON.parse(e.data).error;
+  }
+});
+</script>
+</body>
+</html>
+`
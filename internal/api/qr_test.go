@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestQRHub(t *testing.T) {
+	hub := NewQRHub()
+	eventos, cancelar := hub.Subscribe()
+
+	hub.PublishCode("2@codigo")
+	if evt := <-eventos; evt.State != QRStateCode || evt.Code != "2@codigo" {
+		t.Errorf("Evento incorreto: %+v", evt)
+	}
+	if hub.Latest().Code != "2@codigo" {
+		t.Errorf("O último código não foi guardado: %+v", hub.Latest())
+	}
+
+	// Depois de cancelar a inscrição, o canal não recebe mais nada e Publish não bloqueia
+	cancelar()
+	hub.Publish(QREvent{State: QRStateSuccess})
+	select {
+	case evt := <-eventos:
+		t.Errorf("Evento recebido após cancelar: %+v", evt)
+	default:
+	}
+}
+
+func TestGetQRImage(t *testing.T) {
+	handler := NewWhatsAppHandler(&servicoFalso{desconectado: true})
+
+	imagem := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.GetQRImage(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+
+	// Sem QR Code publicado não há imagem
+	if rec := imagem("/api/whatsapp/qr.png"); rec.Code != http.StatusNotFound {
+		t.Errorf("Esperava 404 sem QR Code, obteve %d", rec.Code)
+	}
+
+	handler.QR().PublishCode("2@codigo")
+	rec := imagem("/api/whatsapp/qr.png?size=200")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("Imagem incorreta (%d): %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte("\x89PNG")) {
+		t.Errorf("A resposta não é um PNG")
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("O QR Code não deveria ficar em cache")
+	}
+
+	for _, url := range []string{"/api/whatsapp/qr.png?size=abc", "/api/whatsapp/qr.png?size=5000"} {
+		if rec := imagem(url); rec.Code != http.StatusBadRequest {
+			t.Errorf("Esperava erro para %s, obteve %d", url, rec.Code)
+		}
+	}
+}
+
+func TestStreamQR(t *testing.T) {
+	handler := NewWhatsAppHandler(&servicoFalso{desconectado: true})
+	handler.QR().PublishCode("2@primeiro")
+
+	server := httptest.NewServer(http.HandlerFunc(handler.StreamQR))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Erro ao abrir o stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type incorreto: %s", resp.Header.Get("Content-Type"))
+	}
+
+	linhas := bufio.NewScanner(resp.Body)
+	proximo := func() QREvent {
+		var evt QREvent
+		for linhas.Scan() {
+			if dados, ok := strings.CutPrefix(linhas.Text(), "data: "); ok {
+				json.Unmarshal([]byte(dados), &evt)
+				return evt
+			}
+		}
+		t.Fatalf("Stream encerrado: %v", linhas.Err())
+		return evt
+	}
+
+	// O primeiro evento é o código já publicado
+	if evt := proximo(); evt.Code != "2@primeiro" {
+		t.Errorf("Primeiro evento incorreto: %+v", evt)
+	}
+
+	// O stream se inscreve antes de enviar o primeiro evento, então nenhum dos próximos se perde
+	handler.QR().PublishCode("2@segundo")
+	if evt := proximo(); evt.State != QRStateCode || evt.Code != "2@segundo" {
+		t.Errorf("Novo código incorreto: %+v", evt)
+	}
+
+	handler.QR().Publish(QREvent{State: QRStateSuccess})
+	if evt := proximo(); evt.State != QRStateSuccess {
+		t.Errorf("Esperava o fim do login: %+v", evt)
+	}
+}
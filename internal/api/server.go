@@ -2,10 +2,14 @@ package api
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +29,7 @@ type APIServer struct {
 
 // APIConfig contém as configurações para o servidor API
 type APIConfig struct {
+	Host           string        // Endereço em que o servidor irá escutar; vazio escuta em todas as interfaces
 	Port           int           // Porta em que o servidor irá escutar
 	ReadTimeout    time.Duration // Timeout para leitura de requisições
 	WriteTimeout   time.Duration // Timeout para escrita de respostas
@@ -34,6 +39,7 @@ type APIConfig struct {
 // DefaultAPIConfig retorna uma configuração padrão para o servidor API
 func DefaultAPIConfig() APIConfig {
 	return APIConfig{
+		Host:           "127.0.0.1",
 		Port:           8080,
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
@@ -46,7 +52,7 @@ func NewAPIServer(config APIConfig) *APIServer {
 	router := mux.NewRouter()
 	
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", config.Port),
+		Addr:         net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
 		Handler:      router,
 		ReadTimeout:  config.ReadTimeout,
 		WriteTimeout: config.WriteTimeout,
@@ -189,6 +195,24 @@ func CORSMiddleware(allowedOrigins []string) mux.MiddlewareFunc {
 	}
 }
 
+// AuthMiddleware cria um middleware que exige o token no cabeçalho "Authorization: Bearer <token>".
+// Como o navegador não envia cabeçalhos em <img> e EventSource, o token também é aceito no parâmetro token
+func AuthMiddleware(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				provided = r.URL.Query().Get("token")
+			}
+			if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				RespondError(w, http.StatusUnauthorized, "Token de acesso inválido ou ausente")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // LoggingMiddleware cria um middleware para logging
 func LoggingMiddleware() mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
@@ -210,7 +234,7 @@ func LoggingMiddleware() mux.MiddlewareFunc {
 			log.Printf(
 				"[API] %s %s %d %s",
 				r.Method,
-				r.RequestURI,
+				r.URL.Path, // sem a query, que pode trazer o token
 				wrapper.statusCode,
 				duration,
 			)
@@ -229,3 +253,8 @@ func (w *responseWriterWrapper) WriteHeader(statusCode int) {
 	w.statusCode = statusCode
 	w.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Unwrap expõe o ResponseWriter original ao http.ResponseController, usado pelos streams
+func (w *responseWriterWrapper) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
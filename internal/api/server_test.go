@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	handler := AuthMiddleware("segredo")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		nome      string
+		url       string
+		cabecalho string
+		status    int
+	}{
+		{"cabeçalho", "/api/whatsapp/status", "Bearer segredo", http.StatusOK},
+		{"parâmetro", "/api/whatsapp/qr.png?token=segredo", "", http.StatusOK},
+		{"sem token", "/api/whatsapp/status", "", http.StatusUnauthorized},
+		{"token errado", "/api/whatsapp/status", "Bearer outro", http.StatusUnauthorized},
+		{"outro esquema", "/api/whatsapp/status", "Basic segredo", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.nome, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.cabecalho != "" {
+				req.Header.Set("Authorization", tt.cabecalho)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("Esperava %d, obteve %d", tt.status, rec.Code)
+			}
+		})
+	}
+
+	// Sem token configurado, nada é liberado
+	rec := httptest.NewRecorder()
+	AuthMiddleware("")(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/whatsapp/status?token=", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Token vazio não deveria liberar o acesso: %d", rec.Code)
+	}
+}
@@ -13,6 +13,15 @@ type Config struct {
 	OllamaModel string            `json:"ollama_model"`
 	OllamaURL   string            `json:"ollama_url"`
 	APIKeys     map[string]string `json:"api_keys"`
+	// OpenAICompatible configura o provedor "openai-compatible" (LM Studio, vLLM, llama.cpp server etc.)
+	OpenAICompatible OpenAICompatibleConfig `json:"openai_compatible"`
+}
+
+// OpenAICompatibleConfig representa um servidor que implementa a API da OpenAI
+type OpenAICompatibleConfig struct {
+	BaseURL string            `json:"base_url"`
+	Model   string            `json:"model"`
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 // DefaultConfig retorna uma configuração padrão
@@ -22,9 +31,14 @@ func DefaultConfig() Config {
 		OllamaModel: "llama2",
 		OllamaURL:   "http://localhost:11434",
 		APIKeys: map[string]string{
-			"openai": "",
-			"google": "",
-			"grok":   "",
+			"openai":            "",
+			"google":            "",
+			"grok":              "",
+			"anthropic":         "",
+			"openai-compatible": "",
+		},
+		OpenAICompatible: OpenAICompatibleConfig{
+			BaseURL: "http://localhost:1234/v1",
 		},
 	}
 }
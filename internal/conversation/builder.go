@@ -0,0 +1,158 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peder/whatszapme/internal/db"
+	"github.com/peder/whatszapme/internal/llm"
+	"github.com/peder/whatszapme/internal/token"
+)
+
+// Valores padrão do contexto de conversa
+const (
+	DefaultMaxExchanges = 10
+	DefaultTokenBudget  = 1500
+)
+
+// tokensPorTurno é um acréscimo por turno que representa a marcação de papel usada pelas APIs
+const tokensPorTurno = 4
+
+// sufixoGrupo identifica os JIDs de grupos do WhatsApp
+const sufixoGrupo = "@g.us"
+
+// Builder monta o contexto de uma conversa a partir do histórico salvo no banco de dados
+type Builder struct {
+	database *db.DB
+	// MaxExchanges é a quantidade máxima de trocas (mensagem e resposta) consideradas
+	MaxExchanges int
+	// TokenBudget é o limite aproximado de tokens ocupados pelo histórico
+	TokenBudget int
+}
+
+// NewBuilder cria um novo construtor de contexto com os limites padrão
+func NewBuilder(database *db.DB) *Builder {
+	return &Builder{
+		database:     database,
+		MaxExchanges: DefaultMaxExchanges,
+		TokenBudget:  DefaultTokenBudget,
+	}
+}
+
+// Build retorna os turnos anteriores da conversa com o contato, do mais antigo ao mais recente.
+// A mensagem com o ID informado em ignorarID (normalmente a que está sendo respondida) não entra no histórico.
+// Nos grupos, cada fala começa com o nome de quem a enviou, pois os participantes dividem o papel do usuário
+func (b *Builder) Build(jid string, ignorarID int64) ([]llm.Message, error) {
+	if b.database == nil || b.MaxExchanges <= 0 {
+		return nil, nil
+	}
+
+	// Busca uma mensagem a mais para compensar a que será ignorada
+	mensagens, err := b.database.ObterUltimasMensagens(jid, b.MaxExchanges+1)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao obter histórico da conversa: %w", err)
+	}
+
+	// As mensagens chegam das mais recentes para as mais antigas
+	grupo := strings.HasSuffix(jid, sufixoGrupo)
+	var turnos []llm.Message
+	for i := len(mensagens) - 1; i >= 0; i-- {
+		msg := mensagens[i]
+		if msg.ID == ignorarID {
+			continue
+		}
+		for _, turno := range turnosDaMensagem(msg) {
+			if grupo && turno.Role == llm.RoleUser && msg.Nome != "" {
+				turno.Content = msg.Nome + ": " + turno.Content
+			}
+			turnos = append(turnos, turno)
+		}
+	}
+
+	return b.trim(mergeTurns(turnos)), nil
+}
+
+// turnosDaMensagem converte um registro do histórico nos turnos correspondentes
+func turnosDaMensagem(msg db.Mensagem) []llm.Message {
+	var turnos []llm.Message
+
+	// Mensagens enviadas manualmente são salvas apenas com o conteúdo
+	if !msg.Entrada && msg.Resposta == "" {
+		if msg.Conteudo != "" {
+			turnos = append(turnos, llm.Message{Role: llm.RoleAssistant, Content: msg.Conteudo})
+		}
+		return turnos
+	}
+
+	// Mensagens de voz entram no histórico pelo texto transcrito
+	conteudo := msg.Conteudo
+	if msg.Transcricao != "" {
+		conteudo = msg.Transcricao
+	}
+	if conteudo != "" {
+		turnos = append(turnos, llm.Message{Role: llm.RoleUser, Content: conteudo})
+	}
+	if msg.Resposta != "" {
+		turnos = append(turnos, llm.Message{Role: llm.RoleAssistant, Content: msg.Resposta})
+	}
+
+	return turnos
+}
+
+// mergeTurns junta turnos consecutivos do mesmo papel, pois algumas APIs exigem papéis alternados
+func mergeTurns(turnos []llm.Message) []llm.Message {
+	var resultado []llm.Message
+	for _, turno := range turnos {
+		if n := len(resultado); n > 0 && resultado[n-1].Role == turno.Role {
+			resultado[n-1].Content += "\n" + turno.Content
+			continue
+		}
+		resultado = append(resultado, turno)
+	}
+	return resultado
+}
+
+// trim descarta os turnos mais antigos até que o histórico caiba no orçamento de tokens
+func (b *Builder) trim(turnos []llm.Message) []llm.Message {
+	if b.TokenBudget <= 0 {
+		return turnos
+	}
+
+	total := 0
+	inicio := len(turnos)
+	for i := len(turnos) - 1; i >= 0; i-- {
+		custo := token.EstimateTokens(turnos[i].Content) + tokensPorTurno
+		if total+custo > b.TokenBudget {
+			break
+		}
+		total += custo
+		inicio = i
+	}
+
+	// O histórico deve começar com uma fala do usuário
+	for inicio < len(turnos) && turnos[inicio].Role != llm.RoleUser {
+		inicio++
+	}
+
+	return turnos[inicio:]
+}
+
+// FormatHistory converte os turnos em texto, para uso na variável {{.History}} dos templates.
+// As falas do usuário recebem o nome informado; com o nome vazio, como nos grupos, em que o Build
+// já identifica cada participante, elas são escritas sem rótulo
+func FormatHistory(turnos []llm.Message, nomeUsuario string) string {
+	var sb strings.Builder
+	for i, turno := range turnos {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if turno.Role == llm.RoleAssistant {
+			sb.WriteString("Assistente: ")
+		} else if nomeUsuario != "" {
+			sb.WriteString(nomeUsuario + ": ")
+		}
+		sb.WriteString(turno.Content)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,127 @@
+package conversation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peder/whatszapme/internal/db"
+	"github.com/peder/whatszapme/internal/llm"
+)
+
+func TestBuilder(t *testing.T) {
+	// Cria um diretório temporário para o banco de dados
+	tempDir, err := os.MkdirTemp("", "conversation-test")
+	if err != nil {
+		t.Fatalf("Erro ao criar diretório temporário: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	database, err := db.New(filepath.Join(tempDir, "historico.db"))
+	if err != nil {
+		t.Fatalf("Erro ao criar banco de dados: %v", err)
+	}
+	defer database.Close()
+
+	jid := "5511999999999@s.whatsapp.net"
+	inicio := time.Now().Add(-time.Hour)
+	trocas := []db.Mensagem{
+		{Conteudo: "Meu nome é Ana", Resposta: "Prazer, Ana!", Entrada: true},
+		{Conteudo: "Quero o plano básico", Resposta: "Anotado, plano básico.", Entrada: true},
+		{Conteudo: "Qual plano eu escolhi?", Entrada: true},
+	}
+
+	var atualID int64
+	for i, msg := range trocas {
+		msg.JID = jid
+		msg.Nome = "Ana"
+		msg.Timestamp = inicio.Add(time.Duration(i) * time.Minute)
+		id, err := database.SalvarMensagem(msg)
+		if err != nil {
+			t.Fatalf("Erro ao salvar mensagem: %v", err)
+		}
+		atualID = id
+	}
+
+	t.Run("Build", func(t *testing.T) {
+		turnos, err := NewBuilder(database).Build(jid, atualID)
+		if err != nil {
+			t.Fatalf("Erro ao montar contexto: %v", err)
+		}
+
+		if len(turnos) != 4 {
+			t.Fatalf("Esperava 4 turnos, obteve %d: %+v", len(turnos), turnos)
+		}
+		if turnos[0].Role != llm.RoleUser || turnos[0].Content != "Meu nome é Ana" {
+			t.Errorf("Primeiro turno incorreto: %+v", turnos[0])
+		}
+		if turnos[3].Role != llm.RoleAssistant || turnos[3].Content != "Anotado, plano básico." {
+			t.Errorf("Último turno incorreto: %+v", turnos[3])
+		}
+	})
+
+	t.Run("OrcamentoDeTokens", func(t *testing.T) {
+		builder := NewBuilder(database)
+		builder.TokenBudget = 20
+
+		turnos, err := builder.Build(jid, atualID)
+		if err != nil {
+			t.Fatalf("Erro ao montar contexto: %v", err)
+		}
+
+		// Só a troca mais recente cabe no orçamento
+		if len(turnos) != 2 || turnos[0].Content != "Quero o plano básico" {
+			t.Errorf("Histórico não foi reduzido corretamente: %+v", turnos)
+		}
+	})
+
+	t.Run("MensagemDeVoz", func(t *testing.T) {
+		turnos := turnosDaMensagem(db.Mensagem{
+			Conteudo:    "[mensagem de voz 0:05]",
+			Transcricao: "Vocês abrem amanhã?",
+			Resposta:    "Sim, das 9h às 18h.",
+			Entrada:     true,
+		})
+		if len(turnos) != 2 || turnos[0].Content != "Vocês abrem amanhã?" {
+			t.Errorf("A transcrição deveria substituir o conteúdo: %+v", turnos)
+		}
+	})
+
+	t.Run("Grupo", func(t *testing.T) {
+		grupo := "120363000000000000@g.us"
+		for i, msg := range []db.Mensagem{
+			{Nome: "Ana", Conteudo: "Bom dia", Entrada: true},
+			{Nome: "Bruno", Conteudo: "Quem abre hoje?", Resposta: "A loja abre às 9h.", Entrada: true},
+		} {
+			msg.JID = grupo
+			msg.Timestamp = inicio.Add(time.Duration(i) * time.Minute)
+			if _, err := database.SalvarMensagem(msg); err != nil {
+				t.Fatalf("Erro ao salvar mensagem: %v", err)
+			}
+		}
+
+		turnos, err := NewBuilder(database).Build(grupo, 0)
+		if err != nil {
+			t.Fatalf("Erro ao montar contexto: %v", err)
+		}
+
+		// Cada participante mantém o próprio nome, mesmo com as falas juntas em um turno
+		texto := FormatHistory(turnos, "")
+		if texto != "Ana: Bom dia\nBruno: Quem abre hoje?\nAssistente: A loja abre às 9h." {
+			t.Errorf("Histórico do grupo incorreto: %q", texto)
+		}
+	})
+
+	t.Run("FormatHistory", func(t *testing.T) {
+		texto := FormatHistory([]llm.Message{
+			{Role: llm.RoleUser, Content: "Oi"},
+			{Role: llm.RoleAssistant, Content: "Olá!"},
+		}, "Ana")
+
+		if !strings.HasPrefix(texto, "Ana: Oi\nAssistente: Olá!") {
+			t.Errorf("Histórico formatado incorreto: %q", texto)
+		}
+	})
+}
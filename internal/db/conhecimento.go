@@ -0,0 +1,164 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// FonteConhecimento representa um arquivo indexado na base de conhecimento
+type FonteConhecimento struct {
+	ID         int64
+	Caminho    string    // Caminho do arquivo, relativo à pasta da base
+	Hash       string    // Hash do conteúdo, usado para detectar alterações
+	Modelo     string    // Modelo de embeddings usado na indexação
+	Trechos    int       // Quantidade de trechos gerados
+	IndexadoEm time.Time // Momento da última indexação
+}
+
+// TrechoConhecimento representa um trecho de uma fonte com o seu embedding
+type TrechoConhecimento struct {
+	ID       int64
+	FonteID  int64
+	Caminho  string    // Caminho da fonte, preenchido nas consultas
+	Posicao  int       // Posição do trecho dentro da fonte
+	Conteudo string    // Texto do trecho
+	Vetor    []float32 // Embedding do trecho
+}
+
+// SalvarFonteConhecimento grava uma fonte e substitui todos os seus trechos anteriores
+func (db *DB) SalvarFonteConhecimento(fonte FonteConhecimento, trechos []TrechoConhecimento) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM trechos_conhecimento WHERE fonte_id IN (SELECT id FROM fontes_conhecimento WHERE caminho = ?)", fonte.Caminho); err != nil {
+		return fmt.Errorf("erro ao excluir trechos antigos de %s: %w", fonte.Caminho, err)
+	}
+	if _, err := tx.Exec("DELETE FROM fontes_conhecimento WHERE caminho = ?", fonte.Caminho); err != nil {
+		return fmt.Errorf("erro ao excluir fonte antiga %s: %w", fonte.Caminho, err)
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO fontes_conhecimento (caminho, hash, modelo, trechos, indexado_em) VALUES (?, ?, ?, ?, ?)",
+		fonte.Caminho, fonte.Hash, fonte.Modelo, len(trechos), fonte.IndexadoEm,
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao salvar fonte %s: %w", fonte.Caminho, err)
+	}
+
+	fonteID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("erro ao obter ID da fonte inserida: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT INTO trechos_conhecimento (fonte_id, posicao, conteudo, vetor) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("erro ao preparar inserção de trechos: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, trecho := range trechos {
+		if _, err := stmt.Exec(fonteID, trecho.Posicao, trecho.Conteudo, codificarVetor(trecho.Vetor)); err != nil {
+			return fmt.Errorf("erro ao salvar trecho %d de %s: %w", trecho.Posicao, fonte.Caminho, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao confirmar transação: %w", err)
+	}
+
+	return nil
+}
+
+// ListarFontesConhecimento retorna as fontes indexadas, ordenadas pelo caminho
+func (db *DB) ListarFontesConhecimento() ([]FonteConhecimento, error) {
+	rows, err := db.conn.Query("SELECT id, caminho, hash, modelo, trechos, indexado_em FROM fontes_conhecimento ORDER BY caminho")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar fontes de conhecimento: %w", err)
+	}
+	defer rows.Close()
+
+	var fontes []FonteConhecimento
+	for rows.Next() {
+		var fonte FonteConhecimento
+		if err := rows.Scan(&fonte.ID, &fonte.Caminho, &fonte.Hash, &fonte.Modelo, &fonte.Trechos, &fonte.IndexadoEm); err != nil {
+			return nil, fmt.Errorf("erro ao ler fonte de conhecimento: %w", err)
+		}
+		fontes = append(fontes, fonte)
+	}
+
+	return fontes, rows.Err()
+}
+
+// ExcluirFonteConhecimento remove uma fonte e os seus trechos
+func (db *DB) ExcluirFonteConhecimento(caminho string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM trechos_conhecimento WHERE fonte_id IN (SELECT id FROM fontes_conhecimento WHERE caminho = ?)", caminho); err != nil {
+		return fmt.Errorf("erro ao excluir trechos de %s: %w", caminho, err)
+	}
+	if _, err := tx.Exec("DELETE FROM fontes_conhecimento WHERE caminho = ?", caminho); err != nil {
+		return fmt.Errorf("erro ao excluir fonte %s: %w", caminho, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao confirmar transação: %w", err)
+	}
+
+	return nil
+}
+
+// ListarTrechosConhecimento retorna todos os trechos indexados com os seus embeddings
+func (db *DB) ListarTrechosConhecimento() ([]TrechoConhecimento, error) {
+	query := `
+		SELECT t.id, t.fonte_id, f.caminho, t.posicao, t.conteudo, t.vetor
+		FROM trechos_conhecimento t
+		JOIN fontes_conhecimento f ON f.id = t.fonte_id
+		ORDER BY f.caminho, t.posicao
+	`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar trechos de conhecimento: %w", err)
+	}
+	defer rows.Close()
+
+	var trechos []TrechoConhecimento
+	for rows.Next() {
+		var trecho TrechoConhecimento
+		var vetor []byte
+		if err := rows.Scan(&trecho.ID, &trecho.FonteID, &trecho.Caminho, &trecho.Posicao, &trecho.Conteudo, &vetor); err != nil {
+			return nil, fmt.Errorf("erro ao ler trecho de conhecimento: %w", err)
+		}
+		trecho.Vetor = decodificarVetor(vetor)
+		trechos = append(trechos, trecho)
+	}
+
+	return trechos, rows.Err()
+}
+
+// codificarVetor converte o embedding em bytes (float32 little-endian) para gravar como BLOB
+func codificarVetor(vetor []float32) []byte {
+	dados := make([]byte, 4*len(vetor))
+	for i, v := range vetor {
+		binary.LittleEndian.PutUint32(dados[4*i:], math.Float32bits(v))
+	}
+	return dados
+}
+
+// decodificarVetor converte o BLOB gravado de volta para o embedding
+func decodificarVetor(dados []byte) []float32 {
+	vetor := make([]float32, len(dados)/4)
+	for i := range vetor {
+		vetor[i] = math.Float32frombits(binary.LittleEndian.Uint32(dados[4*i:]))
+	}
+	return vetor
+}
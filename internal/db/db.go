@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -17,22 +18,116 @@ type DB struct {
 
 // Contato representa um contato do WhatsApp
 type Contato struct {
-	ID         int64
-	JID        string    // ID do contato no WhatsApp
-	Nome       string    // Nome do contato
-	Telefone   string    // Número de telefone formatado
-	UltimaSync time.Time // Último momento de sincronização
+	ID            int64
+	JID           string    // ID do contato no WhatsApp
+	Nome          string    // Nome do contato
+	Telefone      string    // Número de telefone formatado
+	UltimaSync    time.Time // Último momento de sincronização
+	ModoResposta  string    // Como responder ao contato: texto, voz, ambos ou auto; vazio usa o padrão
+	NomeAgenda    string    // Nome salvo na agenda do celular
+	NomePublico   string    // Nome que o contato definiu no WhatsApp
+	NomeComercial string    // Nome verificado das contas comerciais
+}
+
+// Grupo representa um grupo do WhatsApp do qual a conta participa
+type Grupo struct {
+	ID            int64
+	JID           string
+	Nome          string
+	Descricao     string
+	Dono          string // JID de quem criou o grupo
+	CriadoEm      time.Time
+	UltimaSync    time.Time
+	Participantes []Participante
+	Politica      *PoliticaGrupo // nil usa as configurações gerais de grupos
+}
+
+// PoliticaGrupo define como o assistente se comporta em um grupo
+type PoliticaGrupo struct {
+	Ativo                bool          // Se o assistente responde no grupo
+	SomenteMencao        bool          // Se responde apenas quando mencionado ou quando respondem a ele
+	PalavrasChave        []string      // Palavras que também acionam o assistente
+	Persona              string        // System prompt próprio do grupo; vazio usa o padrão
+	ComandosSomenteAdmin bool          // Se apenas administradores podem usar os comandos /bot
+	Intervalo            time.Duration // Intervalo mínimo entre duas respostas no grupo
+}
+
+// ModeracaoGrupo define as mensagens automáticas e as regras de moderação de um grupo
+type ModeracaoGrupo struct {
+	BoasVindas        string        // Mensagem para quem entra no grupo; vazio não envia
+	Despedida         string        // Mensagem para quem sai do grupo; vazio não envia
+	BloquearLinks     bool          // Se links são proibidos
+	LimiteFlood       int           // Máximo de mensagens de um participante dentro de JanelaFlood; 0 desativa
+	JanelaFlood       time.Duration // Período contado pelo limite de flood
+	PalavrasProibidas []string      // Palavras que não podem ser enviadas no grupo
+	Acao              string        // O que fazer com quem viola as regras: avisar, apagar ou remover
+}
+
+// RegistroModeracao é uma ação automática tomada em um grupo
+type RegistroModeracao struct {
+	ID              int64
+	GrupoJID        string
+	ParticipanteJID string
+	Acao            string // boas-vindas, despedida, avisar, apagar ou remover
+	Motivo          string // Regra violada, vazio nas mensagens automáticas
+	MensagemID      string // ID no WhatsApp da mensagem que violou a regra
+	Conteudo        string // Texto da mensagem que violou a regra
+	Erro            string // Falha ao executar a ação, se houver
+	Timestamp       time.Time
+}
+
+// Participante representa um membro de um grupo
+type Participante struct {
+	JID        string
+	Telefone   string // vazio quando o WhatsApp oculta o número
+	Admin      bool
+	SuperAdmin bool // criador do grupo
 }
 
 // Mensagem representa uma mensagem no histórico
 type Mensagem struct {
-	ID        int64
-	JID       string    // ID do contato no WhatsApp
-	Nome      string    // Nome do contato ou remetente
-	Conteudo  string    // Conteúdo da mensagem
-	Resposta  string    // Resposta gerada pelo LLM
-	Timestamp time.Time // Momento do recebimento/envio
-	Entrada   bool      // True = recebida do contato, False = enviada pelo sistema
+	ID                 int64
+	JID                string    // ID do contato no WhatsApp
+	Nome               string    // Nome do contato ou remetente
+	Conteudo           string    // Conteúdo da mensagem
+	Resposta           string    // Resposta gerada pelo LLM
+	Timestamp          time.Time // Momento do recebimento/envio
+	Entrada            bool      // True = recebida do contato, False = enviada pelo sistema
+	Tipo               string    // Tipo da mensagem: text, image, audio, video, document, sticker, location, contact...
+	Anexo              string    // Caminho do arquivo de mídia baixado, se houver
+	AnexoMime          string    // Tipo MIME do anexo
+	Transcricao        string    // Texto reconhecido em mensagens de voz
+	WhatsAppID         string    // ID da mensagem no WhatsApp
+	RespostaWhatsAppID string    // ID no WhatsApp da resposta enviada
+	Reacao             string    // Última reação (emoji) do contato à mensagem ou à resposta
+	Status             string    // Situação da mensagem enviada ao contato (resposta ou envio manual)
+	Editada            bool      // O contato (ou o operador, em envios manuais) editou a mensagem
+	ConteudoOriginal   string    // Conteúdo antes da primeira edição, mantido para auditoria
+	Excluida           bool      // A mensagem foi apagada para todos
+	RespostaEditada    bool      // O operador editou a resposta enviada
+	RespostaOriginal   string    // Resposta antes da primeira edição
+	RespostaExcluida   bool      // O operador apagou a resposta para todos
+}
+
+// Status das mensagens, na ordem em que avançam. Uma mensagem enviada passa de pendente
+// a enviada, entregue e lida, ou termina como falhou
+const (
+	StatusRecebida = "recebida" // mensagem do contato ainda sem resposta
+	StatusPendente = "pendente" // resposta em envio
+	StatusEnviada  = "enviada"  // aceita pelo servidor do WhatsApp
+	StatusEntregue = "entregue" // entregue no aparelho do contato
+	StatusLida     = "lida"     // lida pelo contato
+	StatusFalhou   = "falhou"   // o envio não foi concluído
+)
+
+// statusAnteriores lista, para os status informados pelos recibos do WhatsApp e pelo fim do envio,
+// os status que eles substituem. Recibos chegam fora de ordem, então uma mensagem lida nunca volta
+// a ser apenas entregue
+var statusAnteriores = map[string][]interface{}{
+	StatusEnviada:  {"", StatusRecebida, StatusPendente},
+	StatusEntregue: {"", StatusPendente, StatusEnviada, StatusFalhou},
+	StatusLida:     {"", StatusPendente, StatusEnviada, StatusEntregue, StatusFalhou},
+	StatusFalhou:   {"", StatusPendente, StatusEnviada},
 }
 
 // Opções para consulta de mensagens
@@ -109,22 +204,173 @@ func (db *DB) inicializarTabelas() error {
 		
 		CREATE INDEX IF NOT EXISTS idx_contatos_jid ON contatos(jid);
 		CREATE INDEX IF NOT EXISTS idx_contatos_nome ON contatos(nome);
+		
+		-- Base de conhecimento: arquivos indexados e seus trechos com os embeddings
+		CREATE TABLE IF NOT EXISTS fontes_conhecimento (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			caminho TEXT NOT NULL UNIQUE,
+			hash TEXT NOT NULL,
+			modelo TEXT NOT NULL,
+			trechos INTEGER NOT NULL,
+			indexado_em DATETIME NOT NULL
+		);
+		
+		CREATE TABLE IF NOT EXISTS trechos_conhecimento (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			fonte_id INTEGER NOT NULL,
+			posicao INTEGER NOT NULL,
+			conteudo TEXT NOT NULL,
+			vetor BLOB NOT NULL
+		);
+		
+		CREATE INDEX IF NOT EXISTS idx_trechos_conhecimento_fonte ON trechos_conhecimento(fonte_id);
+		
+		-- Grupos dos quais a conta participa e seus membros
+		CREATE TABLE IF NOT EXISTS grupos (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			jid TEXT NOT NULL UNIQUE,
+			nome TEXT NOT NULL,
+			descricao TEXT NOT NULL DEFAULT '',
+			dono TEXT NOT NULL DEFAULT '',
+			criado_em DATETIME,
+			ultima_sync DATETIME NOT NULL
+		);
+		
+		CREATE TABLE IF NOT EXISTS participantes_grupo (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			grupo_jid TEXT NOT NULL,
+			jid TEXT NOT NULL,
+			telefone TEXT NOT NULL DEFAULT '',
+			admin BOOLEAN NOT NULL DEFAULT 0,
+			superadmin BOOLEAN NOT NULL DEFAULT 0,
+			UNIQUE(grupo_jid, jid)
+		);
+		
+		CREATE INDEX IF NOT EXISTS idx_participantes_grupo_jid ON participantes_grupo(jid);
+		
+		-- Moderação dos grupos e o registro das ações tomadas
+		CREATE TABLE IF NOT EXISTS moderacao_grupos (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			grupo_jid TEXT NOT NULL UNIQUE,
+			boas_vindas TEXT NOT NULL DEFAULT '',
+			despedida TEXT NOT NULL DEFAULT '',
+			bloquear_links BOOLEAN NOT NULL DEFAULT 0,
+			limite_flood INTEGER NOT NULL DEFAULT 0,
+			janela_flood_segundos INTEGER NOT NULL DEFAULT 0,
+			palavras_proibidas TEXT NOT NULL DEFAULT '',
+			acao TEXT NOT NULL DEFAULT 'avisar'
+		);
+		
+		CREATE TABLE IF NOT EXISTS log_moderacao (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			grupo_jid TEXT NOT NULL,
+			participante_jid TEXT NOT NULL,
+			acao TEXT NOT NULL,
+			motivo TEXT NOT NULL DEFAULT '',
+			mensagem_id TEXT NOT NULL DEFAULT '',
+			conteudo TEXT NOT NULL DEFAULT '',
+			erro TEXT NOT NULL DEFAULT '',
+			timestamp DATETIME NOT NULL
+		);
+		
+		CREATE INDEX IF NOT EXISTS idx_log_moderacao_grupo ON log_moderacao(grupo_jid, timestamp);
 	`)
 
 	if err != nil {
 		return fmt.Errorf("erro ao criar tabelas: %w", err)
 	}
 
+	// Colunas adicionadas depois da criação da tabela de mensagens
+	colunas := []struct{ tabela, coluna, definicao string }{
+		{"mensagens", "tipo", "TEXT NOT NULL DEFAULT 'text'"},
+		{"mensagens", "anexo", "TEXT NOT NULL DEFAULT ''"},
+		{"mensagens", "anexo_mime", "TEXT NOT NULL DEFAULT ''"},
+		{"mensagens", "transcricao", "TEXT NOT NULL DEFAULT ''"},
+		{"contatos", "modo_resposta", "TEXT NOT NULL DEFAULT ''"},
+		{"mensagens", "whatsapp_id", "TEXT NOT NULL DEFAULT ''"},
+		{"mensagens", "resposta_whatsapp_id", "TEXT NOT NULL DEFAULT ''"},
+		{"mensagens", "reacao", "TEXT NOT NULL DEFAULT ''"},
+		{"mensagens", "status", "TEXT NOT NULL DEFAULT ''"},
+		{"mensagens", "editada", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"mensagens", "conteudo_original", "TEXT NOT NULL DEFAULT ''"},
+		{"mensagens", "excluida", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"mensagens", "resposta_editada", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"mensagens", "resposta_original", "TEXT NOT NULL DEFAULT ''"},
+		{"mensagens", "resposta_excluida", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"contatos", "nome_agenda", "TEXT NOT NULL DEFAULT ''"},
+		{"contatos", "nome_publico", "TEXT NOT NULL DEFAULT ''"},
+		{"contatos", "nome_comercial", "TEXT NOT NULL DEFAULT ''"},
+		{"grupos", "politica_definida", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"grupos", "ativo", "BOOLEAN NOT NULL DEFAULT 1"},
+		{"grupos", "somente_mencao", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"grupos", "palavras_chave", "TEXT NOT NULL DEFAULT ''"},
+		{"grupos", "persona", "TEXT NOT NULL DEFAULT ''"},
+		{"grupos", "comandos_somente_admin", "BOOLEAN NOT NULL DEFAULT 1"},
+		{"grupos", "intervalo_segundos", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range colunas {
+		if err := db.adicionarColuna(c.tabela, c.coluna, c.definicao); err != nil {
+			return err
+		}
+	}
+
+	// Índices das colunas adicionadas acima
+	indices := `
+		CREATE INDEX IF NOT EXISTS idx_mensagens_whatsapp_id ON mensagens(whatsapp_id);
+		CREATE INDEX IF NOT EXISTS idx_mensagens_resposta_whatsapp_id ON mensagens(resposta_whatsapp_id);
+	`
+	if _, err := db.conn.Exec(indices); err != nil {
+		return fmt.Errorf("erro ao criar índices: %w", err)
+	}
+
+	return nil
+}
+
+// adicionarColuna cria a coluna em bancos criados por versões anteriores, se ainda não existir
+func (db *DB) adicionarColuna(tabela, coluna, definicao string) error {
+	rows, err := db.conn.Query(fmt.Sprintf("PRAGMA table_info(%s)", tabela))
+	if err != nil {
+		return fmt.Errorf("erro ao ler colunas de %s: %w", tabela, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			nome, tipo       string
+			padrao           sql.NullString
+		)
+		if err := rows.Scan(&cid, &nome, &tipo, &notNull, &padrao, &pk); err != nil {
+			return fmt.Errorf("erro ao ler colunas de %s: %w", tabela, err)
+		}
+		if nome == coluna {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("erro ao ler colunas de %s: %w", tabela, err)
+	}
+	rows.Close()
+
+	if _, err := db.conn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tabela, coluna, definicao)); err != nil {
+		return fmt.Errorf("erro ao adicionar coluna %s em %s: %w", coluna, tabela, err)
+	}
 	return nil
 }
 
 // SalvarMensagem salva uma nova mensagem no histórico
 func (db *DB) SalvarMensagem(msg Mensagem) (int64, error) {
 	query := `
-		INSERT INTO mensagens (jid, nome, conteudo, resposta, timestamp, entrada)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO mensagens (jid, nome, conteudo, resposta, timestamp, entrada, tipo, anexo, anexo_mime, transcricao, whatsapp_id, resposta_whatsapp_id, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
+	// Mensagens sem tipo informado são de texto
+	tipo := msg.Tipo
+	if tipo == "" {
+		tipo = "text"
+	}
+
 	result, err := db.conn.Exec(
 		query,
 		msg.JID,
@@ -133,6 +379,13 @@ func (db *DB) SalvarMensagem(msg Mensagem) (int64, error) {
 		msg.Resposta,
 		msg.Timestamp,
 		msg.Entrada,
+		tipo,
+		msg.Anexo,
+		msg.AnexoMime,
+		msg.Transcricao,
+		msg.WhatsAppID,
+		msg.RespostaWhatsAppID,
+		msg.Status,
 	)
 
 	if err != nil {
@@ -147,6 +400,52 @@ func (db *DB) SalvarMensagem(msg Mensagem) (int64, error) {
 	return id, nil
 }
 
+// ImportarMensagens grava mensagens antigas, como as da sincronização de histórico do WhatsApp.
+// Mensagens sem ID do WhatsApp ou já registradas, como mensagem ou como resposta, são ignoradas,
+// então o mesmo lote pode ser importado mais de uma vez. Retorna quantas mensagens foram gravadas
+func (db *DB) ImportarMensagens(mensagens []Mensagem) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao importar mensagens: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO mensagens (jid, nome, conteudo, resposta, timestamp, entrada, tipo, anexo, anexo_mime, transcricao, whatsapp_id, resposta_whatsapp_id, status)
+		SELECT ?, ?, ?, '', ?, ?, ?, '', '', '', ?, '', ?
+		WHERE NOT EXISTS (SELECT 1 FROM mensagens WHERE whatsapp_id = ? OR resposta_whatsapp_id = ?)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao importar mensagens: %w", err)
+	}
+	defer stmt.Close()
+
+	importadas := 0
+	for _, msg := range mensagens {
+		if msg.WhatsAppID == "" {
+			continue
+		}
+
+		tipo := msg.Tipo
+		if tipo == "" {
+			tipo = "text"
+		}
+
+		result, err := stmt.Exec(msg.JID, msg.Nome, msg.Conteudo, msg.Timestamp, msg.Entrada, tipo, msg.WhatsAppID, msg.Status, msg.WhatsAppID, msg.WhatsAppID)
+		if err != nil {
+			return 0, fmt.Errorf("erro ao importar mensagem %s: %w", msg.WhatsAppID, err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			importadas += int(n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("erro ao importar mensagens: %w", err)
+	}
+	return importadas, nil
+}
+
 // AtualizarResposta atualiza a resposta associada a uma mensagem
 func (db *DB) AtualizarResposta(id int64, resposta string) error {
 	_, err := db.conn.Exec("UPDATE mensagens SET resposta = ? WHERE id = ?", resposta, id)
@@ -156,9 +455,212 @@ func (db *DB) AtualizarResposta(id int64, resposta string) error {
 	return nil
 }
 
+// AtualizarTranscricao registra o texto reconhecido de uma mensagem de voz
+func (db *DB) AtualizarTranscricao(id int64, transcricao string) error {
+	_, err := db.conn.Exec("UPDATE mensagens SET transcricao = ? WHERE id = ?", transcricao, id)
+	if err != nil {
+		return fmt.Errorf("erro ao atualizar transcrição da mensagem %d: %w", id, err)
+	}
+	return nil
+}
+
+// AtualizarRespostaWhatsAppID registra o ID no WhatsApp da resposta enviada a uma mensagem
+func (db *DB) AtualizarRespostaWhatsAppID(id int64, whatsappID string) error {
+	_, err := db.conn.Exec("UPDATE mensagens SET resposta_whatsapp_id = ? WHERE id = ?", whatsappID, id)
+	if err != nil {
+		return fmt.Errorf("erro ao atualizar ID da resposta da mensagem %d: %w", id, err)
+	}
+	return nil
+}
+
+// AtualizarReacao registra a reação recebida para a mensagem ou a resposta com o ID do WhatsApp informado.
+// Uma reação vazia indica que o contato a removeu. Retorna falso se a mensagem não estiver no histórico
+func (db *DB) AtualizarReacao(whatsappID, reacao string) (bool, error) {
+	if whatsappID == "" {
+		return false, nil
+	}
+
+	result, err := db.conn.Exec(
+		"UPDATE mensagens SET reacao = ? WHERE whatsapp_id = ? OR resposta_whatsapp_id = ?",
+		reacao, whatsappID, whatsappID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("erro ao atualizar reação da mensagem %s: %w", whatsappID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("erro ao atualizar reação da mensagem %s: %w", whatsappID, err)
+	}
+	return n > 0, nil
+}
+
+// AtualizarStatus define o status da mensagem enviada a partir da mensagem com o ID informado
+func (db *DB) AtualizarStatus(id int64, status string) error {
+	_, err := db.conn.Exec("UPDATE mensagens SET status = ? WHERE id = ?", status, id)
+	if err != nil {
+		return fmt.Errorf("erro ao atualizar status da mensagem %d: %w", id, err)
+	}
+	return nil
+}
+
+// AvancarStatus aplica à mensagem com o ID informado o status do fim do envio da resposta, com a mesma
+// regra dos recibos: o status só avança, para não desfazer os recibos das partes já entregues.
+// Retorna falso se a mensagem não estiver no histórico ou já estiver em um status posterior
+func (db *DB) AvancarStatus(id int64, status string) (bool, error) {
+	anteriores, ok := statusAnteriores[status]
+	if !ok {
+		return false, fmt.Errorf("status %q não pode ser aplicado ao fim do envio", status)
+	}
+
+	query := fmt.Sprintf(
+		"UPDATE mensagens SET status = ? WHERE id = ? AND status IN (?%s)",
+		strings.Repeat(", ?", len(anteriores)-1),
+	)
+	args := append([]interface{}{status, id}, anteriores...)
+
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("erro ao atualizar status da mensagem %d: %w", id, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("erro ao atualizar status da mensagem %d: %w", id, err)
+	}
+	return n > 0, nil
+}
+
+// AtualizarStatusPorWhatsAppID aplica o status de um recibo à mensagem enviada com o ID do WhatsApp informado,
+// seja ela uma resposta ou um envio manual. O status só avança: recibos atrasados não desfazem uma leitura.
+// Retorna falso se a mensagem não estiver no histórico ou já estiver em um status posterior
+func (db *DB) AtualizarStatusPorWhatsAppID(whatsappID, status string) (bool, error) {
+	anteriores, ok := statusAnteriores[status]
+	if whatsappID == "" || !ok {
+		return false, nil
+	}
+
+	query := fmt.Sprintf(
+		"UPDATE mensagens SET status = ? WHERE (resposta_whatsapp_id = ? OR (whatsapp_id = ? AND entrada = 0)) AND status IN (?%s)",
+		strings.Repeat(", ?", len(anteriores)-1),
+	)
+	args := append([]interface{}{status, whatsappID, whatsappID}, anteriores...)
+
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("erro ao atualizar status da mensagem %s: %w", whatsappID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("erro ao atualizar status da mensagem %s: %w", whatsappID, err)
+	}
+	return n > 0, nil
+}
+
+// RegistrarEdicao aplica a edição da mensagem ou da resposta com o ID do WhatsApp informado.
+// O texto anterior à primeira edição é preservado para auditoria. Retorna falso se a mensagem não estiver no histórico
+func (db *DB) RegistrarEdicao(whatsappID, texto string) (bool, error) {
+	if whatsappID == "" {
+		return false, nil
+	}
+
+	// O SQLite avalia o CASE com os valores anteriores ao UPDATE
+	return db.atualizarPorWhatsAppID(whatsappID, "edição",
+		"UPDATE mensagens SET conteudo_original = CASE WHEN editada THEN conteudo_original ELSE conteudo END, conteudo = ?, editada = 1 WHERE whatsapp_id = ?",
+		"UPDATE mensagens SET resposta_original = CASE WHEN resposta_editada THEN resposta_original ELSE resposta END, resposta = ?, resposta_editada = 1 WHERE resposta_whatsapp_id = ?",
+		texto,
+	)
+}
+
+// RegistrarExclusao marca como apagada para todos a mensagem ou a resposta com o ID do WhatsApp informado.
+// O conteúdo é mantido no histórico. Retorna falso se a mensagem não estiver no histórico
+func (db *DB) RegistrarExclusao(whatsappID string) (bool, error) {
+	if whatsappID == "" {
+		return false, nil
+	}
+
+	return db.atualizarPorWhatsAppID(whatsappID, "exclusão",
+		"UPDATE mensagens SET excluida = 1 WHERE whatsapp_id = ?",
+		"UPDATE mensagens SET resposta_excluida = 1 WHERE resposta_whatsapp_id = ?",
+	)
+}
+
+// atualizarPorWhatsAppID executa a atualização da mensagem e a da resposta com o ID do WhatsApp,
+// que é sempre o último argumento. Retorna verdadeiro se alguma linha foi alterada
+func (db *DB) atualizarPorWhatsAppID(whatsappID, operacao, queryMensagem, queryResposta string, args ...interface{}) (bool, error) {
+	args = append(args, whatsappID)
+
+	var total int64
+	for _, query := range []string{queryMensagem, queryResposta} {
+		result, err := db.conn.Exec(query, args...)
+		if err != nil {
+			return false, fmt.Errorf("erro ao registrar %s da mensagem %s: %w", operacao, whatsappID, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("erro ao registrar %s da mensagem %s: %w", operacao, whatsappID, err)
+		}
+		total += n
+	}
+	return total > 0, nil
+}
+
+// BuscarMensagemPorWhatsAppID retorna a mensagem com o ID do WhatsApp informado, recebida ou enviada
+// como resposta, ou nil se ela não estiver no histórico
+func (db *DB) BuscarMensagemPorWhatsAppID(whatsappID string) (*Mensagem, error) {
+	if whatsappID == "" {
+		return nil, nil
+	}
+
+	row := db.conn.QueryRow(
+		"SELECT "+colunasMensagem+" FROM mensagens WHERE whatsapp_id = ? OR resposta_whatsapp_id = ? ORDER BY id LIMIT 1",
+		whatsappID, whatsappID,
+	)
+	msg, err := lerMensagem(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// colunasMensagem são as colunas lidas por lerMensagem, na mesma ordem
+const colunasMensagem = "id, jid, nome, conteudo, resposta, timestamp, entrada, tipo, anexo, anexo_mime, transcricao, whatsapp_id, resposta_whatsapp_id, reacao, status, " +
+	"editada, conteudo_original, excluida, resposta_editada, resposta_original, resposta_excluida"
+
+// lerMensagem lê uma linha com as colunas de colunasMensagem
+func lerMensagem(row interface{ Scan(dest ...interface{}) error }) (Mensagem, error) {
+	var msg Mensagem
+	var timestamp string // SQLite retorna timestamp como string
+
+	err := row.Scan(&msg.ID, &msg.JID, &msg.Nome, &msg.Conteudo, &msg.Resposta, &timestamp, &msg.Entrada, &msg.Tipo,
+		&msg.Anexo, &msg.AnexoMime, &msg.Transcricao, &msg.WhatsAppID, &msg.RespostaWhatsAppID, &msg.Reacao, &msg.Status,
+		&msg.Editada, &msg.ConteudoOriginal, &msg.Excluida, &msg.RespostaEditada, &msg.RespostaOriginal, &msg.RespostaExcluida)
+	if err == sql.ErrNoRows {
+		return msg, err
+	}
+	if err != nil {
+		return msg, fmt.Errorf("erro ao ler mensagem: %w", err)
+	}
+
+	// Converte o timestamp para time.Time
+	// Tenta primeiro no formato RFC3339 (formato padrão do Go)
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		// Se falhar, tenta no formato SQLite padrão
+		t, err = time.Parse("2006-01-02 15:04:05", timestamp)
+		if err != nil {
+			return msg, fmt.Errorf("erro ao processar timestamp: %w", err)
+		}
+	}
+	msg.Timestamp = t
+
+	return msg, nil
+}
+
 // BuscarMensagens busca mensagens no histórico com base nas opções fornecidas
 func (db *DB) BuscarMensagens(opcoes OpcoesConsulta) ([]Mensagem, error) {
-	query := "SELECT id, jid, nome, conteudo, resposta, timestamp, entrada FROM mensagens WHERE 1=1"
+	query := "SELECT " + colunasMensagem + " FROM mensagens WHERE 1=1"
 	args := []interface{}{}
 
 	// Adiciona filtros à consulta
@@ -202,25 +704,10 @@ func (db *DB) BuscarMensagens(opcoes OpcoesConsulta) ([]Mensagem, error) {
 	// Processa os resultados
 	var mensagens []Mensagem
 	for rows.Next() {
-		var msg Mensagem
-		var timestamp string // SQLite retorna timestamp como string
-
-		if err := rows.Scan(&msg.ID, &msg.JID, &msg.Nome, &msg.Conteudo, &msg.Resposta, &timestamp, &msg.Entrada); err != nil {
-			return nil, fmt.Errorf("erro ao ler mensagem: %w", err)
-		}
-
-		// Converte o timestamp para time.Time
-		// Tenta primeiro no formato RFC3339 (formato padrão do Go)
-		t, err := time.Parse(time.RFC3339, timestamp)
+		msg, err := lerMensagem(rows)
 		if err != nil {
-			// Se falhar, tenta no formato SQLite padrão
-			t, err = time.Parse("2006-01-02 15:04:05", timestamp)
-			if err != nil {
-				return nil, fmt.Errorf("erro ao processar timestamp: %w", err)
-			}
+			return nil, err
 		}
-		msg.Timestamp = t
-
 		mensagens = append(mensagens, msg)
 	}
 
@@ -332,9 +819,61 @@ func (db *DB) SincronizarContato(jid, nome, telefone string) error {
 	return nil
 }
 
+// AtualizarContato adiciona ou atualiza um contato com os nomes informados pelo WhatsApp.
+// Campos vazios mantêm o valor salvo. O nome exibido é o da agenda, o comercial, o público
+// ou o telefone, nessa ordem de preferência
+func (db *DB) AtualizarContato(contato Contato) error {
+	nome := contato.Telefone
+	for _, n := range []string{contato.NomePublico, contato.NomeComercial, contato.NomeAgenda} {
+		if n != "" {
+			nome = n
+		}
+	}
+	
+	query := `
+		INSERT INTO contatos (jid, nome, telefone, nome_agenda, nome_publico, nome_comercial, ultima_sync)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET
+			telefone = COALESCE(NULLIF(excluded.telefone, ''), contatos.telefone),
+			nome_agenda = COALESCE(NULLIF(excluded.nome_agenda, ''), contatos.nome_agenda),
+			nome_publico = COALESCE(NULLIF(excluded.nome_publico, ''), contatos.nome_publico),
+			nome_comercial = COALESCE(NULLIF(excluded.nome_comercial, ''), contatos.nome_comercial),
+			nome = COALESCE(
+				NULLIF(excluded.nome_agenda, ''), NULLIF(contatos.nome_agenda, ''),
+				NULLIF(excluded.nome_comercial, ''), NULLIF(contatos.nome_comercial, ''),
+				NULLIF(excluded.nome_publico, ''), NULLIF(contatos.nome_publico, ''),
+				NULLIF(contatos.nome, ''), excluded.nome
+			),
+			ultima_sync = excluded.ultima_sync
+	`
+	_, err := db.conn.Exec(query, contato.JID, nome, contato.Telefone, contato.NomeAgenda, contato.NomePublico, contato.NomeComercial, time.Now())
+	if err != nil {
+		return fmt.Errorf("erro ao sincronizar contato: %w", err)
+	}
+	return nil
+}
+
+// ImportarNomeContato registra o nome público informado pelo WhatsApp e o usa como nome do contato
+// apenas se ainda não houver um conhecido
+func (db *DB) ImportarNomeContato(jid, nome, telefone string) error {
+	query := `
+		INSERT INTO contatos (jid, nome, telefone, nome_publico, ultima_sync)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET
+			nome = CASE WHEN contatos.nome = '' THEN excluded.nome ELSE contatos.nome END,
+			nome_publico = excluded.nome_publico,
+			ultima_sync = excluded.ultima_sync
+	`
+	if _, err := db.conn.Exec(query, jid, nome, telefone, nome, time.Now()); err != nil {
+		return fmt.Errorf("erro ao importar contato: %w", err)
+	}
+	return nil
+}
+
 // ListarTodosContatos retorna todos os contatos cadastrados na tabela de contatos
 func (db *DB) ListarTodosContatos() ([]Contato, error) {
-	query := `SELECT id, jid, nome, telefone, ultima_sync FROM contatos ORDER BY nome`
+	// Contatos criados por versões anteriores podem ter ficado sem telefone
+	query := `SELECT id, jid, nome, COALESCE(telefone, ''), ultima_sync, modo_resposta, nome_agenda, nome_publico, nome_comercial FROM contatos ORDER BY nome`
 	
 	rows, err := db.conn.Query(query)
 	if err != nil {
@@ -345,7 +884,8 @@ func (db *DB) ListarTodosContatos() ([]Contato, error) {
 	var contatos []Contato
 	for rows.Next() {
 		var contato Contato
-		if err := rows.Scan(&contato.ID, &contato.JID, &contato.Nome, &contato.Telefone, &contato.UltimaSync); err != nil {
+		if err := rows.Scan(&contato.ID, &contato.JID, &contato.Nome, &contato.Telefone, &contato.UltimaSync, &contato.ModoResposta,
+			&contato.NomeAgenda, &contato.NomePublico, &contato.NomeComercial); err != nil {
 			return nil, fmt.Errorf("erro ao ler contato: %w", err)
 		}
 		contatos = append(contatos, contato)
@@ -354,6 +894,331 @@ func (db *DB) ListarTodosContatos() ([]Contato, error) {
 	return contatos, nil
 }
 
+// SincronizarGrupo adiciona ou atualiza um grupo e substitui a lista de participantes
+func (db *DB) SincronizarGrupo(grupo Grupo) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("erro ao sincronizar grupo: %w", err)
+	}
+	defer tx.Rollback()
+	
+	_, err = tx.Exec(`
+		INSERT INTO grupos (jid, nome, descricao, dono, criado_em, ultima_sync)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET
+			nome = excluded.nome,
+			descricao = excluded.descricao,
+			dono = excluded.dono,
+			criado_em = excluded.criado_em,
+			ultima_sync = excluded.ultima_sync
+	`, grupo.JID, grupo.Nome, grupo.Descricao, grupo.Dono, grupo.CriadoEm, time.Now())
+	if err != nil {
+		return fmt.Errorf("erro ao sincronizar grupo: %w", err)
+	}
+	
+	if _, err := tx.Exec("DELETE FROM participantes_grupo WHERE grupo_jid = ?", grupo.JID); err != nil {
+		return fmt.Errorf("erro ao atualizar participantes do grupo: %w", err)
+	}
+	for _, p := range grupo.Participantes {
+		_, err := tx.Exec(
+			"INSERT OR REPLACE INTO participantes_grupo (grupo_jid, jid, telefone, admin, superadmin) VALUES (?, ?, ?, ?, ?)",
+			grupo.JID, p.JID, p.Telefone, p.Admin, p.SuperAdmin,
+		)
+		if err != nil {
+			return fmt.Errorf("erro ao atualizar participantes do grupo: %w", err)
+		}
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao sincronizar grupo: %w", err)
+	}
+	return nil
+}
+
+// BuscarGrupo retorna o grupo com seus participantes, ou nil se ele não estiver cadastrado
+func (db *DB) BuscarGrupo(jid string) (*Grupo, error) {
+	grupos, err := db.buscarGrupos("WHERE jid = ?", jid)
+	if err != nil || len(grupos) == 0 {
+		return nil, err
+	}
+	return &grupos[0], nil
+}
+
+// ListarGrupos retorna todos os grupos cadastrados, com seus participantes, ordenados pelo nome
+func (db *DB) ListarGrupos() ([]Grupo, error) {
+	return db.buscarGrupos("")
+}
+
+// buscarGrupos lê os grupos que atendem ao filtro e, em seguida, os participantes de cada um
+func (db *DB) buscarGrupos(filtro string, args ...interface{}) ([]Grupo, error) {
+	rows, err := db.conn.Query(
+		"SELECT id, jid, nome, descricao, dono, criado_em, ultima_sync, "+colunasPolitica+" FROM grupos "+filtro+" ORDER BY nome",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar grupos: %w", err)
+	}
+	defer rows.Close()
+	
+	var grupos []Grupo
+	indices := make(map[string]int)
+	for rows.Next() {
+		var g Grupo
+		var criadoEm sql.NullTime
+		var politica politicaLida
+		if err := rows.Scan(append([]interface{}{&g.ID, &g.JID, &g.Nome, &g.Descricao, &g.Dono, &criadoEm, &g.UltimaSync}, politica.destinos()...)...); err != nil {
+			return nil, fmt.Errorf("erro ao ler grupo: %w", err)
+		}
+		g.CriadoEm = criadoEm.Time
+		g.Politica = politica.politica()
+		indices[g.JID] = len(grupos)
+		grupos = append(grupos, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler grupos: %w", err)
+	}
+	rows.Close()
+	
+	participantes, err := db.conn.Query(
+		"SELECT grupo_jid, jid, telefone, admin, superadmin FROM participantes_grupo WHERE grupo_jid IN (SELECT jid FROM grupos "+filtro+") ORDER BY id",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar participantes: %w", err)
+	}
+	defer participantes.Close()
+	
+	for participantes.Next() {
+		var grupoJID string
+		var p Participante
+		if err := participantes.Scan(&grupoJID, &p.JID, &p.Telefone, &p.Admin, &p.SuperAdmin); err != nil {
+			return nil, fmt.Errorf("erro ao ler participante: %w", err)
+		}
+		if i, ok := indices[grupoJID]; ok {
+			grupos[i].Participantes = append(grupos[i].Participantes, p)
+		}
+	}
+	return grupos, participantes.Err()
+}
+
+// colunasPolitica são as colunas da política do grupo lidas por politicaLida, na mesma ordem
+const colunasPolitica = "politica_definida, ativo, somente_mencao, palavras_chave, persona, comandos_somente_admin, intervalo_segundos"
+
+// politicaLida recebe as colunas de colunasPolitica
+type politicaLida struct {
+	definida          bool
+	ativo             bool
+	somenteMencao     bool
+	palavrasChave     string
+	persona           string
+	somenteAdmin      bool
+	intervaloSegundos int64
+}
+
+func (p *politicaLida) destinos() []interface{} {
+	return []interface{}{&p.definida, &p.ativo, &p.somenteMencao, &p.palavrasChave, &p.persona, &p.somenteAdmin, &p.intervaloSegundos}
+}
+
+// politica retorna a política lida, ou nil se o grupo usa as configurações gerais
+func (p *politicaLida) politica() *PoliticaGrupo {
+	if !p.definida {
+		return nil
+	}
+	politica := &PoliticaGrupo{
+		Ativo:                p.ativo,
+		SomenteMencao:        p.somenteMencao,
+		Persona:              p.persona,
+		ComandosSomenteAdmin: p.somenteAdmin,
+		Intervalo:            time.Duration(p.intervaloSegundos) * time.Second,
+		PalavrasChave:        separarPalavras(p.palavrasChave),
+	}
+	return politica
+}
+
+// separarPalavras lê uma lista de palavras salva separada por vírgulas
+func separarPalavras(texto string) []string {
+	var palavras []string
+	for _, palavra := range strings.Split(texto, ",") {
+		if palavra = strings.TrimSpace(palavra); palavra != "" {
+			palavras = append(palavras, palavra)
+		}
+	}
+	return palavras
+}
+
+// juntarPalavras prepara uma lista de palavras para ser salva, descartando as vazias
+func juntarPalavras(palavras []string) string {
+	return strings.Join(separarPalavras(strings.Join(palavras, ",")), ", ")
+}
+
+// DefinirPoliticaGrupo define a política do grupo, mesmo que ele ainda não tenha sido sincronizado.
+// Uma política nil faz o grupo voltar a usar as configurações gerais
+func (db *DB) DefinirPoliticaGrupo(jid string, politica *PoliticaGrupo) error {
+	if politica == nil {
+		if _, err := db.conn.Exec("UPDATE grupos SET politica_definida = 0 WHERE jid = ?", jid); err != nil {
+			return fmt.Errorf("erro ao remover política do grupo: %w", err)
+		}
+		return nil
+	}
+	
+	query := `
+		INSERT INTO grupos (jid, nome, ultima_sync, ` + colunasPolitica + `)
+		VALUES (?, '', ?, 1, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET
+			politica_definida = 1,
+			ativo = excluded.ativo,
+			somente_mencao = excluded.somente_mencao,
+			palavras_chave = excluded.palavras_chave,
+			persona = excluded.persona,
+			comandos_somente_admin = excluded.comandos_somente_admin,
+			intervalo_segundos = excluded.intervalo_segundos
+	`
+	_, err := db.conn.Exec(query, jid, time.Now(), politica.Ativo, politica.SomenteMencao, juntarPalavras(politica.PalavrasChave),
+		politica.Persona, politica.ComandosSomenteAdmin, int64(politica.Intervalo/time.Second))
+	if err != nil {
+		return fmt.Errorf("erro ao definir política do grupo: %w", err)
+	}
+	return nil
+}
+
+// ObterPoliticaGrupo retorna a política do grupo, ou nil se ele usa as configurações gerais
+func (db *DB) ObterPoliticaGrupo(jid string) (*PoliticaGrupo, error) {
+	var politica politicaLida
+	err := db.conn.QueryRow("SELECT "+colunasPolitica+" FROM grupos WHERE jid = ?", jid).Scan(politica.destinos()...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao obter política do grupo: %w", err)
+	}
+	return politica.politica(), nil
+}
+
+// DefinirModeracaoGrupo define a moderação do grupo. Uma moderação nil desativa as mensagens
+// automáticas e as regras do grupo
+func (db *DB) DefinirModeracaoGrupo(jid string, moderacao *ModeracaoGrupo) error {
+	if moderacao == nil {
+		if _, err := db.conn.Exec("DELETE FROM moderacao_grupos WHERE grupo_jid = ?", jid); err != nil {
+			return fmt.Errorf("erro ao remover moderação do grupo: %w", err)
+		}
+		return nil
+	}
+	
+	query := `
+		INSERT INTO moderacao_grupos (grupo_jid, boas_vindas, despedida, bloquear_links, limite_flood, janela_flood_segundos, palavras_proibidas, acao)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(grupo_jid) DO UPDATE SET
+			boas_vindas = excluded.boas_vindas,
+			despedida = excluded.despedida,
+			bloquear_links = excluded.bloquear_links,
+			limite_flood = excluded.limite_flood,
+			janela_flood_segundos = excluded.janela_flood_segundos,
+			palavras_proibidas = excluded.palavras_proibidas,
+			acao = excluded.acao
+	`
+	_, err := db.conn.Exec(query, jid, moderacao.BoasVindas, moderacao.Despedida, moderacao.BloquearLinks, moderacao.LimiteFlood,
+		int64(moderacao.JanelaFlood/time.Second), juntarPalavras(moderacao.PalavrasProibidas), moderacao.Acao)
+	if err != nil {
+		return fmt.Errorf("erro ao definir moderação do grupo: %w", err)
+	}
+	return nil
+}
+
+// ObterModeracaoGrupo retorna a moderação do grupo, ou nil se ele não é moderado
+func (db *DB) ObterModeracaoGrupo(jid string) (*ModeracaoGrupo, error) {
+	var (
+		moderacao ModeracaoGrupo
+		janela    int64
+		proibidas string
+	)
+	err := db.conn.QueryRow(`
+		SELECT boas_vindas, despedida, bloquear_links, limite_flood, janela_flood_segundos, palavras_proibidas, acao
+		FROM moderacao_grupos WHERE grupo_jid = ?
+	`, jid).Scan(&moderacao.BoasVindas, &moderacao.Despedida, &moderacao.BloquearLinks, &moderacao.LimiteFlood, &janela, &proibidas, &moderacao.Acao)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao obter moderação do grupo: %w", err)
+	}
+	moderacao.JanelaFlood = time.Duration(janela) * time.Second
+	moderacao.PalavrasProibidas = separarPalavras(proibidas)
+	return &moderacao, nil
+}
+
+// RegistrarModeracao grava no log uma ação automática tomada em um grupo
+func (db *DB) RegistrarModeracao(registro RegistroModeracao) (int64, error) {
+	if registro.Timestamp.IsZero() {
+		registro.Timestamp = time.Now()
+	}
+	result, err := db.conn.Exec(`
+		INSERT INTO log_moderacao (grupo_jid, participante_jid, acao, motivo, mensagem_id, conteudo, erro, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, registro.GrupoJID, registro.ParticipanteJID, registro.Acao, registro.Motivo, registro.MensagemID, registro.Conteudo, registro.Erro, registro.Timestamp)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao registrar moderação: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// ListarModeracao retorna as ações de moderação mais recentes primeiro. Um grupoJID vazio lista
+// as de todos os grupos, e um limite menor ou igual a zero não limita a quantidade
+func (db *DB) ListarModeracao(grupoJID string, limite int) ([]RegistroModeracao, error) {
+	query := "SELECT id, grupo_jid, participante_jid, acao, motivo, mensagem_id, conteudo, erro, timestamp FROM log_moderacao WHERE 1=1"
+	var args []interface{}
+	if grupoJID != "" {
+		query += " AND grupo_jid = ?"
+		args = append(args, grupoJID)
+	}
+	query += " ORDER BY timestamp DESC, id DESC"
+	if limite > 0 {
+		query += " LIMIT ?"
+		args = append(args, limite)
+	}
+	
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar moderação: %w", err)
+	}
+	defer rows.Close()
+	
+	var registros []RegistroModeracao
+	for rows.Next() {
+		var r RegistroModeracao
+		if err := rows.Scan(&r.ID, &r.GrupoJID, &r.ParticipanteJID, &r.Acao, &r.Motivo, &r.MensagemID, &r.Conteudo, &r.Erro, &r.Timestamp); err != nil {
+			return nil, fmt.Errorf("erro ao ler moderação: %w", err)
+		}
+		registros = append(registros, r)
+	}
+	return registros, rows.Err()
+}
+
+// DefinirModoResposta define como o assistente responde a um contato; vazio volta ao padrão
+func (db *DB) DefinirModoResposta(jid, modo string) error {
+	query := `
+		INSERT INTO contatos (jid, nome, telefone, ultima_sync, modo_resposta)
+		VALUES (?, '', '', ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET modo_resposta = excluded.modo_resposta
+	`
+	if _, err := db.conn.Exec(query, jid, time.Now(), modo); err != nil {
+		return fmt.Errorf("erro ao definir modo de resposta: %w", err)
+	}
+	return nil
+}
+
+// ObterModoResposta retorna o modo de resposta do contato, ou vazio se não houver um definido
+func (db *DB) ObterModoResposta(jid string) (string, error) {
+	var modo string
+	err := db.conn.QueryRow("SELECT modo_resposta FROM contatos WHERE jid = ?", jid).Scan(&modo)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao obter modo de resposta: %w", err)
+	}
+	return modo, nil
+}
+
 // ObterUltimasMensagens retorna as últimas N mensagens de um contato específico
 func (db *DB) ObterUltimasMensagens(jid string, quantidade int) ([]Mensagem, error) {
 	return db.BuscarMensagens(OpcoesConsulta{
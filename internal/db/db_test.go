@@ -1,7 +1,10 @@
 package db
 
 import (
+	"database/sql"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -82,6 +85,244 @@ func TestDatabase(t *testing.T) {
 		}
 	})
 	
+	// Testa salvar mensagem de mídia com anexo
+	t.Run("MensagemComAnexo", func(t *testing.T) {
+		jid := "5511777777777@s.whatsapp.net"
+		if _, err := db.SalvarMensagem(Mensagem{
+			JID:       jid,
+			Nome:      "Teste Anexo",
+			Conteudo:  "[imagem] meu cachorro",
+			Timestamp: time.Now(),
+			Entrada:   true,
+			Tipo:      "image",
+			Anexo:     "/tmp/midia/ab/abcdef.jpg",
+			AnexoMime: "image/jpeg",
+		}); err != nil {
+			t.Fatalf("Erro ao salvar mensagem com anexo: %v", err)
+		}
+		if _, err := db.SalvarMensagem(Mensagem{JID: jid, Nome: "Teste Anexo", Conteudo: "Oi", Timestamp: time.Now().Add(time.Second), Entrada: true}); err != nil {
+			t.Fatalf("Erro ao salvar mensagem de texto: %v", err)
+		}
+
+		msgs, err := db.BuscarMensagens(OpcoesConsulta{JID: jid, Ordem: "asc"})
+		if err != nil {
+			t.Fatalf("Erro ao buscar mensagens: %v", err)
+		}
+		if len(msgs) != 2 {
+			t.Fatalf("Esperava 2 mensagens, obteve %d", len(msgs))
+		}
+		if msgs[0].Tipo != "image" || msgs[0].Anexo != "/tmp/midia/ab/abcdef.jpg" || msgs[0].AnexoMime != "image/jpeg" {
+			t.Errorf("Anexo não foi salvo corretamente: %+v", msgs[0])
+		}
+		if msgs[1].Tipo != "text" || msgs[1].Anexo != "" {
+			t.Errorf("Mensagem sem tipo deveria ser de texto: %+v", msgs[1])
+		}
+
+		if err := db.AtualizarTranscricao(msgs[0].ID, "Olha o meu cachorro"); err != nil {
+			t.Fatalf("Erro ao atualizar transcrição: %v", err)
+		}
+		msgs, err = db.BuscarMensagens(OpcoesConsulta{JID: jid, Ordem: "asc", Limite: 1})
+		if err != nil {
+			t.Fatalf("Erro ao buscar mensagens: %v", err)
+		}
+		if msgs[0].Transcricao != "Olha o meu cachorro" {
+			t.Errorf("Transcrição não foi salva: %+v", msgs[0])
+		}
+	})
+	
+	// Testa os IDs do WhatsApp e as reações recebidas
+	t.Run("WhatsAppIDEReacao", func(t *testing.T) {
+		id, err := db.SalvarMensagem(Mensagem{
+			JID:        "5511666666666@s.whatsapp.net",
+			Nome:       "Teste Reação",
+			Conteudo:   "Qual o horário?",
+			Timestamp:  time.Now(),
+			Entrada:    true,
+			WhatsAppID: "3EB0ENTRADA",
+		})
+		if err != nil {
+			t.Fatalf("Erro ao salvar mensagem: %v", err)
+		}
+		if err := db.AtualizarRespostaWhatsAppID(id, "3EB0RESPOSTA"); err != nil {
+			t.Fatalf("Erro ao salvar ID da resposta: %v", err)
+		}
+		
+		// A reação à resposta do assistente fica registrada na mensagem que a originou
+		encontrada, err := db.AtualizarReacao("3EB0RESPOSTA", "👍")
+		if err != nil || !encontrada {
+			t.Fatalf("Erro ao salvar reação: %v (encontrada: %v)", err, encontrada)
+		}
+		if encontrada, _ := db.AtualizarReacao("DESCONHECIDO", "👍"); encontrada {
+			t.Errorf("Reação a mensagem fora do histórico não deveria ser encontrada")
+		}
+		
+		msg, err := db.BuscarMensagemPorWhatsAppID("3EB0ENTRADA")
+		if err != nil || msg == nil {
+			t.Fatalf("Erro ao buscar mensagem pelo ID do WhatsApp: %v", err)
+		}
+		if msg.ID != id || msg.RespostaWhatsAppID != "3EB0RESPOSTA" || msg.Reacao != "👍" {
+			t.Errorf("Mensagem incorreta: %+v", msg)
+		}
+		if msg, err := db.BuscarMensagemPorWhatsAppID("DESCONHECIDO"); err != nil || msg != nil {
+			t.Errorf("Esperava mensagem não encontrada: %+v, %v", msg, err)
+		}
+	})
+	
+	t.Run("StatusDaResposta", func(t *testing.T) {
+		id, err := db.SalvarMensagem(Mensagem{
+			JID:        "5511555555555@s.whatsapp.net",
+			Nome:       "Teste Status",
+			Conteudo:   "Vocês abrem no sábado?",
+			Timestamp:  time.Now(),
+			Entrada:    true,
+			WhatsAppID: "3EB0PERGUNTA",
+			Status:     StatusRecebida,
+		})
+		if err != nil {
+			t.Fatalf("Erro ao salvar mensagem: %v", err)
+		}
+		if err := db.AtualizarStatus(id, StatusPendente); err != nil {
+			t.Fatalf("Erro ao atualizar status: %v", err)
+		}
+		if err := db.AtualizarRespostaWhatsAppID(id, "3EB0RESPOSTA2"); err != nil {
+			t.Fatalf("Erro ao salvar ID da resposta: %v", err)
+		}
+		if err := db.AtualizarStatus(id, StatusEnviada); err != nil {
+			t.Fatalf("Erro ao atualizar status: %v", err)
+		}
+		
+		// Recibos de mensagens recebidas do contato não alteram o status
+		if atualizada, _ := db.AtualizarStatusPorWhatsAppID("3EB0PERGUNTA", StatusLida); atualizada {
+			t.Errorf("Recibo aplicado à mensagem recebida")
+		}
+		
+		// A leitura chega antes da entrega; a entrega atrasada não desfaz a leitura
+		recibos := []struct {
+			status     string
+			atualizada bool
+		}{
+			{StatusLida, true},
+			{StatusEntregue, false},
+			{StatusFalhou, false},
+		}
+		for _, r := range recibos {
+			atualizada, err := db.AtualizarStatusPorWhatsAppID("3EB0RESPOSTA2", r.status)
+			if err != nil || atualizada != r.atualizada {
+				t.Errorf("Recibo %s: atualizada=%v, esperava %v (%v)", r.status, atualizada, r.atualizada, err)
+			}
+		}
+		
+		// O fim de uma resposta em partes chega depois dos recibos das primeiras e não os desfaz
+		if avancou, err := db.AvancarStatus(id, StatusEnviada); err != nil || avancou {
+			t.Errorf("O fim do envio não deveria desfazer a leitura: %v, %v", avancou, err)
+		}
+		if _, err := db.AvancarStatus(id, StatusRecebida); err == nil {
+			t.Errorf("Esperava erro para um status que não é de envio")
+		}
+		
+		msg, err := db.BuscarMensagemPorWhatsAppID("3EB0RESPOSTA2")
+		if err != nil || msg == nil || msg.Status != StatusLida {
+			t.Errorf("Status incorreto: %+v, %v", msg, err)
+		}
+	})
+	
+	t.Run("EdicaoEExclusao", func(t *testing.T) {
+		id, err := db.SalvarMensagem(Mensagem{
+			JID:                "5511444444444@s.whatsapp.net",
+			Nome:               "Teste Edição",
+			Conteudo:           "Qual o horário de sábdo?",
+			Resposta:           "Abrimos às 9h.",
+			Timestamp:          time.Now(),
+			Entrada:            true,
+			WhatsAppID:         "3EB0EDITADA",
+			RespostaWhatsAppID: "3EB0RESPOSTA3",
+		})
+		if err != nil {
+			t.Fatalf("Erro ao salvar mensagem: %v", err)
+		}
+		
+		// Duas edições seguidas preservam o texto original
+		for _, texto := range []string{"Qual o horário de sábado?", "Qual o horário de domingo?"} {
+			if encontrada, err := db.RegistrarEdicao("3EB0EDITADA", texto); err != nil || !encontrada {
+				t.Fatalf("Erro ao registrar edição: %v (encontrada: %v)", err, encontrada)
+			}
+		}
+		if encontrada, err := db.RegistrarEdicao("3EB0RESPOSTA3", "Abrimos às 10h."); err != nil || !encontrada {
+			t.Fatalf("Erro ao registrar edição da resposta: %v (encontrada: %v)", err, encontrada)
+		}
+		if encontrada, err := db.RegistrarExclusao("3EB0RESPOSTA3"); err != nil || !encontrada {
+			t.Fatalf("Erro ao registrar exclusão: %v (encontrada: %v)", err, encontrada)
+		}
+		if encontrada, _ := db.RegistrarExclusao("DESCONHECIDO"); encontrada {
+			t.Errorf("Exclusão de mensagem fora do histórico não deveria ser encontrada")
+		}
+		
+		msg, err := db.BuscarMensagemPorWhatsAppID("3EB0EDITADA")
+		if err != nil || msg == nil || msg.ID != id {
+			t.Fatalf("Erro ao buscar mensagem: %+v, %v", msg, err)
+		}
+		if !msg.Editada || msg.Conteudo != "Qual o horário de domingo?" || msg.ConteudoOriginal != "Qual o horário de sábdo?" || msg.Excluida {
+			t.Errorf("Edição da mensagem incorreta: %+v", msg)
+		}
+		if !msg.RespostaEditada || msg.Resposta != "Abrimos às 10h." || msg.RespostaOriginal != "Abrimos às 9h." || !msg.RespostaExcluida {
+			t.Errorf("Edição da resposta incorreta: %+v", msg)
+		}
+	})
+	
+	// Testa a importação do histórico sincronizado pelo WhatsApp
+	t.Run("ImportarMensagens", func(t *testing.T) {
+		jid := "5511333333333@s.whatsapp.net"
+		lote := []Mensagem{
+			{JID: jid, Nome: "Teste Histórico", Conteudo: "Vocês abrem amanhã?", Timestamp: time.Now().Add(-48 * time.Hour), Entrada: true, WhatsAppID: "3EB0ANTIGA1", Status: StatusRecebida},
+			{JID: jid, Nome: "Teste Histórico", Conteudo: "Abrimos sim!", Timestamp: time.Now().Add(-47 * time.Hour), WhatsAppID: "3EB0ANTIGA2", Status: StatusEnviada},
+			{JID: jid, Nome: "Teste Histórico", Conteudo: "Sem ID", Timestamp: time.Now().Add(-46 * time.Hour), Entrada: true},
+			// Resposta já registrada pelo assistente
+			{JID: jid, Nome: "Teste Histórico", Conteudo: "Abrimos às 10h.", Timestamp: time.Now(), WhatsAppID: "3EB0RESPOSTA3", Status: StatusEnviada},
+		}
+		
+		importadas, err := db.ImportarMensagens(lote)
+		if err != nil || importadas != 2 {
+			t.Fatalf("Importação incorreta: %d mensagens, %v", importadas, err)
+		}
+		// O mesmo lote pode chegar de novo sem duplicar o histórico
+		if importadas, err := db.ImportarMensagens(lote); err != nil || importadas != 0 {
+			t.Fatalf("Reimportação não deveria gravar mensagens: %d, %v", importadas, err)
+		}
+		
+		msgs, err := db.BuscarMensagens(OpcoesConsulta{JID: jid, Ordem: "asc"})
+		if err != nil || len(msgs) != 2 {
+			t.Fatalf("Esperava 2 mensagens importadas, obteve %d (%v)", len(msgs), err)
+		}
+		if !msgs[0].Entrada || msgs[0].WhatsAppID != "3EB0ANTIGA1" || msgs[0].Tipo != "text" || msgs[0].Status != StatusRecebida {
+			t.Errorf("Mensagem recebida importada incorretamente: %+v", msgs[0])
+		}
+		if msgs[1].Entrada || msgs[1].Conteudo != "Abrimos sim!" || msgs[1].Status != StatusEnviada {
+			t.Errorf("Mensagem enviada importada incorretamente: %+v", msgs[1])
+		}
+		
+		// O nome informado pelo WhatsApp não substitui o nome já cadastrado
+		if err := db.SincronizarContato(jid, "Cliente Antigo", "5511333333333"); err != nil {
+			t.Fatalf("Erro ao sincronizar contato: %v", err)
+		}
+		if err := db.ImportarNomeContato(jid, "Nome Público", "5511333333333"); err != nil {
+			t.Fatalf("Erro ao importar nome do contato: %v", err)
+		}
+		if err := db.ImportarNomeContato("5511222222222@s.whatsapp.net", "Novo Contato", "5511222222222"); err != nil {
+			t.Fatalf("Erro ao importar nome do contato: %v", err)
+		}
+		contatos, err := db.ListarTodosContatos()
+		if err != nil {
+			t.Fatalf("Erro ao listar contatos: %v", err)
+		}
+		nomes := make(map[string]string)
+		for _, c := range contatos {
+			nomes[c.JID] = c.Nome
+		}
+		if nomes[jid] != "Cliente Antigo" || nomes["5511222222222@s.whatsapp.net"] != "Novo Contato" {
+			t.Errorf("Nomes dos contatos incorretos: %v", nomes)
+		}
+	})
+	
 	// Testa buscar mensagens por JID
 	t.Run("BuscarMensagens", func(t *testing.T) {
 		// Limpa o banco para começar do zero
@@ -220,6 +461,237 @@ func TestDatabase(t *testing.T) {
 		}
 	})
 	
+	// Testa o modo de resposta definido por contato
+	t.Run("ModoResposta", func(t *testing.T) {
+		jid := "5511444444444@s.whatsapp.net"
+		
+		modo, err := db.ObterModoResposta(jid)
+		if err != nil || modo != "" {
+			t.Fatalf("Contato sem modo definido deveria usar o padrão: %q, %v", modo, err)
+		}
+		
+		if err := db.DefinirModoResposta(jid, "voz"); err != nil {
+			t.Fatalf("Erro ao definir modo de resposta: %v", err)
+		}
+		// A sincronização do contato não deve apagar o modo escolhido
+		if err := db.SincronizarContato(jid, "Contato Voz", "5511444444444"); err != nil {
+			t.Fatalf("Erro ao sincronizar contato: %v", err)
+		}
+		
+		modo, err = db.ObterModoResposta(jid)
+		if err != nil || modo != "voz" {
+			t.Errorf("Modo de resposta incorreto: %q, %v", modo, err)
+		}
+		
+		// Um contato criado apenas pelo modo de resposta ainda aparece na lista de contatos,
+		// assim como os que versões anteriores gravaram sem telefone
+		novo := "5511454545454@s.whatsapp.net"
+		if err := db.DefinirModoResposta(novo, "texto"); err != nil {
+			t.Fatalf("Erro ao definir modo de resposta: %v", err)
+		}
+		if _, err := db.conn.Exec("INSERT INTO contatos (jid, nome, ultima_sync) VALUES (?, '', ?)", "5511464646464@s.whatsapp.net", time.Now()); err != nil {
+			t.Fatalf("Erro ao inserir contato sem telefone: %v", err)
+		}
+		contatos, err := db.ListarTodosContatos()
+		if err != nil {
+			t.Fatalf("Erro ao listar contatos: %v", err)
+		}
+		encontrado := false
+		for _, c := range contatos {
+			if c.JID == novo {
+				encontrado = c.ModoResposta == "texto" && c.Telefone == ""
+			}
+		}
+		if !encontrado {
+			t.Errorf("Contato com modo de resposta não listado corretamente")
+		}
+	})
+	
+	// Testa a sincronização de contatos e grupos do WhatsApp
+	t.Run("ContatosEGrupos", func(t *testing.T) {
+		jid := "5511777777777@s.whatsapp.net"
+		
+		// Cada sincronização traz apenas parte dos nomes; os demais são mantidos
+		etapas := []struct {
+			contato Contato
+			nome    string
+		}{
+			{Contato{JID: jid, Telefone: "5511777777777"}, "5511777777777"},
+			{Contato{JID: jid, NomePublico: "Ana"}, "Ana"},
+			{Contato{JID: jid, NomeComercial: "Ana Doces"}, "Ana Doces"},
+			{Contato{JID: jid, NomeAgenda: "Ana Confeitaria", NomePublico: "Ana 🍰"}, "Ana Confeitaria"},
+			{Contato{JID: jid, NomePublico: "Ana B."}, "Ana Confeitaria"},
+		}
+		for _, etapa := range etapas {
+			if err := db.AtualizarContato(etapa.contato); err != nil {
+				t.Fatalf("Erro ao atualizar contato: %v", err)
+			}
+			contatos, err := db.ListarTodosContatos()
+			if err != nil {
+				t.Fatalf("Erro ao listar contatos: %v", err)
+			}
+			for _, c := range contatos {
+				if c.JID == jid && c.Nome != etapa.nome {
+					t.Errorf("Nome exibido incorreto após %+v: %q, esperava %q", etapa.contato, c.Nome, etapa.nome)
+				}
+				if c.JID == jid && c.Telefone != "5511777777777" {
+					t.Errorf("Telefone apagado por sincronização sem telefone: %+v", c)
+				}
+			}
+		}
+		
+		grupo := Grupo{
+			JID:       "120363000000000000@g.us",
+			Nome:      "Clientes",
+			Descricao: "Dúvidas sobre pedidos",
+			Dono:      jid,
+			CriadoEm:  time.Now().Add(-24 * time.Hour),
+			Participantes: []Participante{
+				{JID: jid, Telefone: "5511777777777", Admin: true, SuperAdmin: true},
+				{JID: "123456789@lid"},
+			},
+		}
+		if err := db.SincronizarGrupo(grupo); err != nil {
+			t.Fatalf("Erro ao sincronizar grupo: %v", err)
+		}
+		
+		// A nova sincronização substitui os participantes
+		grupo.Nome = "Clientes VIP"
+		grupo.Participantes = grupo.Participantes[:1]
+		if err := db.SincronizarGrupo(grupo); err != nil {
+			t.Fatalf("Erro ao sincronizar grupo: %v", err)
+		}
+		
+		salvo, err := db.BuscarGrupo(grupo.JID)
+		if err != nil || salvo == nil {
+			t.Fatalf("Erro ao buscar grupo: %+v, %v", salvo, err)
+		}
+		if salvo.Nome != "Clientes VIP" || salvo.Descricao != grupo.Descricao || salvo.Dono != jid || len(salvo.Participantes) != 1 {
+			t.Errorf("Grupo incorreto: %+v", salvo)
+		}
+		if p := salvo.Participantes[0]; p != grupo.Participantes[0] {
+			t.Errorf("Participante incorreto: %+v", p)
+		}
+		
+		if ausente, err := db.BuscarGrupo("000@g.us"); err != nil || ausente != nil {
+			t.Errorf("Grupo não cadastrado deveria retornar nil: %+v, %v", ausente, err)
+		}
+		grupos, err := db.ListarGrupos()
+		if err != nil || len(grupos) != 1 {
+			t.Errorf("Esperava 1 grupo, obteve %d (%v)", len(grupos), err)
+		}
+	})
+	
+	// Testa as políticas definidas por grupo
+	t.Run("PoliticaGrupo", func(t *testing.T) {
+		jid := "120363111111111111@g.us"
+		
+		if politica, err := db.ObterPoliticaGrupo(jid); err != nil || politica != nil {
+			t.Fatalf("Grupo sem política deveria usar as configurações gerais: %+v, %v", politica, err)
+		}
+		
+		// A política pode ser definida antes da sincronização do grupo
+		politica := &PoliticaGrupo{
+			Ativo:                true,
+			SomenteMencao:        true,
+			PalavrasChave:        []string{"preço", " entrega ", ""},
+			Persona:              "Você é o atendente da loja.",
+			ComandosSomenteAdmin: true,
+			Intervalo:            90 * time.Second,
+		}
+		if err := db.DefinirPoliticaGrupo(jid, politica); err != nil {
+			t.Fatalf("Erro ao definir política: %v", err)
+		}
+		
+		// A sincronização do grupo não apaga a política
+		if err := db.SincronizarGrupo(Grupo{JID: jid, Nome: "Loja"}); err != nil {
+			t.Fatalf("Erro ao sincronizar grupo: %v", err)
+		}
+		
+		salva, err := db.ObterPoliticaGrupo(jid)
+		if err != nil || salva == nil {
+			t.Fatalf("Erro ao obter política: %+v, %v", salva, err)
+		}
+		esperada := *politica
+		esperada.PalavrasChave = []string{"preço", "entrega"}
+		if !reflect.DeepEqual(*salva, esperada) {
+			t.Errorf("Política incorreta: %+v, esperava %+v", *salva, esperada)
+		}
+		
+		grupo, err := db.BuscarGrupo(jid)
+		if err != nil || grupo == nil || grupo.Nome != "Loja" || grupo.Politica == nil || !grupo.Politica.SomenteMencao {
+			t.Errorf("Grupo com política incorreto: %+v, %v", grupo, err)
+		}
+		
+		if err := db.DefinirPoliticaGrupo(jid, nil); err != nil {
+			t.Fatalf("Erro ao remover política: %v", err)
+		}
+		if salva, err := db.ObterPoliticaGrupo(jid); err != nil || salva != nil {
+			t.Errorf("Política removida deveria voltar às configurações gerais: %+v, %v", salva, err)
+		}
+	})
+	
+	t.Run("ModeracaoGrupo", func(t *testing.T) {
+		jid := "120363222222222222@g.us"
+		
+		if moderacao, err := db.ObterModeracaoGrupo(jid); err != nil || moderacao != nil {
+			t.Fatalf("Grupo sem moderação deveria retornar nil: %+v, %v", moderacao, err)
+		}
+		
+		moderacao := &ModeracaoGrupo{
+			BoasVindas:        "Bem-vindo, {{.Mention}}!",
+			BloquearLinks:     true,
+			LimiteFlood:       5,
+			JanelaFlood:       10 * time.Second,
+			PalavrasProibidas: []string{"golpe", " pix grátis ", ""},
+			Acao:              "apagar",
+		}
+		if err := db.DefinirModeracaoGrupo(jid, moderacao); err != nil {
+			t.Fatalf("Erro ao definir moderação: %v", err)
+		}
+		
+		salva, err := db.ObterModeracaoGrupo(jid)
+		if err != nil || salva == nil {
+			t.Fatalf("Erro ao obter moderação: %+v, %v", salva, err)
+		}
+		esperada := *moderacao
+		esperada.PalavrasProibidas = []string{"golpe", "pix grátis"}
+		if !reflect.DeepEqual(*salva, esperada) {
+			t.Errorf("Moderação incorreta: %+v, esperava %+v", *salva, esperada)
+		}
+		
+		// O log guarda as ações de cada grupo, das mais recentes para as mais antigas
+		agora := time.Now()
+		registros := []RegistroModeracao{
+			{GrupoJID: jid, ParticipanteJID: "123456789@lid", Acao: "boas-vindas", Timestamp: agora.Add(-time.Minute)},
+			{GrupoJID: jid, ParticipanteJID: "123456789@lid", Acao: "apagar", Motivo: "link", MensagemID: "3EB0", Conteudo: "https://exemplo.com", Timestamp: agora},
+			{GrupoJID: "120363333333333333@g.us", ParticipanteJID: "987654321@lid", Acao: "avisar", Motivo: "flood", Timestamp: agora},
+		}
+		for _, r := range registros {
+			if _, err := db.RegistrarModeracao(r); err != nil {
+				t.Fatalf("Erro ao registrar moderação: %v", err)
+			}
+		}
+		
+		log, err := db.ListarModeracao(jid, 0)
+		if err != nil {
+			t.Fatalf("Erro ao listar moderação: %v", err)
+		}
+		if len(log) != 2 || log[0].Acao != "apagar" || log[0].MensagemID != "3EB0" || log[0].Conteudo != "https://exemplo.com" || log[1].Acao != "boas-vindas" {
+			t.Errorf("Log do grupo incorreto: %+v", log)
+		}
+		if todos, err := db.ListarModeracao("", 2); err != nil || len(todos) != 2 {
+			t.Errorf("Log limitado incorreto: %+v, %v", todos, err)
+		}
+		
+		if err := db.DefinirModeracaoGrupo(jid, nil); err != nil {
+			t.Fatalf("Erro ao remover moderação: %v", err)
+		}
+		if salva, err := db.ObterModeracaoGrupo(jid); err != nil || salva != nil {
+			t.Errorf("Moderação removida deveria retornar nil: %+v, %v", salva, err)
+		}
+	})
+	
 	// Testa exclusão do histórico de um contato
 	t.Run("ExcluirHistoricoContato", func(t *testing.T) {
 		// Limpa o banco para começar do zero
@@ -326,3 +798,49 @@ func TestDatabase(t *testing.T) {
 		}
 	})
 }
+
+func TestMigracaoColunas(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "whatszapme-db-test")
+	if err != nil {
+		t.Fatalf("Erro ao criar diretório temporário: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	caminho := filepath.Join(tempDir, "antigo.db")
+
+	// Cria a tabela de mensagens como nas versões anteriores, sem as colunas novas
+	conn, err := sql.Open("sqlite3", caminho)
+	if err != nil {
+		t.Fatalf("Erro ao abrir banco de dados: %v", err)
+	}
+	_, err = conn.Exec(`
+		CREATE TABLE mensagens (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			jid TEXT NOT NULL,
+			nome TEXT NOT NULL,
+			conteudo TEXT NOT NULL,
+			resposta TEXT,
+			timestamp DATETIME NOT NULL,
+			entrada BOOLEAN NOT NULL
+		);
+		INSERT INTO mensagens (jid, nome, conteudo, resposta, timestamp, entrada)
+		VALUES ('5511999999999@s.whatsapp.net', 'Antigo', 'Olá', '', '2024-01-01 10:00:00', 1);
+	`)
+	conn.Close()
+	if err != nil {
+		t.Fatalf("Erro ao criar tabela antiga: %v", err)
+	}
+
+	db, err := New(caminho)
+	if err != nil {
+		t.Fatalf("Erro ao abrir banco de dados antigo: %v", err)
+	}
+	defer db.Close()
+
+	msgs, err := db.BuscarMensagens(OpcoesConsulta{})
+	if err != nil {
+		t.Fatalf("Erro ao buscar mensagens: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].Tipo != "text" {
+		t.Errorf("Mensagens antigas deveriam ser de texto: %+v", msgs)
+	}
+}
@@ -0,0 +1,128 @@
+package knowledge
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tamanhos padrão dos trechos, em caracteres
+const (
+	DefaultChunkSize    = 800
+	DefaultChunkOverlap = 120
+)
+
+// paragraphBreak marca a separação entre parágrafos dentro de um trecho
+const paragraphBreak = "\n\n"
+
+var blankLines = regexp.MustCompile(`\n\s*\n`)
+
+// Chunk divide o texto em trechos de até size caracteres, preferindo cortar entre parágrafos.
+// Cada trecho começa repetindo até overlap caracteres do final do anterior, para que uma
+// informação dividida entre dois trechos continue encontrável
+func Chunk(text string, size, overlap int) []string {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	var chunks []string
+	var current []string
+	length := 0
+	// fresh indica que o trecho atual tem palavras além das repetidas do anterior
+	fresh := false
+
+	flush := func() {
+		if !fresh {
+			return
+		}
+		chunks = append(chunks, joinWords(current))
+		current = tailWords(current, overlap)
+		length = measureWords(current)
+		fresh = false
+	}
+
+	for _, paragraph := range blankLines.Split(strings.ReplaceAll(text, "\r\n", "\n"), -1) {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			continue
+		}
+
+		// Um parágrafo que não cabe inteiro começa um novo trecho, se o atual já tiver conteúdo suficiente
+		if fresh && length > size/2 && length+measureWords(words) > size {
+			flush()
+		}
+		if length > 0 {
+			current = append(current, paragraphBreak)
+		}
+
+		for _, word := range words {
+			wordLength := utf8.RuneCountInString(word) + 1
+			if fresh && length+wordLength > size {
+				flush()
+			}
+			current = append(current, word)
+			length += wordLength
+			fresh = true
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// joinWords junta as palavras de um trecho, preservando as quebras de parágrafo
+func joinWords(words []string) string {
+	var b strings.Builder
+	for _, word := range words {
+		if word == paragraphBreak {
+			if b.Len() > 0 {
+				b.WriteString(paragraphBreak)
+			}
+			continue
+		}
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), paragraphBreak) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// tailWords retorna as últimas palavras que somam até limit caracteres
+func tailWords(words []string, limit int) []string {
+	start := len(words)
+	length := 0
+	for start > 0 {
+		word := words[start-1]
+		wordLength := utf8.RuneCountInString(word) + 1
+		if word != paragraphBreak && length+wordLength > limit {
+			break
+		}
+		if word != paragraphBreak {
+			length += wordLength
+		}
+		start--
+	}
+
+	// O trecho seguinte não deve começar com uma quebra de parágrafo
+	for start < len(words) && words[start] == paragraphBreak {
+		start++
+	}
+
+	return append([]string(nil), words[start:]...)
+}
+
+// measureWords soma o tamanho das palavras, contando um espaço após cada uma
+func measureWords(words []string) int {
+	length := 0
+	for _, word := range words {
+		if word == paragraphBreak {
+			continue
+		}
+		length += utf8.RuneCountInString(word) + 1
+	}
+	return length
+}
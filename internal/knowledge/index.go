@@ -0,0 +1,346 @@
+package knowledge
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peder/whatszapme/internal/db"
+	"github.com/peder/whatszapme/internal/llm"
+)
+
+// Valores padrão da busca
+const (
+	DefaultTopK = 3
+	// embedBatchSize limita quantos trechos são enviados por chamada ao gerador de embeddings
+	embedBatchSize = 16
+)
+
+// Extensões dos arquivos aceitos na pasta da base de conhecimento.
+// Arquivos PDF são convertidos com o pdftotext (poppler-utils), quando instalado
+var supportedExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".txt":      true,
+	".pdf":      true,
+}
+
+// Passage é um trecho da base de conhecimento encontrado na busca
+type Passage struct {
+	Source  string
+	Content string
+	// Score é a similaridade de cosseno com a pergunta (1 = idêntico)
+	Score float64
+}
+
+// IndexResult resume uma indexação da pasta
+type IndexResult struct {
+	Indexed   []string
+	Unchanged int
+	Removed   []string
+	// Errors guarda as falhas de arquivos individuais, que não interrompem a indexação dos demais
+	Errors map[string]error
+}
+
+// Index mantém os trechos de uma pasta de documentos indexados no banco de dados
+// e responde às buscas por similaridade
+type Index struct {
+	// Dir é a pasta com os arquivos Markdown, TXT ou PDF
+	Dir string
+	// ChunkSize e ChunkOverlap controlam o corte dos arquivos em trechos, em caracteres
+	ChunkSize    int
+	ChunkOverlap int
+	// MinScore descarta os trechos com similaridade abaixo deste valor
+	MinScore float64
+
+	database *db.DB
+	embedder llm.Embedder
+
+	// Cache dos trechos em memória, recarregado após cada indexação
+	chunks     []db.TrechoConhecimento
+	loaded     bool
+	mutex      sync.RWMutex
+	indexMutex sync.Mutex
+}
+
+// NewIndex cria um índice para a pasta informada, gravando os trechos no banco de dados
+func NewIndex(database *db.DB, embedder llm.Embedder, dir string) *Index {
+	return &Index{
+		Dir:          dir,
+		ChunkSize:    DefaultChunkSize,
+		ChunkOverlap: DefaultChunkOverlap,
+		database:     database,
+		embedder:     embedder,
+	}
+}
+
+// Reindex indexa os arquivos novos ou alterados da pasta e remove as fontes cujos arquivos
+// foram apagados. Arquivos sem alteração e indexados com o mesmo modelo são mantidos
+func (i *Index) Reindex(ctx context.Context) (IndexResult, error) {
+	i.indexMutex.Lock()
+	defer i.indexMutex.Unlock()
+
+	result := IndexResult{Errors: make(map[string]error)}
+
+	files, err := i.listFiles()
+	if err != nil {
+		return result, err
+	}
+
+	sources, err := i.database.ListarFontesConhecimento()
+	if err != nil {
+		return result, err
+	}
+	existing := make(map[string]db.FonteConhecimento, len(sources))
+	for _, source := range sources {
+		existing[source.Caminho] = source
+	}
+
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		changed, err := i.indexFile(ctx, file, existing[file])
+		if err != nil {
+			result.Errors[file] = err
+			continue
+		}
+		if changed {
+			result.Indexed = append(result.Indexed, file)
+		} else {
+			result.Unchanged++
+		}
+		delete(existing, file)
+	}
+
+	// O que sobrou no banco não existe mais na pasta
+	for path := range existing {
+		if _, failed := result.Errors[path]; failed {
+			continue
+		}
+		if err := i.database.ExcluirFonteConhecimento(path); err != nil {
+			return result, err
+		}
+		result.Removed = append(result.Removed, path)
+	}
+	sort.Strings(result.Removed)
+
+	i.mutex.Lock()
+	i.loaded = false
+	i.mutex.Unlock()
+
+	return result, nil
+}
+
+// listFiles retorna os arquivos aceitos da pasta, com caminhos relativos a ela
+func (i *Index) listFiles() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(i.Dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			// Pastas ocultas, como .git, são ignoradas
+			if path != i.Dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !supportedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(i.Dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar arquivos de %s: %w", i.Dir, err)
+	}
+
+	return files, nil
+}
+
+// indexFile indexa um arquivo se o conteúdo ou o modelo de embeddings mudou desde a última indexação
+func (i *Index) indexFile(ctx context.Context, file string, previous db.FonteConhecimento) (bool, error) {
+	text, err := extractText(filepath.Join(i.Dir, filepath.FromSlash(file)))
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256([]byte(text))
+	hash := hex.EncodeToString(sum[:])
+	if previous.Hash == hash && previous.Modelo == i.embedder.Model() {
+		return false, nil
+	}
+
+	pieces := Chunk(text, i.ChunkSize, i.ChunkOverlap)
+	chunks := make([]db.TrechoConhecimento, 0, len(pieces))
+	for start := 0; start < len(pieces); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(pieces) {
+			end = len(pieces)
+		}
+
+		vectors, err := i.embedder.Embed(ctx, pieces[start:end])
+		if err != nil {
+			return false, fmt.Errorf("erro ao gerar embeddings de %s: %w", file, err)
+		}
+		for j, vector := range vectors {
+			chunks = append(chunks, db.TrechoConhecimento{
+				Posicao:  start + j,
+				Conteudo: pieces[start+j],
+				Vetor:    vector,
+			})
+		}
+	}
+
+	err = i.database.SalvarFonteConhecimento(db.FonteConhecimento{
+		Caminho:    file,
+		Hash:       hash,
+		Modelo:     i.embedder.Model(),
+		IndexadoEm: time.Now(),
+	}, chunks)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// extractText lê o texto do arquivo, convertendo os PDFs com o pdftotext
+func extractText(path string) (string, error) {
+	if strings.ToLower(filepath.Ext(path)) != ".pdf" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("erro ao ler %s: %w", path, err)
+		}
+		return string(data), nil
+	}
+
+	if _, err := exec.LookPath("pdftotext"); err != nil {
+		return "", fmt.Errorf("pdftotext não encontrado; instale o poppler-utils ou converta %s para TXT", filepath.Base(path))
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command("pdftotext", "-enc", "UTF-8", path, "-")
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("erro ao extrair texto de %s: %w", path, err)
+	}
+	return out.String(), nil
+}
+
+// Search retorna os k trechos mais parecidos com a pergunta, do mais para o menos parecido
+func (i *Index) Search(ctx context.Context, query string, k int) ([]Passage, error) {
+	if k <= 0 {
+		k = DefaultTopK
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
+	chunks, err := i.loadChunks()
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+
+	vectors, err := i.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gerar embedding da pergunta: %w", err)
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("embedding da pergunta não retornado")
+	}
+
+	passages := make([]Passage, 0, len(chunks))
+	for _, chunk := range chunks {
+		// Vetores de outro modelo têm outra dimensão e não podem ser comparados
+		if len(chunk.Vetor) != len(vectors[0]) {
+			continue
+		}
+
+		score := cosineSimilarity(vectors[0], chunk.Vetor)
+		if score <= 0 || score < i.MinScore {
+			continue
+		}
+		passages = append(passages, Passage{Source: chunk.Caminho, Content: chunk.Conteudo, Score: score})
+	}
+
+	sort.SliceStable(passages, func(a, b int) bool {
+		return passages[a].Score > passages[b].Score
+	})
+	if len(passages) > k {
+		passages = passages[:k]
+	}
+
+	return passages, nil
+}
+
+// loadChunks carrega os trechos do banco na primeira busca após cada indexação
+func (i *Index) loadChunks() ([]db.TrechoConhecimento, error) {
+	i.mutex.RLock()
+	if i.loaded {
+		chunks := i.chunks
+		i.mutex.RUnlock()
+		return chunks, nil
+	}
+	i.mutex.RUnlock()
+
+	chunks, err := i.database.ListarTrechosConhecimento()
+	if err != nil {
+		return nil, err
+	}
+
+	i.mutex.Lock()
+	i.chunks = chunks
+	i.loaded = true
+	i.mutex.Unlock()
+
+	return chunks, nil
+}
+
+// Sources retorna os arquivos indexados
+func (i *Index) Sources() ([]db.FonteConhecimento, error) {
+	return i.database.ListarFontesConhecimento()
+}
+
+// cosineSimilarity calcula a similaridade de cosseno entre dois vetores de mesmo tamanho
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for j := range a {
+		dot += float64(a[j]) * float64(b[j])
+		normA += float64(a[j]) * float64(a[j])
+		normB += float64(b[j]) * float64(b[j])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// FormatContext monta o texto dos trechos para a variável {{.Context}} dos templates de prompt
+func FormatContext(passages []Passage) string {
+	parts := make([]string, 0, len(passages))
+	for n, passage := range passages {
+		parts = append(parts, fmt.Sprintf("[%d] (fonte: %s)\n%s", n+1, passage.Source, passage.Content))
+	}
+	return strings.Join(parts, "\n\n")
+}
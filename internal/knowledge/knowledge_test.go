@@ -0,0 +1,249 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/peder/whatszapme/internal/db"
+)
+
+// embedderDePalavras gera vetores contando palavras de um vocabulário fixo
+type embedderDePalavras struct {
+	vocabulario []string
+	chamadas    int
+}
+
+func (e *embedderDePalavras) Model() string {
+	return "palavras"
+}
+
+func (e *embedderDePalavras) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.chamadas++
+	vetores := make([][]float32, len(texts))
+	for i, text := range texts {
+		vetor := make([]float32, len(e.vocabulario))
+		for j, palavra := range e.vocabulario {
+			vetor[j] = float32(strings.Count(strings.ToLower(text), palavra))
+		}
+		vetores[i] = vetor
+	}
+	return vetores, nil
+}
+
+// embedderInstavel falha nas primeiras chamadas, como um servidor de embeddings fora do ar
+type embedderInstavel struct {
+	embedderDePalavras
+	falhas int
+	mutex  sync.Mutex
+}
+
+func (e *embedderInstavel) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.falhas > 0 {
+		e.falhas--
+		return nil, errors.New("servidor de embeddings indisponível")
+	}
+	return e.embedderDePalavras.Embed(ctx, texts)
+}
+
+func TestChunk(t *testing.T) {
+	t.Run("TextoCurto", func(t *testing.T) {
+		trechos := Chunk("Abrimos às 9h.\n\nFechamos às 18h.", 100, 20)
+		if len(trechos) != 1 || trechos[0] != "Abrimos às 9h.\n\nFechamos às 18h." {
+			t.Errorf("Trechos incorretos: %q", trechos)
+		}
+	})
+
+	t.Run("TextoLongo", func(t *testing.T) {
+		texto := strings.Repeat("palavra ", 100)
+		trechos := Chunk(texto, 100, 20)
+		if len(trechos) < 2 {
+			t.Fatalf("Esperava vários trechos, obteve %d", len(trechos))
+		}
+		for _, trecho := range trechos {
+			if utf8.RuneCountInString(trecho) > 100 {
+				t.Errorf("Trecho maior que o limite: %d caracteres", utf8.RuneCountInString(trecho))
+			}
+		}
+	})
+
+	t.Run("Sobreposicao", func(t *testing.T) {
+		trechos := Chunk("um dois três quatro cinco seis sete oito nove dez", 30, 10)
+		if len(trechos) < 2 {
+			t.Fatalf("Esperava vários trechos, obteve %d", len(trechos))
+		}
+		ultimas := strings.Fields(trechos[0])
+		if !strings.HasPrefix(trechos[1], ultimas[len(ultimas)-1]) {
+			t.Errorf("O segundo trecho deveria repetir o final do primeiro: %q", trechos)
+		}
+	})
+
+	t.Run("Vazio", func(t *testing.T) {
+		if trechos := Chunk(" \n\n ", 100, 20); len(trechos) != 0 {
+			t.Errorf("Texto vazio não deveria gerar trechos: %q", trechos)
+		}
+	})
+}
+
+func TestIndex(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "knowledge-test")
+	if err != nil {
+		t.Fatalf("Erro ao criar diretório temporário: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	database, err := db.New(filepath.Join(tempDir, "test.db"))
+	if err != nil {
+		t.Fatalf("Erro ao criar banco de dados: %v", err)
+	}
+	defer database.Close()
+
+	docs := filepath.Join(tempDir, "docs")
+	if err := os.MkdirAll(docs, 0755); err != nil {
+		t.Fatalf("Erro ao criar pasta de documentos: %v", err)
+	}
+
+	escrever := func(nome, conteudo string) {
+		if err := os.WriteFile(filepath.Join(docs, nome), []byte(conteudo), 0644); err != nil {
+			t.Fatalf("Erro ao escrever %s: %v", nome, err)
+		}
+	}
+	escrever("horarios.md", "# Horários\n\nAbrimos de segunda a sexta, das 9h às 18h.")
+	escrever("precos.txt", "Tabela de preços: corte de cabelo custa R$ 50. Barba custa R$ 30.")
+	escrever("imagem.png", "não é texto")
+
+	embedder := &embedderDePalavras{vocabulario: []string{"horário", "abrimos", "preço", "corte", "barba"}}
+	index := NewIndex(database, embedder, docs)
+	ctx := context.Background()
+
+	t.Run("Indexacao", func(t *testing.T) {
+		result, err := index.Reindex(ctx)
+		if err != nil {
+			t.Fatalf("Erro ao indexar: %v", err)
+		}
+		if len(result.Indexed) != 2 || len(result.Errors) != 0 {
+			t.Errorf("Resultado incorreto: %+v", result)
+		}
+
+		fontes, err := index.Sources()
+		if err != nil {
+			t.Fatalf("Erro ao listar fontes: %v", err)
+		}
+		if len(fontes) != 2 || fontes[0].Caminho != "horarios.md" || fontes[0].Trechos != 1 {
+			t.Errorf("Fontes incorretas: %+v", fontes)
+		}
+	})
+
+	t.Run("Busca", func(t *testing.T) {
+		passagens, err := index.Search(ctx, "Quanto custa o corte?", 1)
+		if err != nil {
+			t.Fatalf("Erro na busca: %v", err)
+		}
+		if len(passagens) != 1 || passagens[0].Source != "precos.txt" {
+			t.Fatalf("Passagens incorretas: %+v", passagens)
+		}
+
+		contexto := FormatContext(passagens)
+		if !strings.Contains(contexto, "(fonte: precos.txt)") || !strings.Contains(contexto, "R$ 50") {
+			t.Errorf("Contexto incorreto: %q", contexto)
+		}
+	})
+
+	t.Run("SemAlteracao", func(t *testing.T) {
+		chamadas := embedder.chamadas
+		result, err := index.Reindex(ctx)
+		if err != nil {
+			t.Fatalf("Erro ao reindexar: %v", err)
+		}
+		if result.Unchanged != 2 || len(result.Indexed) != 0 || embedder.chamadas != chamadas {
+			t.Errorf("Arquivos sem alteração não deveriam ser reindexados: %+v", result)
+		}
+	})
+
+	t.Run("AlteracaoERemocao", func(t *testing.T) {
+		escrever("horarios.md", "# Horários\n\nAbrimos também aos sábados, das 9h às 13h.")
+		if err := os.Remove(filepath.Join(docs, "precos.txt")); err != nil {
+			t.Fatalf("Erro ao remover arquivo: %v", err)
+		}
+
+		result, err := index.Reindex(ctx)
+		if err != nil {
+			t.Fatalf("Erro ao reindexar: %v", err)
+		}
+		if len(result.Indexed) != 1 || len(result.Removed) != 1 || result.Removed[0] != "precos.txt" {
+			t.Errorf("Resultado incorreto: %+v", result)
+		}
+
+		passagens, err := index.Search(ctx, "Quando vocês abrimos?", 3)
+		if err != nil {
+			t.Fatalf("Erro na busca: %v", err)
+		}
+		if len(passagens) != 1 || !strings.Contains(passagens[0].Content, "sábados") {
+			t.Errorf("A busca deveria usar o conteúdo atualizado: %+v", passagens)
+		}
+	})
+}
+
+func TestWatch(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "knowledge-watch-test")
+	if err != nil {
+		t.Fatalf("Erro ao criar diretório temporário: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	database, err := db.New(filepath.Join(tempDir, "test.db"))
+	if err != nil {
+		t.Fatalf("Erro ao criar banco de dados: %v", err)
+	}
+	defer database.Close()
+
+	docs := filepath.Join(tempDir, "docs")
+	if err := os.MkdirAll(docs, 0755); err != nil {
+		t.Fatalf("Erro ao criar pasta de documentos: %v", err)
+	}
+
+	embedder := &embedderInstavel{embedderDePalavras: embedderDePalavras{vocabulario: []string{"abrimos"}}, falhas: 1}
+	index := NewIndex(database, embedder, docs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resultados := make(chan IndexResult, 10)
+	go index.Watch(ctx, 10*time.Millisecond, func(result IndexResult, err error) {
+		if err != nil {
+			t.Errorf("Erro inesperado: %v", err)
+		}
+		resultados <- result
+	})
+
+	// Espera o primeiro retrato da pasta antes de criar o arquivo
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(docs, "horarios.md"), []byte("Abrimos às 9h."), 0644); err != nil {
+		t.Fatalf("Erro ao escrever arquivo: %v", err)
+	}
+
+	proximo := func() IndexResult {
+		select {
+		case result := <-resultados:
+			return result
+		case <-time.After(5 * time.Second):
+			t.Fatal("A pasta não foi reindexada")
+			return IndexResult{}
+		}
+	}
+
+	if result := proximo(); len(result.Errors) != 1 {
+		t.Fatalf("A primeira indexação deveria falhar: %+v", result)
+	}
+	// O arquivo que falhou é tentado de novo na verificação seguinte, sem nova alteração
+	if result := proximo(); len(result.Indexed) != 1 || result.Indexed[0] != "horarios.md" {
+		t.Errorf("O arquivo deveria ser indexado após a falha: %+v", result)
+	}
+}
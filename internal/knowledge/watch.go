@@ -0,0 +1,89 @@
+package knowledge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultWatchInterval é o intervalo padrão entre as verificações da pasta
+const DefaultWatchInterval = 30 * time.Second
+
+// fileState identifica a versão de um arquivo sem precisar ler o conteúdo
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Watch verifica a pasta periodicamente e reindexa quando algum arquivo é criado, alterado ou
+// removido, chamando onChange com o resultado. Bloqueia até o contexto ser cancelado
+func (i *Index) Watch(ctx context.Context, interval time.Duration, onChange func(IndexResult, error)) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	last := i.snapshot()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := i.snapshot()
+		if sameSnapshot(last, current) {
+			continue
+		}
+
+		result, err := i.Reindex(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		// Os arquivos que falharam ficam fora do retrato, para a próxima verificação tentar de novo
+		if err == nil {
+			for file := range result.Errors {
+				delete(current, file)
+			}
+			last = current
+		}
+		if onChange != nil {
+			onChange(result, err)
+		}
+	}
+}
+
+// snapshot registra o tamanho e a data de alteração dos arquivos aceitos da pasta
+func (i *Index) snapshot() map[string]fileState {
+	state := make(map[string]fileState)
+	files, err := i.listFiles()
+	if err != nil {
+		return state
+	}
+
+	for _, file := range files {
+		info, err := os.Stat(filepath.Join(i.Dir, filepath.FromSlash(file)))
+		if err != nil {
+			continue
+		}
+		state[file] = fileState{size: info.Size(), modTime: info.ModTime()}
+	}
+	return state
+}
+
+// sameSnapshot compara dois retratos da pasta
+func sameSnapshot(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, state := range a {
+		other, ok := b[file]
+		if !ok || other.size != state.size || !other.modTime.Equal(state.modTime) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,375 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AnthropicVersion é a versão da API enviada no cabeçalho anthropic-version
+const AnthropicVersion = "2023-06-01"
+
+// AnthropicClient representa um cliente para a API Messages da Anthropic
+type AnthropicClient struct {
+	APIKey  string
+	Model   string
+	BaseURL string
+	Client  *http.Client
+}
+
+// AnthropicRequest representa uma solicitação para o endpoint /v1/messages
+type AnthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []AnthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   float64            `json:"temperature"`
+	TopP          float64            `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+}
+
+// AnthropicMessage representa um turno da conversa na API da Anthropic
+type AnthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+
+	images []Image
+}
+
+// AnthropicContentBlock é um bloco de conteúdo de texto ou imagem
+type AnthropicContentBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *AnthropicImageSource `json:"source,omitempty"`
+}
+
+// AnthropicImageSource traz uma imagem codificada em base64
+type AnthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// MarshalJSON envia o conteúdo como texto simples ou, quando há imagens, como lista de blocos.
+// As imagens vão antes do texto, como recomenda a documentação da API
+func (m AnthropicMessage) MarshalJSON() ([]byte, error) {
+	if len(m.images) == 0 {
+		return json.Marshal(struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		}{m.Role, m.Content})
+	}
+
+	blocks := make([]AnthropicContentBlock, 0, len(m.images)+1)
+	for _, img := range m.images {
+		blocks = append(blocks, AnthropicContentBlock{
+			Type:   "image",
+			Source: &AnthropicImageSource{Type: "base64", MediaType: img.mimeType(), Data: img.base64Data()},
+		})
+	}
+	if m.Content != "" {
+		blocks = append(blocks, AnthropicContentBlock{Type: "text", Text: m.Content})
+	}
+	return json.Marshal(struct {
+		Role    string                  `json:"role"`
+		Content []AnthropicContentBlock `json:"content"`
+	}{m.Role, blocks})
+}
+
+// AnthropicUsage representa a contagem de tokens informada pela Anthropic
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// AnthropicResponse representa a resposta da API Messages
+type AnthropicResponse struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Role    string `json:"role"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      AnthropicUsage `json:"usage"`
+}
+
+// AnthropicStreamEvent representa um evento da resposta em streaming.
+// Cada tipo de evento preenche apenas parte dos campos
+type AnthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message *struct {
+		Model string         `json:"model"`
+		Usage AnthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	Delta *struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	Usage *AnthropicUsage `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// NewAnthropicClient cria um novo cliente para a API da Anthropic
+func NewAnthropicClient(apiKey string, model string) *AnthropicClient {
+	if model == "" {
+		model = "claude-3-5-haiku-latest"
+	}
+
+	return &AnthropicClient{
+		APIKey:  apiKey,
+		Model:   model,
+		BaseURL: "https://api.anthropic.com",
+		Client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// GenerateCompletion gera um texto com base no prompt fornecido
+func (c *AnthropicClient) GenerateCompletion(prompt string, systemPrompt string) (string, error) {
+	return c.GenerateChat(systemPrompt, []Message{{Role: RoleUser, Content: prompt}}, nil)
+}
+
+// GenerateCompletionStream gera um texto em modo streaming, chamando onChunk a cada trecho recebido
+func (c *AnthropicClient) GenerateCompletionStream(prompt string, systemPrompt string, onChunk StreamHandler) (string, error) {
+	return c.GenerateChat(systemPrompt, []Message{{Role: RoleUser, Content: prompt}}, streamOrDiscard(onChunk))
+}
+
+// GenerateChat gera a próxima resposta de uma conversa com vários turnos
+func (c *AnthropicClient) GenerateChat(systemPrompt string, messages []Message, onChunk StreamHandler) (string, error) {
+	resp, err := c.Generate(Request{SystemPrompt: systemPrompt, Messages: messages, OnChunk: onChunk})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// Generate gera a próxima resposta usando o endpoint /v1/messages
+func (c *AnthropicClient) Generate(request Request) (*Response, error) {
+	if c.APIKey == "" {
+		return nil, fmt.Errorf("API Key da Anthropic não configurada")
+	}
+
+	reqBody := AnthropicRequest{
+		Model:       c.Model,
+		System:      request.SystemPrompt,
+		Messages:    anthropicMessages(prepareImages(request.Messages, c.Model)),
+		MaxTokens:   1024,
+		Temperature: 0.7,
+		Stream:      request.OnChunk != nil,
+	}
+	if opts := request.Options; opts != nil {
+		// A API não aceita seed; os demais parâmetros têm equivalente direto
+		reqBody.Temperature = opts.Temperature
+		reqBody.TopP = opts.TopP
+		if opts.MaxTokens > 0 {
+			reqBody.MaxTokens = opts.MaxTokens
+		}
+		reqBody.StopSequences = opts.Stop
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar solicitação: %v", err)
+	}
+
+	// Cria e envia a requisição
+	req, err := http.NewRequestWithContext(request.context(), "POST", c.BaseURL+"/v1/messages", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	c.setHeaders(req)
+	if reqBody.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var result *Response
+	if reqBody.Stream {
+		result, err = readAnthropicStream(resp.Body, request.OnChunk)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var anthropicResp AnthropicResponse
+		if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
+			return nil, fmt.Errorf("erro ao decodificar resposta: %v", err)
+		}
+
+		var text strings.Builder
+		for _, block := range anthropicResp.Content {
+			if block.Type == "text" {
+				text.WriteString(block.Text)
+			}
+		}
+
+		result = &Response{
+			Text:         text.String(),
+			FinishReason: normalizeFinishReason(anthropicResp.StopReason),
+			Model:        anthropicResp.Model,
+			Usage: Usage{
+				PromptTokens:     anthropicResp.Usage.InputTokens,
+				CompletionTokens: anthropicResp.Usage.OutputTokens,
+				TotalTokens:      anthropicResp.Usage.InputTokens + anthropicResp.Usage.OutputTokens,
+			},
+		}
+	}
+
+	if result.Model == "" {
+		result.Model = c.Model
+	}
+	return result, nil
+}
+
+// SupportsImages informa se o modelo configurado aceita imagens
+func (c *AnthropicClient) SupportsImages() bool {
+	return SupportsVision(c.Model)
+}
+
+// anthropicMessages converte a conversa para o formato da API, que exige começar pelo
+// usuário e alternar os papéis: turnos seguidos do mesmo papel são unidos
+func anthropicMessages(messages []Message) []AnthropicMessage {
+	converted := make([]AnthropicMessage, 0, len(messages))
+	for _, msg := range messages {
+		role := RoleUser
+		if msg.Role == RoleAssistant {
+			role = RoleAssistant
+		}
+
+		if len(converted) == 0 && role == RoleAssistant {
+			continue
+		}
+
+		if last := len(converted) - 1; last >= 0 && converted[last].Role == role {
+			converted[last].Content += "\n\n" + msg.Content
+			converted[last].images = append(converted[last].images, msg.Images...)
+			continue
+		}
+
+		converted = append(converted, AnthropicMessage{Role: role, Content: msg.Content, images: msg.Images})
+	}
+	return converted
+}
+
+// readAnthropicStream lê os eventos SSE de uma resposta em streaming e repassa cada trecho
+func readAnthropicStream(body io.Reader, onChunk StreamHandler) (*Response, error) {
+	result := &Response{}
+	var full bytes.Buffer
+	err := readSSE(body, func(data string) error {
+		var event AnthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("erro ao decodificar trecho da resposta: %v", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			// O primeiro evento traz o modelo e os tokens de entrada
+			if event.Message != nil {
+				result.Model = event.Message.Model
+				result.Usage.PromptTokens = event.Message.Usage.InputTokens
+				result.Usage.CompletionTokens = event.Message.Usage.OutputTokens
+			}
+		case "content_block_delta":
+			if event.Delta != nil && event.Delta.Text != "" {
+				full.WriteString(event.Delta.Text)
+				onChunk(event.Delta.Text)
+			}
+		case "message_delta":
+			// O último evento traz o motivo de término e a contagem final de tokens de saída
+			if event.Delta != nil && event.Delta.StopReason != "" {
+				result.FinishReason = normalizeFinishReason(event.Delta.StopReason)
+			}
+			if event.Usage != nil {
+				result.Usage.CompletionTokens = event.Usage.OutputTokens
+			}
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("erro da API: %s", event.Error.Message)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler resposta em streaming: %v", err)
+	}
+
+	result.Text = full.String()
+	result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	return result, nil
+}
+
+// ListModels lista os modelos disponíveis no endpoint /v1/models
+func (c *AnthropicClient) ListModels() ([]string, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/v1/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+	c.setHeaders(req)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	// Estrutura para parsear a resposta
+	var modelsResp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&modelsResp); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar resposta: %v", err)
+	}
+
+	models := make([]string, 0, len(modelsResp.Data))
+	for _, model := range modelsResp.Data {
+		models = append(models, model.ID)
+	}
+	sort.Strings(models)
+
+	return models, nil
+}
+
+// CheckHealth verifica se a API responde à listagem de modelos
+func (c *AnthropicClient) CheckHealth() error {
+	if _, err := c.ListModels(); err != nil {
+		return fmt.Errorf("erro ao conectar com a API da Anthropic: %w", err)
+	}
+	return nil
+}
+
+// setHeaders adiciona a autenticação e a versão da API
+func (c *AnthropicClient) setHeaders(req *http.Request) {
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", AnthropicVersion)
+}
@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Embedder converte textos em vetores para a busca por similaridade
+type Embedder interface {
+	// Embed retorna um vetor para cada texto, na mesma ordem
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model retorna o nome do modelo de embeddings, para detectar vetores gerados por outro modelo
+	Model() string
+}
+
+// OllamaEmbedder gera embeddings com o endpoint /api/embeddings do Ollama
+type OllamaEmbedder struct {
+	BaseURL        string
+	EmbeddingModel string
+	Client         *http.Client
+}
+
+// NewOllamaEmbedder cria um gerador de embeddings do Ollama
+func NewOllamaEmbedder(baseURL string, model string) *OllamaEmbedder {
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+
+	return &OllamaEmbedder{
+		BaseURL:        baseURL,
+		EmbeddingModel: model,
+		Client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// Model retorna o nome do modelo de embeddings
+func (e *OllamaEmbedder) Model() string {
+	return e.EmbeddingModel
+}
+
+// Embed gera os vetores, um texto por requisição, pois o endpoint não aceita lotes
+func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		jsonData, err := json.Marshal(map[string]string{"model": e.EmbeddingModel, "prompt": text})
+		if err != nil {
+			return nil, fmt.Errorf("erro ao serializar solicitação: %v", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", e.BaseURL+"/api/embeddings", bytes.NewBuffer(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := e.Client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
+		}
+
+		var embeddingResp struct {
+			Embedding []float32 `json:"embedding"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = newAPIError(resp)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&embeddingResp)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao gerar embedding: %w", err)
+		}
+
+		if len(embeddingResp.Embedding) == 0 {
+			return nil, fmt.Errorf("embedding vazio retornado pelo modelo %s", e.EmbeddingModel)
+		}
+		vectors = append(vectors, embeddingResp.Embedding)
+	}
+
+	return vectors, nil
+}
+
+// OpenAIEmbedder gera embeddings com o endpoint /embeddings da OpenAI ou de servidores compatíveis
+type OpenAIEmbedder struct {
+	APIKey         string
+	BaseURL        string
+	EmbeddingModel string
+	Client         *http.Client
+}
+
+// NewOpenAIEmbedder cria um gerador de embeddings da OpenAI
+func NewOpenAIEmbedder(apiKey string, model string) *OpenAIEmbedder {
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+
+	return &OpenAIEmbedder{
+		APIKey:         apiKey,
+		BaseURL:        "https://api.openai.com/v1",
+		EmbeddingModel: model,
+		Client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// Model retorna o nome do modelo de embeddings
+func (e *OpenAIEmbedder) Model() string {
+	return e.EmbeddingModel
+}
+
+// Embed gera os vetores de todos os textos em uma única requisição
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{"model": e.EmbeddingModel, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar solicitação: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.BaseURL+"/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	var embeddingResp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&embeddingResp); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar resposta: %v", err)
+	}
+
+	if len(embeddingResp.Data) != len(texts) {
+		return nil, fmt.Errorf("quantidade de embeddings (%d) diferente da de textos (%d)", len(embeddingResp.Data), len(texts))
+	}
+
+	// Os itens trazem o índice do texto correspondente, que nem sempre vem em ordem
+	vectors := make([][]float32, len(texts))
+	for _, item := range embeddingResp.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("índice de embedding inválido: %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+
+	return vectors, nil
+}
@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// APIError representa uma resposta de erro HTTP de um provedor
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter é o tempo de espera sugerido pelo servidor no cabeçalho Retry-After, se houver
+	RetryAfter time.Duration
+}
+
+// Error implementa a interface error
+func (e *APIError) Error() string {
+	return fmt.Sprintf("erro da API [%d]: %s", e.StatusCode, e.Body)
+}
+
+// Retryable indica se vale a pena repetir a requisição (limite de taxa ou erro do servidor)
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// newAPIError lê o corpo de uma resposta com status de erro e cria o APIError correspondente
+func newAPIError(resp *http.Response) error {
+	bodyBytes, _ := io.ReadAll(resp.Body)
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(bodyBytes),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return apiErr
+}
+
+// isRetryable indica se um erro é temporário: limite de taxa, erro do servidor ou falha de rede
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,344 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Valores padrão da cadeia de fallback
+const (
+	DefaultMaxRetries       = 2
+	DefaultBaseBackoff      = 500 * time.Millisecond
+	DefaultMaxBackoff       = 8 * time.Second
+	DefaultFailureThreshold = 3
+	DefaultOpenTimeout      = 30 * time.Second
+)
+
+// Estados do circuit breaker de cada backend
+const (
+	CircuitClosed   = "fechado"
+	CircuitOpen     = "aberto"
+	CircuitHalfOpen = "meio-aberto"
+)
+
+// HealthChecker é implementado pelos provedores capazes de verificar a própria disponibilidade
+type HealthChecker interface {
+	CheckHealth() error
+}
+
+// Backend é um provedor nomeado dentro da cadeia de fallback
+type Backend struct {
+	Name     string
+	Provider Provider
+}
+
+// BackendStatus descreve a situação atual de um backend da cadeia
+type BackendStatus struct {
+	Name      string
+	State     string
+	Failures  int
+	LastError string
+}
+
+// backendState guarda o estado do circuit breaker de um backend
+type backendState struct {
+	Backend
+	failures  int
+	openUntil time.Time
+	lastError string
+}
+
+// FallbackProvider tenta uma lista ordenada de provedores até que um deles responda.
+// Erros temporários (429, 5xx e falhas de rede) são repetidos com backoff exponencial e,
+// após falhas seguidas, o circuito do backend é aberto para que ele seja pulado por um tempo
+type FallbackProvider struct {
+	// MaxRetries é a quantidade de novas tentativas no mesmo backend para erros temporários
+	MaxRetries int
+	// BaseBackoff é a espera antes da primeira nova tentativa; dobra a cada tentativa até MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// FailureThreshold é a quantidade de falhas seguidas que abre o circuito do backend
+	FailureThreshold int
+	// OpenTimeout é o tempo que um circuito aberto espera antes de testar o backend novamente
+	OpenTimeout time.Duration
+	// OnFailover, se definido, é chamado quando um backend falha e o próximo será tentado
+	OnFailover func(failed string, err error)
+
+	backends    []*backendState
+	lastBackend string
+	mutex       sync.Mutex
+	now         func() time.Time
+}
+
+// NewFallbackProvider cria uma cadeia com os backends na ordem de preferência
+func NewFallbackProvider(backends ...Backend) *FallbackProvider {
+	states := make([]*backendState, 0, len(backends))
+	for _, b := range backends {
+		states = append(states, &backendState{Backend: b})
+	}
+
+	return &FallbackProvider{
+		MaxRetries:       DefaultMaxRetries,
+		BaseBackoff:      DefaultBaseBackoff,
+		MaxBackoff:       DefaultMaxBackoff,
+		FailureThreshold: DefaultFailureThreshold,
+		OpenTimeout:      DefaultOpenTimeout,
+		backends:         states,
+		now:              time.Now,
+	}
+}
+
+// GenerateCompletion gera um texto com base no prompt fornecido
+func (f *FallbackProvider) GenerateCompletion(prompt string, systemPrompt string) (string, error) {
+	resp, err := f.Generate(completionRequest(prompt, systemPrompt))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// GenerateCompletionStream gera um texto em modo streaming, chamando onChunk a cada trecho recebido
+func (f *FallbackProvider) GenerateCompletionStream(prompt string, systemPrompt string, onChunk StreamHandler) (string, error) {
+	req := completionRequest(prompt, systemPrompt)
+	req.OnChunk = streamOrDiscard(onChunk)
+
+	resp, err := f.Generate(req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// GenerateChat gera a próxima resposta de uma conversa com vários turnos
+func (f *FallbackProvider) GenerateChat(systemPrompt string, messages []Message, onChunk StreamHandler) (string, error) {
+	resp, err := f.Generate(Request{SystemPrompt: systemPrompt, Messages: messages, OnChunk: onChunk})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// Generate envia a solicitação ao primeiro backend disponível e passa para o próximo em caso de falha.
+// O nome do backend que respondeu é informado em Response.Backend
+func (f *FallbackProvider) Generate(req Request) (*Response, error) {
+	ctx := req.context()
+
+	// Depois que algum trecho foi entregue em streaming, trocar de backend duplicaria o texto
+	delivered := false
+	if req.OnChunk != nil {
+		onChunk := req.OnChunk
+		req.OnChunk = func(chunk string) {
+			delivered = true
+			onChunk(chunk)
+		}
+	}
+
+	var failures []string
+	for _, b := range f.backends {
+		if !f.allow(b) {
+			failures = append(failures, fmt.Sprintf("%s: circuito aberto", b.Name))
+			continue
+		}
+
+		resp, err := f.tryBackend(ctx, b, req, &delivered)
+		if err == nil {
+			f.recordSuccess(b)
+			resp.Backend = b.Name
+			return resp, nil
+		}
+
+		// Cancelamento por quem chamou não é culpa do backend
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		f.recordFailure(b, err)
+		if delivered {
+			return nil, fmt.Errorf("erro no provedor %s: %w", b.Name, err)
+		}
+
+		failures = append(failures, fmt.Sprintf("%s: %v", b.Name, err))
+		if f.OnFailover != nil {
+			f.OnFailover(b.Name, err)
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil, fmt.Errorf("nenhum provedor configurado na cadeia de fallback")
+	}
+	return nil, fmt.Errorf("todos os provedores falharam: %s", strings.Join(failures, "; "))
+}
+
+// tryBackend chama um backend, repetindo com backoff exponencial os erros temporários
+func (f *FallbackProvider) tryBackend(ctx context.Context, b *backendState, req Request, delivered *bool) (*Response, error) {
+	backoff := f.BaseBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := b.Provider.Generate(req)
+		if err == nil {
+			return resp, nil
+		}
+
+		if attempt >= f.MaxRetries || !isRetryable(err) || *delivered || ctx.Err() != nil {
+			return nil, err
+		}
+
+		// Respeita o tempo sugerido pelo servidor em respostas 429
+		wait := backoff
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+		if f.MaxBackoff > 0 && wait > f.MaxBackoff {
+			wait = f.MaxBackoff
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+// allow indica se o backend pode ser usado, testando a saúde dos que estão com o circuito aberto.
+// No estado meio-aberto só a primeira chamada testa o backend; as demais o encontram aberto até o resultado
+func (f *FallbackProvider) allow(b *backendState) bool {
+	f.mutex.Lock()
+	if b.failures < f.FailureThreshold {
+		f.mutex.Unlock()
+		return true
+	}
+	now := f.now()
+	if now.Before(b.openUntil) {
+		f.mutex.Unlock()
+		return false
+	}
+	// O sucesso ou a falha desta chamada fecham ou reabrem o circuito
+	b.openUntil = now.Add(f.OpenTimeout)
+	f.mutex.Unlock()
+
+	// Meio-aberto: o backend volta a ser tentado se responder à verificação de saúde
+	if checker, ok := healthChecker(b.Provider); ok {
+		if err := checker.CheckHealth(); err != nil {
+			f.recordFailure(b, err)
+			return false
+		}
+	}
+	return true
+}
+
+// recordSuccess fecha o circuito do backend e o registra como o último a responder
+func (f *FallbackProvider) recordSuccess(b *backendState) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.lastError = ""
+	f.lastBackend = b.Name
+}
+
+// recordFailure contabiliza a falha e abre o circuito ao atingir o limite
+func (f *FallbackProvider) recordFailure(b *backendState, err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	b.failures++
+	b.lastError = err.Error()
+	if b.failures >= f.FailureThreshold {
+		b.openUntil = f.now().Add(f.OpenTimeout)
+	}
+}
+
+// LastBackend retorna o nome do último backend que respondeu com sucesso
+func (f *FallbackProvider) LastBackend() string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.lastBackend
+}
+
+// Status retorna a situação do circuit breaker de cada backend, na ordem da cadeia
+func (f *FallbackProvider) Status() []BackendStatus {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := f.now()
+	status := make([]BackendStatus, 0, len(f.backends))
+	for _, b := range f.backends {
+		state := CircuitClosed
+		if b.failures >= f.FailureThreshold {
+			state = CircuitHalfOpen
+			if now.Before(b.openUntil) {
+				state = CircuitOpen
+			}
+		}
+		status = append(status, BackendStatus{
+			Name:      b.Name,
+			State:     state,
+			Failures:  b.failures,
+			LastError: b.lastError,
+		})
+	}
+	return status
+}
+
+// CheckHealth verifica se ao menos um backend da cadeia está disponível. Backends sem verificação de
+// saúde são ignorados, e a cadeia só é considerada saudável sem verificar nada quando nenhum a oferece
+func (f *FallbackProvider) CheckHealth() error {
+	var failures []string
+	for _, b := range f.backends {
+		checker, ok := healthChecker(b.Provider)
+		if !ok {
+			continue
+		}
+		err := checker.CheckHealth()
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", b.Name, err))
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("nenhum provedor disponível: %s", strings.Join(failures, "; "))
+}
+
+// healthChecker devolve a verificação de saúde do provedor, olhando através do MeteredProvider,
+// que implementa CheckHealth mesmo quando o provedor que ele envolve não a oferece
+func healthChecker(provider Provider) (HealthChecker, bool) {
+	if metered, ok := provider.(*MeteredProvider); ok {
+		provider = metered.Provider
+	}
+	checker, ok := provider.(HealthChecker)
+	return checker, ok
+}
+
+// SupportsImages informa se o primeiro backend que será tentado, com o circuito fechado ou pronto para
+// novo teste, aceita imagens. Se a cadeia cair para um backend apenas de texto, as imagens são
+// trocadas por um aviso pelo próprio backend
+func (f *FallbackProvider) SupportsImages() bool {
+	f.mutex.Lock()
+	var next Provider
+	now := f.now()
+	for _, b := range f.backends {
+		if b.failures < f.FailureThreshold || !now.Before(b.openUntil) {
+			next = b.Provider
+			break
+		}
+	}
+	f.mutex.Unlock()
+
+	if next == nil {
+		return false
+	}
+	return ImagesSupported(next)
+}
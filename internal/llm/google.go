@@ -2,40 +2,54 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 )
 
 // GoogleClient representa um cliente para a API do Google Gemini
 type GoogleClient struct {
-	APIKey string
-	Model  string
-	Client *http.Client
+	APIKey  string
+	Model   string
+	BaseURL string
+	Client  *http.Client
 }
 
 // GoogleRequest representa uma solicitação para a API do Google
 type GoogleRequest struct {
 	Contents []GoogleContent `json:"contents"`
+	SystemInstruction *GoogleContent `json:"systemInstruction,omitempty"`
 	GenerationConfig GoogleGenerationConfig `json:"generationConfig"`
 }
 
 // GoogleContent representa o conteúdo de uma solicitação
 type GoogleContent struct {
-	Role  string `json:"role"`
-	Parts []struct {
-		Text string `json:"text"`
-	} `json:"parts"`
+	Role  string       `json:"role,omitempty"`
+	Parts []GooglePart `json:"parts"`
+}
+
+// GooglePart representa uma parte do conteúdo: texto ou dados de uma imagem
+type GooglePart struct {
+	Text       string            `json:"text,omitempty"`
+	InlineData *GoogleInlineData `json:"inlineData,omitempty"`
+}
+
+// GoogleInlineData contém um arquivo enviado junto com a solicitação, codificado em base64
+type GoogleInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
 }
 
 // GoogleGenerationConfig representa as configurações de geração
 type GoogleGenerationConfig struct {
-	Temperature     float64 `json:"temperature"`
-	MaxOutputTokens int     `json:"maxOutputTokens"`
-	TopK            int     `json:"topK"`
-	TopP            float64 `json:"topP"`
+	Temperature     float64  `json:"temperature"`
+	MaxOutputTokens int      `json:"maxOutputTokens"`
+	TopK            int      `json:"topK"`
+	TopP            float64  `json:"topP"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
 }
 
 // GoogleResponse representa a resposta da API do Google
@@ -52,6 +66,12 @@ type GoogleResponse struct {
 	PromptFeedback struct {
 		BlockReason string `json:"blockReason"`
 	} `json:"promptFeedback"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion,omitempty"`
 }
 
 // NewGoogleClient cria um novo cliente para a API do Google
@@ -61,8 +81,9 @@ func NewGoogleClient(apiKey string, model string) *GoogleClient {
 	}
 	
 	return &GoogleClient{
-		APIKey: apiKey,
-		Model:  model,
+		APIKey:  apiKey,
+		Model:   model,
+		BaseURL: "https://generativelanguage.googleapis.com/v1beta",
 		Client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
@@ -71,35 +92,88 @@ func NewGoogleClient(apiKey string, model string) *GoogleClient {
 
 // GenerateCompletion gera um texto com base no prompt fornecido
 func (c *GoogleClient) GenerateCompletion(prompt string, systemPrompt string) (string, error) {
+	return c.GenerateChat(systemPrompt, []Message{{Role: RoleUser, Content: prompt}}, nil)
+}
+
+// GenerateCompletionStream gera um texto em modo streaming, chamando onChunk a cada trecho recebido
+func (c *GoogleClient) GenerateCompletionStream(prompt string, systemPrompt string, onChunk StreamHandler) (string, error) {
+	return c.GenerateChat(systemPrompt, []Message{{Role: RoleUser, Content: prompt}}, streamOrDiscard(onChunk))
+}
+
+// GenerateChat gera a próxima resposta de uma conversa com vários turnos
+func (c *GoogleClient) GenerateChat(systemPrompt string, messages []Message, onChunk StreamHandler) (string, error) {
+	resp, err := c.Generate(Request{SystemPrompt: systemPrompt, Messages: messages, OnChunk: onChunk})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// Generate gera a próxima resposta usando os endpoints generateContent e streamGenerateContent
+func (c *GoogleClient) Generate(request Request) (*Response, error) {
 	if c.APIKey == "" {
-		return "", fmt.Errorf("API Key do Google não configurada")
-	}
-
-	// Prepara os dados da requisição
-	systemContent := GoogleContent{
-		Role: "system",
-		Parts: []struct {
-			Text string `json:"text"`
-		}{
-			{
-				Text: systemPrompt,
-			},
-		},
+		return nil, fmt.Errorf("API Key do Google não configurada")
 	}
 
-	userContent := GoogleContent{
-		Role: "user",
-		Parts: []struct {
-			Text string `json:"text"`
-		}{
-			{
-				Text: prompt,
-			},
-		},
+	request.Messages = prepareImages(request.Messages, c.Model)
+	reqBody := newGoogleRequest(request)
+
+	var result *Response
+	var err error
+	if request.OnChunk != nil {
+		url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse&key=%s", c.BaseURL, c.Model, c.APIKey)
+		result, err = streamGoogleContent(request.context(), c.Client, url, reqBody, request.OnChunk)
+	} else {
+		url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", c.BaseURL, c.Model, c.APIKey)
+		result, err = generateGoogleContent(request.context(), c.Client, url, reqBody)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Model == "" {
+		result.Model = c.Model
+	}
+	return result, nil
+}
+
+// SupportsImages informa se o modelo configurado aceita imagens
+func (c *GoogleClient) SupportsImages() bool {
+	return SupportsVision(c.Model)
+}
+
+// googleTextContent cria um conteúdo do Gemini com uma única parte de texto
+func googleTextContent(role string, text string) GoogleContent {
+	return GoogleContent{
+		Role:  role,
+		Parts: []GooglePart{{Text: text}},
+	}
+}
+
+// newGoogleRequest monta o corpo da requisição para a API do Gemini.
+// O Gemini usa o papel "model" para as respostas do assistente e recebe o
+// system prompt separado, em systemInstruction
+func newGoogleRequest(request Request) GoogleRequest {
+	contents := make([]GoogleContent, 0, len(request.Messages))
+	for _, msg := range request.Messages {
+		role := "user"
+		if msg.Role == RoleAssistant {
+			role = "model"
+		}
+		content := GoogleContent{Role: role}
+		if msg.Content != "" || len(msg.Images) == 0 {
+			content.Parts = append(content.Parts, GooglePart{Text: msg.Content})
+		}
+		for _, img := range msg.Images {
+			content.Parts = append(content.Parts, GooglePart{
+				InlineData: &GoogleInlineData{MimeType: img.mimeType(), Data: img.base64Data()},
+			})
+		}
+		contents = append(contents, content)
 	}
 
 	reqBody := GoogleRequest{
-		Contents: []GoogleContent{systemContent, userContent},
+		Contents: contents,
 		GenerationConfig: GoogleGenerationConfig{
 			Temperature:     0.7,
 			MaxOutputTokens: 1024,
@@ -108,40 +182,135 @@ func (c *GoogleClient) GenerateCompletion(prompt string, systemPrompt string) (s
 		},
 	}
 
+	if opts := request.Options; opts != nil {
+		reqBody.GenerationConfig.Temperature = opts.Temperature
+		if opts.TopP > 0 {
+			reqBody.GenerationConfig.TopP = opts.TopP
+		}
+		if opts.MaxTokens > 0 {
+			reqBody.GenerationConfig.MaxOutputTokens = opts.MaxTokens
+		}
+		reqBody.GenerationConfig.StopSequences = opts.Stop
+		reqBody.GenerationConfig.Seed = opts.Seed
+	}
+
+	if request.SystemPrompt != "" {
+		system := googleTextContent("", request.SystemPrompt)
+		reqBody.SystemInstruction = &system
+	}
+
+	return reqBody
+}
+
+// apply copia o motivo de término e a contagem de tokens de uma resposta do Gemini
+func (r *GoogleResponse) apply(result *Response) {
+	if r.ModelVersion != "" {
+		result.Model = r.ModelVersion
+	}
+	if len(r.Candidates) > 0 && r.Candidates[0].FinishReason != "" {
+		result.FinishReason = normalizeFinishReason(r.Candidates[0].FinishReason)
+	}
+	if r.PromptFeedback.BlockReason != "" {
+		result.FinishReason = FinishContentFilter
+	}
+	if r.UsageMetadata.TotalTokenCount > 0 {
+		result.Usage = Usage{
+			PromptTokens:     r.UsageMetadata.PromptTokenCount,
+			CompletionTokens: r.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      r.UsageMetadata.TotalTokenCount,
+		}
+	}
+}
+
+// generateGoogleContent envia a requisição para o endpoint generateContent e retorna o texto gerado
+func generateGoogleContent(ctx context.Context, httpClient *http.Client, url string, reqBody GoogleRequest) (*Response, error) {
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("erro ao serializar solicitação: %v", err)
+		return nil, fmt.Errorf("erro ao serializar solicitação: %v", err)
 	}
 
 	// Cria e envia a requisição
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", c.Model, c.APIKey)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("erro ao criar requisição: %v", err)
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
 	}
-	
+
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.Client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("erro ao fazer requisição: %v", err)
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("erro da API [%d]: %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(resp)
 	}
 
 	// Lê e processa a resposta
 	var googleResp GoogleResponse
 	if err := json.NewDecoder(resp.Body).Decode(&googleResp); err != nil {
-		return "", fmt.Errorf("erro ao decodificar resposta: %v", err)
+		return nil, fmt.Errorf("erro ao decodificar resposta: %v", err)
 	}
 
 	if len(googleResp.Candidates) == 0 || len(googleResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("resposta vazia da API")
+		return nil, fmt.Errorf("resposta vazia da API")
+	}
+
+	result := &Response{Text: googleResp.Candidates[0].Content.Parts[0].Text}
+	googleResp.apply(result)
+	return result, nil
+}
+
+// streamGoogleContent envia a requisição para o endpoint de streaming do Gemini e repassa cada trecho
+func streamGoogleContent(ctx context.Context, httpClient *http.Client, url string, reqBody GoogleRequest, onChunk StreamHandler) (*Response, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar solicitação: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	// Com alt=sse cada evento traz um GoogleResponse parcial
+	result := &Response{}
+	var full bytes.Buffer
+	err = readSSE(resp.Body, func(data string) error {
+		var part GoogleResponse
+		if err := json.Unmarshal([]byte(data), &part); err != nil {
+			return fmt.Errorf("erro ao decodificar trecho da resposta: %v", err)
+		}
+		part.apply(result)
+		if len(part.Candidates) == 0 {
+			return nil
+		}
+
+		for _, p := range part.Candidates[0].Content.Parts {
+			if p.Text == "" {
+				continue
+			}
+			full.WriteString(p.Text)
+			onChunk(p.Text)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler resposta em streaming: %v", err)
 	}
 
-	return googleResp.Candidates[0].Content.Parts[0].Text, nil
+	result.Text = full.String()
+	return result, nil
 }
@@ -1,12 +1,8 @@
 package llm
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 
 	"github.com/peder/whatszapme/internal/auth"
 )
@@ -45,94 +41,54 @@ func NewGoogleOAuthClient(oauth *auth.GoogleOAuth, model string, ctx context.Con
 
 // GenerateCompletion gera um texto com base no prompt fornecido usando autenticação OAuth2
 func (c *GoogleOAuthClient) GenerateCompletion(prompt string, systemPrompt string) (string, error) {
+	return c.GenerateChat(systemPrompt, []Message{{Role: RoleUser, Content: prompt}}, nil)
+}
+
+// GenerateCompletionStream gera um texto em modo streaming usando autenticação OAuth2
+func (c *GoogleOAuthClient) GenerateCompletionStream(prompt string, systemPrompt string, onChunk StreamHandler) (string, error) {
+	return c.GenerateChat(systemPrompt, []Message{{Role: RoleUser, Content: prompt}}, streamOrDiscard(onChunk))
+}
+
+// GenerateChat gera a próxima resposta de uma conversa usando autenticação OAuth2
+func (c *GoogleOAuthClient) GenerateChat(systemPrompt string, messages []Message, onChunk StreamHandler) (string, error) {
+	resp, err := c.Generate(Request{SystemPrompt: systemPrompt, Messages: messages, OnChunk: onChunk})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// Generate gera a próxima resposta usando autenticação OAuth2
+func (c *GoogleOAuthClient) Generate(request Request) (*Response, error) {
 	// Verifica se há autenticação válida
 	if !c.oauth.IsAuthenticated() {
-		return "", fmt.Errorf("a sessão OAuth2 expirou ou não está disponível")
+		return nil, fmt.Errorf("a sessão OAuth2 expirou ou não está disponível")
 	}
 
 	// Obtém um cliente HTTP autenticado
 	httpClient, err := c.oauth.GetClient(c.ctx)
 	if err != nil {
-		return "", fmt.Errorf("erro ao obter cliente autenticado: %v", err)
-	}
-
-	// Temporariamente substitui o cliente HTTP interno
-	originalClient := c.Client
-	c.Client = httpClient
-	defer func() {
-		// Restaura o cliente original ao sair
-		c.Client = originalClient
-	}()
-
-	// Prepara os dados da requisição - igual ao método da classe base
-	systemContent := GoogleContent{
-		Role: "system",
-		Parts: []struct {
-			Text string `json:"text"`
-		}{
-			{
-				Text: systemPrompt,
-			},
-		},
+		return nil, fmt.Errorf("erro ao obter cliente autenticado: %v", err)
 	}
 
-	userContent := GoogleContent{
-		Role: "user",
-		Parts: []struct {
-			Text string `json:"text"`
-		}{
-			{
-				Text: prompt,
-			},
-		},
-	}
-
-	reqBody := GoogleRequest{
-		Contents: []GoogleContent{systemContent, userContent},
-		GenerationConfig: GoogleGenerationConfig{
-			Temperature:     0.7,
-			MaxOutputTokens: 1024,
-			TopK:            40,
-			TopP:            0.95,
-		},
-	}
-
-	// Converte a requisição para JSON
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("erro ao serializar solicitação: %v", err)
-	}
+	request.Messages = prepareImages(request.Messages, c.Model)
+	reqBody := newGoogleRequest(request)
 
 	// URL sem a chave API, pois usaremos o token OAuth no cabeçalho
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent", c.Model)
-	
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("erro ao criar requisição: %v", err)
+	var result *Response
+	if request.OnChunk != nil {
+		url := fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", c.BaseURL, c.Model)
+		result, err = streamGoogleContent(request.context(), httpClient, url, reqBody, request.OnChunk)
+	} else {
+		url := fmt.Sprintf("%s/models/%s:generateContent", c.BaseURL, c.Model)
+		result, err = generateGoogleContent(request.context(), httpClient, url, reqBody)
 	}
-	
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("erro ao fazer requisição: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("erro da API [%d]: %s", resp.StatusCode, string(bodyBytes))
+	if result.Model == "" {
+		result.Model = c.Model
 	}
-
-	// Lê e processa a resposta
-	var googleResp GoogleResponse
-	if err := json.NewDecoder(resp.Body).Decode(&googleResp); err != nil {
-		return "", fmt.Errorf("erro ao decodificar resposta: %v", err)
-	}
-
-	if len(googleResp.Candidates) == 0 || len(googleResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("resposta vazia da API")
-	}
-
-	return googleResp.Candidates[0].Content.Parts[0].Text, nil
+	return result, nil
 }
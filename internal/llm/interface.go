@@ -1,8 +1,50 @@
 package llm
 
+import "fmt"
+
 // Provider define a interface comum para diferentes provedores de LLM
 type Provider interface {
 	GenerateCompletion(prompt string, systemPrompt string) (string, error)
+	// Generate gera a próxima resposta respeitando o contexto e as opções da solicitação
+	Generate(req Request) (*Response, error)
+}
+
+// StreamHandler recebe cada trecho de texto assim que ele é gerado pelo modelo
+type StreamHandler func(chunk string)
+
+// StreamingProvider é implementado pelos provedores capazes de devolver a resposta
+// em partes, à medida que o modelo gera o texto
+type StreamingProvider interface {
+	Provider
+	// GenerateCompletionStream chama onChunk para cada trecho recebido e retorna o texto completo ao final
+	GenerateCompletionStream(prompt string, systemPrompt string, onChunk StreamHandler) (string, error)
+}
+
+// Papéis possíveis de um turno da conversa
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message representa um turno da conversa enviado ao modelo
+type Message struct {
+	Role    string
+	Content string
+	// Images são enviadas junto com o texto aos modelos que aceitam imagens
+	Images []Image
+}
+
+// ChatProvider é implementado pelos provedores que recebem a conversa em turnos,
+// no formato nativo de cada API, em vez de um único prompt
+type ChatProvider interface {
+	Provider
+	// GenerateChat gera a próxima resposta da conversa; se onChunk não for nil, a resposta é transmitida em streaming
+	GenerateChat(systemPrompt string, messages []Message, onChunk StreamHandler) (string, error)
+}
+
+// ModelLister é implementado pelos provedores capazes de listar os modelos disponíveis no servidor
+type ModelLister interface {
+	ListModels() ([]string, error)
 }
 
 // Factory cria um Provider baseado no tipo e configuração
@@ -32,8 +74,26 @@ func Factory(providerType string, config map[string]string) (Provider, error) {
 			model = "gemini-pro"
 		}
 		return NewGoogleClient(apiKey, model), nil
+	case "anthropic":
+		return NewAnthropicClient(config["api_key"], config["model"]), nil
+	case "openai-compatible":
+		baseURL := config["base_url"]
+		if baseURL == "" {
+			return nil, fmt.Errorf("URL base do provedor compatível com OpenAI não configurada")
+		}
+		return NewOpenAICompatibleClient(baseURL, config["api_key"], config["model"], ParseHeaders(config["headers"])), nil
+	case "grok", "groq":
+		// Serviços compatíveis com a API da OpenAI, com a URL base já conhecida
+		baseURL := config["base_url"]
+		if baseURL == "" {
+			baseURL = OpenAICompatiblePresets[providerType]
+		}
+		model := config["model"]
+		if model == "" {
+			model = OpenAICompatibleDefaultModels[providerType]
+		}
+		return NewOpenAICompatibleClient(baseURL, config["api_key"], model, ParseHeaders(config["headers"])), nil
 	default:
-		// Padrão para Ollama
-		return NewOllamaClient("http://localhost:11434", "llama2"), nil
+		return nil, fmt.Errorf("tipo de provedor desconhecido: %s", providerType)
 	}
 }
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"github.com/peder/whatszapme/internal/token"
+)
+
+// UsageRecorder recebe a contagem de tokens de cada geração; token.Counter implementa esta interface
+type UsageRecorder interface {
+	RecordUsage(provider, model string, promptTokens, completionTokens int)
+}
+
+// ContactUsageRecorder é implementado pelos registradores que separam o uso por contato
+type ContactUsageRecorder interface {
+	RecordContactUsage(jid, provider, model string, promptTokens, completionTokens int)
+}
+
+// MeteredProvider envolve um Provider e registra o uso de tokens de cada chamada.
+// Quando o backend não informa a contagem, os tokens são estimados a partir do texto
+type MeteredProvider struct {
+	Provider
+	name     string
+	model    string
+	recorder UsageRecorder
+}
+
+// NewMeteredProvider cria um provedor que registra o uso em recorder com o nome e o modelo informados.
+// Se model for vazio, é usado o modelo informado em cada resposta
+func NewMeteredProvider(provider Provider, name, model string, recorder UsageRecorder) *MeteredProvider {
+	return &MeteredProvider{
+		Provider: provider,
+		name:     name,
+		model:    model,
+		recorder: recorder,
+	}
+}
+
+// Generate repassa a solicitação ao provedor e registra os tokens usados
+func (m *MeteredProvider) Generate(req Request) (*Response, error) {
+	resp, err := m.Provider.Generate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	fillEstimatedUsage(req, resp)
+	if m.recorder != nil {
+		model := m.model
+		if model == "" {
+			model = resp.Model
+		}
+		if contacts, ok := m.recorder.(ContactUsageRecorder); ok {
+			contacts.RecordContactUsage(req.User, m.name, model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+		} else {
+			m.recorder.RecordUsage(m.name, model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+		}
+	}
+
+	return resp, nil
+}
+
+// CheckHealth repassa a verificação de saúde ao provedor, quando ele a implementa
+func (m *MeteredProvider) CheckHealth() error {
+	if checker, ok := m.Provider.(HealthChecker); ok {
+		return checker.CheckHealth()
+	}
+	return nil
+}
+
+// SupportsImages repassa ao provedor a informação de suporte a imagens
+func (m *MeteredProvider) SupportsImages() bool {
+	return ImagesSupported(m.Provider)
+}
+
+// GenerateCompletion gera um texto passando por Generate, para que o uso seja registrado
+func (m *MeteredProvider) GenerateCompletion(prompt string, systemPrompt string) (string, error) {
+	resp, err := m.Generate(completionRequest(prompt, systemPrompt))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// GenerateCompletionStream gera um texto em streaming passando por Generate
+func (m *MeteredProvider) GenerateCompletionStream(prompt string, systemPrompt string, onChunk StreamHandler) (string, error) {
+	req := completionRequest(prompt, systemPrompt)
+	req.OnChunk = streamOrDiscard(onChunk)
+
+	resp, err := m.Generate(req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// GenerateChat gera a próxima resposta de uma conversa passando por Generate
+func (m *MeteredProvider) GenerateChat(systemPrompt string, messages []Message, onChunk StreamHandler) (string, error) {
+	resp, err := m.Generate(Request{SystemPrompt: systemPrompt, Messages: messages, OnChunk: onChunk})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// fillEstimatedUsage completa a contagem de tokens que o backend não informou
+func fillEstimatedUsage(req Request, resp *Response) {
+	if resp.Usage.PromptTokens == 0 {
+		prompt := token.EstimateTokens(req.SystemPrompt)
+		for _, msg := range req.Messages {
+			prompt += token.EstimateTokens(msg.Content)
+		}
+		resp.Usage.PromptTokens = prompt
+		resp.Usage.Estimated = true
+	}
+
+	if resp.Usage.CompletionTokens == 0 && resp.Text != "" {
+		resp.Usage.CompletionTokens = token.EstimateTokens(resp.Text)
+		resp.Usage.Estimated = true
+	}
+
+	if resp.Usage.Estimated {
+		resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+	}
+}
@@ -1,10 +1,10 @@
 package llm
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 )
@@ -18,21 +18,24 @@ type OllamaClient struct {
 
 // OllamaRequest representa uma solicitação para a API do Ollama
 type OllamaRequest struct {
-	Model    string  `json:"model"`
-	Prompt   string  `json:"prompt"`
-	Stream   bool    `json:"stream,omitempty"`
-	Options  Options `json:"options,omitempty"`
-	System   string  `json:"system,omitempty"`
-	Template string  `json:"template,omitempty"`
-	Context  []int   `json:"context,omitempty"`
+	Model    string   `json:"model"`
+	Prompt   string   `json:"prompt"`
+	Stream   bool     `json:"stream,omitempty"`
+	Options  Options  `json:"options,omitempty"`
+	System   string   `json:"system,omitempty"`
+	Template string   `json:"template,omitempty"`
+	Context  []int    `json:"context,omitempty"`
+	Images   []string `json:"images,omitempty"`
 }
 
 // Options representa as opções para a geração de texto
 type Options struct {
-	Temperature float64 `json:"temperature,omitempty"`
-	TopP        float64 `json:"top_p,omitempty"`
-	TopK        int     `json:"top_k,omitempty"`
-	MaxTokens   int     `json:"num_predict,omitempty"`
+	Temperature float64  `json:"temperature"`
+	TopP        float64  `json:"top_p,omitempty"`
+	TopK        int      `json:"top_k,omitempty"`
+	MaxTokens   int      `json:"num_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
 }
 
 // OllamaResponse representa a resposta da API do Ollama
@@ -48,6 +51,32 @@ type OllamaResponse struct {
 	EvalDuration    int64     `json:"eval_duration,omitempty"`
 }
 
+// OllamaChatMessage representa um turno da conversa no endpoint /api/chat
+type OllamaChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// Images contém as imagens do turno em base64, para modelos multimodais como o llava
+	Images []string `json:"images,omitempty"`
+}
+
+// OllamaChatRequest representa uma solicitação para o endpoint /api/chat
+type OllamaChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []OllamaChatMessage `json:"messages"`
+	Stream   bool                `json:"stream"`
+	Options  Options             `json:"options,omitempty"`
+}
+
+// OllamaChatResponse representa a resposta (ou um trecho dela, em streaming) do endpoint /api/chat
+type OllamaChatResponse struct {
+	Model           string            `json:"model"`
+	Message         OllamaChatMessage `json:"message"`
+	Done            bool              `json:"done"`
+	DoneReason      string            `json:"done_reason,omitempty"`
+	PromptEvalCount int               `json:"prompt_eval_count,omitempty"`
+	EvalCount       int               `json:"eval_count,omitempty"`
+}
+
 // NewOllamaClient cria um novo cliente para a API do Ollama
 func NewOllamaClient(baseURL string, model string) *OllamaClient {
 	return &OllamaClient{
@@ -61,47 +90,145 @@ func NewOllamaClient(baseURL string, model string) *OllamaClient {
 
 // GenerateCompletion gera um texto com base no prompt fornecido
 func (c *OllamaClient) GenerateCompletion(prompt string, systemPrompt string) (string, error) {
-	reqBody := OllamaRequest{
-		Model:  c.Model,
-		Prompt: prompt,
-		Stream: false,
-		Options: Options{
-			Temperature: 0.7,
-			MaxTokens:   2048,
-		},
-		System: systemPrompt,
+	resp, err := c.Generate(completionRequest(prompt, systemPrompt))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// GenerateCompletionStream gera um texto em modo streaming, chamando onChunk a cada trecho recebido
+func (c *OllamaClient) GenerateCompletionStream(prompt string, systemPrompt string, onChunk StreamHandler) (string, error) {
+	req := completionRequest(prompt, systemPrompt)
+	req.OnChunk = streamOrDiscard(onChunk)
+
+	resp, err := c.Generate(req)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// GenerateChat gera a próxima resposta de uma conversa usando o endpoint /api/chat
+func (c *OllamaClient) GenerateChat(systemPrompt string, messages []Message, onChunk StreamHandler) (string, error) {
+	resp, err := c.Generate(Request{SystemPrompt: systemPrompt, Messages: messages, OnChunk: onChunk})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// Generate gera a próxima resposta usando o endpoint /api/chat
+func (c *OllamaClient) Generate(request Request) (*Response, error) {
+	chatMessages := make([]OllamaChatMessage, 0, len(request.Messages)+1)
+	if request.SystemPrompt != "" {
+		chatMessages = append(chatMessages, OllamaChatMessage{Role: "system", Content: request.SystemPrompt})
+	}
+	for _, msg := range prepareImages(request.Messages, c.Model) {
+		chatMessage := OllamaChatMessage{Role: msg.Role, Content: msg.Content}
+		for _, img := range msg.Images {
+			chatMessage.Images = append(chatMessage.Images, img.base64Data())
+		}
+		chatMessages = append(chatMessages, chatMessage)
+	}
+
+	reqBody := OllamaChatRequest{
+		Model:    c.Model,
+		Messages: chatMessages,
+		Stream:   request.OnChunk != nil,
+		Options:  ollamaOptions(request.Options),
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("erro ao serializar solicitação: %v", err)
+		return nil, fmt.Errorf("erro ao serializar solicitação: %v", err)
 	}
 
-	// Cria e envia a requisição
-	req, err := http.NewRequest("POST", c.BaseURL+"/api/generate", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(request.context(), "POST", c.BaseURL+"/api/chat", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("erro ao criar requisição: %v", err)
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("erro ao fazer requisição: %v", err)
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("erro da API [%d]: %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(resp)
+	}
+
+	// Sem streaming a resposta vem em um único objeto; com streaming, um objeto por linha
+	result := &Response{Model: c.Model}
+	var full bytes.Buffer
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var part OllamaChatResponse
+		if err := json.Unmarshal(line, &part); err != nil {
+			return nil, fmt.Errorf("erro ao decodificar resposta: %v", err)
+		}
+
+		if part.Message.Content != "" {
+			full.WriteString(part.Message.Content)
+			if request.OnChunk != nil {
+				request.OnChunk(part.Message.Content)
+			}
+		}
+
+		if part.Done {
+			// As contagens de tokens só vêm no último objeto
+			if part.Model != "" {
+				result.Model = part.Model
+			}
+			result.FinishReason = normalizeFinishReason(part.DoneReason)
+			result.Usage = Usage{
+				PromptTokens:     part.PromptEvalCount,
+				CompletionTokens: part.EvalCount,
+				TotalTokens:      part.PromptEvalCount + part.EvalCount,
+			}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler resposta: %v", err)
 	}
 
-	// Lê e processa a resposta
-	var ollamaResp OllamaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		return "", fmt.Errorf("erro ao decodificar resposta: %v", err)
+	result.Text = full.String()
+	return result, nil
+}
+
+// ollamaOptions converte as opções de geração para o formato do Ollama
+func ollamaOptions(opts *GenerationOptions) Options {
+	options := Options{
+		Temperature: 0.7,
+		MaxTokens:   2048,
+	}
+	if opts == nil {
+		return options
 	}
 
-	return ollamaResp.Response, nil
+	options.Temperature = opts.Temperature
+	options.TopP = opts.TopP
+	if opts.MaxTokens > 0 {
+		options.MaxTokens = opts.MaxTokens
+	}
+	options.Stop = opts.Stop
+	options.Seed = opts.Seed
+
+	return options
+}
+
+// SupportsImages informa se o modelo configurado aceita imagens
+func (c *OllamaClient) SupportsImages() bool {
+	return SupportsVision(c.Model)
 }
 
 // ListModels lista os modelos disponíveis no servidor Ollama
@@ -113,13 +240,12 @@ func (c *OllamaClient) ListModels() ([]string, error) {
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao fazer requisição: %v", err)
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("erro da API [%d]: %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(resp)
 	}
 
 	// Estrutura para parsear a resposta
@@ -11,23 +11,83 @@ import (
 
 // OpenAIClient representa um cliente para a API da OpenAI
 type OpenAIClient struct {
-	APIKey string
-	Model  string
-	Client *http.Client
+	APIKey  string
+	Model   string
+	BaseURL string
+	Client  *http.Client
+	// Headers são enviados em todas as requisições, além da autenticação
+	Headers map[string]string
+
+	// keyOptional permite usar servidores compatíveis que não exigem API Key
+	keyOptional bool
 }
 
 // OpenAIRequest representa uma solicitação para a API da OpenAI
 type OpenAIRequest struct {
-	Model       string        `json:"model"`
-	Messages    []OpenAIMessage `json:"messages"`
-	Temperature float64       `json:"temperature"`
-	MaxTokens   int           `json:"max_tokens"`
+	Model         string               `json:"model"`
+	Messages      []OpenAIMessage      `json:"messages"`
+	Temperature   float64              `json:"temperature"`
+	TopP          float64              `json:"top_p,omitempty"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Stop          []string             `json:"stop,omitempty"`
+	Seed          *int                 `json:"seed,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *OpenAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+// OpenAIStreamOptions representa as opções do modo streaming da OpenAI
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// OpenAIUsage representa a contagem de tokens informada pela OpenAI
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // OpenAIMessage representa uma mensagem para o modelo da OpenAI
 type OpenAIMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+
+	// images transforma o conteúdo em uma lista de partes de texto e imagem
+	images []Image
+}
+
+// OpenAIContentPart representa uma parte do conteúdo multimodal de uma mensagem
+type OpenAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *OpenAIImageURL `json:"image_url,omitempty"`
+}
+
+// OpenAIImageURL aponta para a imagem de uma parte do conteúdo, como URL ou data URL em base64
+type OpenAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON envia o conteúdo como texto simples ou, quando há imagens, como lista de partes
+func (m OpenAIMessage) MarshalJSON() ([]byte, error) {
+	if len(m.images) == 0 {
+		return json.Marshal(struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		}{m.Role, m.Content})
+	}
+
+	parts := make([]OpenAIContentPart, 0, len(m.images)+1)
+	if m.Content != "" {
+		parts = append(parts, OpenAIContentPart{Type: "text", Text: m.Content})
+	}
+	for _, img := range m.images {
+		parts = append(parts, OpenAIContentPart{Type: "image_url", ImageURL: &OpenAIImageURL{URL: img.dataURL()}})
+	}
+	return json.Marshal(struct {
+		Role    string              `json:"role"`
+		Content []OpenAIContentPart `json:"content"`
+	}{m.Role, parts})
 }
 
 // OpenAIResponse representa a resposta da API da OpenAI
@@ -35,6 +95,7 @@ type OpenAIResponse struct {
 	ID      string `json:"id"`
 	Object  string `json:"object"`
 	Created int64  `json:"created"`
+	Model   string `json:"model"`
 	Choices []struct {
 		Index   int `json:"index"`
 		Message struct {
@@ -43,11 +104,21 @@ type OpenAIResponse struct {
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage OpenAIUsage `json:"usage"`
+}
+
+// OpenAIStreamChunk representa um evento da resposta em streaming da OpenAI
+type OpenAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *OpenAIUsage `json:"usage,omitempty"`
 }
 
 // NewOpenAIClient cria um novo cliente para a API da OpenAI
@@ -57,8 +128,9 @@ func NewOpenAIClient(apiKey string, model string) *OpenAIClient {
 	}
 	
 	return &OpenAIClient{
-		APIKey: apiKey,
-		Model:  model,
+		APIKey:  apiKey,
+		Model:   model,
+		BaseURL: "https://api.openai.com/v1",
 		Client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
@@ -67,63 +139,178 @@ func NewOpenAIClient(apiKey string, model string) *OpenAIClient {
 
 // GenerateCompletion gera um texto com base no prompt fornecido
 func (c *OpenAIClient) GenerateCompletion(prompt string, systemPrompt string) (string, error) {
-	if c.APIKey == "" {
-		return "", fmt.Errorf("API Key da OpenAI não configurada")
+	return c.GenerateChat(systemPrompt, []Message{{Role: RoleUser, Content: prompt}}, nil)
+}
+
+// GenerateCompletionStream gera um texto em modo streaming, chamando onChunk a cada trecho recebido
+func (c *OpenAIClient) GenerateCompletionStream(prompt string, systemPrompt string, onChunk StreamHandler) (string, error) {
+	return c.GenerateChat(systemPrompt, []Message{{Role: RoleUser, Content: prompt}}, streamOrDiscard(onChunk))
+}
+
+// GenerateChat gera a próxima resposta de uma conversa com vários turnos
+func (c *OpenAIClient) GenerateChat(systemPrompt string, messages []Message, onChunk StreamHandler) (string, error) {
+	resp, err := c.Generate(Request{SystemPrompt: systemPrompt, Messages: messages, OnChunk: onChunk})
+	if err != nil {
+		return "", err
 	}
+	return resp.Text, nil
+}
 
-	// Prepara os dados da requisição
-	messages := []OpenAIMessage{
-		{
-			Role:    "system",
-			Content: systemPrompt,
-		},
-		{
-			Role:    "user",
-			Content: prompt,
-		},
+// Generate gera a próxima resposta usando o endpoint /chat/completions
+func (c *OpenAIClient) Generate(request Request) (*Response, error) {
+	if c.APIKey == "" && !c.keyOptional {
+		return nil, fmt.Errorf("API Key da OpenAI não configurada")
+	}
+
+	// Prepara os dados da requisição: o system prompt vai como primeira mensagem
+	openaiMessages := make([]OpenAIMessage, 0, len(request.Messages)+1)
+	if request.SystemPrompt != "" {
+		openaiMessages = append(openaiMessages, OpenAIMessage{Role: "system", Content: request.SystemPrompt})
+	}
+	for _, msg := range prepareImages(request.Messages, c.Model) {
+		openaiMessages = append(openaiMessages, OpenAIMessage{Role: msg.Role, Content: msg.Content, images: msg.Images})
 	}
 
 	reqBody := OpenAIRequest{
 		Model:       c.Model,
-		Messages:    messages,
+		Messages:    openaiMessages,
 		Temperature: 0.7,
 		MaxTokens:   1024,
+		Stream:      request.OnChunk != nil,
+	}
+	if opts := request.Options; opts != nil {
+		reqBody.Temperature = opts.Temperature
+		reqBody.TopP = opts.TopP
+		if opts.MaxTokens > 0 {
+			reqBody.MaxTokens = opts.MaxTokens
+		}
+		reqBody.Stop = opts.Stop
+		reqBody.Seed = opts.Seed
+	}
+	if reqBody.Stream {
+		// Sem esta opção a contagem de tokens não é enviada em streaming
+		reqBody.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("erro ao serializar solicitação: %v", err)
+		return nil, fmt.Errorf("erro ao serializar solicitação: %v", err)
 	}
 
 	// Cria e envia a requisição
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(request.context(), "POST", c.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("erro ao criar requisição: %v", err)
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
 	}
-	
+
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	c.setHeaders(req)
+	if reqBody.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("erro ao fazer requisição: %v", err)
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("erro da API [%d]: %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(resp)
+	}
+
+	if reqBody.Stream {
+		result, err := readOpenAIStream(resp.Body, request.OnChunk)
+		if err != nil {
+			return nil, err
+		}
+		if result.Model == "" {
+			result.Model = c.Model
+		}
+		return result, nil
 	}
 
 	// Lê e processa a resposta
 	var openaiResp OpenAIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&openaiResp); err != nil {
-		return "", fmt.Errorf("erro ao decodificar resposta: %v", err)
+		return nil, fmt.Errorf("erro ao decodificar resposta: %v", err)
 	}
 
 	if len(openaiResp.Choices) == 0 {
-		return "", fmt.Errorf("resposta vazia da API")
+		return nil, fmt.Errorf("resposta vazia da API")
 	}
 
-	return openaiResp.Choices[0].Message.Content, nil
+	result := &Response{
+		Text:         openaiResp.Choices[0].Message.Content,
+		FinishReason: normalizeFinishReason(openaiResp.Choices[0].FinishReason),
+		Model:        openaiResp.Model,
+		Usage: Usage{
+			PromptTokens:     openaiResp.Usage.PromptTokens,
+			CompletionTokens: openaiResp.Usage.CompletionTokens,
+			TotalTokens:      openaiResp.Usage.TotalTokens,
+		},
+	}
+	if result.Model == "" {
+		result.Model = c.Model
+	}
+
+	return result, nil
+}
+
+// readOpenAIStream lê os eventos SSE de uma resposta em streaming e repassa cada trecho
+func readOpenAIStream(body io.Reader, onChunk StreamHandler) (*Response, error) {
+	result := &Response{}
+	var full bytes.Buffer
+	err := readSSE(body, func(data string) error {
+		var chunk OpenAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("erro ao decodificar trecho da resposta: %v", err)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		// O último evento traz apenas a contagem de tokens, sem escolhas
+		if chunk.Usage != nil {
+			result.Usage = Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		if reason := chunk.Choices[0].FinishReason; reason != "" {
+			result.FinishReason = normalizeFinishReason(reason)
+		}
+
+		text := chunk.Choices[0].Delta.Content
+		if text == "" {
+			return nil
+		}
+		full.WriteString(text)
+		onChunk(text)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler resposta em streaming: %v", err)
+	}
+
+	result.Text = full.String()
+	return result, nil
+}
+
+// SupportsImages informa se o modelo configurado aceita imagens
+func (c *OpenAIClient) SupportsImages() bool {
+	return SupportsVision(c.Model)
+}
+
+// setHeaders adiciona a autenticação e os cabeçalhos extras configurados
+func (c *OpenAIClient) setHeaders(req *http.Request) {
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
 }
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// URLs base conhecidas de servidores compatíveis com a API da OpenAI
+var OpenAICompatiblePresets = map[string]string{
+	"lmstudio": "http://localhost:1234/v1",
+	"vllm":     "http://localhost:8000/v1",
+	"llamacpp": "http://localhost:8080/v1",
+	"groq":     "https://api.groq.com/openai/v1",
+	"grok":     "https://api.x.ai/v1",
+}
+
+// Modelos usados nos serviços hospedados quando nenhum é configurado, já que eles exigem o nome do modelo
+var OpenAICompatibleDefaultModels = map[string]string{
+	"groq": "llama-3.1-8b-instant",
+	"grok": "grok-beta",
+}
+
+// NewOpenAICompatibleClient cria um cliente para qualquer servidor que implemente a API da OpenAI,
+// como LM Studio, vLLM, llama.cpp server, Groq ou Grok. A API Key é opcional, pois servidores locais
+// normalmente não exigem autenticação
+func NewOpenAICompatibleClient(baseURL string, apiKey string, model string, headers map[string]string) *OpenAIClient {
+	client := NewOpenAIClient(apiKey, model)
+	client.BaseURL = strings.TrimRight(baseURL, "/")
+	client.Headers = headers
+	client.keyOptional = true
+
+	// Servidores como o llama.cpp usam o modelo carregado e ignoram o nome
+	if model == "" {
+		client.Model = ""
+	}
+
+	return client
+}
+
+// ParseHeaders converte cabeçalhos no formato "Nome: valor", um por linha. O ";" não separa cabeçalhos,
+// pois faz parte de valores como Cookie e Accept
+func ParseHeaders(text string) map[string]string {
+	headers := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// ListModels lista os modelos disponíveis no endpoint /models
+func (c *OpenAIClient) ListModels() ([]string, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+	c.setHeaders(req)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	// Estrutura para parsear a resposta
+	var modelsResp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&modelsResp); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar resposta: %v", err)
+	}
+
+	models := make([]string, 0, len(modelsResp.Data))
+	for _, model := range modelsResp.Data {
+		models = append(models, model.ID)
+	}
+	sort.Strings(models)
+
+	return models, nil
+}
+
+// CheckHealth verifica se o servidor responde à listagem de modelos
+func (c *OpenAIClient) CheckHealth() error {
+	if _, err := c.ListModels(); err != nil {
+		return fmt.Errorf("erro ao conectar com %s: %w", c.BaseURL, err)
+	}
+	return nil
+}
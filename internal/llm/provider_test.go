@@ -1,9 +1,17 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestProviderFactory(t *testing.T) {
@@ -41,11 +49,43 @@ func TestProviderFactory(t *testing.T) {
 			expectError:  false,
 		},
 		{
-			name:         "Tipo Desconhecido - Deve usar padrão Ollama",
-			providerType: "desconhecido",
+			name:         "Anthropic Provider",
+			providerType: "anthropic",
+			config: map[string]string{
+				"api_key": "test-key",
+				"model":   "claude-3-5-haiku-latest",
+			},
+			expectError:  false,
+		},
+		{
+			name:         "OpenAI Compatível",
+			providerType: "openai-compatible",
+			config: map[string]string{
+				"base_url": "http://localhost:1234/v1",
+				"model":    "local-model",
+			},
+			expectError:  false,
+		},
+		{
+			name:         "OpenAI Compatível sem URL base",
+			providerType: "openai-compatible",
 			config: map[string]string{},
+			expectError:  true,
+		},
+		{
+			name:         "Grok",
+			providerType: "grok",
+			config: map[string]string{
+				"api_key": "test-key",
+			},
 			expectError:  false,
 		},
+		{
+			name:         "Tipo Desconhecido - Deve retornar erro",
+			providerType: "desconhecido",
+			config: map[string]string{},
+			expectError:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -139,3 +179,764 @@ func TestGoogleClient(t *testing.T) {
 		t.Errorf("GoogleClient deve implementar a interface Provider")
 	}
 }
+
+func TestOllamaClientStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Caminho inesperado: %s", r.URL.Path)
+		}
+		fmt.Fprintln(w, `{"model":"llama2","message":{"role":"assistant","content":"Olá"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama2","message":{"role":"assistant","content":", tudo bem?"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama2","message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer server.Close()
+
+	provider := NewOllamaClient(server.URL, "llama2")
+
+	var chunks []string
+	resposta, err := provider.GenerateCompletionStream("Oi", "Sistema", func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	if resposta != "Olá, tudo bem?" {
+		t.Errorf("Resposta incorreta: %q", resposta)
+	}
+	if len(chunks) != 2 {
+		t.Errorf("Esperava 2 trechos, obteve %d", len(chunks))
+	}
+}
+
+func TestOpenAIClientStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Cabeçalho de autorização incorreto: %s", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Olá\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\" mundo\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIClient("test-key", "gpt-3.5-turbo")
+	provider.BaseURL = server.URL
+
+	var chunks []string
+	resposta, err := provider.GenerateCompletionStream("Oi", "Sistema", func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	if resposta != "Olá mundo" || len(chunks) != 2 {
+		t.Errorf("Resposta incorreta: %q (%d trechos)", resposta, len(chunks))
+	}
+}
+
+func TestGoogleClientStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":streamGenerateContent") || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("Endpoint de streaming incorreto: %s", r.URL.String())
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Bom\"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\" dia\"}]}}]}\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewGoogleClient("test-key", "gemini-pro")
+	provider.BaseURL = server.URL
+
+	resposta, err := provider.GenerateCompletionStream("Oi", "Sistema", nil)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	if resposta != "Bom dia" {
+		t.Errorf("Resposta incorreta: %q", resposta)
+	}
+}
+
+func TestSentenceSplitter(t *testing.T) {
+	splitter := &SentenceSplitter{MinLength: 10, MaxLength: 200}
+
+	var partes []string
+	for _, chunk := range []string{"Primeira frase", " completa. Segunda", " frase aqui! E", " o resto"} {
+		partes = append(partes, splitter.Push(chunk)...)
+	}
+	if resto := splitter.Flush(); resto != "" {
+		partes = append(partes, resto)
+	}
+
+	esperado := []string{"Primeira frase completa.", "Segunda frase aqui!", "E o resto"}
+	if !reflect.DeepEqual(partes, esperado) {
+		t.Errorf("Divisão incorreta: %q", partes)
+	}
+}
+
+func TestOllamaClientChat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Caminho inesperado: %s", r.URL.Path)
+		}
+
+		var req OllamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Erro ao decodificar requisição: %v", err)
+		}
+
+		papeis := []string{}
+		for _, m := range req.Messages {
+			papeis = append(papeis, m.Role)
+		}
+		if !reflect.DeepEqual(papeis, []string{"system", "user", "assistant", "user"}) {
+			t.Errorf("Papéis incorretos: %v", papeis)
+		}
+
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Seu pedido é o 42."},"done":true}`)
+	}))
+	defer server.Close()
+
+	provider := NewOllamaClient(server.URL, "llama2")
+	resposta, err := provider.GenerateChat("Sistema", []Message{
+		{Role: RoleUser, Content: "Meu pedido é o 42"},
+		{Role: RoleAssistant, Content: "Anotado!"},
+		{Role: RoleUser, Content: "Qual é o meu pedido?"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	if resposta != "Seu pedido é o 42." {
+		t.Errorf("Resposta incorreta: %q", resposta)
+	}
+}
+
+func TestGoogleClientChatRoles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GoogleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Erro ao decodificar requisição: %v", err)
+		}
+
+		if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "Sistema" {
+			t.Errorf("System prompt deveria ir em systemInstruction")
+		}
+		if len(req.Contents) != 3 || req.Contents[1].Role != "model" {
+			t.Errorf("Conteúdos incorretos: %+v", req.Contents)
+		}
+
+		fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Ok"}]}}]}`)
+	}))
+	defer server.Close()
+
+	provider := NewGoogleClient("test-key", "gemini-pro")
+	provider.BaseURL = server.URL
+
+	_, err := provider.GenerateChat("Sistema", []Message{
+		{Role: RoleUser, Content: "Oi"},
+		{Role: RoleAssistant, Content: "Olá!"},
+		{Role: RoleUser, Content: "Tudo bem?"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+}
+
+func TestOpenAIClientGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Erro ao decodificar requisição: %v", err)
+		}
+
+		if req.Temperature != 0 || req.TopP != 0.5 || req.MaxTokens != 64 {
+			t.Errorf("Opções de geração incorretas: %+v", req)
+		}
+		if !reflect.DeepEqual(req.Stop, []string{"FIM"}) || req.Seed == nil || *req.Seed != 7 {
+			t.Errorf("Stop ou seed incorretos: %v %v", req.Stop, req.Seed)
+		}
+
+		fmt.Fprint(w, `{"model":"gpt-4o-mini","choices":[{"message":{"role":"assistant","content":"Oi"},"finish_reason":"length"}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIClient("test-key", "gpt-4o-mini")
+	provider.BaseURL = server.URL
+
+	seed := 7
+	resp, err := provider.Generate(Request{
+		Messages: []Message{{Role: RoleUser, Content: "Olá"}},
+		Options: &GenerationOptions{
+			Temperature: 0,
+			TopP:        0.5,
+			MaxTokens:   64,
+			Stop:        []string{"FIM"},
+			Seed:        &seed,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	if resp.Text != "Oi" || resp.FinishReason != FinishLength || resp.Model != "gpt-4o-mini" {
+		t.Errorf("Resposta incorreta: %+v", resp)
+	}
+	if resp.Usage != (Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}) {
+		t.Errorf("Uso de tokens incorreto: %+v", resp.Usage)
+	}
+}
+
+func TestOpenAICompatibleClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("Não deveria enviar autenticação sem API Key: %s", auth)
+		}
+		if r.Header.Get("X-Title") != "WhatsZapMe" {
+			t.Errorf("Cabeçalho extra não enviado: %v", r.Header)
+		}
+
+		switch r.URL.Path {
+		case "/v1/models":
+			fmt.Fprint(w, `{"object":"list","data":[{"id":"qwen2.5-7b"},{"id":"llama-3.1-8b"}]}`)
+		case "/v1/chat/completions":
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Oi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`)
+		default:
+			t.Errorf("Caminho inesperado: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewOpenAICompatibleClient(server.URL+"/v1/", "", "llama-3.1-8b", ParseHeaders("X-Title: WhatsZapMe"))
+
+	models, err := provider.ListModels()
+	if err != nil {
+		t.Fatalf("Erro ao listar modelos: %v", err)
+	}
+	if !reflect.DeepEqual(models, []string{"llama-3.1-8b", "qwen2.5-7b"}) {
+		t.Errorf("Modelos incorretos: %v", models)
+	}
+
+	resp, err := provider.Generate(completionRequest("Olá", ""))
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if resp.Text != "Oi" || resp.Model != "llama-3.1-8b" || resp.Usage.TotalTokens != 6 {
+		t.Errorf("Resposta incorreta: %+v", resp)
+	}
+}
+
+func TestAnthropicClientGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("Caminho inesperado: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") != AnthropicVersion {
+			t.Errorf("Cabeçalhos de autenticação incorretos: %v", r.Header)
+		}
+
+		var req AnthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Erro ao decodificar requisição: %v", err)
+		}
+
+		if req.System != "Seja breve" {
+			t.Errorf("System prompt incorreto: %q", req.System)
+		}
+		// A resposta do assistente no início é descartada e os turnos seguidos do usuário são unidos
+		expected := []AnthropicMessage{
+			{Role: "user", Content: "Olá\n\nTudo bem?"},
+			{Role: "assistant", Content: "Tudo ótimo"},
+			{Role: "user", Content: "Que bom"},
+		}
+		if !reflect.DeepEqual(req.Messages, expected) {
+			t.Errorf("Mensagens incorretas: %+v", req.Messages)
+		}
+		if req.MaxTokens != 64 || !reflect.DeepEqual(req.StopSequences, []string{"FIM"}) {
+			t.Errorf("Opções de geração incorretas: %+v", req)
+		}
+
+		if !req.Stream {
+			fmt.Fprint(w, `{"type":"message","role":"assistant","model":"claude-3-5-haiku-20241022","content":[{"type":"text","text":"Oi"}],"stop_reason":"max_tokens","usage":{"input_tokens":20,"output_tokens":4}}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-3-5-haiku-20241022\",\"usage\":{\"input_tokens\":20,\"output_tokens\":1}}}\n\n")
+		fmt.Fprint(w, "event: ping\ndata: {\"type\":\"ping\"}\n\n")
+		for _, part := range []string{"Oi, ", "tudo bem"} {
+			fmt.Fprintf(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":%q}}\n\n", part)
+		}
+		fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":6}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewAnthropicClient("test-key", "claude-3-5-haiku-latest")
+	provider.BaseURL = server.URL
+
+	req := Request{
+		SystemPrompt: "Seja breve",
+		Messages: []Message{
+			{Role: RoleAssistant, Content: "Mensagem antiga"},
+			{Role: RoleUser, Content: "Olá"},
+			{Role: RoleUser, Content: "Tudo bem?"},
+			{Role: RoleAssistant, Content: "Tudo ótimo"},
+			{Role: RoleUser, Content: "Que bom"},
+		},
+		Options: &GenerationOptions{MaxTokens: 64, Stop: []string{"FIM"}},
+	}
+
+	t.Run("Completo", func(t *testing.T) {
+		resp, err := provider.Generate(req)
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+		if resp.Text != "Oi" || resp.FinishReason != FinishLength || resp.Model != "claude-3-5-haiku-20241022" {
+			t.Errorf("Resposta incorreta: %+v", resp)
+		}
+		if resp.Usage != (Usage{PromptTokens: 20, CompletionTokens: 4, TotalTokens: 24}) {
+			t.Errorf("Uso de tokens incorreto: %+v", resp.Usage)
+		}
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		var chunks []string
+		streamReq := req
+		streamReq.OnChunk = func(chunk string) { chunks = append(chunks, chunk) }
+
+		resp, err := provider.Generate(streamReq)
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+		if resp.Text != "Oi, tudo bem" || len(chunks) != 2 || resp.FinishReason != FinishStop {
+			t.Errorf("Resposta incorreta: %+v (trechos: %v)", resp, chunks)
+		}
+		if resp.Usage != (Usage{PromptTokens: 20, CompletionTokens: 6, TotalTokens: 26}) {
+			t.Errorf("Uso de tokens incorreto: %+v", resp.Usage)
+		}
+	})
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers := ParseHeaders("X-Title: WhatsZapMe\r\nCookie: a=1; b=2\ninválido\n")
+	expected := map[string]string{
+		"X-Title": "WhatsZapMe",
+		"Cookie":  "a=1; b=2",
+	}
+	if !reflect.DeepEqual(headers, expected) {
+		t.Errorf("Cabeçalhos incorretos: %v", headers)
+	}
+}
+
+func TestFactoryPresetModel(t *testing.T) {
+	provider, err := Factory("groq", map[string]string{"api_key": "test-key"})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if model := provider.(*OpenAIClient).Model; model != OpenAICompatibleDefaultModels["groq"] {
+		t.Errorf("Modelo padrão incorreto: %q", model)
+	}
+}
+
+func TestGoogleClientGenerateUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Olá\"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"!\"}]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":5,\"candidatesTokenCount\":2,\"totalTokenCount\":7}}\n\n")
+	}))
+	defer server.Close()
+
+	provider := NewGoogleClient("test-key", "gemini-pro")
+	provider.BaseURL = server.URL
+
+	resp, err := provider.Generate(Request{
+		Messages: []Message{{Role: RoleUser, Content: "Oi"}},
+		OnChunk:  func(string) {},
+	})
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+
+	if resp.Text != "Olá!" || resp.FinishReason != FinishStop || resp.Usage.TotalTokens != 7 {
+		t.Errorf("Resposta incorreta: %+v", resp)
+	}
+}
+
+func TestGenerateCancelado(t *testing.T) {
+	bloqueio := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-bloqueio:
+		}
+	}))
+	defer server.Close()
+	defer close(bloqueio)
+
+	provider := NewOllamaClient(server.URL, "llama2")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	inicio := time.Now()
+	_, err := provider.Generate(Request{
+		Context:  ctx,
+		Messages: []Message{{Role: RoleUser, Content: "Oi"}},
+	})
+	if err == nil {
+		t.Fatal("Esperava erro com o contexto expirado")
+	}
+	if time.Since(inicio) > 5*time.Second {
+		t.Errorf("A geração não foi interrompida pelo contexto")
+	}
+}
+
+// usoRegistrado guarda as chamadas recebidas por um UsageRecorder de teste
+type usoRegistrado struct {
+	provider, model          string
+	promptTokens, completion int
+}
+
+type gravadorDeUso struct {
+	registros []usoRegistrado
+}
+
+func (g *gravadorDeUso) RecordUsage(provider, model string, promptTokens, completionTokens int) {
+	g.registros = append(g.registros, usoRegistrado{provider, model, promptTokens, completionTokens})
+}
+
+func TestMeteredProvider(t *testing.T) {
+	t.Run("UsoInformadoPeloBackend", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"model":"llama2","message":{"role":"assistant","content":"Oi!"},"done":true,"prompt_eval_count":26,"eval_count":3}`)
+		}))
+		defer server.Close()
+
+		gravador := &gravadorDeUso{}
+		provider := NewMeteredProvider(NewOllamaClient(server.URL, "llama2"), "ollama", "llama2", gravador)
+
+		if _, err := provider.GenerateCompletion("Olá", "Sistema"); err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+
+		esperado := []usoRegistrado{{"ollama", "llama2", 26, 3}}
+		if !reflect.DeepEqual(gravador.registros, esperado) {
+			t.Errorf("Uso registrado incorreto: %+v", gravador.registros)
+		}
+	})
+
+	t.Run("UsoEstimado", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Resposta de doze"}]}}]}`)
+		}))
+		defer server.Close()
+
+		client := NewGoogleClient("test-key", "gemini-pro")
+		client.BaseURL = server.URL
+
+		gravador := &gravadorDeUso{}
+		provider := NewMeteredProvider(client, "google", "", gravador)
+
+		resp, err := provider.Generate(Request{Messages: []Message{{Role: RoleUser, Content: "Pergunta"}}})
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+
+		if !resp.Usage.Estimated || resp.Usage.PromptTokens != 2 || resp.Usage.CompletionTokens != 4 {
+			t.Errorf("Estimativa incorreta: %+v", resp.Usage)
+		}
+		if len(gravador.registros) != 1 || gravador.registros[0].model != "gemini-pro" {
+			t.Errorf("Uso registrado incorreto: %+v", gravador.registros)
+		}
+	})
+}
+
+func TestFallbackProvider(t *testing.T) {
+	// Backend local fora do ar: a conexão é recusada
+	offline := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	offlineURL := offline.URL
+	offline.Close()
+
+	// Backend remoto que responde 503 uma vez antes de atender
+	chamadas := 0
+	remoto := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chamadas++
+		if chamadas == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":"sobrecarregado"}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Oi do remoto"},"finish_reason":"stop"}]}`)
+	}))
+	defer remoto.Close()
+
+	openai := NewOpenAIClient("test-key", "gpt-4o-mini")
+	openai.BaseURL = remoto.URL
+
+	chain := NewFallbackProvider(
+		Backend{Name: "ollama", Provider: NewOllamaClient(offlineURL, "llama2")},
+		Backend{Name: "openai", Provider: openai},
+	)
+	chain.MaxRetries = 1
+	chain.BaseBackoff = time.Millisecond
+	chain.FailureThreshold = 1
+	chain.OpenTimeout = time.Hour
+
+	t.Run("Failover", func(t *testing.T) {
+		resp, err := chain.Generate(Request{Messages: []Message{{Role: RoleUser, Content: "Oi"}}})
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+
+		if resp.Text != "Oi do remoto" || resp.Backend != "openai" || chain.LastBackend() != "openai" {
+			t.Errorf("Resposta incorreta: %+v", resp)
+		}
+		if chamadas != 2 {
+			t.Errorf("Esperava uma nova tentativa após o 503, houve %d chamadas", chamadas)
+		}
+	})
+
+	t.Run("CircuitoAberto", func(t *testing.T) {
+		status := chain.Status()
+		if status[0].State != CircuitOpen || status[1].State != CircuitClosed {
+			t.Errorf("Estados incorretos: %+v", status)
+		}
+
+		// Com o circuito aberto o backend local nem é tentado
+		if chain.allow(chain.backends[0]) {
+			t.Errorf("Backend com circuito aberto não deveria ser permitido")
+		}
+	})
+
+	t.Run("ErroNaoTemporario", func(t *testing.T) {
+		recusado := 0
+		servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recusado++
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer servidor.Close()
+
+		cliente := NewOpenAIClient("chave-invalida", "gpt-4o-mini")
+		cliente.BaseURL = servidor.URL
+
+		unico := NewFallbackProvider(Backend{Name: "openai", Provider: cliente})
+		unico.BaseBackoff = time.Millisecond
+		if _, err := unico.Generate(Request{Messages: []Message{{Role: RoleUser, Content: "Oi"}}}); err == nil {
+			t.Fatal("Esperava erro com todos os provedores falhando")
+		}
+		if recusado != 1 {
+			t.Errorf("Erro 401 não deveria ser repetido, houve %d chamadas", recusado)
+		}
+	})
+
+	t.Run("SondagemUnica", func(t *testing.T) {
+		agora := time.Now()
+		cadeia := NewFallbackProvider(Backend{Name: "ollama", Provider: NewOllamaClient(offlineURL, "llama2")})
+		cadeia.FailureThreshold = 1
+		cadeia.now = func() time.Time { return agora }
+		cadeia.recordFailure(cadeia.backends[0], errors.New("fora do ar"))
+
+		// Passado o tempo do circuito aberto, só uma das chamadas simultâneas testa o backend
+		agora = agora.Add(cadeia.OpenTimeout)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cadeia.allow(cadeia.backends[0])
+			}()
+		}
+		wg.Wait()
+		if status := cadeia.Status(); status[0].Failures != 2 || status[0].State != CircuitOpen {
+			t.Errorf("Esperava um único teste de saúde com falha: %+v", status)
+		}
+	})
+
+	t.Run("ImagensNoBackendDisponivel", func(t *testing.T) {
+		visao := NewOpenAIClient("test-key", "gpt-4o")
+		cadeia := NewFallbackProvider(
+			Backend{Name: "openai", Provider: visao},
+			Backend{Name: "ollama", Provider: NewOllamaClient(offlineURL, "llama2")},
+		)
+		cadeia.FailureThreshold = 1
+		if !cadeia.SupportsImages() {
+			t.Errorf("O backend preferido aceita imagens")
+		}
+
+		cadeia.recordFailure(cadeia.backends[0], errors.New("fora do ar"))
+		if cadeia.SupportsImages() {
+			t.Errorf("Com o circuito aberto, quem responde é o backend apenas de texto")
+		}
+	})
+
+	t.Run("Saude", func(t *testing.T) {
+		// O Google não oferece verificação de saúde e não conta como disponível
+		google := NewMeteredProvider(NewGoogleClient("test-key", "gemini-pro"), "google", "", nil)
+		fora := NewFallbackProvider(
+			Backend{Name: "ollama", Provider: NewOllamaClient(offlineURL, "llama2")},
+			Backend{Name: "google", Provider: google},
+		)
+		if err := fora.CheckHealth(); err == nil {
+			t.Errorf("Esperava erro com o único backend verificável fora do ar")
+		}
+
+		if err := NewFallbackProvider(Backend{Name: "google", Provider: google}).CheckHealth(); err != nil {
+			t.Errorf("Sem backends verificáveis a cadeia deveria ser considerada saudável: %v", err)
+		}
+	})
+}
+
+func TestSupportsVision(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected bool
+	}{
+		{"llava:13b", true},
+		{"llama3.2-vision", true},
+		{"llama2", false},
+		{"gpt-4o-mini", true},
+		{"gpt-3.5-turbo", false},
+		{"o3-mini", false},
+		{"models/gemini-1.5-flash", true},
+		{"claude-3-5-haiku-20241022", true},
+		{"meu-modelo-local", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := SupportsVision(tt.model); got != tt.expected {
+				t.Errorf("SupportsVision(%q) = %v, esperado %v", tt.model, got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("ConfiguracaoManual", func(t *testing.T) {
+		SetVisionSupport("meu-modelo-local", true)
+		defer SetVisionSupport("meu-modelo-local", false)
+		if !SupportsVision("meu-modelo-local:latest") {
+			t.Errorf("A configuração manual deveria habilitar imagens")
+		}
+		provider := NewMeteredProvider(NewOllamaClient("http://localhost:11434", "meu-modelo-local"), "ollama", "", nil)
+		if !ImagesSupported(provider) || !ImagesSupported(NewFallbackProvider(Backend{Name: "ollama", Provider: provider})) {
+			t.Errorf("O provedor deveria aceitar imagens")
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		SetVisionSupport("meu-modelo-vl", true)
+		SetVisionSupport("gpt-4o", false)
+		ResetVisionSupport()
+		if SupportsVision("meu-modelo-vl") || !SupportsVision("gpt-4o") {
+			t.Errorf("Reset deveria voltar à lista de modelos conhecidos")
+		}
+	})
+}
+
+func TestVisionRequests(t *testing.T) {
+	image := Image{MimeType: "image/png", Data: []byte("png")}
+	encoded := "cG5n"
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Erro ao decodificar requisição: %v", err)
+		}
+
+		switch {
+		case strings.Contains(r.URL.Path, "generateContent"):
+			fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Um gato"}]}}]}`)
+		case r.URL.Path == "/v1/messages":
+			fmt.Fprint(w, `{"type":"message","role":"assistant","content":[{"type":"text","text":"Um gato"}],"usage":{"input_tokens":1,"output_tokens":1}}`)
+		case r.URL.Path == "/chat/completions":
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Um gato"}}]}`)
+		default:
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"Um gato"},"done":true}`)
+		}
+	}))
+	defer server.Close()
+
+	ollama := NewOllamaClient(server.URL, "llava")
+	openai := NewOpenAIClient("test-key", "gpt-4o")
+	openai.BaseURL = server.URL
+	google := NewGoogleClient("test-key", "gemini-1.5-flash")
+	google.BaseURL = server.URL
+	anthropic := NewAnthropicClient("test-key", "claude-3-5-sonnet-latest")
+	anthropic.BaseURL = server.URL
+	textOnly := NewOllamaClient(server.URL, "llama2")
+
+	// userContent devolve o conteúdo do último turno enviado, em JSON
+	userContent := func(key string) string {
+		list, _ := body[key].([]any)
+		if len(list) == 0 {
+			return ""
+		}
+		data, _ := json.Marshal(list[len(list)-1])
+		return string(data)
+	}
+
+	tests := []struct {
+		name     string
+		provider Provider
+		expected []string
+	}{
+		{
+			name:     "Ollama",
+			provider: ollama,
+			expected: []string{`"images":["` + encoded + `"]`, `"content":"O que é isto?"`},
+		},
+		{
+			name:     "OpenAI",
+			provider: openai,
+			expected: []string{`{"text":"O que é isto?","type":"text"}`, `"image_url":{"url":"data:image/png;base64,` + encoded + `"}`},
+		},
+		{
+			name:     "Gemini",
+			provider: google,
+			expected: []string{`{"text":"O que é isto?"}`, `"inlineData":{"data":"` + encoded + `","mimeType":"image/png"}`},
+		},
+		{
+			name:     "Anthropic",
+			provider: anthropic,
+			expected: []string{`"source":{"data":"` + encoded + `","media_type":"image/png","type":"base64"}`, `{"text":"O que é isto?","type":"text"}`},
+		},
+		{
+			name:     "ModeloSemVisao",
+			provider: textOnly,
+			expected: []string{`"content":"` + imageOmittedNotice + `\nO que é isto?"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.provider.Generate(Request{
+				Messages: []Message{{Role: RoleUser, Content: "O que é isto?", Images: []Image{image}}},
+			})
+			if err != nil {
+				t.Fatalf("Erro inesperado: %v", err)
+			}
+			if resp.Text != "Um gato" {
+				t.Errorf("Resposta incorreta: %q", resp.Text)
+			}
+
+			key := "messages"
+			if _, ok := body["contents"]; ok {
+				key = "contents"
+			}
+			content := userContent(key)
+			for _, part := range tt.expected {
+				if !strings.Contains(content, part) {
+					t.Errorf("Esperava %s na requisição: %s", part, content)
+				}
+			}
+			if tt.provider == textOnly && strings.Contains(content, "images") {
+				t.Errorf("Modelo sem visão não deveria receber imagens: %s", content)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// Motivos de término normalizados entre os provedores
+const (
+	FinishStop          = "stop"
+	FinishLength        = "length"
+	FinishContentFilter = "content_filter"
+)
+
+// GenerationOptions reúne os parâmetros de geração aceitos por todos os provedores.
+// Campos com valor zero (exceto Temperature) mantêm o padrão do provedor
+type GenerationOptions struct {
+	Temperature float64
+	TopP        float64
+	MaxTokens   int
+	Stop        []string
+	Seed        *int
+}
+
+// Request representa uma solicitação de geração para qualquer provedor
+type Request struct {
+	// Context permite cancelar a geração ou limitar sua duração; nil equivale a context.Background()
+	Context context.Context
+	// SystemPrompt contém as instruções de sistema para o modelo
+	SystemPrompt string
+	// Messages contém os turnos da conversa, terminando na mensagem a ser respondida
+	Messages []Message
+	// Options sobrescreve os parâmetros padrão do provedor quando não é nil
+	Options *GenerationOptions
+	// OnChunk, quando definido, faz a resposta ser transmitida em streaming
+	OnChunk StreamHandler
+	// User identifica quem originou a solicitação (o JID do contato), para a contabilização de uso
+	User string
+}
+
+// Usage representa a contagem de tokens de uma geração
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	// Estimated indica que parte da contagem foi estimada por falta de dados do backend
+	Estimated bool
+}
+
+// Response representa o resultado de uma geração
+type Response struct {
+	Text         string
+	FinishReason string
+	Usage        Usage
+	Model        string
+	// Backend é o nome do provedor que respondeu, preenchido pela cadeia de fallback
+	Backend string
+}
+
+// context retorna o contexto da solicitação, usando context.Background() quando não informado
+func (r Request) context() context.Context {
+	if r.Context == nil {
+		return context.Background()
+	}
+	return r.Context
+}
+
+// completionRequest cria uma solicitação simples a partir de um prompt e um system prompt
+func completionRequest(prompt string, systemPrompt string) Request {
+	return Request{
+		SystemPrompt: systemPrompt,
+		Messages:     []Message{{Role: RoleUser, Content: prompt}},
+	}
+}
+
+// normalizeFinishReason converte os motivos de término de cada API para os valores comuns
+func normalizeFinishReason(reason string) string {
+	switch strings.ToLower(reason) {
+	case "":
+		return ""
+	case "stop", "end_turn", "stop_sequence":
+		return FinishStop
+	case "length", "max_tokens":
+		return FinishLength
+	case "content_filter", "safety", "recitation", "blocklist", "prohibited_content":
+		return FinishContentFilter
+	default:
+		return strings.ToLower(reason)
+	}
+}
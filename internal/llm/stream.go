@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// readSSE lê um corpo no formato Server-Sent Events e chama fn para cada campo "data"
+// O laço termina no fim do corpo ou quando o servidor envia "[DONE]"
+func readSSE(body io.Reader, fn func(data string) error) error {
+	scanner := bufio.NewScanner(body)
+	// Eventos podem ser maiores que o buffer padrão de 64KB
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			return nil
+		}
+
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// streamOrDiscard garante um handler não nulo, para que o modo streaming seja usado
+// mesmo quando quem chamou não se interessa pelos trechos intermediários
+func streamOrDiscard(onChunk StreamHandler) StreamHandler {
+	if onChunk == nil {
+		return func(string) {}
+	}
+	return onChunk
+}
+
+// SentenceSplitter acumula os trechos recebidos em streaming e os corta em frases,
+// permitindo enviar respostas longas em várias mensagens curtas
+type SentenceSplitter struct {
+	// MinLength é o tamanho mínimo de um bloco antes de cortar em um fim de frase
+	MinLength int
+	// MaxLength força o corte mesmo sem fim de frase, para não segurar textos muito longos
+	MaxLength int
+	buffer    strings.Builder
+}
+
+// NewSentenceSplitter cria um divisor de frases com limites adequados para o WhatsApp
+func NewSentenceSplitter() *SentenceSplitter {
+	return &SentenceSplitter{
+		MinLength: 80,
+		MaxLength: 1000,
+	}
+}
+
+// Push adiciona um trecho ao buffer e retorna os blocos que já estão prontos para envio
+func (s *SentenceSplitter) Push(chunk string) []string {
+	s.buffer.WriteString(chunk)
+
+	var ready []string
+	for {
+		text := s.buffer.String()
+		cut := s.findCut(text)
+		if cut <= 0 {
+			break
+		}
+
+		if part := strings.TrimSpace(text[:cut]); part != "" {
+			ready = append(ready, part)
+		}
+		s.buffer.Reset()
+		s.buffer.WriteString(text[cut:])
+	}
+
+	return ready
+}
+
+// Flush retorna o texto restante no buffer e o esvazia
+func (s *SentenceSplitter) Flush() string {
+	text := strings.TrimSpace(s.buffer.String())
+	s.buffer.Reset()
+	return text
+}
+
+// findCut retorna a posição onde o texto deve ser cortado, ou -1 se ainda não houver corte
+func (s *SentenceSplitter) findCut(text string) int {
+	// Parágrafos são sempre enviados separadamente
+	if idx := strings.Index(text, "\n\n"); idx >= 0 && strings.TrimSpace(text[:idx]) != "" {
+		return idx + 2
+	}
+
+	cut := -1
+	for i := 0; i < len(text)-1; i++ {
+		switch text[i] {
+		case '.', '!', '?', '\n':
+			next := text[i+1]
+			if next != ' ' && next != '\n' && next != '\t' {
+				continue
+			}
+			if i+1 >= s.MinLength {
+				return i + 1
+			}
+		}
+		if s.MaxLength > 0 && i+1 >= s.MaxLength {
+			// Sem fim de frase dentro do limite: corta no último espaço disponível
+			cut = strings.LastIndex(text[:i+1], " ")
+			if cut <= 0 {
+				cut = i + 1
+			}
+			return cut
+		}
+	}
+
+	return cut
+}
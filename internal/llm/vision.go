@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"encoding/base64"
+	"strings"
+	"sync"
+)
+
+// Image é uma imagem enviada ao modelo junto com um turno da conversa
+type Image struct {
+	MimeType string
+	Data     []byte
+}
+
+// VisionProvider é implementado pelos provedores que informam se o modelo configurado aceita imagens
+type VisionProvider interface {
+	SupportsImages() bool
+}
+
+// imageOmittedNotice substitui as imagens enviadas a modelos que só aceitam texto
+const imageOmittedNotice = "[imagem omitida: o modelo atual não consegue ver imagens]"
+
+// visionModelPrefixes lista os modelos conhecidos por aceitar imagens, pelo início do nome
+var visionModelPrefixes = []string{
+	// Ollama
+	"llava", "bakllava", "llama3.2-vision", "llama4", "moondream", "minicpm-v", "gemma3",
+	"qwen2.5vl", "qwen2-vl", "granite3.2-vision", "mistral-small3.1",
+	// OpenAI
+	"gpt-4o", "gpt-4.1", "gpt-4-turbo", "gpt-4-vision", "gpt-5", "o1", "o3", "o4",
+	// Google
+	"gemini-1.5", "gemini-2", "gemini-pro-vision",
+	// Anthropic
+	"claude-3", "claude-sonnet-4", "claude-opus-4",
+	// xAI
+	"grok-2-vision", "grok-4",
+}
+
+// textOnlyModelPrefixes são exceções da lista acima que não aceitam imagens
+var textOnlyModelPrefixes = []string{"o1-mini", "o3-mini"}
+
+var (
+	visionOverrides = make(map[string]bool)
+	visionMutex     sync.RWMutex
+)
+
+// SetVisionSupport define manualmente se o modelo aceita imagens, sobrepondo a lista de modelos conhecidos
+func SetVisionSupport(model string, supported bool) {
+	visionMutex.Lock()
+	defer visionMutex.Unlock()
+	visionOverrides[normalizeModelName(model)] = supported
+}
+
+// ResetVisionSupport remove as definições feitas com SetVisionSupport, voltando à lista de modelos conhecidos
+func ResetVisionSupport() {
+	visionMutex.Lock()
+	defer visionMutex.Unlock()
+	visionOverrides = make(map[string]bool)
+}
+
+// SupportsVision informa se o modelo aceita imagens, pela configuração manual ou pela lista de modelos conhecidos
+func SupportsVision(model string) bool {
+	name := normalizeModelName(model)
+
+	visionMutex.RLock()
+	supported, ok := visionOverrides[name]
+	visionMutex.RUnlock()
+	if ok {
+		return supported
+	}
+
+	for _, prefix := range textOnlyModelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	for _, prefix := range visionModelPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImagesSupported informa se o provedor aceita imagens. Provedores que não implementam
+// VisionProvider são tratados como apenas texto
+func ImagesSupported(provider Provider) bool {
+	if vp, ok := provider.(VisionProvider); ok {
+		return vp.SupportsImages()
+	}
+	return false
+}
+
+// normalizeModelName remove o prefixo do fornecedor (ex: "models/", "openai/") e a tag do Ollama
+func normalizeModelName(model string) string {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// prepareImages devolve a conversa pronta para o modelo: sem alterações se ele aceita imagens,
+// ou com cada imagem trocada por um aviso em texto, para que a resposta não falhe
+func prepareImages(messages []Message, model string) []Message {
+	if SupportsVision(model) {
+		return messages
+	}
+
+	var converted []Message
+	for i, msg := range messages {
+		if len(msg.Images) == 0 {
+			continue
+		}
+		if converted == nil {
+			converted = append([]Message(nil), messages...)
+		}
+		text := imageOmittedNotice
+		if msg.Content != "" {
+			text += "\n" + msg.Content
+		}
+		converted[i] = Message{Role: msg.Role, Content: text}
+	}
+
+	if converted == nil {
+		return messages
+	}
+	return converted
+}
+
+// base64Data retorna o conteúdo da imagem codificado em base64
+func (img Image) base64Data() string {
+	return base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// mimeType retorna o tipo da imagem, usando JPEG quando não informado
+func (img Image) mimeType() string {
+	if img.MimeType == "" {
+		return "image/jpeg"
+	}
+	return img.MimeType
+}
+
+// dataURL retorna a imagem no formato data:<tipo>;base64,<conteúdo>
+func (img Image) dataURL() string {
+	return "data:" + img.mimeType() + ";base64," + img.base64Data()
+}
@@ -0,0 +1,94 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// VoiceNoteMimeType é o formato aceito pelo WhatsApp para mensagens de voz
+const VoiceNoteMimeType = "audio/ogg; codecs=opus"
+
+// DefaultOpusBitrate é a taxa usada nas mensagens de voz, suficiente para fala em mono
+const DefaultOpusBitrate = 32000
+
+// OpusEncoder converte áudio para OGG/Opus usando o ffmpeg
+type OpusEncoder struct {
+	// FFmpegPath é o caminho do executável; vazio procura "ffmpeg" no PATH
+	FFmpegPath string
+	Bitrate    int
+}
+
+// VoiceNote é uma mensagem de voz pronta para envio
+type VoiceNote struct {
+	Data     []byte
+	MimeType string
+	Seconds  uint32
+}
+
+// NewOpusEncoder cria um conversor com o ffmpeg do PATH
+func NewOpusEncoder() *OpusEncoder {
+	return &OpusEncoder{
+		FFmpegPath: "ffmpeg",
+		Bitrate:    DefaultOpusBitrate,
+	}
+}
+
+// Encode converte o áudio recebido (WAV ou outro formato reconhecido pelo ffmpeg) para OGG/Opus mono em 48 kHz
+func (e *OpusEncoder) Encode(ctx context.Context, audio []byte) ([]byte, error) {
+	path := e.FFmpegPath
+	if path == "" {
+		path = "ffmpeg"
+	}
+	bitrate := e.Bitrate
+	if bitrate <= 0 {
+		bitrate = DefaultOpusBitrate
+	}
+
+	cmd := exec.CommandContext(ctx, path,
+		"-hide_banner", "-loglevel", "error",
+		"-i", "pipe:0",
+		"-vn", "-ac", "1", "-ar", "48000",
+		"-c:a", "libopus", "-b:a", strconv.Itoa(bitrate), "-application", "voip",
+		"-f", "ogg", "pipe:1",
+	)
+	cmd.Stdin = bytes.NewReader(audio)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("erro ao converter áudio para opus: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("erro ao converter áudio para opus: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// SynthesizeVoiceNote gera a fala do texto e a converte em uma mensagem de voz do WhatsApp
+func SynthesizeVoiceNote(ctx context.Context, synth Synthesizer, encoder *OpusEncoder, text string) (*VoiceNote, error) {
+	wav, err := synth.Synthesize(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := WAVDuration(wav)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encoder.Encode(ctx, wav)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VoiceNote{
+		Data:     data,
+		MimeType: VoiceNoteMimeType,
+		Seconds:  uint32(math.Ceil(duration.Seconds())),
+	}, nil
+}
@@ -0,0 +1,150 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultPiperURL é o endereço padrão do servidor HTTP do Piper (python -m piper.http_server)
+const DefaultPiperURL = "http://localhost:5000"
+
+// ErrInvalidWAV indica que o áudio gerado não é um arquivo WAV válido
+var ErrInvalidWAV = errors.New("áudio WAV inválido")
+
+// Synthesizer converte um texto em áudio WAV
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string) ([]byte, error)
+}
+
+// PiperClient usa o servidor HTTP do Piper, que recebe o texto em JSON e devolve um WAV
+type PiperClient struct {
+	BaseURL string
+	// Voice é o nome da voz carregada no servidor (ex: pt_BR-faber-medium); vazio usa a voz padrão
+	Voice  string
+	Client *http.Client
+}
+
+// piperRequest é o corpo aceito pelo servidor HTTP do Piper
+type piperRequest struct {
+	Text  string `json:"text"`
+	Voice string `json:"voice,omitempty"`
+}
+
+// NewPiperClient cria um cliente de síntese de voz
+func NewPiperClient(baseURL, voice string) *PiperClient {
+	if baseURL == "" {
+		baseURL = DefaultPiperURL
+	}
+
+	return &PiperClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Voice:   voice,
+		Client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+// Synthesize envia o texto ao servidor e retorna o áudio WAV gerado
+func (c *PiperClient) Synthesize(ctx context.Context, text string) ([]byte, error) {
+	body, err := json.Marshal(piperRequest{Text: text, Voice: c.Voice})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao montar requisição: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao fazer requisição: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler resposta: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("erro na síntese de voz (status %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if _, err := WAVDuration(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// WAVDuration calcula a duração de um áudio WAV a partir dos blocos fmt e data
+func WAVDuration(wav []byte) (time.Duration, error) {
+	if len(wav) < 12 || string(wav[0:4]) != "RIFF" || string(wav[8:12]) != "WAVE" {
+		return 0, ErrInvalidWAV
+	}
+
+	var byteRate uint32
+	for offset := 12; offset+8 <= len(wav); {
+		id := string(wav[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(wav[offset+4 : offset+8]))
+		start := offset + 8
+
+		switch id {
+		case "fmt ":
+			if start+12 > len(wav) {
+				return 0, ErrInvalidWAV
+			}
+			byteRate = binary.LittleEndian.Uint32(wav[start+8 : start+12])
+		case "data":
+			if byteRate == 0 {
+				return 0, ErrInvalidWAV
+			}
+			// Servidores que geram o áudio em streaming não sabem o tamanho final do bloco
+			if start+size > len(wav) {
+				size = len(wav) - start
+			}
+			return time.Duration(int64(size) * int64(time.Second) / int64(byteRate)), nil
+		}
+
+		// Os blocos têm tamanho par; blocos ímpares recebem um byte de preenchimento
+		offset = start + size + size%2
+	}
+	return 0, ErrInvalidWAV
+}
+
+// ReplyMode define como o assistente responde a um contato
+type ReplyMode string
+
+// Modos de resposta
+const (
+	ReplyText  ReplyMode = "texto" // apenas texto
+	ReplyVoice ReplyMode = "voz"   // apenas mensagem de voz
+	ReplyBoth  ReplyMode = "ambos" // texto seguido da mensagem de voz
+	ReplyAuto  ReplyMode = "auto"  // voz quando o contato enviou áudio, texto nos demais casos
+)
+
+// ReplyModes lista os modos na ordem exibida nas configurações
+var ReplyModes = []ReplyMode{ReplyText, ReplyVoice, ReplyBoth, ReplyAuto}
+
+// Resolve informa se a resposta deve ser enviada em texto e/ou em voz.
+// Modos desconhecidos respondem apenas em texto
+func (m ReplyMode) Resolve(receivedAudio bool) (text, voice bool) {
+	switch m {
+	case ReplyVoice:
+		return false, true
+	case ReplyBoth:
+		return true, true
+	case ReplyAuto:
+		return !receivedAudio, receivedAudio
+	default:
+		return true, false
+	}
+}
@@ -0,0 +1,136 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// wavDeTeste monta um WAV mono de 16 bits com a duração informada
+func wavDeTeste(sampleRate int, duration time.Duration) []byte {
+	samples := int(duration.Seconds() * float64(sampleRate))
+	dataSize := samples * 2
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
+
+func TestPiperClient(t *testing.T) {
+	wav := wavDeTeste(22050, 1500*time.Millisecond)
+
+	var recebido piperRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&recebido); err != nil {
+			http.Error(w, "json inválido", http.StatusBadRequest)
+			return
+		}
+		if recebido.Text == "falha" {
+			http.Error(w, "voz não encontrada", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wav)
+	}))
+	defer server.Close()
+
+	client := NewPiperClient(server.URL+"/", "pt_BR-faber-medium")
+	ctx := context.Background()
+
+	t.Run("Sintese", func(t *testing.T) {
+		data, err := client.Synthesize(ctx, "Olá, tudo bem?")
+		if err != nil {
+			t.Fatalf("Erro na síntese: %v", err)
+		}
+		if !bytes.Equal(data, wav) {
+			t.Errorf("Áudio incorreto")
+		}
+		if recebido.Text != "Olá, tudo bem?" || recebido.Voice != "pt_BR-faber-medium" {
+			t.Errorf("Requisição incorreta: %+v", recebido)
+		}
+	})
+
+	t.Run("ErroDoServidor", func(t *testing.T) {
+		if _, err := client.Synthesize(ctx, "falha"); err == nil {
+			t.Errorf("Esperava erro do servidor")
+		}
+	})
+}
+
+func TestWAVDuration(t *testing.T) {
+	duration, err := WAVDuration(wavDeTeste(16000, 2*time.Second))
+	if err != nil || duration != 2*time.Second {
+		t.Errorf("Duração incorreta: %v, %v", duration, err)
+	}
+
+	// Tamanho desconhecido do bloco data, como nos servidores que geram o áudio em streaming
+	streaming := wavDeTeste(16000, time.Second)
+	binary.LittleEndian.PutUint32(streaming[40:44], 0xFFFFFFFF)
+	if duration, err := WAVDuration(streaming); err != nil || duration != time.Second {
+		t.Errorf("Duração incorreta em streaming: %v, %v", duration, err)
+	}
+
+	if _, err := WAVDuration([]byte("não é wav")); !errors.Is(err, ErrInvalidWAV) {
+		t.Errorf("Esperava WAV inválido, obteve: %v", err)
+	}
+}
+
+func TestReplyMode(t *testing.T) {
+	tests := []struct {
+		mode          ReplyMode
+		receivedAudio bool
+		text, voice   bool
+	}{
+		{ReplyText, true, true, false},
+		{ReplyVoice, false, false, true},
+		{ReplyBoth, false, true, true},
+		{ReplyAuto, true, false, true},
+		{ReplyAuto, false, true, false},
+		{"", true, true, false},
+	}
+
+	for _, tt := range tests {
+		text, voice := tt.mode.Resolve(tt.receivedAudio)
+		if text != tt.text || voice != tt.voice {
+			t.Errorf("Modo %q com áudio=%v: texto=%v voz=%v", tt.mode, tt.receivedAudio, text, voice)
+		}
+	}
+}
+
+func TestSynthesizeVoiceNote(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg não encontrado")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(wavDeTeste(22050, 1200*time.Millisecond))
+	}))
+	defer server.Close()
+
+	note, err := SynthesizeVoiceNote(context.Background(), NewPiperClient(server.URL, ""), NewOpusEncoder(), "Olá")
+	if err != nil {
+		t.Fatalf("Erro ao gerar mensagem de voz: %v", err)
+	}
+	if !bytes.HasPrefix(note.Data, []byte("OggS")) || note.Seconds != 2 || note.MimeType != VoiceNoteMimeType {
+		t.Errorf("Mensagem de voz incorreta: %d bytes, %d segundos", len(note.Data), note.Seconds)
+	}
+}
@@ -0,0 +1,153 @@
+// Package speech converte mensagens de voz em texto usando servidores de transcrição
+// compatíveis com a API da OpenAI, como o whisper.cpp server e o faster-whisper,
+// e gera mensagens de voz a partir das respostas com um servidor local de síntese, como o Piper
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Valores padrão da transcrição
+const (
+	DefaultWhisperURL   = "http://localhost:8000/v1"
+	DefaultWhisperModel = "whisper-1"
+)
+
+// ErrEmptyTranscription indica que o servidor não reconheceu fala no áudio
+var ErrEmptyTranscription = errors.New("transcrição vazia")
+
+// Transcriber converte um áudio em texto
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio io.Reader, fileName string) (string, error)
+}
+
+// WhisperClient usa o endpoint /audio/transcriptions de um servidor compatível com a API da OpenAI
+type WhisperClient struct {
+	// BaseURL inclui o prefixo da API, ex: http://localhost:8000/v1
+	BaseURL string
+	APIKey  string
+	Model   string
+	// Language é a dica de idioma no formato ISO-639-1 (ex: "pt"); vazio deixa o servidor detectar
+	Language string
+	Client   *http.Client
+}
+
+// transcriptionResponse é a resposta do endpoint no formato json
+type transcriptionResponse struct {
+	Text  string `json:"text"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// NewWhisperClient cria um cliente de transcrição
+func NewWhisperClient(baseURL, model, language string) *WhisperClient {
+	if baseURL == "" {
+		baseURL = DefaultWhisperURL
+	}
+	if model == "" {
+		model = DefaultWhisperModel
+	}
+
+	return &WhisperClient{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		Model:    model,
+		Language: language,
+		Client: &http.Client{
+			Timeout: 120 * time.Second,
+		},
+	}
+}
+
+// TranscribeFile transcreve um arquivo de áudio
+func (c *WhisperClient) TranscribeFile(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("erro ao abrir áudio: %w", err)
+	}
+	defer file.Close()
+
+	return c.Transcribe(ctx, file, filepath.Base(path))
+}
+
+// Transcribe envia o áudio ao servidor e retorna o texto reconhecido
+func (c *WhisperClient) Transcribe(ctx context.Context, audio io.Reader, fileName string) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return "", fmt.Errorf("erro ao montar requisição: %w", err)
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return "", fmt.Errorf("erro ao ler áudio: %w", err)
+	}
+
+	fields := map[string]string{
+		"model":           c.Model,
+		"language":        c.Language,
+		"response_format": "json",
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return "", fmt.Errorf("erro ao montar requisição: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("erro ao montar requisição: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", fmt.Errorf("erro ao criar requisição: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("erro ao fazer requisição: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler resposta: %w", err)
+	}
+
+	var result transcriptionResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("erro na API de transcrição (status %d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+		}
+		return "", fmt.Errorf("erro ao decodificar resposta: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if result.Error != nil {
+			return "", fmt.Errorf("erro na API de transcrição (status %d): %s", resp.StatusCode, result.Error.Message)
+		}
+		return "", fmt.Errorf("erro na API de transcrição (status %d)", resp.StatusCode)
+	}
+
+	text := strings.TrimSpace(result.Text)
+	if text == "" {
+		return "", ErrEmptyTranscription
+	}
+	return text, nil
+}
@@ -0,0 +1,78 @@
+package speech
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWhisperClient(t *testing.T) {
+	var recebido struct {
+		caminho, arquivo, conteudo, modelo, idioma, autorizacao string
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recebido.caminho = r.URL.Path
+		recebido.autorizacao = r.Header.Get("Authorization")
+		recebido.modelo = r.FormValue("model")
+		recebido.idioma = r.FormValue("language")
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, `{"error":{"message":"arquivo ausente"}}`, http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		recebido.arquivo = header.Filename
+		recebido.conteudo = string(data)
+
+		switch string(data) {
+		case "silencio":
+			w.Write([]byte(`{"text":"  "}`))
+		case "invalido":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":{"message":"modelo não carregado"}}`))
+		default:
+			w.Write([]byte(`{"text":" Olá, qual o horário de hoje? "}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewWhisperClient(server.URL+"/v1/", "", "pt")
+	client.APIKey = "chave"
+	ctx := context.Background()
+
+	t.Run("Transcricao", func(t *testing.T) {
+		texto, err := client.Transcribe(ctx, strings.NewReader("audio"), "voz.ogg")
+		if err != nil {
+			t.Fatalf("Erro na transcrição: %v", err)
+		}
+		if texto != "Olá, qual o horário de hoje?" {
+			t.Errorf("Texto incorreto: %q", texto)
+		}
+		if recebido.caminho != "/v1/audio/transcriptions" || recebido.arquivo != "voz.ogg" || recebido.conteudo != "audio" {
+			t.Errorf("Requisição incorreta: %+v", recebido)
+		}
+		if recebido.modelo != DefaultWhisperModel || recebido.idioma != "pt" || recebido.autorizacao != "Bearer chave" {
+			t.Errorf("Campos incorretos: %+v", recebido)
+		}
+	})
+
+	t.Run("SemFala", func(t *testing.T) {
+		if _, err := client.Transcribe(ctx, strings.NewReader("silencio"), "voz.ogg"); !errors.Is(err, ErrEmptyTranscription) {
+			t.Errorf("Esperava transcrição vazia, obteve: %v", err)
+		}
+	})
+
+	t.Run("ErroDoServidor", func(t *testing.T) {
+		_, err := client.Transcribe(ctx, strings.NewReader("invalido"), "voz.ogg")
+		if err == nil || !strings.Contains(err.Error(), "modelo não carregado") {
+			t.Errorf("Esperava erro do servidor, obteve: %v", err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package token
+
+import (
+	"time"
+)
+
+// BudgetAction define o que fazer quando o orçamento mensal é ultrapassado
+type BudgetAction string
+
+// Ações possíveis ao ultrapassar o orçamento
+const (
+	// BudgetActionNone apenas avisa
+	BudgetActionNone BudgetAction = "none"
+	// BudgetActionFallback passa a responder com um provedor mais barato
+	BudgetActionFallback BudgetAction = "fallback"
+	// BudgetActionPause deixa de responder até o mês seguinte
+	BudgetActionPause BudgetAction = "pause"
+)
+
+// DefaultWarnThreshold é a fração do orçamento a partir da qual um aviso é emitido
+const DefaultWarnThreshold = 0.8
+
+// BudgetStatus indica a situação do gasto em relação ao orçamento
+type BudgetStatus int
+
+// Situações possíveis do orçamento
+const (
+	BudgetOK BudgetStatus = iota
+	BudgetWarning
+	BudgetExceeded
+)
+
+// Budget representa um limite mensal de gastos com os provedores
+type Budget struct {
+	// MonthlyLimit é o limite na moeda do catálogo de preços; zero desativa o orçamento
+	MonthlyLimit float64 `json:"monthly_limit"`
+	// WarnThreshold é a fração do limite (entre 0 e 1) que dispara o aviso
+	WarnThreshold float64 `json:"warn_threshold"`
+	// Action é aplicada quando o limite é ultrapassado
+	Action BudgetAction `json:"action"`
+}
+
+// Evaluate classifica um gasto em relação ao orçamento
+func (b Budget) Evaluate(spent float64) BudgetStatus {
+	if b.MonthlyLimit <= 0 {
+		return BudgetOK
+	}
+
+	if spent >= b.MonthlyLimit {
+		return BudgetExceeded
+	}
+
+	threshold := b.WarnThreshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultWarnThreshold
+	}
+	if spent >= b.MonthlyLimit*threshold {
+		return BudgetWarning
+	}
+
+	return BudgetOK
+}
+
+// CheckBudget retorna a situação do orçamento no mês da data informada e o valor gasto
+func (c *Counter) CheckBudget(b Budget, at time.Time) (BudgetStatus, float64) {
+	spent := c.MonthlyCost(at)
+	return b.Evaluate(spent), spent
+}
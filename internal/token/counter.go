@@ -12,13 +12,18 @@ import (
 
 // UsageStats armazena estatísticas de uso de tokens
 type UsageStats struct {
-	Provider          string    `json:"provider"`
-	TotalTokens       int       `json:"total_tokens"`
-	PromptTokens      int       `json:"prompt_tokens"`
-	CompletionTokens  int       `json:"completion_tokens"`
-	LastUsed          time.Time `json:"last_used"`
-	RequestCount      int       `json:"request_count"`
-	EstimatedCostUSD  float64   `json:"estimated_cost_usd"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	TotalTokens      int       `json:"total_tokens"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LastUsed         time.Time `json:"last_used"`
+	RequestCount     int       `json:"request_count"`
+	// EstimatedCostUSD é o custo acumulado na moeda indicada em Currency (USD no catálogo padrão)
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
+	Currency         string  `json:"currency,omitempty"`
+	// UnpricedRequests conta as requisições feitas sem preço cadastrado no catálogo
+	UnpricedRequests int `json:"unpriced_requests,omitempty"`
 }
 
 // Counter gerencia contagem de tokens e custos associados
@@ -26,8 +31,12 @@ type Counter struct {
 	stats      map[string]UsageStats
 	mutex      sync.RWMutex
 	configPath string
-	// Custos aproximados por 1000 tokens, em USD
-	costPerThousand map[string]float64
+	pricing    *PricingCatalog
+	// Uso diário por contato e provedor/modelo, mantido mesmo após ResetAll
+	history     map[string]UsageBucket
+	historyPath string
+	now         func() time.Time
+	saveMutex   sync.Mutex
 }
 
 // NewCounter cria um novo contador de tokens
@@ -36,16 +45,18 @@ func NewCounter(configDir string) (*Counter, error) {
 		return nil, fmt.Errorf("erro ao criar diretório de configuração: %w", err)
 	}
 
+	pricing, err := LoadPricingCatalog(configDir)
+	if err != nil {
+		return nil, err
+	}
+
 	counter := &Counter{
-		stats:      make(map[string]UsageStats),
-		configPath: filepath.Join(configDir, "token_usage.json"),
-		costPerThousand: map[string]float64{
-			"gpt-3.5-turbo": 0.0015,  // $0.0015 / 1K tokens
-			"gpt-4":         0.03,    // $0.03 / 1K tokens input, $0.06 / 1K tokens output
-			"gpt-4-turbo":   0.01,    // $0.01 / 1K tokens input, $0.03 / 1K tokens output
-			"gemini-pro":    0.0005,  // $0.0005 / 1K tokens (aproximado)
-			"claude-3":      0.008,   // Valor médio aproximado
-		},
+		stats:       make(map[string]UsageStats),
+		configPath:  filepath.Join(configDir, "token_usage.json"),
+		pricing:     pricing,
+		history:     make(map[string]UsageBucket),
+		historyPath: filepath.Join(configDir, "token_usage_daily.json"),
+		now:         time.Now,
 	}
 
 	// Carrega dados existentes, se disponíveis
@@ -56,24 +67,38 @@ func NewCounter(configDir string) (*Counter, error) {
 		}
 	}
 
+	if err := counter.loadHistory(); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("erro ao carregar histórico de tokens: %w", err)
+		}
+	}
+
 	return counter, nil
 }
 
 // RecordUsage registra o uso de tokens
 func (c *Counter) RecordUsage(provider, model string, promptTokens, completionTokens int) {
+	c.RecordContactUsage("", provider, model, promptTokens, completionTokens)
+}
+
+// RecordContactUsage registra o uso de tokens atribuído a um contato (JID)
+func (c *Counter) RecordContactUsage(jid, provider, model string, promptTokens, completionTokens int) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	now := c.now()
+
 	key := provider + ":" + model
 	stats, exists := c.stats[key]
 	if !exists {
 		stats = UsageStats{
 			Provider:         provider,
+			Model:            model,
 			TotalTokens:      0,
 			PromptTokens:     0,
 			CompletionTokens: 0,
 			RequestCount:     0,
-			LastUsed:         time.Now(),
+			LastUsed:         now,
 		}
 	}
 
@@ -81,17 +106,22 @@ func (c *Counter) RecordUsage(provider, model string, promptTokens, completionTo
 	stats.CompletionTokens += completionTokens
 	stats.TotalTokens = stats.PromptTokens + stats.CompletionTokens
 	stats.RequestCount++
-	stats.LastUsed = time.Now()
-
-	// Calcula custo estimado
-	costRate := c.costPerThousand[model]
-	if costRate == 0 {
-		// Modelo desconhecido, usa um valor padrão conservador
-		costRate = 0.01
+	stats.LastUsed = now
+
+	// O custo é acumulado com o preço em vigor no momento da requisição,
+	// separando o preço dos tokens de entrada e de saída
+	var cost float64
+	price, ok := c.pricing.Lookup(provider, model, now)
+	if ok {
+		cost = price.Cost(promptTokens, completionTokens)
+		stats.EstimatedCostUSD += cost
+		stats.Currency = price.Currency
+	} else {
+		stats.UnpricedRequests++
 	}
-	stats.EstimatedCostUSD = float64(stats.TotalTokens) * costRate / 1000.0
 
 	c.stats[key] = stats
+	c.addToHistory(now, jid, provider, model, promptTokens, completionTokens, cost, price.Currency)
 
 	// Salva a cada atualização
 	go c.saveStats()
@@ -119,6 +149,11 @@ func (c *Counter) GetAllStats() []UsageStats {
 	return stats
 }
 
+// Pricing retorna o catálogo de preços usado no cálculo dos custos
+func (c *Counter) Pricing() *PricingCatalog {
+	return c.pricing
+}
+
 // GetTotalCost retorna o custo total estimado de todos os provedores
 func (c *Counter) GetTotalCost() float64 {
 	c.mutex.RLock()
@@ -164,7 +199,7 @@ func (c *Counter) loadStats() error {
 
 	c.stats = make(map[string]UsageStats)
 	for _, s := range stats {
-		key := s.Provider + ":" + s.Provider
+		key := s.Provider + ":" + s.Model
 		c.stats[key] = s
 	}
 
@@ -173,6 +208,10 @@ func (c *Counter) loadStats() error {
 
 // saveStats salva estatísticas em arquivo
 func (c *Counter) saveStats() error {
+	// Os salvamentos acontecem em goroutines e não podem se intercalar no arquivo
+	c.saveMutex.Lock()
+	defer c.saveMutex.Unlock()
+
 	c.mutex.RLock()
 	stats := make([]UsageStats, 0, len(c.stats))
 	for _, s := range c.stats {
@@ -185,5 +224,9 @@ func (c *Counter) saveStats() error {
 		return fmt.Errorf("erro ao serializar estatísticas: %w", err)
 	}
 
-	return ioutil.WriteFile(c.configPath, data, 0644)
+	if err := ioutil.WriteFile(c.configPath, data, 0644); err != nil {
+		return err
+	}
+
+	return c.saveHistory()
 }
@@ -1,6 +1,7 @@
 package token
 
 import (
+	"math"
 	"os"
 	"testing"
 )
@@ -45,9 +46,9 @@ func TestTokenCounter(t *testing.T) {
 		}
 		
 		// Verifica se o custo foi calculado corretamente
-		// gpt-3.5-turbo custa $0.0015 / 1K tokens
-		expectedCost := float64(150) * 0.0015 / 1000.0
-		if stats.EstimatedCostUSD != expectedCost {
+		// gpt-3.5-turbo custa $0.0005 / 1K tokens de entrada e $0.0015 / 1K tokens de saída
+		expectedCost := float64(100)*0.0005/1000.0 + float64(50)*0.0015/1000.0
+		if math.Abs(stats.EstimatedCostUSD-expectedCost) > 1e-12 {
 			t.Errorf("Esperava custo de $%.6f, obteve $%.6f", expectedCost, stats.EstimatedCostUSD)
 		}
 	})
@@ -84,11 +85,11 @@ func TestTokenCounter(t *testing.T) {
 		
 		// Verifica custo total
 		totalCost := counter.GetTotalCost()
-		openaiCost := float64(425) * 0.0015 / 1000.0
-		googleCost := float64(250) * 0.0005 / 1000.0
+		openaiCost := float64(300)*0.0005/1000.0 + float64(125)*0.0015/1000.0
+		googleCost := float64(150)*0.0005/1000.0 + float64(100)*0.0015/1000.0
 		expectedTotalCost := openaiCost + googleCost
 		
-		if totalCost != expectedTotalCost {
+		if math.Abs(totalCost-expectedTotalCost) > 1e-12 {
 			t.Errorf("Esperava custo total de $%.6f, obteve $%.6f", expectedTotalCost, totalCost)
 		}
 	})
@@ -133,4 +134,25 @@ func TestTokenCounter(t *testing.T) {
 			t.Errorf("Esperava 3 estatísticas, obteve %d", len(allStats))
 		}
 	})
+	
+	// Testa se as estatísticas são recarregadas com a chave provedor:modelo
+	t.Run("Persistencia", func(t *testing.T) {
+		counter.ResetAll()
+		counter.RecordUsage("ollama", "llama2", 30, 10)
+		
+		// O salvamento acontece em segundo plano; salva de forma síncrona para o teste
+		if err := counter.saveStats(); err != nil {
+			t.Fatalf("Erro ao salvar estatísticas: %v", err)
+		}
+		
+		reloaded, err := NewCounter(tempDir)
+		if err != nil {
+			t.Fatalf("Erro ao recarregar contador: %v", err)
+		}
+		
+		stats, exists := reloaded.GetStats("ollama", "llama2")
+		if !exists || stats.TotalTokens != 40 {
+			t.Errorf("Estatísticas não recarregadas corretamente: %+v", stats)
+		}
+	})
 }
@@ -0,0 +1,17 @@
+package token
+
+import (
+	"unicode/utf8"
+)
+
+// charsPerToken é a média aproximada de caracteres por token nos tokenizadores mais comuns
+const charsPerToken = 4
+
+// EstimateTokens estima a quantidade de tokens de um texto quando não há contagem real disponível
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
@@ -0,0 +1,215 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// UsageBucket acumula o uso de um dia para um contato e um provedor/modelo
+type UsageBucket struct {
+	Day              string  `json:"day"`
+	JID              string  `json:"jid,omitempty"`
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	RequestCount     int     `json:"request_count"`
+	Cost             float64 `json:"cost"`
+	Currency         string  `json:"currency,omitempty"`
+}
+
+// UsageQuery filtra o histórico de uso; campos vazios não filtram
+type UsageQuery struct {
+	// Since e Until limitam o período, considerando apenas a data (inclusive)
+	Since    time.Time
+	Until    time.Time
+	JID      string
+	Provider string
+	Model    string
+}
+
+// GroupBy define como o relatório agrupa o histórico
+type GroupBy int
+
+// Agrupamentos disponíveis para os relatórios
+const (
+	GroupByDay GroupBy = iota
+	GroupByMonth
+	GroupByContact
+	GroupByModel
+)
+
+// UsageTotal é uma linha de relatório com o uso somado de um grupo
+type UsageTotal struct {
+	Key              string
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	RequestCount     int
+	Cost             float64
+}
+
+// bucketKey identifica um bucket de uso no mapa do histórico
+func bucketKey(day, jid, provider, model string) string {
+	return day + "|" + jid + "|" + provider + "|" + model
+}
+
+// addToHistory soma uma requisição ao bucket do dia; deve ser chamado com o mutex travado
+func (c *Counter) addToHistory(at time.Time, jid, provider, model string, promptTokens, completionTokens int, cost float64, currency string) {
+	day := at.Format(DateLayout)
+	key := bucketKey(day, jid, provider, model)
+
+	bucket, exists := c.history[key]
+	if !exists {
+		bucket = UsageBucket{Day: day, JID: jid, Provider: provider, Model: model}
+	}
+
+	bucket.PromptTokens += promptTokens
+	bucket.CompletionTokens += completionTokens
+	bucket.RequestCount++
+	bucket.Cost += cost
+	if currency != "" {
+		bucket.Currency = currency
+	}
+
+	c.history[key] = bucket
+}
+
+// QueryUsage retorna os buckets do histórico que atendem ao filtro, ordenados por dia
+func (c *Counter) QueryUsage(q UsageQuery) []UsageBucket {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	since, until := "", ""
+	if !q.Since.IsZero() {
+		since = q.Since.Format(DateLayout)
+	}
+	if !q.Until.IsZero() {
+		until = q.Until.Format(DateLayout)
+	}
+
+	var buckets []UsageBucket
+	for _, b := range c.history {
+		// As datas no formato AAAA-MM-DD podem ser comparadas como texto
+		if since != "" && b.Day < since {
+			continue
+		}
+		if until != "" && b.Day > until {
+			continue
+		}
+		if q.JID != "" && b.JID != q.JID {
+			continue
+		}
+		if q.Provider != "" && b.Provider != q.Provider {
+			continue
+		}
+		if q.Model != "" && b.Model != q.Model {
+			continue
+		}
+		buckets = append(buckets, b)
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Day != buckets[j].Day {
+			return buckets[i].Day < buckets[j].Day
+		}
+		return bucketKey("", buckets[i].JID, buckets[i].Provider, buckets[i].Model) <
+			bucketKey("", buckets[j].JID, buckets[j].Provider, buckets[j].Model)
+	})
+
+	return buckets
+}
+
+// Report soma o histórico filtrado por q, agrupando conforme group.
+// Relatórios por dia ou mês vêm em ordem cronológica; os demais, do maior para o menor custo.
+// Por exemplo, o custo por contato nos últimos 30 dias é
+// Report(UsageQuery{Since: time.Now().AddDate(0, 0, -30)}, GroupByContact)
+func (c *Counter) Report(q UsageQuery, group GroupBy) []UsageTotal {
+	totals := make(map[string]*UsageTotal)
+	var keys []string
+
+	for _, b := range c.QueryUsage(q) {
+		var key string
+		switch group {
+		case GroupByMonth:
+			key = b.Day[:7]
+		case GroupByContact:
+			key = b.JID
+		case GroupByModel:
+			key = b.Provider + ":" + b.Model
+		default:
+			key = b.Day
+		}
+
+		total, exists := totals[key]
+		if !exists {
+			total = &UsageTotal{Key: key}
+			totals[key] = total
+			keys = append(keys, key)
+		}
+
+		total.PromptTokens += b.PromptTokens
+		total.CompletionTokens += b.CompletionTokens
+		total.TotalTokens += b.PromptTokens + b.CompletionTokens
+		total.RequestCount += b.RequestCount
+		total.Cost += b.Cost
+	}
+
+	report := make([]UsageTotal, 0, len(keys))
+	for _, key := range keys {
+		report = append(report, *totals[key])
+	}
+
+	if group == GroupByContact || group == GroupByModel {
+		sort.SliceStable(report, func(i, j int) bool {
+			return report[i].Cost > report[j].Cost
+		})
+	}
+
+	return report
+}
+
+// MonthlyCost retorna o custo acumulado no mês da data informada
+func (c *Counter) MonthlyCost(month time.Time) float64 {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	last := first.AddDate(0, 1, -1)
+
+	var cost float64
+	for _, b := range c.QueryUsage(UsageQuery{Since: first, Until: last}) {
+		cost += b.Cost
+	}
+	return cost
+}
+
+// loadHistory carrega o histórico diário do arquivo
+func (c *Counter) loadHistory() error {
+	data, err := ioutil.ReadFile(c.historyPath)
+	if err != nil {
+		return err
+	}
+
+	var buckets []UsageBucket
+	if err := json.Unmarshal(data, &buckets); err != nil {
+		return fmt.Errorf("erro ao deserializar histórico: %w", err)
+	}
+
+	c.history = make(map[string]UsageBucket)
+	for _, b := range buckets {
+		c.history[bucketKey(b.Day, b.JID, b.Provider, b.Model)] = b
+	}
+
+	return nil
+}
+
+// saveHistory salva o histórico diário em arquivo
+func (c *Counter) saveHistory() error {
+	data, err := json.MarshalIndent(c.QueryUsage(UsageQuery{}), "", "  ")
+	if err != nil {
+		return fmt.Errorf("erro ao serializar histórico: %w", err)
+	}
+
+	return ioutil.WriteFile(c.historyPath, data, 0644)
+}
@@ -0,0 +1,98 @@
+package token
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUsageHistory(t *testing.T) {
+	// Cria um diretório temporário para o teste
+	tempDir, err := os.MkdirTemp("", "token-history-test")
+	if err != nil {
+		t.Fatalf("Erro ao criar diretório temporário: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	counter, err := NewCounter(tempDir)
+	if err != nil {
+		t.Fatalf("Erro ao criar contador de tokens: %v", err)
+	}
+
+	// Registra o uso em dias diferentes controlando o relógio do contador
+	registrar := func(dia time.Time, jid string, prompt, completion int) {
+		counter.now = func() time.Time { return dia }
+		counter.RecordContactUsage(jid, "openai", "gpt-4o-mini", prompt, completion)
+	}
+
+	hoje := time.Date(2025, 3, 20, 12, 0, 0, 0, time.Local)
+	ana := "5511999990001@s.whatsapp.net"
+	bruno := "5511999990002@s.whatsapp.net"
+
+	registrar(hoje.AddDate(0, 0, -40), ana, 100000, 0)
+	registrar(hoje.AddDate(0, 0, -10), ana, 1000, 1000)
+	registrar(hoje.AddDate(0, 0, -10), ana, 1000, 1000)
+	registrar(hoje, bruno, 10000, 10000)
+
+	t.Run("CustoPorContato", func(t *testing.T) {
+		relatorio := counter.Report(UsageQuery{Since: hoje.AddDate(0, 0, -30)}, GroupByContact)
+		if len(relatorio) != 2 {
+			t.Fatalf("Esperava 2 contatos, obteve %d", len(relatorio))
+		}
+
+		// Ordenado pelo maior custo
+		if relatorio[0].Key != bruno || relatorio[1].Key != ana {
+			t.Errorf("Ordem incorreta: %+v", relatorio)
+		}
+		if relatorio[1].RequestCount != 2 || relatorio[1].TotalTokens != 4000 {
+			t.Errorf("Totais de Ana incorretos: %+v", relatorio[1])
+		}
+
+		esperado := 2 * (1000*0.00015/1000 + 1000*0.0006/1000)
+		if math.Abs(relatorio[1].Cost-esperado) > 1e-12 {
+			t.Errorf("Custo de Ana incorreto: %f", relatorio[1].Cost)
+		}
+	})
+
+	t.Run("PorDia", func(t *testing.T) {
+		relatorio := counter.Report(UsageQuery{JID: ana}, GroupByDay)
+		if len(relatorio) != 2 || relatorio[0].Key > relatorio[1].Key {
+			t.Errorf("Relatório diário incorreto: %+v", relatorio)
+		}
+	})
+
+	t.Run("Orcamento", func(t *testing.T) {
+		gasto := counter.MonthlyCost(hoje)
+		orcamento := Budget{MonthlyLimit: gasto * 2, WarnThreshold: 0.5, Action: BudgetActionPause}
+
+		if status, _ := counter.CheckBudget(orcamento, hoje); status != BudgetWarning {
+			t.Errorf("Esperava aviso de orçamento, obteve %v", status)
+		}
+
+		orcamento.MonthlyLimit = gasto
+		if status, _ := counter.CheckBudget(orcamento, hoje); status != BudgetExceeded {
+			t.Errorf("Esperava orçamento excedido, obteve %v", status)
+		}
+
+		// Dois meses depois, os gastos registrados hoje não contam para o orçamento
+		if status, _ := counter.CheckBudget(Budget{MonthlyLimit: 1}, hoje.AddDate(0, 2, 0)); status != BudgetOK {
+			t.Errorf("Esperava orçamento livre em outro mês, obteve %v", status)
+		}
+	})
+
+	t.Run("Persistencia", func(t *testing.T) {
+		if err := counter.saveStats(); err != nil {
+			t.Fatalf("Erro ao salvar estatísticas: %v", err)
+		}
+
+		reloaded, err := NewCounter(tempDir)
+		if err != nil {
+			t.Fatalf("Erro ao recarregar contador: %v", err)
+		}
+
+		if len(reloaded.QueryUsage(UsageQuery{})) != 3 {
+			t.Errorf("Histórico não foi recarregado: %+v", reloaded.QueryUsage(UsageQuery{}))
+		}
+	})
+}
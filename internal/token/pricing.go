@@ -0,0 +1,281 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Nomes aceitos para o arquivo do catálogo de preços, em ordem de preferência
+var pricingFileNames = []string{"pricing.yaml", "pricing.yml", "pricing.json"}
+
+// DateLayout é o formato das datas de vigência no catálogo (AAAA-MM-DD)
+const DateLayout = "2006-01-02"
+
+// DefaultCurrency é a moeda usada quando o catálogo ou o preço não informam outra
+const DefaultCurrency = "USD"
+
+// Price representa o preço de um modelo a partir de uma data de vigência
+type Price struct {
+	// Provider restringe o preço a um provedor; vazio vale para qualquer provedor
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty"`
+	// Model é o nome do modelo; "*" vale para todos os modelos do provedor
+	Model string `json:"model" yaml:"model"`
+	// PromptPer1K é o preço de 1000 tokens de entrada
+	PromptPer1K float64 `json:"prompt_per_1k" yaml:"prompt_per_1k"`
+	// CompletionPer1K é o preço de 1000 tokens de saída
+	CompletionPer1K float64 `json:"completion_per_1k" yaml:"completion_per_1k"`
+	// Currency é a moeda do preço; vazio usa a moeda do catálogo
+	Currency string `json:"currency,omitempty" yaml:"currency,omitempty"`
+	// EffectiveFrom é a data (AAAA-MM-DD) a partir da qual o preço vale
+	EffectiveFrom string `json:"effective_from" yaml:"effective_from"`
+
+	effective time.Time
+}
+
+// Cost calcula o custo de uma requisição com a quantidade de tokens informada
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return float64(promptTokens)*p.PromptPer1K/1000.0 + float64(completionTokens)*p.CompletionPer1K/1000.0
+}
+
+// PricingCatalog é o catálogo de preços dos modelos, carregado de um arquivo JSON ou YAML
+type PricingCatalog struct {
+	Currency string  `json:"currency" yaml:"currency"`
+	Prices   []Price `json:"prices" yaml:"prices"`
+
+	path  string
+	mutex sync.RWMutex
+}
+
+// DefaultPricingCatalog retorna o catálogo com os preços públicos conhecidos, em USD
+func DefaultPricingCatalog() *PricingCatalog {
+	catalog := &PricingCatalog{
+		Currency: DefaultCurrency,
+		Prices: []Price{
+			// Modelos locais não têm custo por token
+			{Provider: "ollama", Model: "*", EffectiveFrom: "2023-01-01"},
+			{Provider: "openai", Model: "gpt-3.5-turbo", PromptPer1K: 0.0015, CompletionPer1K: 0.002, EffectiveFrom: "2023-06-13"},
+			{Provider: "openai", Model: "gpt-3.5-turbo", PromptPer1K: 0.0005, CompletionPer1K: 0.0015, EffectiveFrom: "2024-01-25"},
+			{Provider: "openai", Model: "gpt-4", PromptPer1K: 0.03, CompletionPer1K: 0.06, EffectiveFrom: "2023-03-14"},
+			{Provider: "openai", Model: "gpt-4-turbo", PromptPer1K: 0.01, CompletionPer1K: 0.03, EffectiveFrom: "2024-04-09"},
+			{Provider: "openai", Model: "gpt-4o", PromptPer1K: 0.0025, CompletionPer1K: 0.01, EffectiveFrom: "2024-08-06"},
+			{Provider: "openai", Model: "gpt-4o-mini", PromptPer1K: 0.00015, CompletionPer1K: 0.0006, EffectiveFrom: "2024-07-18"},
+			{Provider: "google", Model: "gemini-pro", PromptPer1K: 0.0005, CompletionPer1K: 0.0015, EffectiveFrom: "2024-02-15"},
+			{Provider: "google", Model: "gemini-1.5-flash", PromptPer1K: 0.000075, CompletionPer1K: 0.0003, EffectiveFrom: "2024-10-01"},
+			{Provider: "google", Model: "gemini-1.5-pro", PromptPer1K: 0.00125, CompletionPer1K: 0.005, EffectiveFrom: "2024-10-01"},
+			{Provider: "anthropic", Model: "claude-3-haiku", PromptPer1K: 0.00025, CompletionPer1K: 0.00125, EffectiveFrom: "2024-03-07"},
+			{Provider: "anthropic", Model: "claude-3-sonnet", PromptPer1K: 0.003, CompletionPer1K: 0.015, EffectiveFrom: "2024-03-04"},
+			{Provider: "anthropic", Model: "claude-3-opus", PromptPer1K: 0.015, CompletionPer1K: 0.075, EffectiveFrom: "2024-03-04"},
+			{Provider: "anthropic", Model: "claude-3-5-sonnet", PromptPer1K: 0.003, CompletionPer1K: 0.015, EffectiveFrom: "2024-06-20"},
+			{Provider: "anthropic", Model: "claude-3-5-haiku", PromptPer1K: 0.0008, CompletionPer1K: 0.004, EffectiveFrom: "2024-11-04"},
+		},
+	}
+
+	// Os preços padrão são fixos e válidos
+	_ = catalog.parseDates()
+	return catalog
+}
+
+// LoadPricingCatalog carrega o catálogo de preços do diretório de configuração.
+// Se nenhum arquivo existir, o catálogo padrão é gravado em pricing.json para que possa ser editado
+func LoadPricingCatalog(configDir string) (*PricingCatalog, error) {
+	for _, name := range pricingFileNames {
+		path := filepath.Join(configDir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler catálogo de preços: %w", err)
+		}
+
+		catalog := &PricingCatalog{path: path}
+		if isYAML(path) {
+			err = yaml.Unmarshal(data, catalog)
+		} else {
+			err = json.Unmarshal(data, catalog)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao deserializar catálogo de preços %s: %w", name, err)
+		}
+
+		if catalog.Currency == "" {
+			catalog.Currency = DefaultCurrency
+		}
+		if err := catalog.parseDates(); err != nil {
+			return nil, fmt.Errorf("catálogo de preços %s inválido: %w", name, err)
+		}
+		catalog.mergeDefaults(DefaultPricingCatalog())
+
+		return catalog, nil
+	}
+
+	catalog := DefaultPricingCatalog()
+	catalog.path = filepath.Join(configDir, "pricing.json")
+	if err := catalog.Save(); err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// mergeDefaults acrescenta os preços padrão dos modelos que o catálogo não define, para que catálogos
+// gravados por versões anteriores conheçam os modelos novos. Os preços do arquivo prevalecem, e
+// catálogos em outra moeda não recebem os preços padrão, que estão em dólares. O arquivo não é alterado
+func (c *PricingCatalog) mergeDefaults(defaults *PricingCatalog) {
+	if c.Currency != defaults.Currency {
+		return
+	}
+
+	for _, price := range defaults.Prices {
+		defined := false
+		for _, existing := range c.Prices {
+			if existing.Model == price.Model && (existing.Provider == "" || existing.Provider == price.Provider) {
+				defined = true
+				break
+			}
+		}
+		if !defined {
+			c.Prices = append(c.Prices, price)
+		}
+	}
+}
+
+// parseDates valida e converte as datas de vigência de todos os preços
+func (c *PricingCatalog) parseDates() error {
+	for i := range c.Prices {
+		price := &c.Prices[i]
+		if price.Model == "" {
+			return fmt.Errorf("preço %d sem modelo", i+1)
+		}
+
+		effective, err := time.Parse(DateLayout, price.EffectiveFrom)
+		if err != nil {
+			return fmt.Errorf("data de vigência inválida para %s: %q", price.Model, price.EffectiveFrom)
+		}
+		price.effective = effective
+	}
+	return nil
+}
+
+// Lookup retorna o preço em vigor na data informada para o provedor e modelo.
+// Variantes como "gpt-4o-2024-08-06" ou "llama2:latest" usam o preço do modelo base
+func (c *PricingCatalog) Lookup(provider, model string, at time.Time) (Price, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	var best Price
+	bestScore := -1
+	for _, price := range c.Prices {
+		if price.Provider != "" && price.Provider != provider {
+			continue
+		}
+		if price.effective.After(at) {
+			continue
+		}
+
+		score := matchScore(price.Model, model)
+		if score < 0 {
+			continue
+		}
+
+		// Vence o modelo mais específico e, entre iguais, a vigência mais recente
+		if score > bestScore || (score == bestScore && price.effective.After(best.effective)) {
+			best = price
+			bestScore = score
+		}
+	}
+
+	if bestScore < 0 {
+		return Price{}, false
+	}
+	if best.Currency == "" {
+		best.Currency = c.Currency
+	}
+	return best, true
+}
+
+// matchScore indica quão bem o modelo do catálogo corresponde ao modelo usado (-1 = não corresponde)
+func matchScore(catalogModel, model string) int {
+	switch {
+	case catalogModel == model:
+		return len(catalogModel) + 1
+	case catalogModel == "*":
+		return 0
+	case strings.HasPrefix(model, catalogModel+"-"), strings.HasPrefix(model, catalogModel+":"):
+		return len(catalogModel)
+	default:
+		return -1
+	}
+}
+
+// SetPrice inclui um preço ou substitui o existente com o mesmo provedor, modelo e vigência
+func (c *PricingCatalog) SetPrice(price Price) error {
+	effective, err := time.Parse(DateLayout, price.EffectiveFrom)
+	if err != nil {
+		return fmt.Errorf("data de vigência inválida: %q", price.EffectiveFrom)
+	}
+	price.effective = effective
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, existing := range c.Prices {
+		if existing.Provider == price.Provider && existing.Model == price.Model && existing.EffectiveFrom == price.EffectiveFrom {
+			c.Prices[i] = price
+			return nil
+		}
+	}
+	c.Prices = append(c.Prices, price)
+
+	sort.SliceStable(c.Prices, func(i, j int) bool {
+		if c.Prices[i].Model != c.Prices[j].Model {
+			return c.Prices[i].Model < c.Prices[j].Model
+		}
+		return c.Prices[i].effective.Before(c.Prices[j].effective)
+	})
+	return nil
+}
+
+// Save grava o catálogo no arquivo de onde foi carregado, mantendo o formato
+func (c *PricingCatalog) Save() error {
+	if c.path == "" {
+		return fmt.Errorf("catálogo de preços sem arquivo associado")
+	}
+
+	c.mutex.RLock()
+	var data []byte
+	var err error
+	if isYAML(c.path) {
+		data, err = yaml.Marshal(c)
+	} else {
+		data, err = json.MarshalIndent(c, "", "  ")
+	}
+	c.mutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("erro ao serializar catálogo de preços: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("erro ao gravar catálogo de preços: %w", err)
+	}
+	return nil
+}
+
+// Path retorna o arquivo do catálogo
+func (c *PricingCatalog) Path() string {
+	return c.path
+}
+
+// isYAML indica se o arquivo deve ser lido e gravado como YAML
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
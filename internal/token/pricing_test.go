@@ -0,0 +1,135 @@
+package token
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPricingCatalog(t *testing.T) {
+	// Cria um diretório temporário para o teste
+	tempDir, err := os.MkdirTemp("", "pricing-test")
+	if err != nil {
+		t.Fatalf("Erro ao criar diretório temporário: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	t.Run("CatalogoPadrao", func(t *testing.T) {
+		catalog, err := LoadPricingCatalog(tempDir)
+		if err != nil {
+			t.Fatalf("Erro ao carregar catálogo: %v", err)
+		}
+
+		// O catálogo padrão deve ser gravado para poder ser editado
+		if _, err := os.Stat(filepath.Join(tempDir, "pricing.json")); err != nil {
+			t.Errorf("Catálogo padrão não foi gravado: %v", err)
+		}
+
+		// Antes da redução de preço vale a versão anterior
+		antigo, ok := catalog.Lookup("openai", "gpt-3.5-turbo", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC))
+		if !ok || antigo.PromptPer1K != 0.0015 {
+			t.Errorf("Preço vigente em 2023 incorreto: %+v", antigo)
+		}
+
+		atual, ok := catalog.Lookup("openai", "gpt-3.5-turbo-0125", time.Now())
+		if !ok || atual.PromptPer1K != 0.0005 || atual.CompletionPer1K != 0.0015 {
+			t.Errorf("Preço atual incorreto: %+v", atual)
+		}
+
+		local, ok := catalog.Lookup("ollama", "llama2:latest", time.Now())
+		if !ok || local.Cost(1000, 1000) != 0 {
+			t.Errorf("Modelos locais não deveriam ter custo: %+v", local)
+		}
+
+		// Versões datadas do Claude usam o preço do modelo base
+		claude, ok := catalog.Lookup("anthropic", "claude-3-5-haiku-20241022", time.Now())
+		if !ok || claude.PromptPer1K != 0.0008 || claude.CompletionPer1K != 0.004 {
+			t.Errorf("Preço do Claude incorreto: %+v", claude)
+		}
+
+		if _, ok := catalog.Lookup("openai", "modelo-desconhecido", time.Now()); ok {
+			t.Errorf("Modelo desconhecido não deveria ter preço")
+		}
+	})
+
+	t.Run("CatalogoYAML", func(t *testing.T) {
+		yamlDir := filepath.Join(tempDir, "yaml")
+		if err := os.MkdirAll(yamlDir, 0755); err != nil {
+			t.Fatalf("Erro ao criar diretório: %v", err)
+		}
+
+		conteudo := `currency: BRL
+prices:
+  - provider: openai
+    model: gpt-4o-mini
+    prompt_per_1k: 0.001
+    completion_per_1k: 0.004
+    effective_from: "2025-01-01"
+`
+		if err := os.WriteFile(filepath.Join(yamlDir, "pricing.yaml"), []byte(conteudo), 0644); err != nil {
+			t.Fatalf("Erro ao gravar catálogo: %v", err)
+		}
+
+		counter, err := NewCounter(yamlDir)
+		if err != nil {
+			t.Fatalf("Erro ao criar contador: %v", err)
+		}
+
+		counter.RecordUsage("openai", "gpt-4o-mini", 1000, 500)
+		stats, _ := counter.GetStats("openai", "gpt-4o-mini")
+		if stats.Currency != "BRL" || stats.EstimatedCostUSD != 0.003 {
+			t.Errorf("Custo calculado incorretamente: %+v", stats)
+		}
+
+		// Modelos sem preço não recebem um custo inventado
+		counter.RecordUsage("openai", "gpt-4", 1000, 1000)
+		stats, _ = counter.GetStats("openai", "gpt-4")
+		if stats.EstimatedCostUSD != 0 || stats.UnpricedRequests != 1 {
+			t.Errorf("Modelo sem preço tratado incorretamente: %+v", stats)
+		}
+	})
+
+	t.Run("CatalogoAntigo", func(t *testing.T) {
+		antigoDir := filepath.Join(tempDir, "antigo")
+		if err := os.MkdirAll(antigoDir, 0755); err != nil {
+			t.Fatalf("Erro ao criar diretório: %v", err)
+		}
+
+		// Catálogo gravado antes dos preços da Anthropic, com um preço editado pelo usuário
+		conteudo := `{"currency":"USD","prices":[{"provider":"openai","model":"gpt-4o","prompt_per_1k":0.002,"completion_per_1k":0.008,"effective_from":"2024-08-06"}]}`
+		if err := os.WriteFile(filepath.Join(antigoDir, "pricing.json"), []byte(conteudo), 0644); err != nil {
+			t.Fatalf("Erro ao gravar catálogo: %v", err)
+		}
+
+		catalog, err := LoadPricingCatalog(antigoDir)
+		if err != nil {
+			t.Fatalf("Erro ao carregar catálogo: %v", err)
+		}
+
+		claude, ok := catalog.Lookup("anthropic", "claude-3-5-sonnet-20241022", time.Now())
+		if !ok || claude.PromptPer1K != 0.003 {
+			t.Errorf("Preço padrão do Claude não foi acrescentado: %+v", claude)
+		}
+		editado, ok := catalog.Lookup("openai", "gpt-4o", time.Now())
+		if !ok || editado.PromptPer1K != 0.002 {
+			t.Errorf("O preço do arquivo deveria prevalecer: %+v", editado)
+		}
+	})
+
+	t.Run("DataInvalida", func(t *testing.T) {
+		invalidDir := filepath.Join(tempDir, "invalido")
+		if err := os.MkdirAll(invalidDir, 0755); err != nil {
+			t.Fatalf("Erro ao criar diretório: %v", err)
+		}
+
+		conteudo := `{"prices":[{"model":"gpt-4","prompt_per_1k":0.03,"completion_per_1k":0.06,"effective_from":"01/01/2024"}]}`
+		if err := os.WriteFile(filepath.Join(invalidDir, "pricing.json"), []byte(conteudo), 0644); err != nil {
+			t.Fatalf("Erro ao gravar catálogo: %v", err)
+		}
+
+		if _, err := LoadPricingCatalog(invalidDir); err == nil {
+			t.Errorf("Esperava erro com data de vigência inválida")
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/peder/whatszapme/internal/db"
+	"github.com/peder/whatszapme/internal/knowledge"
+)
+
+// GerenciadorConhecimento exibe os arquivos indexados na base de conhecimento
+type GerenciadorConhecimento struct {
+	index       *knowledge.Index
+	window      fyne.Window
+	fontes      []db.FonteConhecimento
+	lista       *widget.List
+	statusLabel *widget.Label
+}
+
+// NewGerenciadorConhecimento cria o gerenciador da base de conhecimento
+func NewGerenciadorConhecimento(index *knowledge.Index, window fyne.Window) *GerenciadorConhecimento {
+	return &GerenciadorConhecimento{
+		index:       index,
+		window:      window,
+		statusLabel: widget.NewLabel(""),
+	}
+}
+
+// Container retorna o container principal da interface da base de conhecimento
+func (g *GerenciadorConhecimento) Container() fyne.CanvasObject {
+	g.lista = widget.NewList(
+		func() int {
+			return len(g.fontes)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewIcon(theme.DocumentIcon()), widget.NewLabel("detalhes"), widget.NewLabel("arquivo"))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id >= len(g.fontes) {
+				return
+			}
+			fonte := g.fontes[id]
+			linha := item.(*fyne.Container)
+			linha.Objects[0].(*widget.Label).SetText(fonte.Caminho)
+			linha.Objects[2].(*widget.Label).SetText(fmt.Sprintf("%d trechos · %s · %s",
+				fonte.Trechos, fonte.Modelo, fonte.IndexadoEm.Local().Format("02/01/2006 15:04")))
+		},
+	)
+
+	reindexarButton := widget.NewButtonWithIcon("Reindexar agora", theme.ViewRefreshIcon(), func() {
+		g.statusLabel.SetText("Indexando...")
+		go func() {
+			result, err := g.index.Reindex(context.Background())
+			g.MostrarResultado(result, err)
+		}()
+	})
+
+	g.Atualizar()
+
+	cabecalho := container.NewVBox(
+		widget.NewCard("Base de Conhecimento",
+			"Trechos destes arquivos são enviados ao modelo na variável {{.Context}}",
+			container.NewVBox(
+				widget.NewLabel("Pasta: "+g.index.Dir),
+				container.NewHBox(reindexarButton),
+				g.statusLabel,
+			)),
+	)
+
+	return container.NewBorder(cabecalho, nil, nil, nil, g.lista)
+}
+
+// Atualizar recarrega a lista de arquivos indexados
+func (g *GerenciadorConhecimento) Atualizar() {
+	fontes, err := g.index.Sources()
+	if err != nil {
+		g.statusLabel.SetText(fmt.Sprintf("Erro ao listar arquivos indexados: %v", err))
+		return
+	}
+
+	g.fontes = fontes
+	if g.lista != nil {
+		g.lista.Refresh()
+	}
+
+	var trechos int
+	for _, fonte := range fontes {
+		trechos += fonte.Trechos
+	}
+	g.statusLabel.SetText(fmt.Sprintf("%d arquivos, %d trechos indexados", len(fontes), trechos))
+}
+
+// MostrarResultado exibe o resumo de uma indexação e atualiza a lista
+func (g *GerenciadorConhecimento) MostrarResultado(result knowledge.IndexResult, err error) {
+	if err != nil {
+		g.statusLabel.SetText("Erro na indexação")
+		dialog.ShowError(fmt.Errorf("erro ao indexar a base de conhecimento: %w", err), g.window)
+		return
+	}
+
+	resumo := fmt.Sprintf("%d indexados, %d sem alteração, %d removidos", len(result.Indexed), result.Unchanged, len(result.Removed))
+	if len(result.Errors) > 0 {
+		var falhas []string
+		for arquivo, erro := range result.Errors {
+			falhas = append(falhas, fmt.Sprintf("%s: %v", arquivo, erro))
+		}
+		resumo += fmt.Sprintf(", %d com erro", len(result.Errors))
+		dialog.ShowInformation("Arquivos não indexados", strings.Join(falhas, "\n"), g.window)
+	}
+
+	g.Atualizar()
+	g.statusLabel.SetText(resumo + " — " + g.statusLabel.Text)
+}
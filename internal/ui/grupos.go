@@ -0,0 +1,345 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/peder/whatszapme/internal/db"
+)
+
+// GerenciadorGrupos lista os grupos sincronizados e edita a política e a moderação de cada um
+type GerenciadorGrupos struct {
+	database    *db.DB
+	window      fyne.Window
+	grupos      []db.Grupo
+	lista       *widget.List
+	statusLabel *widget.Label
+}
+
+// NewGerenciadorGrupos cria o gerenciador das políticas de grupos
+func NewGerenciadorGrupos(database *db.DB, window fyne.Window) *GerenciadorGrupos {
+	return &GerenciadorGrupos{
+		database:    database,
+		window:      window,
+		statusLabel: widget.NewLabel(""),
+	}
+}
+
+// Container retorna o container principal da interface de grupos
+func (g *GerenciadorGrupos) Container() fyne.CanvasObject {
+	g.lista = widget.NewList(
+		func() int {
+			return len(g.grupos)
+		},
+		func() fyne.CanvasObject {
+			moderacao := widget.NewButtonWithIcon("Moderação", theme.WarningIcon(), nil)
+			return container.NewBorder(nil, nil, widget.NewIcon(theme.AccountIcon()), container.NewHBox(widget.NewLabel("política"), moderacao), widget.NewLabel("grupo"))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id >= len(g.grupos) {
+				return
+			}
+			grupo := g.grupos[id]
+			linha := item.(*fyne.Container)
+			linha.Objects[0].(*widget.Label).SetText(fmt.Sprintf("%s (%d participantes)", nomeGrupo(grupo), len(grupo.Participantes)))
+			direita := linha.Objects[2].(*fyne.Container)
+			direita.Objects[0].(*widget.Label).SetText(resumoPolitica(grupo.Politica))
+			direita.Objects[1].(*widget.Button).OnTapped = func() {
+				g.editarModeracao(grupo)
+			}
+		},
+	)
+	g.lista.OnSelected = func(id widget.ListItemID) {
+		if id < len(g.grupos) {
+			g.editarPolitica(g.grupos[id])
+		}
+		g.lista.UnselectAll()
+	}
+
+	atualizarButton := widget.NewButtonWithIcon("Atualizar lista", theme.ViewRefreshIcon(), g.Atualizar)
+	logButton := widget.NewButtonWithIcon("Log de moderação", theme.ListIcon(), g.mostrarLogModeracao)
+
+	g.Atualizar()
+
+	cabecalho := widget.NewCard("Políticas por Grupo",
+		"Clique em um grupo para definir como o assistente se comporta nele. Os grupos aparecem após a sincronização de contatos",
+		container.NewVBox(container.NewHBox(atualizarButton, logButton), g.statusLabel))
+
+	return container.NewBorder(cabecalho, nil, nil, nil, container.NewGridWrap(fyne.NewSize(600, 300), g.lista))
+}
+
+// Atualizar recarrega a lista de grupos
+func (g *GerenciadorGrupos) Atualizar() {
+	grupos, err := g.database.ListarGrupos()
+	if err != nil {
+		g.statusLabel.SetText(fmt.Sprintf("Erro ao listar grupos: %v", err))
+		return
+	}
+
+	g.grupos = grupos
+	if g.lista != nil {
+		g.lista.Refresh()
+	}
+	g.statusLabel.SetText(fmt.Sprintf("%d grupos sincronizados", len(grupos)))
+}
+
+// editarPolitica abre o formulário da política do grupo
+func (g *GerenciadorGrupos) editarPolitica(grupo db.Grupo) {
+	politica := db.PoliticaGrupo{Ativo: true, ComandosSomenteAdmin: true}
+	if grupo.Politica != nil {
+		politica = *grupo.Politica
+	}
+
+	ativo := widget.NewCheck("Responder neste grupo", nil)
+	ativo.SetChecked(politica.Ativo)
+	somenteMencao := widget.NewCheck("Apenas quando mencionado ou quando respondem ao assistente", nil)
+	somenteMencao.SetChecked(politica.SomenteMencao)
+	somenteAdmin := widget.NewCheck("Apenas administradores usam os comandos /bot", nil)
+	somenteAdmin.SetChecked(politica.ComandosSomenteAdmin)
+
+	palavras := widget.NewEntry()
+	palavras.SetPlaceHolder("preço, entrega, horário")
+	palavras.SetText(strings.Join(politica.PalavrasChave, ", "))
+
+	intervalo := widget.NewEntry()
+	intervalo.SetPlaceHolder("0 = sem intervalo")
+	intervalo.SetText(strconv.Itoa(int(politica.Intervalo / time.Second)))
+
+	persona := widget.NewMultiLineEntry()
+	persona.SetPlaceHolder("Vazio usa o system prompt padrão")
+	persona.SetText(politica.Persona)
+	persona.Wrapping = fyne.TextWrapWord
+
+	// Sem política própria, o grupo segue as configurações gerais de grupos
+	padrao := widget.NewCheck("Usar as configurações gerais de grupos", nil)
+	campos := []fyne.Disableable{ativo, somenteMencao, somenteAdmin, palavras, intervalo, persona}
+	padrao.OnChanged = func(usarPadrao bool) {
+		for _, campo := range campos {
+			if usarPadrao {
+				campo.Disable()
+			} else {
+				campo.Enable()
+			}
+		}
+	}
+	padrao.SetChecked(grupo.Politica == nil)
+
+	itens := []*widget.FormItem{
+		widget.NewFormItem("", padrao),
+		widget.NewFormItem("", ativo),
+		widget.NewFormItem("", somenteMencao),
+		widget.NewFormItem("Palavras-chave", palavras),
+		widget.NewFormItem("Intervalo (segundos)", intervalo),
+		widget.NewFormItem("", somenteAdmin),
+		widget.NewFormItem("Persona", persona),
+	}
+	formulario := dialog.NewForm(nomeGrupo(grupo), "Salvar", "Cancelar", itens, func(confirmado bool) {
+		if !confirmado {
+			return
+		}
+
+		var nova *db.PoliticaGrupo
+		if !padrao.Checked {
+			segundos, err := strconv.Atoi(strings.TrimSpace(intervalo.Text))
+			if err != nil || segundos < 0 {
+				segundos = 0
+			}
+			nova = &db.PoliticaGrupo{
+				Ativo:                ativo.Checked,
+				SomenteMencao:        somenteMencao.Checked,
+				PalavrasChave:        strings.Split(palavras.Text, ","),
+				Persona:              strings.TrimSpace(persona.Text),
+				ComandosSomenteAdmin: somenteAdmin.Checked,
+				Intervalo:            time.Duration(segundos) * time.Second,
+			}
+		}
+
+		if err := g.database.DefinirPoliticaGrupo(grupo.JID, nova); err != nil {
+			dialog.ShowError(err, g.window)
+			return
+		}
+		g.Atualizar()
+	}, g.window)
+	formulario.Resize(fyne.NewSize(550, 450))
+	formulario.Show()
+}
+
+// Ações disponíveis contra quem viola as regras, na ordem exibida no formulário
+var acoesModeracao = []string{"avisar", "apagar", "remover"}
+
+// editarModeracao abre o formulário das mensagens automáticas e das regras do grupo
+func (g *GerenciadorGrupos) editarModeracao(grupo db.Grupo) {
+	moderacao, err := g.database.ObterModeracaoGrupo(grupo.JID)
+	if err != nil {
+		dialog.ShowError(err, g.window)
+		return
+	}
+	ativa := moderacao != nil
+	if moderacao == nil {
+		moderacao = &db.ModeracaoGrupo{Acao: acoesModeracao[0]}
+	}
+
+	boasVindas := widget.NewMultiLineEntry()
+	boasVindas.SetPlaceHolder("Bem-vindo(a) ao {{.Group}}, {{.Mention}}! Leia a descrição do grupo.")
+	boasVindas.SetText(moderacao.BoasVindas)
+	boasVindas.Wrapping = fyne.TextWrapWord
+
+	despedida := widget.NewEntry()
+	despedida.SetPlaceHolder("{{.Name}} saiu do grupo.")
+	despedida.SetText(moderacao.Despedida)
+
+	bloquearLinks := widget.NewCheck("Proibir links", nil)
+	bloquearLinks.SetChecked(moderacao.BloquearLinks)
+
+	limiteFlood := widget.NewEntry()
+	limiteFlood.SetPlaceHolder("0 = sem limite")
+	limiteFlood.SetText(strconv.Itoa(moderacao.LimiteFlood))
+
+	janelaFlood := widget.NewEntry()
+	janelaFlood.SetText(strconv.Itoa(int(moderacao.JanelaFlood / time.Second)))
+
+	proibidas := widget.NewEntry()
+	proibidas.SetPlaceHolder("golpe, pix grátis")
+	proibidas.SetText(strings.Join(moderacao.PalavrasProibidas, ", "))
+
+	acao := widget.NewSelect(acoesModeracao, nil)
+	acao.SetSelected(moderacao.Acao)
+
+	// Sem moderação, o assistente não envia mensagens automáticas nem aplica regras no grupo
+	moderar := widget.NewCheck("Moderar este grupo", nil)
+	campos := []fyne.Disableable{boasVindas, despedida, bloquearLinks, limiteFlood, janelaFlood, proibidas, acao}
+	moderar.OnChanged = func(ligado bool) {
+		for _, campo := range campos {
+			if ligado {
+				campo.Enable()
+			} else {
+				campo.Disable()
+			}
+		}
+	}
+	moderar.SetChecked(ativa)
+	moderar.OnChanged(ativa)
+
+	itens := []*widget.FormItem{
+		widget.NewFormItem("", moderar),
+		widget.NewFormItem("Boas-vindas", boasVindas),
+		widget.NewFormItem("Despedida", despedida),
+		widget.NewFormItem("", bloquearLinks),
+		widget.NewFormItem("Máximo de mensagens", limiteFlood),
+		widget.NewFormItem("Em (segundos)", janelaFlood),
+		widget.NewFormItem("Palavras proibidas", proibidas),
+		widget.NewFormItem("Ação", acao),
+		widget.NewFormItem("", widget.NewLabel("Campos das mensagens: {{.Name}}, {{.Mention}} e {{.Group}}. Apagar e remover exigem que a conta seja administradora")),
+	}
+	formulario := dialog.NewForm("Moderação: "+nomeGrupo(grupo), "Salvar", "Cancelar", itens, func(confirmado bool) {
+		if !confirmado {
+			return
+		}
+
+		var nova *db.ModeracaoGrupo
+		if moderar.Checked {
+			limite, err := strconv.Atoi(strings.TrimSpace(limiteFlood.Text))
+			if err != nil || limite < 0 {
+				limite = 0
+			}
+			janela, err := strconv.Atoi(strings.TrimSpace(janelaFlood.Text))
+			if err != nil || janela < 0 {
+				janela = 0
+			}
+			nova = &db.ModeracaoGrupo{
+				BoasVindas:        strings.TrimSpace(boasVindas.Text),
+				Despedida:         strings.TrimSpace(despedida.Text),
+				BloquearLinks:     bloquearLinks.Checked,
+				LimiteFlood:       limite,
+				JanelaFlood:       time.Duration(janela) * time.Second,
+				PalavrasProibidas: strings.Split(proibidas.Text, ","),
+				Acao:              acao.Selected,
+			}
+		}
+
+		if err := g.database.DefinirModeracaoGrupo(grupo.JID, nova); err != nil {
+			dialog.ShowError(err, g.window)
+		}
+	}, g.window)
+	formulario.Resize(fyne.NewSize(600, 550))
+	formulario.Show()
+}
+
+// mostrarLogModeracao exibe as ações de moderação mais recentes de todos os grupos
+func (g *GerenciadorGrupos) mostrarLogModeracao() {
+	registros, err := g.database.ListarModeracao("", 200)
+	if err != nil {
+		dialog.ShowError(err, g.window)
+		return
+	}
+
+	// Os grupos aparecem pelo nome quando já sincronizados
+	nomes := make(map[string]string, len(g.grupos))
+	for _, grupo := range g.grupos {
+		nomes[grupo.JID] = nomeGrupo(grupo)
+	}
+
+	lista := widget.NewList(
+		func() int {
+			return len(registros)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("registro")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			r := registros[id]
+			grupo := nomes[r.GrupoJID]
+			if grupo == "" {
+				grupo = r.GrupoJID
+			}
+			texto := fmt.Sprintf("%s  %s  %s: %s", r.Timestamp.Format("02/01 15:04"), grupo, r.Acao, r.ParticipanteJID)
+			if r.Motivo != "" {
+				texto += " (" + r.Motivo + ")"
+			}
+			if r.Erro != "" {
+				texto += " — falhou: " + r.Erro
+			}
+			item.(*widget.Label).SetText(texto)
+		},
+	)
+
+	var conteudo fyne.CanvasObject = lista
+	if len(registros) == 0 {
+		conteudo = widget.NewLabel("Nenhuma ação de moderação registrada")
+	}
+	janela := dialog.NewCustom("Log de moderação", "Fechar", conteudo, g.window)
+	janela.Resize(fyne.NewSize(750, 450))
+	janela.Show()
+}
+
+// nomeGrupo retorna o nome do grupo ou, se ainda não sincronizado, o JID
+func nomeGrupo(grupo db.Grupo) string {
+	if grupo.Nome != "" {
+		return grupo.Nome
+	}
+	return grupo.JID
+}
+
+// resumoPolitica descreve a política em poucas palavras para a lista de grupos
+func resumoPolitica(politica *db.PoliticaGrupo) string {
+	switch {
+	case politica == nil:
+		return "configurações gerais"
+	case !politica.Ativo:
+		return "desativado"
+	case politica.SomenteMencao:
+		return "apenas menções"
+	case len(politica.PalavrasChave) > 0:
+		return "palavras-chave"
+	default:
+		return "todas as mensagens"
+	}
+}
@@ -17,8 +17,14 @@ import (
 )
 
 // GerenciadorHistorico é um componente para visualizar e gerenciar o histórico de conversas
-// Função de callback para envio de mensagens
-type EnviarMensagemCallback func(destinatario, texto string) error
+// Função de callback para envio de mensagens; retorna o ID da mensagem enviada no WhatsApp
+type EnviarMensagemCallback func(destinatario, texto string) (string, error)
+
+// EditarMensagemCallback edita no WhatsApp uma mensagem já enviada ao contato
+type EditarMensagemCallback func(destinatario, whatsappID, texto string) error
+
+// ApagarMensagemCallback apaga para todos uma mensagem já enviada ao contato
+type ApagarMensagemCallback func(destinatario, whatsappID string) error
 
 type GerenciadorHistorico struct {
 	database         *db.DB
@@ -33,6 +39,8 @@ type GerenciadorHistorico struct {
 	campoMensagem    *widget.Entry
 	botaoEnviar      *widget.Button
 	enviarMensagemFn EnviarMensagemCallback
+	editarMensagemFn EditarMensagemCallback
+	apagarMensagemFn ApagarMensagemCallback
 }
 
 // NewGerenciadorHistorico cria uma nova instância do gerenciador de histórico
@@ -261,6 +269,16 @@ func (gh *GerenciadorHistorico) ConfigurarEnvioCallback(fn EnviarMensagemCallbac
 	gh.enviarMensagemFn = fn
 }
 
+// ConfigurarEdicaoCallback configura a função usada para editar as mensagens enviadas
+func (gh *GerenciadorHistorico) ConfigurarEdicaoCallback(fn EditarMensagemCallback) {
+	gh.editarMensagemFn = fn
+}
+
+// ConfigurarExclusaoCallback configura a função usada para apagar as mensagens enviadas
+func (gh *GerenciadorHistorico) ConfigurarExclusaoCallback(fn ApagarMensagemCallback) {
+	gh.apagarMensagemFn = fn
+}
+
 // enviarMensagemManual processa o envio manual de uma mensagem para o contato atual
 func (gh *GerenciadorHistorico) enviarMensagemManual() {
 	// Verifica se tem contato selecionado
@@ -297,7 +315,7 @@ func (gh *GerenciadorHistorico) enviarMensagemManual() {
 	// Envia em goroutine para não bloquear a UI
 	go func() {
 		// Tenta enviar a mensagem usando a função de callback
-		err := gh.enviarMensagemFn(destinatario, mensagemTexto)
+		whatsappID, err := gh.enviarMensagemFn(destinatario, mensagemTexto)
 		
 		// Volta para a thread principal usando fyne.CurrentApp()
 		// Isso é thread-safe e pode ser chamado de qualquer goroutine
@@ -333,11 +351,13 @@ func (gh *GerenciadorHistorico) enviarMensagemManual() {
 		
 		// Cria mensagem para o histórico local
 		msg := db.Mensagem{
-			JID:       destinatario,
-			Nome:      nomeContato,
-			Conteudo:  mensagemTexto,
-			Timestamp: time.Now(),
-			Entrada:   false, // não é uma mensagem de entrada
+			JID:        destinatario,
+			Nome:       nomeContato,
+			Conteudo:   mensagemTexto,
+			Timestamp:  time.Now(),
+			Entrada:    false, // não é uma mensagem de entrada
+			WhatsAppID: whatsappID,
+			Status:     db.StatusEnviada,
 		}
 		
 		// Salva no banco de dados
@@ -450,6 +470,13 @@ func (gh *GerenciadorHistorico) carregarMensagens(jid string) {
 			widget.NewLabel(tempoFormatado),
 		)
 		
+		// Tiques de envio, entrega e leitura da mensagem enviada ou da resposta do assistente
+		if marcador, importancia := marcadorStatus(msg.Status); marcador != "" {
+			statusLabel := widget.NewLabel(marcador)
+			statusLabel.Importance = importancia
+			cabecalho.Add(statusLabel)
+		}
+		
 		// Cria o label para o conteúdo da mensagem com cor personalizada
 		conteudoLabel := canvas.NewText(msg.Conteudo, cor)
 		conteudoLabel.Alignment = alinhamento
@@ -467,6 +494,70 @@ func (gh *GerenciadorHistorico) carregarMensagens(jid string) {
 			conteudoContainer,
 		)
 		
+		// Mensagens de voz mostram o texto transcrito abaixo do conteúdo
+		if msg.Transcricao != "" {
+			transcricaoLabel := widget.NewLabel("Transcrição: " + msg.Transcricao)
+			transcricaoLabel.Wrapping = fyne.TextWrapWord
+			transcricaoLabel.TextStyle = fyne.TextStyle{Italic: true}
+			transcricaoLabel.Alignment = alinhamento
+			mensagemBox.Add(transcricaoLabel)
+		}
+		
+		// Edições e exclusões do contato mantêm o conteúdo original visível para auditoria
+		if msg.Editada {
+			editadaLabel := widget.NewLabel("Editada. Original: " + msg.ConteudoOriginal)
+			editadaLabel.Wrapping = fyne.TextWrapWord
+			editadaLabel.Importance = widget.LowImportance
+			editadaLabel.Alignment = alinhamento
+			mensagemBox.Add(editadaLabel)
+		}
+		if msg.Excluida {
+			excluidaLabel := widget.NewLabel("Apagada para todos")
+			excluidaLabel.Importance = widget.DangerImportance
+			excluidaLabel.Alignment = alinhamento
+			mensagemBox.Add(excluidaLabel)
+		}
+		
+		// Envios manuais podem ser editados ou apagados pelo operador
+		if !msg.Entrada && msg.Resposta == "" && msg.WhatsAppID != "" && !msg.Excluida {
+			if acoes := gh.acoesMensagem(msg.JID, msg.WhatsAppID, msg.Conteudo); acoes != nil {
+				mensagemBox.Add(acoes)
+			}
+		}
+		
+		// Resposta do assistente, com a indicação de edição ou exclusão pelo operador
+		if msg.Entrada && msg.Resposta != "" {
+			respostaLabel := widget.NewLabel("Resposta: " + msg.Resposta)
+			respostaLabel.Wrapping = fyne.TextWrapWord
+			respostaLabel.Alignment = fyne.TextAlignTrailing
+			mensagemBox.Add(respostaLabel)
+			
+			if msg.RespostaEditada {
+				editadaLabel := widget.NewLabel("Resposta editada. Original: " + msg.RespostaOriginal)
+				editadaLabel.Wrapping = fyne.TextWrapWord
+				editadaLabel.Importance = widget.LowImportance
+				editadaLabel.Alignment = fyne.TextAlignTrailing
+				mensagemBox.Add(editadaLabel)
+			}
+			if msg.RespostaExcluida {
+				excluidaLabel := widget.NewLabel("Resposta apagada para todos")
+				excluidaLabel.Importance = widget.DangerImportance
+				excluidaLabel.Alignment = fyne.TextAlignTrailing
+				mensagemBox.Add(excluidaLabel)
+			} else if msg.RespostaWhatsAppID != "" {
+				if acoes := gh.acoesMensagem(msg.JID, msg.RespostaWhatsAppID, msg.Resposta); acoes != nil {
+					mensagemBox.Add(acoes)
+				}
+			}
+		}
+		
+		// Reação do contato à mensagem ou à resposta do assistente
+		if msg.Reacao != "" {
+			reacaoLabel := widget.NewLabel("Reação: " + msg.Reacao)
+			reacaoLabel.Alignment = alinhamento
+			mensagemBox.Add(reacaoLabel)
+		}
+		
 		// Adiciona separador
 		mensagemBox.Add(widget.NewSeparator())
 		
@@ -483,3 +574,94 @@ func (gh *GerenciadorHistorico) carregarMensagens(jid string) {
 		}()
 	}
 }
+
+// marcadorStatus retorna os tiques exibidos para o status de uma mensagem enviada, como no WhatsApp:
+// um tique quando o servidor aceitou a mensagem, dois quando ela foi entregue e dois destacados quando foi lida
+func marcadorStatus(status string) (string, widget.Importance) {
+	switch status {
+	case db.StatusPendente:
+		return "🕓", widget.LowImportance
+	case db.StatusEnviada:
+		return "✓", widget.MediumImportance
+	case db.StatusEntregue:
+		return "✓✓", widget.MediumImportance
+	case db.StatusLida:
+		return "✓✓", widget.HighImportance
+	case db.StatusFalhou:
+		return "não enviada", widget.DangerImportance
+	default:
+		return "", widget.MediumImportance
+	}
+}
+
+// acoesMensagem cria os botões para editar e apagar uma mensagem enviada ao contato.
+// Retorna nil se as funções de edição e exclusão não estiverem configuradas
+func (gh *GerenciadorHistorico) acoesMensagem(destinatario, whatsappID, texto string) fyne.CanvasObject {
+	if gh.editarMensagemFn == nil && gh.apagarMensagemFn == nil {
+		return nil
+	}
+	
+	acoes := container.NewHBox(layout.NewSpacer())
+	if gh.editarMensagemFn != nil {
+		acoes.Add(widget.NewButtonWithIcon("Editar", theme.DocumentCreateIcon(), func() {
+			gh.editarMensagem(destinatario, whatsappID, texto)
+		}))
+	}
+	if gh.apagarMensagemFn != nil {
+		acoes.Add(widget.NewButtonWithIcon("Apagar", theme.DeleteIcon(), func() {
+			gh.apagarMensagem(destinatario, whatsappID)
+		}))
+	}
+	return acoes
+}
+
+// editarMensagem pede o novo texto e edita a mensagem no WhatsApp e no histórico
+func (gh *GerenciadorHistorico) editarMensagem(destinatario, whatsappID, texto string) {
+	campo := widget.NewMultiLineEntry()
+	campo.SetText(texto)
+	campo.Wrapping = fyne.TextWrapWord
+	
+	itens := []*widget.FormItem{
+		widget.NewFormItem("Novo texto", campo),
+	}
+	formulario := dialog.NewForm("Editar mensagem", "Salvar", "Cancelar", itens, func(confirmado bool) {
+		novoTexto := campo.Text
+		if !confirmado || novoTexto == "" || novoTexto == texto {
+			return
+		}
+		
+		go func() {
+			// O WhatsApp recusa edições feitas mais de 15 minutos depois do envio
+			if err := gh.editarMensagemFn(destinatario, whatsappID, novoTexto); err != nil {
+				dialog.ShowError(fmt.Errorf("erro ao editar mensagem: %v", err), gh.window)
+				return
+			}
+			if _, err := gh.database.RegistrarEdicao(whatsappID, novoTexto); err != nil {
+				fmt.Printf("Erro ao registrar edição no histórico: %v\n", err)
+			}
+			gh.carregarMensagens(gh.contatoAtual)
+		}()
+	}, gh.window)
+	formulario.Resize(fyne.NewSize(500, 250))
+	formulario.Show()
+}
+
+// apagarMensagem confirma e apaga a mensagem para todos; o conteúdo continua no histórico
+func (gh *GerenciadorHistorico) apagarMensagem(destinatario, whatsappID string) {
+	dialog.ShowConfirm("Apagar mensagem", "Apagar esta mensagem para todos? O conteúdo continuará no histórico.", func(confirmado bool) {
+		if !confirmado {
+			return
+		}
+		
+		go func() {
+			if err := gh.apagarMensagemFn(destinatario, whatsappID); err != nil {
+				dialog.ShowError(fmt.Errorf("erro ao apagar mensagem: %v", err), gh.window)
+				return
+			}
+			if _, err := gh.database.RegistrarExclusao(whatsappID); err != nil {
+				fmt.Printf("Erro ao registrar exclusão no histórico: %v\n", err)
+			}
+			gh.carregarMensagens(gh.contatoAtual)
+		}()
+	}, gh.window)
+}
@@ -18,6 +18,7 @@ type GerenciadorTokens struct {
 	counter          *token.Counter
 	window           fyne.Window
 	statsContainer   *fyne.Container
+	reportContainer  *fyne.Container
 	totalCost        binding.Float
 	totalRequests    binding.Int
 	lastUpdated      binding.String
@@ -50,8 +51,9 @@ func (g *GerenciadorTokens) Container() fyne.CanvasObject {
 	// Container de resumo
 	resumoContainer := container.NewVBox(
 		widget.NewCard("Resumo de Uso", "", container.NewVBox(
-			widget.NewLabelWithData(binding.FloatToStringWithFormat(g.totalCost, "Custo Total Estimado: $%.4f USD")),
+			widget.NewLabelWithData(binding.FloatToStringWithFormat(g.totalCost, "Custo Total Estimado: %.4f "+g.counter.Pricing().Currency)),
 			widget.NewLabelWithData(binding.IntToStringWithFormat(g.totalRequests, "Total de Requisições: %d")),
+			widget.NewLabel("Catálogo de preços: "+g.counter.Pricing().Path()),
 			widget.NewLabel("Última Atualização:"),
 			widget.NewLabelWithData(g.lastUpdated),
 			widget.NewButton("Atualizar", func() {
@@ -62,6 +64,7 @@ func (g *GerenciadorTokens) Container() fyne.CanvasObject {
 	
 	// Container de estatísticas detalhadas
 	g.statsContainer = container.NewVBox()
+	g.reportContainer = container.NewVBox()
 	g.atualizarEstatisticas()
 	
 	// Container de controles
@@ -83,10 +86,19 @@ func (g *GerenciadorTokens) Container() fyne.CanvasObject {
 	return container.NewVBox(
 		resumoContainer,
 		widget.NewCard("Estatísticas por Provedor", "", g.statsContainer),
+		widget.NewCard("Custo por Contato", "Últimos 30 dias", g.reportContainer),
 		controlesContainer,
 	)
 }
 
+// Atualizar recarrega as estatísticas exibidas, por exemplo após uma nova resposta do LLM
+func (g *GerenciadorTokens) Atualizar() {
+	if g.statsContainer == nil {
+		return
+	}
+	g.atualizarEstatisticas()
+}
+
 // atualizarEstatisticas atualiza as estatísticas de uso na interface
 func (g *GerenciadorTokens) atualizarEstatisticas() {
 	// Limpa o container de estatísticas
@@ -102,18 +114,18 @@ func (g *GerenciadorTokens) atualizarEstatisticas() {
 	// Adiciona cada estatística ao container
 	for _, stat := range stats {
 		providerCard := widget.NewCard(
-			fmt.Sprintf("%s", stat.Provider),
+			fmt.Sprintf("%s - %s", stat.Provider, stat.Model),
 			fmt.Sprintf("Última utilização: %s", stat.LastUsed.Format("02/01/2006 15:04:05")),
 			container.NewVBox(
 				widget.NewLabel(fmt.Sprintf("Tokens de Entrada: %d", stat.PromptTokens)),
 				widget.NewLabel(fmt.Sprintf("Tokens de Saída: %d", stat.CompletionTokens)),
 				widget.NewLabel(fmt.Sprintf("Total de Tokens: %d", stat.TotalTokens)),
 				widget.NewLabel(fmt.Sprintf("Requisições: %d", stat.RequestCount)),
-				widget.NewLabel(fmt.Sprintf("Custo Estimado: $%.4f USD", stat.EstimatedCostUSD)),
+				widget.NewLabel(fmt.Sprintf("Custo Estimado: %.4f %s", stat.EstimatedCostUSD, moedaOuPadrao(stat.Currency))),
+				widget.NewLabel(fmt.Sprintf("Requisições sem preço cadastrado: %d", stat.UnpricedRequests)),
 				widget.NewButton("Resetar", func(s token.UsageStats) func() {
 					return func() {
-						// A key usada pelo Counter é provider:provider conforme implementado em loadStats
-						g.counter.Reset(s.Provider, s.Provider)
+						g.counter.Reset(s.Provider, s.Model)
 						g.atualizarEstatisticas()
 					}
 				}(stat)),
@@ -133,6 +145,40 @@ func (g *GerenciadorTokens) atualizarEstatisticas() {
 	
 	g.lastUpdated.Set(time.Now().Format("02/01/2006 15:04:05"))
 	
+	g.atualizarRelatorio()
+	
 	// Redesenha o container
 	g.statsContainer.Refresh()
 }
+
+// atualizarRelatorio mostra o gasto do mês e o custo por contato nos últimos 30 dias
+func (g *GerenciadorTokens) atualizarRelatorio() {
+	g.reportContainer.Objects = nil
+	
+	moeda := g.counter.Pricing().Currency
+	g.reportContainer.Add(widget.NewLabel(fmt.Sprintf("Gasto no mês atual: %.4f %s", g.counter.MonthlyCost(time.Now()), moeda)))
+	
+	relatorio := g.counter.Report(token.UsageQuery{Since: time.Now().AddDate(0, 0, -30)}, token.GroupByContact)
+	if len(relatorio) == 0 {
+		g.reportContainer.Add(widget.NewLabel("Nenhum uso registrado no período."))
+	}
+	
+	for _, total := range relatorio {
+		contato := total.Key
+		if contato == "" {
+			contato = "Sem contato associado"
+		}
+		g.reportContainer.Add(widget.NewLabel(fmt.Sprintf("%s: %.4f %s (%d tokens, %d requisições)",
+			contato, total.Cost, moeda, total.TotalTokens, total.RequestCount)))
+	}
+	
+	g.reportContainer.Refresh()
+}
+
+// moedaOuPadrao retorna a moeda informada ou a moeda padrão do catálogo de preços
+func moedaOuPadrao(moeda string) string {
+	if moeda == "" {
+		return token.DefaultCurrency
+	}
+	return moeda
+}
@@ -4,31 +4,30 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
-	"runtime"
 	"sync"
 	"time"
 
-	"github.com/mdp/qrterminal/v3"
 	"go.mau.fi/whatsmeow"
-	waProto "go.mau.fi/whatsmeow/binary/proto"
 	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 	waLog "go.mau.fi/whatsmeow/util/log"
-	"google.golang.org/protobuf/proto"
 	_ "github.com/mattn/go-sqlite3" // Driver SQLite
 )
 
 // Re-exportação de tipos para compatibilidade com código existente
 type (
-	ConnectionState string
-	MessageHandler  func(string, string) (string, error)
-	QRCallback      func(qrCode string)
-	StateCallback   func(state ConnectionState, err error)
-	MessageCallback func(jid string, sender string, message string)
+	ConnectionState  string
+	MessageHandler   func(string, string) (string, error)
+	QRCallback       func(qrCode string)
+	StateCallback    func(state ConnectionState, err error)
+	MessageCallback  func(msg *IncomingMessage)
+	ReactionCallback func(reaction *Reaction)
+	ReceiptCallback  func(receipt *Receipt)
+	UpdateCallback   func(update *MessageUpdate)
+	HistoryCallback  func(batch *HistoryBatch)
 )
 
 const (
@@ -37,6 +36,7 @@ const (
 	StateConnected    ConnectionState = "connected"
 	StateLoggedIn     ConnectionState = "logged_in"
 	StateQRScanned    ConnectionState = "qr_scanned"
+	StatePairing      ConnectionState = "pairing" // código de pareamento gerado, aguardando o celular
 	StateError        ConnectionState = "error"
 )
 
@@ -46,14 +46,25 @@ var (
 	ErrNotLoggedIn          = errors.New("cliente não está logado")
 	ErrAlreadyConnected     = errors.New("cliente já está conectado")
 	ErrSyncStoreNotSet      = errors.New("syncStore não configurado")
+	ErrAlreadyLoggedIn      = errors.New("cliente já está logado")
+	ErrLoginTimeout         = errors.New("o prazo para concluir o login expirou")
+	ErrLoginNotReady        = errors.New("a conexão de login ainda está sendo aberta; tente novamente em instantes")
 )
 
 // SyncStore define a interface para sincronização de configurações e contatos
 type SyncStore interface {
 	// GetRespondToGroupsConfig retorna as configurações de resposta a grupos
 	GetRespondToGroupsConfig(respondToGroups, respondOnlyIfMentioned *bool)
-	// SincronizarContato adiciona ou atualiza um contato no banco de dados
-	SincronizarContato(jid, nome, telefone string) error
+	// SincronizarContato adiciona ou atualiza um contato no banco de dados, sem apagar os nomes não informados
+	SincronizarContato(contato Contact) error
+	// SincronizarGrupo adiciona ou atualiza um grupo e substitui a lista de participantes
+	SincronizarGrupo(grupo Group) error
+	// GetGroupPolicy retorna a política definida para o grupo, ou nil para usar as configurações gerais
+	GetGroupPolicy(groupJID string) (*GroupPolicy, error)
+	// GetModerationRules retorna as regras de moderação do grupo, ou nil se ele não é moderado
+	GetModerationRules(groupJID string) (*ModerationRules, error)
+	// RegistrarModeracao grava no registro de moderação uma ação tomada em um grupo
+	RegistrarModeracao(evento ModerationEvent) error
 }
 
 // ClientConfig contém as configurações para o cliente WhatsApp
@@ -72,8 +83,17 @@ type ClientConfig struct {
 	OnQRCode      QRCallback
 	OnStateChange StateCallback
 	OnMessage     MessageCallback
+	OnReaction    ReactionCallback
+	OnReceipt     ReceiptCallback
+	OnUpdate      UpdateCallback
+	OnHistory     HistoryCallback
 	// Store para sincronização de configurações
 	SyncStore SyncStore
+	// Pasta onde as mídias recebidas são baixadas (vazio = não baixa)
+	MediaDir string
+	// Tamanho máximo de cada anexo e da pasta de anexos, em bytes (0 = padrão)
+	MaxMediaFileSize  int64
+	MaxMediaTotalSize int64
 	// Configurações de reconexão
 	AutoReconnect bool
 }
@@ -87,6 +107,8 @@ func DefaultConfig() *ClientConfig {
 		MaxReconnectAttempts:     0,   // infinito
 		InitialReconnectInterval: 2,   // 2 segundos
 		AutoReconnect:            true,
+		MaxMediaFileSize:         DefaultMaxMediaFileSize,
+		MaxMediaTotalSize:        DefaultMaxMediaTotalSize,
 	}
 }
 
@@ -101,15 +123,27 @@ type Client struct {
 	qrCodeCallback           QRCallback
 	stateCallback            StateCallback
 	messageCallback          MessageCallback
+	reactionCallback         ReactionCallback
+	receiptCallback          ReceiptCallback
+	updateCallback           UpdateCallback
+	historyCallback          HistoryCallback
 	reconnectAttempts        int
 	reconnectInterval        time.Duration
 	reconnectTimer           *time.Timer
 	reconnectMutex           sync.Mutex
 	connectionMutex          sync.Mutex
+	qrLogin                  qrLoginStatus // andamento do Login por QR Code, protegido por connectionMutex
+	openLogin                func() (<-chan whatsmeow.QRChannelItem, error) // etapas do login que falam com o servidor, substituídas nos testes
+	requestPairCode          func(phone string) (string, error)
 	eventHandlerID           uint32
 	syncStore                SyncStore
+	mediaStore               *MediaStore
 	respondToGroups          bool
 	onlyIfMentioned          bool
+	lastGroupReply           map[string]time.Time   // horário da última resposta em cada grupo
+	recentMessages           map[string][]time.Time // mensagens recentes de cada participante, para o limite de flood
+	groupInfo                map[types.JID]*types.GroupInfo // participantes dos grupos, para a moderação não consultar o servidor a cada mensagem
+	groupMutex               sync.Mutex
 	lastReconnectTime        time.Time
 	maxReconnectTime         time.Duration
 	maxReconnectAttempts     int
@@ -145,14 +179,32 @@ func NewClient(config *ClientConfig) (*Client, error) {
 		qrCodeCallback:           config.OnQRCode,
 		stateCallback:            config.OnStateChange,
 		messageCallback:          config.OnMessage,
+		reactionCallback:         config.OnReaction,
+		receiptCallback:          config.OnReceipt,
+		updateCallback:           config.OnUpdate,
+		historyCallback:          config.OnHistory,
 		reconnectAttempts:        0,
 		reconnectInterval:        time.Duration(config.InitialReconnectInterval) * time.Second,
 		syncStore:                config.SyncStore,
+		lastGroupReply:           make(map[string]time.Time),
+		recentMessages:           make(map[string][]time.Time),
+		groupInfo:                make(map[types.JID]*types.GroupInfo),
 		maxReconnectTime:         time.Duration(config.MaxReconnectTime) * time.Second,
 		maxReconnectAttempts:     config.MaxReconnectAttempts,
 		initialReconnectInterval: time.Duration(config.InitialReconnectInterval) * time.Second,
 		autoReconnect:            config.AutoReconnect,
 	}
+	client.openLogin = client.openLoginChannel
+	client.requestPairCode = client.requestPairingCode
+
+	// Prepara a pasta de anexos, se configurada
+	if config.MediaDir != "" {
+		mediaStore, err := NewMediaStore(config.MediaDir, config.MaxMediaFileSize, config.MaxMediaTotalSize)
+		if err != nil {
+			return nil, err
+		}
+		client.mediaStore = mediaStore
+	}
 
 	// Inicializa o banco de dados
 	if err := client.initDatabase(); err != nil {
@@ -227,53 +279,46 @@ func (c *Client) Connect() error {
 	return nil
 }
 
-// Login faz login no WhatsApp via QR Code
+// Login faz login no WhatsApp via QR Code. Com uma sessão salva, apenas conecta
 func (c *Client) Login() error {
 	if c.client == nil {
 		return ErrClientNotInitialized
 	}
 
-	if c.state != StateConnected {
-		if err := c.Connect(); err != nil {
-			return fmt.Errorf("erro ao conectar: %w", err)
+	if c.client.Store.ID != nil {
+		if c.client.IsConnected() {
+			return nil
 		}
+		return c.Connect()
 	}
 
-	qrChan, _ := c.client.GetQRChannel(context.Background())
-	err := c.client.Connect()
+	c.connectionMutex.Lock()
+	qrChan, err := c.openLogin()
 	if err != nil {
-		return fmt.Errorf("erro ao conectar para login: %w", err)
+		c.connectionMutex.Unlock()
+		return err
 	}
+	// O mutex não fica preso enquanto os códigos são exibidos, para que PairPhone use a mesma conexão
+	c.qrLogin = qrLoginConnecting
+	c.connectionMutex.Unlock()
+	defer c.setQRLogin(qrLoginIdle)
 
+	// O pacote não escreve no terminal: quem quiser o QR Code nele usa TerminalQR como callback
 	for evt := range qrChan {
 		if evt.Event == "code" {
+			c.setQRLogin(qrLoginShowing)
 			if c.qrCodeCallback != nil {
 				c.qrCodeCallback(evt.Code)
 			} else {
-				// Exibe o QR Code no terminal
-				config := qrterminal.Config{
-					Level:     qrterminal.M,
-					Writer:    os.Stdout,
-					BlackChar: qrterminal.WHITE,
-					WhiteChar: qrterminal.BLACK,
-					QuietZone: 1,
-				}
-
-				// No Windows, use caracteres ASCII em vez de Unicode
-				if runtime.GOOS == "windows" {
-					config.BlackChar = qrterminal.BLACK
-					config.WhiteChar = qrterminal.WHITE
-					qrterminal.GenerateWithConfig(evt.Code, config)
-				} else {
-					// No macOS e Linux, use caracteres Unicode para melhor resolução
-					qrterminal.GenerateHalfBlock(evt.Code, qrterminal.L, os.Stdout)
-				}
-
-				fmt.Println("Escaneie o QR Code acima com o WhatsApp no seu celular")
+				c.log.Warnf("QR Code de login gerado, mas nenhum callback de QR Code foi definido")
 			}
 		} else if evt.Event == "success" {
 			c.updateState(StateLoggedIn, nil)
 			return nil
+		} else {
+			err := loginChannelError(evt)
+			c.updateState(StateError, err)
+			return err
 		}
 	}
 
@@ -303,6 +348,15 @@ func (c *Client) Logout() error {
 	return nil
 }
 
+// Disconnect encerra a conexão mantendo a sessão, que pode ser retomada com Connect
+func (c *Client) Disconnect() {
+	if c.client == nil {
+		return
+	}
+	c.client.Disconnect()
+	c.updateState(StateDisconnected, nil)
+}
+
 // Close fecha a conexão com o WhatsApp
 func (c *Client) Close() {
 	if c.client != nil {
@@ -328,6 +382,12 @@ func (c *Client) IsLoggedIn() bool {
 
 // SendMessage envia uma mensagem para um contato
 func (c *Client) SendMessage(jid, message string) error {
+	_, err := c.SendText(jid, message, nil)
+	return err
+}
+
+// SendTyping envia o indicador "digitando..." para um chat, ou o encerra quando typing é falso
+func (c *Client) SendTyping(jid string, typing bool) error {
 	if c.client == nil {
 		return ErrClientNotInitialized
 	}
@@ -341,13 +401,14 @@ func (c *Client) SendMessage(jid, message string) error {
 		return fmt.Errorf("JID inválido: %w", err)
 	}
 
-	msg := &waProto.Message{
-		Conversation: proto.String(message),
+	state := types.ChatPresencePaused
+	if typing {
+		state = types.ChatPresenceComposing
 	}
 
-	_, err = c.client.SendMessage(context.Background(), recipient, msg)
+	err = c.client.SendChatPresence(recipient, state, types.ChatPresenceMediaText)
 	if err != nil {
-		return fmt.Errorf("erro ao enviar mensagem: %w", err)
+		return fmt.Errorf("erro ao enviar presença: %w", err)
 	}
 
 	return nil
@@ -370,6 +431,26 @@ func (c *Client) SetMessageHandler(callback MessageCallback) {
 	c.messageCallback = callback
 }
 
+// SetReactionHandler define o handler para as reações recebidas
+func (c *Client) SetReactionHandler(callback ReactionCallback) {
+	c.reactionCallback = callback
+}
+
+// SetReceiptHandler define o handler para as confirmações de entrega e leitura das mensagens enviadas
+func (c *Client) SetReceiptHandler(callback ReceiptCallback) {
+	c.receiptCallback = callback
+}
+
+// SetUpdateHandler define o handler para as mensagens editadas ou apagadas
+func (c *Client) SetUpdateHandler(callback UpdateCallback) {
+	c.updateCallback = callback
+}
+
+// SetHistoryHandler define o handler para os lotes de conversas antigas sincronizados após o pareamento
+func (c *Client) SetHistoryHandler(callback HistoryCallback) {
+	c.historyCallback = callback
+}
+
 // updateState atualiza o estado da conexão e notifica o callback
 func (c *Client) updateState(state ConnectionState, err error) {
 	c.state = state
@@ -382,63 +463,116 @@ func (c *Client) updateState(state ConnectionState, err error) {
 func (c *Client) eventHandler(evt interface{}) {
 	switch v := evt.(type) {
 	case *events.Message:
-		if c.messageCallback != nil {
-			// Ignora mensagens de grupos se configurado para não responder
-			if v.Info.IsGroup && !c.respondToGroups {
-				return
+		// Reações são entregues separadamente, inclusive as de grupos que o assistente não responde
+		if reaction := parseReaction(v); reaction != nil {
+			if c.reactionCallback != nil {
+				c.reactionCallback(reaction)
 			}
+			return
+		}
 
-			// Se configurado para responder apenas se mencionado em grupos
-			if v.Info.IsGroup && c.onlyIfMentioned {
-				// Verifica se o bot foi mencionado
-				if c.onlyIfMentioned && v.Message.ExtendedTextMessage != nil && v.Message.ExtendedTextMessage.ContextInfo != nil {
-					mentioned := false
-					if v.Message.ExtendedTextMessage.ContextInfo.MentionedJID != nil {
-						for _, jid := range v.Message.ExtendedTextMessage.ContextInfo.MentionedJID {
-							if jid == c.client.Store.ID.String() {
-								mentioned = true
-								break
-							}
-						}
-					}
-					if !mentioned {
-						return
-					}
-				}
+		// Edições e exclusões chegam como mensagens de protocolo que apontam para a mensagem original
+		if update := parseMessageUpdate(v); update != nil {
+			if c.updateCallback != nil {
+				c.updateCallback(update)
 			}
+			return
+		}
 
-			// Extrai a mensagem
-			var msgText string
-			if v.Message.Conversation != nil {
-				msgText = *v.Message.Conversation
-			} else if v.Message.ExtendedTextMessage != nil && v.Message.ExtendedTextMessage.Text != nil {
-				msgText = *v.Message.ExtendedTextMessage.Text
-			} else {
-				// Ignora mensagens que não são de texto
+		if c.messageCallback != nil {
+			// Converte a mensagem no modelo tipado; reações e mensagens de protocolo são ignoradas
+			msg := parseMessage(v)
+			if msg == nil {
 				return
 			}
 
-			// Obtém informações do remetente
-			senderJID := v.Info.Sender.String()
-			senderName := v.Info.PushName
+			// Nos grupos, mensagens que violam as regras de moderação não são respondidas,
+			// e a política do grupo decide se as demais são
+			if msg.IsGroup {
+				if c.moderateMessage(msg, v) {
+					return
+				}
+				msg.Mentioned = mentions(messageContextInfo(v.Message), c.ownJIDs()...)
+				if !c.allowGroupMessage(msg, v.Info.Chat) {
+					return
+				}
+			}
 
 			// Sincroniza o contato se o SyncStore estiver configurado
 			if c.syncStore != nil {
-				phone := v.Info.Sender.User
-				err := c.syncStore.SincronizarContato(senderJID, senderName, phone)
-				if err != nil {
+				contact := Contact{JID: msg.SenderJID, PushName: msg.SenderName}
+				if v.Info.Sender.Server == types.DefaultUserServer {
+					contact.Phone = v.Info.Sender.User
+				}
+				if err := c.syncStore.SincronizarContato(contact); err != nil {
 					c.log.Errorf("Erro ao sincronizar contato: %v", err)
 				}
 			}
 
+			// O download da mídia pode levar minutos; ele e a entrega da mensagem seguem em outra
+			// goroutine para não atrasar os demais eventos, como recibos e as próximas mensagens
+			if msg.Attachment != nil {
+				go func() {
+					c.downloadAttachment(msg.Attachment)
+					if msg.Attachment.Err != nil {
+						c.log.Warnf("Anexo da mensagem %s não baixado: %v", msg.ID, msg.Attachment.Err)
+					}
+					c.messageCallback(msg)
+				}()
+				return
+			}
+
 			// Chama o callback
-			c.messageCallback(senderJID, senderName, msgText)
+			c.messageCallback(msg)
+		}
+
+	case *events.HistorySync:
+		if c.historyCallback != nil && isHistoryBatch(v.Data) {
+			batch := c.parseHistorySync(v)
+			c.log.Infof("Histórico sincronizado: %d mensagens, %d nomes (%d%%)", len(batch.Messages), len(batch.Names), batch.Progress)
+			c.historyCallback(batch)
+		}
+
+	// Alterações de contatos e grupos mantêm a sincronização em dia sem repetir a sincronização completa
+	case *events.Contact:
+		c.syncContact(v.JID)
+
+	case *events.PushName:
+		c.syncContact(v.JID)
+
+	case *events.BusinessName:
+		c.syncContact(v.JID)
+
+	case *events.GroupInfo:
+		// A alteração pode ter promovido ou rebaixado administradores
+		c.forgetGroupInfo(v.JID)
+		go c.syncGroup(v.JID)
+		if len(v.Join) > 0 || len(v.Leave) > 0 {
+			go c.greetParticipants(v)
+		}
+
+	case *events.JoinedGroup:
+		c.cacheGroupInfo(&v.GroupInfo)
+		if c.syncStore != nil {
+			if err := c.syncStore.SincronizarGrupo(groupFromInfo(&v.GroupInfo)); err != nil {
+				c.log.Errorf("Erro ao sincronizar grupo: %v", err)
+			}
+		}
+
+	case *events.Receipt:
+		if receipt := parseReceipt(v); receipt != nil && c.receiptCallback != nil {
+			c.receiptCallback(receipt)
 		}
 
 	case *events.Connected:
 		c.updateState(StateConnected, nil)
 		c.resetReconnectAttempts()
 
+		// O WhatsApp só repassa o "digitando..." de clientes marcados como disponíveis
+		if err := c.client.SendPresence(types.PresenceAvailable); err != nil {
+			c.log.Warnf("Erro ao enviar presença disponível: %v", err)
+		}
+
 	case *events.LoggedOut:
 		c.updateState(StateDisconnected, nil)
 		if c.autoReconnect {
@@ -534,15 +668,4 @@ func (c *Client) AddEventHandler(handler func(interface{})) uint32 {
 	return c.client.AddEventHandler(handler)
 }
 
-// SyncContacts sincroniza contatos do WhatsApp com o banco de dados local
-func (c *Client) SyncContacts() error {
-	if c.syncStore == nil {
-		return ErrSyncStoreNotSet
-	}
-
-	// Implementação básica - em uma versão real, você sincronizaria os contatos
-	// do WhatsApp com o banco de dados local
-	return nil
-}
-
 // Funções utilitárias movidas para utils.go
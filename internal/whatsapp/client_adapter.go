@@ -51,6 +51,11 @@ func (c *ClientAdapter) Login() error {
 	return c.client.Login()
 }
 
+// PairPhone faz login pelo código de pareamento enviado ao telefone, em vez do QR Code
+func (c *ClientAdapter) PairPhone(phone string) (string, error) {
+	return c.client.PairPhone(phone)
+}
+
 // Logout encerra a sessão no WhatsApp
 func (c *ClientAdapter) Logout() error {
 	return c.client.Logout()
@@ -66,7 +71,7 @@ func (c *ClientAdapter) RegisterEventHandler(handler func(interface{})) {
 // Adapta a assinatura antiga para a nova implementação
 func (c *ClientAdapter) SetupMessageHandler(handler MessageHandler) {
 	// Configura o callback de mensagens no cliente refatorado
-	c.client.SetMessageHandler(func(jid string, sender string, message string) {
+	c.SetMessageHandler(func(jid string, sender string, message string) {
 		// Chama o handler antigo com os parâmetros adaptados
 		response, err := handler(sender, message)
 		if err == nil && response != "" {
@@ -81,6 +86,31 @@ func (c *ClientAdapter) SendMessage(recipient string, message string) error {
 	return c.client.SendMessage(recipient, message)
 }
 
+// SendImage envia uma imagem com legenda opcional e retorna o ID da mensagem
+func (c *ClientAdapter) SendImage(recipient string, data []byte, mimeType, caption string) (string, error) {
+	return c.client.SendImage(recipient, data, mimeType, caption)
+}
+
+// SendDocument envia um arquivo com o nome exibido ao contato
+func (c *ClientAdapter) SendDocument(recipient string, data []byte, mimeType, fileName, caption string) (string, error) {
+	return c.client.SendDocument(recipient, data, mimeType, fileName, caption)
+}
+
+// SendAudio envia um áudio, como arquivo ou como mensagem de voz
+func (c *ClientAdapter) SendAudio(recipient string, data []byte, mimeType string, seconds uint32, voiceNote bool) (string, error) {
+	return c.client.SendAudio(recipient, data, mimeType, seconds, voiceNote)
+}
+
+// SendLocation envia uma localização
+func (c *ClientAdapter) SendLocation(recipient string, latitude, longitude float64, name, address string) (string, error) {
+	return c.client.SendLocation(recipient, latitude, longitude, name, address)
+}
+
+// SendContact envia um cartão de contato no formato vCard
+func (c *ClientAdapter) SendContact(recipient, displayName, vcard string) (string, error) {
+	return c.client.SendContact(recipient, displayName, vcard)
+}
+
 // SetQRCallback define a função de callback para o QR Code
 func (c *ClientAdapter) SetQRCallback(handler func(string)) {
 	c.client.SetQRCallback(handler)
@@ -96,7 +126,18 @@ func (c *ClientAdapter) SetConnectionCallback(callback func(string)) {
 }
 
 // SetMessageHandler define a função de handler para mensagens
+// Como no cliente original, apenas as mensagens de texto são repassadas
 func (c *ClientAdapter) SetMessageHandler(handler func(string, string, string)) {
+	c.client.SetMessageHandler(func(msg *IncomingMessage) {
+		if msg.Type != MessageTypeText {
+			return
+		}
+		handler(msg.SenderJID, msg.SenderName, msg.Text)
+	})
+}
+
+// SetIncomingMessageHandler define o handler que recebe as mensagens de todos os tipos
+func (c *ClientAdapter) SetIncomingMessageHandler(handler MessageCallback) {
 	c.client.SetMessageHandler(handler)
 }
 
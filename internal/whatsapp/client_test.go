@@ -100,7 +100,7 @@ func TestMessageHandler(t *testing.T) {
 	client := &Client{}
 
 	// Testa handler de mensagem com callback vazio
-	client.SetMessageHandler(func(jid string, senderName string, message string) {
+	client.SetMessageHandler(func(msg *IncomingMessage) {
 		// Neste teste, apenas verifica se não há pânico ao chamar
 	})
 
@@ -110,17 +110,22 @@ func TestMessageHandler(t *testing.T) {
 	expectedSender := "Contato Teste"
 	expectedMessage := "Olá, como vai?"
 
-	client.SetMessageHandler(func(jid string, senderName string, message string) {
+	client.SetMessageHandler(func(msg *IncomingMessage) {
 		messageCalled = true
-		if jid != expectedJID || senderName != expectedSender || message != expectedMessage {
+		if msg.SenderJID != expectedJID || msg.SenderName != expectedSender || msg.Text != expectedMessage {
 			t.Errorf("Mensagem recebida incorreta: jid=%s, sender=%s, message=%s",
-				jid, senderName, message)
+				msg.SenderJID, msg.SenderName, msg.Text)
 		}
 	})
 
 	// Simula mensagem recebida chamando diretamente o callback
 	if client.messageCallback != nil {
-		client.messageCallback(expectedJID, expectedSender, expectedMessage)
+		client.messageCallback(&IncomingMessage{
+			SenderJID:  expectedJID,
+			SenderName: expectedSender,
+			Type:       MessageTypeText,
+			Text:       expectedMessage,
+		})
 	}
 
 	if !messageCalled {
@@ -173,9 +178,9 @@ func TestClientRefactored(t *testing.T) {
 	})
 
 	// Define o handler para mensagens
-	client.SetMessageHandler(func(jid, sender, message string) {
+	client.SetMessageHandler(func(msg *IncomingMessage) {
 		messageReceived = true
-		t.Logf("Mensagem recebida de %s (%s): %s", sender, jid, message)
+		t.Logf("Mensagem recebida de %s (%s): %s", msg.SenderName, msg.SenderJID, msg.Summary())
 	})
 
 	// Testa o adaptador usando ClientAdapter
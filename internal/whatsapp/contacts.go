@@ -0,0 +1,173 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Contact é um contato do WhatsApp com os nomes conhecidos pela conta. Campos vazios não são
+// conhecidos e não devem apagar o valor já salvo
+type Contact struct {
+	JID   string
+	Phone string
+	// FullName é o nome salvo na agenda do celular
+	FullName string
+	// PushName é o nome que o próprio contato definiu no WhatsApp
+	PushName string
+	// BusinessName é o nome verificado das contas comerciais
+	BusinessName string
+}
+
+// DisplayName retorna o melhor nome conhecido do contato: o da agenda, o comercial, o público ou o telefone
+func (c Contact) DisplayName() string {
+	for _, name := range []string{c.FullName, c.BusinessName, c.PushName, c.Phone} {
+		if name != "" {
+			return name
+		}
+	}
+	return c.JID
+}
+
+// GroupParticipant é um membro de um grupo
+type GroupParticipant struct {
+	JID          string
+	Phone        string // vazio quando o WhatsApp oculta o número do participante
+	IsAdmin      bool
+	IsSuperAdmin bool // criador do grupo
+}
+
+// Group é um grupo do qual a conta participa
+type Group struct {
+	JID          string
+	Name         string
+	Topic        string // descrição do grupo
+	OwnerJID     string
+	Created      time.Time
+	Participants []GroupParticipant
+}
+
+// contactFromInfo converte um contato do armazenamento do whatsmeow
+func contactFromInfo(jid types.JID, info types.ContactInfo) Contact {
+	contact := Contact{
+		JID:          jid.ToNonAD().String(),
+		FullName:     info.FullName,
+		PushName:     info.PushName,
+		BusinessName: info.BusinessName,
+	}
+	if contact.FullName == "" {
+		contact.FullName = info.FirstName
+	}
+	if jid.Server == types.DefaultUserServer {
+		contact.Phone = jid.User
+	}
+	return contact
+}
+
+// groupFromInfo converte as informações de um grupo do whatsmeow
+func groupFromInfo(info *types.GroupInfo) Group {
+	group := Group{
+		JID:          info.JID.String(),
+		Name:         info.Name,
+		Topic:        info.Topic,
+		Created:      info.GroupCreated,
+		Participants: make([]GroupParticipant, 0, len(info.Participants)),
+	}
+	if !info.OwnerJID.IsEmpty() {
+		group.OwnerJID = info.OwnerJID.String()
+	}
+
+	for _, p := range info.Participants {
+		participant := GroupParticipant{
+			JID:          p.JID.String(),
+			IsAdmin:      p.IsAdmin || p.IsSuperAdmin,
+			IsSuperAdmin: p.IsSuperAdmin,
+		}
+		// Em grupos com LID, o número só vem em PhoneNumber, quando o WhatsApp o revela
+		if !p.PhoneNumber.IsEmpty() {
+			participant.Phone = p.PhoneNumber.User
+		} else if p.JID.Server == types.DefaultUserServer {
+			participant.Phone = p.JID.User
+		}
+		group.Participants = append(group.Participants, participant)
+	}
+	return group
+}
+
+// SyncContacts sincroniza com o SyncStore os contatos do armazenamento do whatsmeow,
+// alimentado pela agenda do celular e pelas mensagens recebidas, e os grupos dos quais a conta participa.
+// Depois da primeira sincronização, as alterações chegam pelos eventos do WhatsApp
+func (c *Client) SyncContacts() error {
+	if c.syncStore == nil {
+		return ErrSyncStoreNotSet
+	}
+	if c.client == nil {
+		return ErrClientNotInitialized
+	}
+	if !c.client.IsLoggedIn() {
+		return ErrNotLoggedIn
+	}
+
+	contacts, err := c.client.Store.Contacts.GetAllContacts(context.Background())
+	if err != nil {
+		return fmt.Errorf("erro ao ler contatos: %w", err)
+	}
+
+	var errs []error
+	for jid, info := range contacts {
+		if err := c.syncStore.SincronizarContato(contactFromInfo(jid, info)); err != nil {
+			errs = append(errs, fmt.Errorf("contato %s: %w", jid, err))
+		}
+	}
+
+	groups, err := c.client.GetJoinedGroups()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("erro ao listar grupos: %w", err))
+	}
+	for _, info := range groups {
+		c.cacheGroupInfo(info)
+		if err := c.syncStore.SincronizarGrupo(groupFromInfo(info)); err != nil {
+			errs = append(errs, fmt.Errorf("grupo %s: %w", info.JID, err))
+		}
+	}
+
+	c.log.Infof("Sincronização concluída: %d contatos, %d grupos, %d erros", len(contacts), len(groups), len(errs))
+	return errors.Join(errs...)
+}
+
+// syncContact sincroniza um contato alterado, lendo do armazenamento do whatsmeow os nomes já atualizados
+func (c *Client) syncContact(jid types.JID) {
+	if c.syncStore == nil || (jid.Server != types.DefaultUserServer && jid.Server != types.HiddenUserServer) {
+		return
+	}
+
+	info, err := c.client.Store.Contacts.GetContact(context.Background(), jid)
+	if err != nil {
+		c.log.Warnf("Erro ao ler contato %s: %v", jid, err)
+		return
+	}
+	if err := c.syncStore.SincronizarContato(contactFromInfo(jid, info)); err != nil {
+		c.log.Errorf("Erro ao sincronizar contato: %v", err)
+	}
+}
+
+// syncGroup busca no servidor e sincroniza um grupo alterado. Deve rodar fora do handler de eventos,
+// que não pode esperar respostas do servidor
+func (c *Client) syncGroup(jid types.JID) {
+	if c.syncStore == nil {
+		return
+	}
+
+	info, err := c.client.GetGroupInfo(jid)
+	if err != nil {
+		c.log.Warnf("Erro ao buscar grupo %s: %v", jid, err)
+		return
+	}
+	c.cacheGroupInfo(info)
+	if err := c.syncStore.SincronizarGrupo(groupFromInfo(info)); err != nil {
+		c.log.Errorf("Erro ao sincronizar grupo: %v", err)
+	}
+}
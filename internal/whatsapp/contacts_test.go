@@ -0,0 +1,101 @@
+package whatsapp
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestContactFromInfo(t *testing.T) {
+	telefone := types.NewJID("5511999999999", types.DefaultUserServer)
+
+	tests := []struct {
+		name    string
+		jid     types.JID
+		info    types.ContactInfo
+		contato Contact
+		exibido string
+	}{
+		{
+			name:    "Agenda",
+			jid:     telefone,
+			info:    types.ContactInfo{Found: true, FirstName: "Maria", FullName: "Maria Souza", PushName: "Mari"},
+			contato: Contact{JID: telefone.String(), Phone: "5511999999999", FullName: "Maria Souza", PushName: "Mari"},
+			exibido: "Maria Souza",
+		},
+		{
+			name:    "ApenasPrimeiroNome",
+			jid:     telefone,
+			info:    types.ContactInfo{Found: true, FirstName: "Maria"},
+			contato: Contact{JID: telefone.String(), Phone: "5511999999999", FullName: "Maria"},
+			exibido: "Maria",
+		},
+		{
+			name:    "Comercial",
+			jid:     telefone,
+			info:    types.ContactInfo{Found: true, PushName: "Padaria", BusinessName: "Padaria Pão Quente"},
+			contato: Contact{JID: telefone.String(), Phone: "5511999999999", PushName: "Padaria", BusinessName: "Padaria Pão Quente"},
+			exibido: "Padaria Pão Quente",
+		},
+		{
+			name:    "SemNome",
+			jid:     types.NewADJID("5511999999999", 0, 2),
+			info:    types.ContactInfo{},
+			contato: Contact{JID: telefone.String(), Phone: "5511999999999"},
+			exibido: "5511999999999",
+		},
+		{
+			name:    "LIDSemTelefone",
+			jid:     types.NewJID("123456789", types.HiddenUserServer),
+			info:    types.ContactInfo{Found: true, PushName: "João"},
+			contato: Contact{JID: "123456789@lid", PushName: "João"},
+			exibido: "João",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contato := contactFromInfo(tt.jid, tt.info)
+			if contato != tt.contato {
+				t.Errorf("Contato incorreto: %+v, esperava %+v", contato, tt.contato)
+			}
+			if nome := contato.DisplayName(); nome != tt.exibido {
+				t.Errorf("Nome exibido incorreto: %q, esperava %q", nome, tt.exibido)
+			}
+		})
+	}
+}
+
+func TestGroupFromInfo(t *testing.T) {
+	dono := types.NewJID("5511999999999", types.DefaultUserServer)
+	info := &types.GroupInfo{
+		JID:        types.NewJID("120363000000000000", types.GroupServer),
+		OwnerJID:   dono,
+		GroupName:  types.GroupName{Name: "Clientes"},
+		GroupTopic: types.GroupTopic{Topic: "Dúvidas sobre pedidos"},
+		Participants: []types.GroupParticipant{
+			{JID: dono, IsSuperAdmin: true},
+			{JID: types.NewJID("123456789", types.HiddenUserServer), PhoneNumber: types.NewJID("5511888888888", types.DefaultUserServer), IsAdmin: true},
+			{JID: types.NewJID("987654321", types.HiddenUserServer)},
+		},
+	}
+
+	grupo := groupFromInfo(info)
+	if grupo.JID != "120363000000000000@g.us" || grupo.Name != "Clientes" || grupo.Topic != "Dúvidas sobre pedidos" || grupo.OwnerJID != dono.String() {
+		t.Errorf("Grupo incorreto: %+v", grupo)
+	}
+
+	esperados := []GroupParticipant{
+		{JID: dono.String(), Phone: "5511999999999", IsAdmin: true, IsSuperAdmin: true},
+		{JID: "123456789@lid", Phone: "5511888888888", IsAdmin: true},
+		{JID: "987654321@lid"},
+	}
+	if len(grupo.Participants) != len(esperados) {
+		t.Fatalf("Esperava %d participantes, obteve %d", len(esperados), len(grupo.Participants))
+	}
+	for i, p := range grupo.Participants {
+		if p != esperados[i] {
+			t.Errorf("Participante %d incorreto: %+v, esperava %+v", i, p, esperados[i])
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// CommandPrefix inicia os comandos enviados ao assistente nos grupos, como "/bot desativar"
+const CommandPrefix = "/bot"
+
+// GroupPolicy define como o assistente se comporta em um grupo
+type GroupPolicy struct {
+	// Enabled indica se o assistente responde no grupo; os comandos continuam aceitos quando desativado
+	Enabled bool
+	// MentionOnly restringe as respostas às mensagens que mencionam o assistente ou respondem a ele
+	MentionOnly bool
+	// Keywords são palavras que também acionam o assistente, sem diferenciar maiúsculas
+	Keywords []string
+	// Persona substitui o system prompt nas respostas do grupo; vazio usa o padrão
+	Persona string
+	// AdminOnlyCommands restringe os comandos aos administradores do grupo
+	AdminOnlyCommands bool
+	// Cooldown é o intervalo mínimo entre duas respostas no grupo
+	Cooldown time.Duration
+}
+
+// allows informa se a mensagem do grupo deve ser respondida, dada a última resposta enviada nele.
+// Comandos sempre passam, para que o grupo possa reativar o assistente
+func (p GroupPolicy) allows(msg *IncomingMessage, lastReply, now time.Time) bool {
+	if IsGroupCommand(msg.Text) {
+		return true
+	}
+	if !p.Enabled {
+		return false
+	}
+	if (p.MentionOnly || len(p.Keywords) > 0) && !msg.Mentioned && !containsKeyword(msg.Text, p.Keywords) {
+		return false
+	}
+	return p.Cooldown <= 0 || now.Sub(lastReply) >= p.Cooldown
+}
+
+// containsKeyword informa se o texto contém alguma das palavras-chave
+func containsKeyword(text string, keywords []string) bool {
+	return matchKeyword(text, keywords) != ""
+}
+
+// matchKeyword retorna a primeira palavra-chave contida no texto, ou vazio
+func matchKeyword(text string, keywords []string) string {
+	text = strings.ToLower(text)
+	for _, keyword := range keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" && strings.Contains(text, keyword) {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// IsGroupCommand informa se o texto é um comando para o assistente
+func IsGroupCommand(text string) bool {
+	_, _, ok := ParseGroupCommand(text)
+	return ok
+}
+
+// ParseGroupCommand separa o comando e os argumentos de um texto como "/bot intervalo 30".
+// Um "/bot" sozinho equivale ao comando "ajuda"
+func ParseGroupCommand(text string) (command string, args []string, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], CommandPrefix) {
+		return "", nil, false
+	}
+	if len(fields) == 1 {
+		return "ajuda", nil, true
+	}
+	return strings.ToLower(fields[1]), fields[2:], true
+}
+
+// messageContextInfo retorna as informações de contexto (menções e citação) de qualquer tipo de mensagem
+func messageContextInfo(m *waProto.Message) *waProto.ContextInfo {
+	switch {
+	case m == nil:
+		return nil
+	case m.ExtendedTextMessage != nil:
+		return m.ExtendedTextMessage.GetContextInfo()
+	case m.ImageMessage != nil:
+		return m.ImageMessage.GetContextInfo()
+	case m.VideoMessage != nil:
+		return m.VideoMessage.GetContextInfo()
+	case m.AudioMessage != nil:
+		return m.AudioMessage.GetContextInfo()
+	case m.DocumentMessage != nil:
+		return m.DocumentMessage.GetContextInfo()
+	case m.StickerMessage != nil:
+		return m.StickerMessage.GetContextInfo()
+	case m.LocationMessage != nil:
+		return m.LocationMessage.GetContextInfo()
+	case m.ContactMessage != nil:
+		return m.ContactMessage.GetContextInfo()
+	}
+	return nil
+}
+
+// mentions informa se a mensagem menciona a conta, por qualquer um dos seus JIDs (telefone ou LID),
+// ou se responde a uma mensagem enviada por ela
+func mentions(info *waProto.ContextInfo, own ...types.JID) bool {
+	if info == nil {
+		return false
+	}
+
+	isOwn := func(raw string) bool {
+		jid, err := types.ParseJID(raw)
+		if err != nil || jid.IsEmpty() {
+			return false
+		}
+		for _, o := range own {
+			if !o.IsEmpty() && jid.User == o.User && jid.Server == o.Server {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, jid := range info.GetMentionedJID() {
+		if isOwn(jid) {
+			return true
+		}
+	}
+	return info.GetStanzaID() != "" && isOwn(info.GetParticipant())
+}
+
+// ownJIDs retorna os JIDs da conta conectada, pelo telefone e pelo LID
+func (c *Client) ownJIDs() []types.JID {
+	if c.client == nil || c.client.Store == nil {
+		return nil
+	}
+	return []types.JID{c.client.Store.GetJID().ToNonAD(), c.client.Store.GetLID().ToNonAD()}
+}
+
+// groupPolicy retorna a política do grupo informada pelo SyncStore ou, se não houver uma,
+// a política padrão definida pelas configurações gerais de grupos
+func (c *Client) groupPolicy(chat types.JID) GroupPolicy {
+	policy := GroupPolicy{Enabled: c.respondToGroups, MentionOnly: c.onlyIfMentioned}
+	if c.syncStore == nil {
+		return policy
+	}
+
+	// As configurações gerais podem mudar com o cliente conectado
+	c.syncStore.GetRespondToGroupsConfig(&policy.Enabled, &policy.MentionOnly)
+
+	custom, err := c.syncStore.GetGroupPolicy(chat.String())
+	if err != nil {
+		c.log.Warnf("Erro ao ler a política do grupo %s: %v", chat, err)
+	} else if custom != nil {
+		policy = *custom
+	}
+	return policy
+}
+
+// allowGroupMessage aplica a política do grupo e registra o horário da resposta que será enviada
+func (c *Client) allowGroupMessage(msg *IncomingMessage, chat types.JID) bool {
+	policy := c.groupPolicy(chat)
+
+	c.groupMutex.Lock()
+	defer c.groupMutex.Unlock()
+
+	now := time.Now()
+	if !policy.allows(msg, c.lastGroupReply[msg.ChatJID], now) {
+		return false
+	}
+	if !IsGroupCommand(msg.Text) {
+		c.lastGroupReply[msg.ChatJID] = now
+	}
+	return true
+}
+
+// IsGroupAdmin consulta no servidor se o usuário é administrador do grupo. userJID pode ser
+// o JID pelo telefone ou pelo LID do participante
+func (c *Client) IsGroupAdmin(groupJID, userJID string) (bool, error) {
+	group, err := c.recipient(groupJID)
+	if err != nil {
+		return false, err
+	}
+	user, err := types.ParseJID(userJID)
+	if err != nil {
+		return false, fmt.Errorf("JID inválido: %w", err)
+	}
+	user = user.ToNonAD()
+
+	info, err := c.client.GetGroupInfo(group)
+	if err != nil {
+		return false, fmt.Errorf("erro ao buscar grupo: %w", err)
+	}
+	return isGroupAdmin(info, user), nil
+}
+
+// isGroupAdmin informa se algum dos JIDs (pelo telefone ou pelo LID) é de um administrador do grupo
+func isGroupAdmin(info *types.GroupInfo, users ...types.JID) bool {
+	for _, p := range info.Participants {
+		if !p.IsAdmin && !p.IsSuperAdmin {
+			continue
+		}
+		for _, user := range users {
+			if !user.IsEmpty() && (p.JID.ToNonAD() == user || p.PhoneNumber.ToNonAD() == user || p.LID.ToNonAD() == user) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package whatsapp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGroupPolicyAllows(t *testing.T) {
+	agora := time.Now()
+	texto := func(s string, mencionado bool) *IncomingMessage {
+		return &IncomingMessage{ChatJID: "120363000000000000@g.us", Text: s, IsGroup: true, Mentioned: mencionado}
+	}
+
+	tests := []struct {
+		name     string
+		policy   GroupPolicy
+		msg      *IncomingMessage
+		anterior time.Time
+		permite  bool
+	}{
+		{"Ativo", GroupPolicy{Enabled: true}, texto("Bom dia", false), time.Time{}, true},
+		{"Desativado", GroupPolicy{}, texto("Bom dia", true), time.Time{}, false},
+		{"ComandoComDesativado", GroupPolicy{}, texto("/bot ativar", false), time.Time{}, true},
+		{"SomenteMencaoSemMencao", GroupPolicy{Enabled: true, MentionOnly: true}, texto("Bom dia", false), time.Time{}, false},
+		{"SomenteMencaoComMencao", GroupPolicy{Enabled: true, MentionOnly: true}, texto("@bot bom dia", true), time.Time{}, true},
+		{"PalavraChave", GroupPolicy{Enabled: true, Keywords: []string{"preço", " Entrega "}}, texto("Qual o prazo de ENTREGA?", false), time.Time{}, true},
+		{"SemPalavraChave", GroupPolicy{Enabled: true, Keywords: []string{"preço"}}, texto("Bom dia", false), time.Time{}, false},
+		{"PalavraChaveComSomenteMencao", GroupPolicy{Enabled: true, MentionOnly: true, Keywords: []string{"preço"}}, texto("Qual o preço?", false), time.Time{}, true},
+		{"DentroDoIntervalo", GroupPolicy{Enabled: true, Cooldown: time.Minute}, texto("Bom dia", true), agora.Add(-30 * time.Second), false},
+		{"AposOIntervalo", GroupPolicy{Enabled: true, Cooldown: time.Minute}, texto("Bom dia", true), agora.Add(-2 * time.Minute), true},
+		{"ComandoDentroDoIntervalo", GroupPolicy{Enabled: true, Cooldown: time.Minute}, texto("/bot status", false), agora, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if permite := tt.policy.allows(tt.msg, tt.anterior, agora); permite != tt.permite {
+				t.Errorf("Decisão incorreta: %v, esperava %v", permite, tt.permite)
+			}
+		})
+	}
+}
+
+func TestParseGroupCommand(t *testing.T) {
+	tests := []struct {
+		texto   string
+		comando string
+		args    []string
+		ok      bool
+	}{
+		{"/bot desativar", "desativar", []string{}, true},
+		{"  /BOT Intervalo 30 ", "intervalo", []string{"30"}, true},
+		{"/bot", "ajuda", nil, true},
+		{"/bots ativar", "", nil, false},
+		{"Olá /bot ativar", "", nil, false},
+		{"", "", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.texto, func(t *testing.T) {
+			comando, args, ok := ParseGroupCommand(tt.texto)
+			if comando != tt.comando || ok != tt.ok || len(args) != len(tt.args) || (len(args) > 0 && !reflect.DeepEqual(args, tt.args)) {
+				t.Errorf("Comando incorreto: %q %v %v", comando, args, ok)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	telefone := types.NewJID("5511999999999", types.DefaultUserServer)
+	lid := types.NewJID("123456789", types.HiddenUserServer)
+
+	tests := []struct {
+		name       string
+		info       *waProto.ContextInfo
+		mencionado bool
+	}{
+		{"SemContexto", nil, false},
+		{"MencaoPeloTelefone", &waProto.ContextInfo{MentionedJID: []string{"5511999999999@s.whatsapp.net"}}, true},
+		{"MencaoPeloLID", &waProto.ContextInfo{MentionedJID: []string{"5511888888888@s.whatsapp.net", "123456789@lid"}}, true},
+		{"MencaoAOutroContato", &waProto.ContextInfo{MentionedJID: []string{"5511888888888@s.whatsapp.net"}}, false},
+		{"RespostaAoAssistente", &waProto.ContextInfo{StanzaID: proto.String("3EB0"), Participant: proto.String("123456789@lid")}, true},
+		{"RespostaAOutroContato", &waProto.ContextInfo{StanzaID: proto.String("3EB0"), Participant: proto.String("5511888888888@s.whatsapp.net")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if mencionado := mentions(tt.info, telefone, lid, types.EmptyJID); mencionado != tt.mencionado {
+				t.Errorf("Menção incorreta: %v, esperava %v", mencionado, tt.mencionado)
+			}
+		})
+	}
+
+	// As menções também chegam em legendas de mídia
+	imagem := &waProto.Message{ImageMessage: &waProto.ImageMessage{ContextInfo: &waProto.ContextInfo{MentionedJID: []string{telefone.String()}}}}
+	if !mentions(messageContextInfo(imagem), telefone) {
+		t.Errorf("Menção na legenda da imagem não detectada")
+	}
+}
+
+func TestMentionText(t *testing.T) {
+	tests := []struct {
+		texto    string
+		mencoes  []string
+		esperado string
+	}{
+		{"Abrimos às 9h.", []string{"5511999999999@s.whatsapp.net"}, "@5511999999999 Abrimos às 9h."},
+		{"@5511999999999, abrimos às 9h.", []string{"5511999999999@s.whatsapp.net"}, "@5511999999999, abrimos às 9h."},
+		{"Olá!", []string{"123456789@lid", "5511888888888@s.whatsapp.net"}, "@123456789 @5511888888888 Olá!"},
+	}
+
+	for _, tt := range tests {
+		if texto := mentionText(tt.texto, tt.mencoes); texto != tt.esperado {
+			t.Errorf("Texto incorreto: %q, esperava %q", texto, tt.esperado)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package whatsapp
+
+import (
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// HistoryBatch é um lote de conversas antigas enviado pelo WhatsApp depois do pareamento.
+// A sincronização completa chega em vários lotes, ao longo de alguns minutos
+type HistoryBatch struct {
+	// Messages são as mensagens das conversas do lote, enviadas e recebidas, sem as mídias baixadas
+	Messages []*IncomingMessage
+	// Names associa o JID de cada contato ao nome exibido no WhatsApp
+	Names map[string]string
+	// Progress é o avanço da sincronização informado pelo WhatsApp, de 0 a 100 (0 quando não informado)
+	Progress int
+	// Skipped conta as mensagens que não puderam ser lidas
+	Skipped int
+}
+
+// parseHistorySync converte um lote da sincronização de histórico. Reações, mensagens de protocolo
+// e mensagens sem conteúdo próprio são ignoradas, como nas mensagens recebidas em tempo real
+func (c *Client) parseHistorySync(v *events.HistorySync) *HistoryBatch {
+	data := v.Data
+	batch := &HistoryBatch{
+		Names:    make(map[string]string),
+		Progress: int(data.GetProgress()),
+	}
+
+	for _, pushName := range data.GetPushnames() {
+		if pushName.GetID() != "" && pushName.GetPushname() != "" {
+			batch.Names[pushName.GetID()] = pushName.GetPushname()
+		}
+	}
+
+	for _, conv := range data.GetConversations() {
+		chatJID, err := types.ParseJID(conv.GetID())
+		if err != nil {
+			batch.Skipped += len(conv.GetMessages())
+			continue
+		}
+
+		// O nome da conversa vale para os contatos sem nome público conhecido
+		name := conv.GetName()
+		if name == "" {
+			name = conv.GetDisplayName()
+		}
+		if _, ok := batch.Names[chatJID.String()]; !ok && name != "" && !chatJID.IsEmpty() && chatJID.Server != types.GroupServer {
+			batch.Names[chatJID.String()] = name
+		}
+
+		for _, item := range conv.GetMessages() {
+			evt, err := c.client.ParseWebMessage(chatJID, item.GetMessage())
+			if err != nil {
+				batch.Skipped++
+				continue
+			}
+			if msg := parseMessage(evt); msg != nil {
+				if msg.SenderName == "" {
+					msg.SenderName = batch.Names[msg.SenderJID]
+				}
+				batch.Messages = append(batch.Messages, msg)
+			}
+		}
+	}
+	return batch
+}
+
+// isHistoryBatch informa se o lote traz conversas ou nomes; os demais tipos de sincronização,
+// como os status e as configurações, não interessam ao histórico
+func isHistoryBatch(data *waHistorySync.HistorySync) bool {
+	switch data.GetSyncType() {
+	case waHistorySync.HistorySync_INITIAL_BOOTSTRAP, waHistorySync.HistorySync_RECENT,
+		waHistorySync.HistorySync_FULL, waHistorySync.HistorySync_PUSH_NAME, waHistorySync.HistorySync_ON_DEMAND:
+		return true
+	default:
+		return false
+	}
+}
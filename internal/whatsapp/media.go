@@ -0,0 +1,211 @@
+package whatsapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limites padrão da pasta de anexos
+const (
+	DefaultMaxMediaFileSize  int64 = 16 << 20 // 16 MB por arquivo
+	DefaultMaxMediaTotalSize int64 = 1 << 30  // 1 GB no total
+	// mediaDownloadTimeout limita a duração do download de um anexo
+	mediaDownloadTimeout = 2 * time.Minute
+)
+
+// Erros da pasta de anexos
+var (
+	ErrMediaTooLarge      = errors.New("anexo maior que o limite por arquivo")
+	ErrMediaQuotaExceeded = errors.New("limite de espaço da pasta de anexos atingido")
+)
+
+// Extensões preferidas para os tipos de mídia mais comuns do WhatsApp; os demais usam a
+// tabela de tipos do sistema
+var mediaExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"audio/ogg":       ".ogg",
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/aac":       ".aac",
+	"video/mp4":       ".mp4",
+	"video/3gpp":      ".3gp",
+	"application/pdf": ".pdf",
+}
+
+// MediaStore guarda os anexos baixados em uma pasta endereçada pelo conteúdo: cada arquivo
+// é nomeado pelo seu SHA-256, de modo que a mesma mídia recebida várias vezes ocupa espaço uma vez só
+type MediaStore struct {
+	Dir string
+	// MaxFileSize é o tamanho máximo de cada anexo, em bytes
+	MaxFileSize int64
+	// MaxTotalSize é o espaço máximo ocupado pela pasta, em bytes
+	MaxTotalSize int64
+
+	mutex   sync.Mutex
+	used    int64
+	scanned bool
+}
+
+// NewMediaStore cria a pasta de anexos. Limites zerados usam os valores padrão
+func NewMediaStore(dir string, maxFileSize, maxTotalSize int64) (*MediaStore, error) {
+	if err := createDirIfNotExists(dir); err != nil {
+		return nil, fmt.Errorf("erro ao criar pasta de anexos: %w", err)
+	}
+	if maxFileSize <= 0 {
+		maxFileSize = DefaultMaxMediaFileSize
+	}
+	if maxTotalSize <= 0 {
+		maxTotalSize = DefaultMaxMediaTotalSize
+	}
+
+	return &MediaStore{
+		Dir:          dir,
+		MaxFileSize:  maxFileSize,
+		MaxTotalSize: maxTotalSize,
+	}, nil
+}
+
+// Save grava o conteúdo na pasta e retorna o caminho do arquivo e o hash do conteúdo.
+// Um conteúdo já guardado não é gravado de novo nem conta outra vez para o limite, mesmo que chegue
+// com outro nome ou tipo: a extensão é escolhida na primeira gravação
+func (s *MediaStore) Save(data []byte, mimeType, fileName string) (string, string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if path, ok := s.find(hash); ok {
+		return path, hash, nil
+	}
+	path := filepath.Join(s.Dir, hash[:2], hash+mediaExtension(mimeType, fileName))
+
+	size := int64(len(data))
+	if size > s.MaxFileSize {
+		return "", hash, ErrMediaTooLarge
+	}
+	used, err := s.usage()
+	if err != nil {
+		return "", hash, err
+	}
+	if used+size > s.MaxTotalSize {
+		return "", hash, ErrMediaQuotaExceeded
+	}
+
+	if err := createDirIfNotExists(filepath.Dir(path)); err != nil {
+		return "", hash, fmt.Errorf("erro ao criar pasta de anexos: %w", err)
+	}
+	// Grava em um arquivo temporário e renomeia, para nunca deixar um anexo pela metade
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", hash, fmt.Errorf("erro ao gravar anexo: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", hash, fmt.Errorf("erro ao gravar anexo: %w", err)
+	}
+
+	s.used += size
+	return path, hash, nil
+}
+
+// find procura o arquivo já gravado com o hash, qualquer que seja a extensão
+func (s *MediaStore) find(hash string) (string, bool) {
+	matches, _ := filepath.Glob(filepath.Join(s.Dir, hash[:2], hash+".*"))
+	for _, match := range matches {
+		if !strings.HasSuffix(match, ".tmp") {
+			return match, true
+		}
+	}
+	return "", false
+}
+
+// Usage retorna o espaço ocupado pelos anexos, em bytes
+func (s *MediaStore) Usage() (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.usage()
+}
+
+// usage soma o tamanho dos arquivos da pasta na primeira chamada e depois mantém o total em memória
+func (s *MediaStore) usage() (int64, error) {
+	if s.scanned {
+		return s.used, nil
+	}
+
+	var total int64
+	err := filepath.WalkDir(s.Dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("erro ao calcular o espaço da pasta de anexos: %w", err)
+	}
+
+	s.used = total
+	s.scanned = true
+	return total, nil
+}
+
+// mediaExtension escolhe a extensão do arquivo pelo nome original ou pelo tipo MIME
+func mediaExtension(mimeType, fileName string) string {
+	if ext := strings.ToLower(filepath.Ext(fileName)); ext != "" && len(ext) <= 10 {
+		return ext
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return ".bin"
+	}
+	if ext, ok := mediaExtensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// downloadAttachment baixa a mídia da mensagem para a pasta de anexos, respeitando os limites.
+// Falhas ficam registradas em Attachment.Err e não impedem a entrega da mensagem
+func (c *Client) downloadAttachment(att *Attachment) {
+	if c.mediaStore == nil || att == nil || att.media == nil {
+		return
+	}
+	if att.Size > c.mediaStore.MaxFileSize {
+		att.Err = ErrMediaTooLarge
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mediaDownloadTimeout)
+	defer cancel()
+
+	data, err := c.client.Download(ctx, att.media)
+	if err != nil {
+		att.Err = fmt.Errorf("erro ao baixar anexo: %w", err)
+		return
+	}
+
+	att.Path, att.SHA256, att.Err = c.mediaStore.Save(data, att.MimeType, att.FileName)
+}
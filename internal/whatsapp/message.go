@@ -0,0 +1,433 @@
+package whatsapp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// MessageType identifica o tipo de conteúdo de uma mensagem recebida
+type MessageType string
+
+// Tipos de mensagem reconhecidos
+const (
+	MessageTypeText     MessageType = "text"
+	MessageTypeImage    MessageType = "image"
+	MessageTypeAudio    MessageType = "audio"
+	MessageTypeVideo    MessageType = "video"
+	MessageTypeDocument MessageType = "document"
+	MessageTypeSticker  MessageType = "sticker"
+	MessageTypeLocation MessageType = "location"
+	MessageTypeContact  MessageType = "contact"
+	MessageTypePoll     MessageType = "poll"
+	MessageTypeUnknown  MessageType = "unknown"
+)
+
+// Attachment descreve a mídia de uma mensagem de imagem, áudio, vídeo, documento ou figurinha
+type Attachment struct {
+	MimeType string
+	FileName string
+	// Size é o tamanho informado pelo remetente, em bytes
+	Size int64
+	// Seconds é a duração de áudios e vídeos
+	Seconds uint32
+	// VoiceNote indica um áudio gravado no próprio WhatsApp (PTT)
+	VoiceNote bool
+	Width     uint32
+	Height    uint32
+
+	// Path é o caminho do arquivo baixado; vazio quando o download não foi feito
+	Path string
+	// SHA256 é o hash do conteúdo em hexadecimal, que também dá nome ao arquivo
+	SHA256 string
+	// Err guarda o motivo de o anexo não ter sido baixado
+	Err error
+
+	media whatsmeow.DownloadableMessage
+}
+
+// Location é uma localização enviada, fixa ou em tempo real
+type Location struct {
+	Latitude  float64
+	Longitude float64
+	Name      string
+	Address   string
+	Live      bool
+}
+
+// ContactCard é um cartão de contato (vCard) enviado na conversa
+type ContactCard struct {
+	DisplayName string
+	VCard       string
+}
+
+// IncomingMessage é uma mensagem recebida do WhatsApp, de qualquer tipo
+type IncomingMessage struct {
+	ID         string
+	ChatJID    string
+	SenderJID  string
+	SenderName string
+	Timestamp  time.Time
+	IsGroup    bool
+	FromMe     bool
+	// Mentioned indica, nos grupos, que a mensagem menciona a conta ou responde a uma mensagem dela
+	Mentioned bool
+
+	Type MessageType
+	// Text é o texto da mensagem ou a legenda da mídia
+	Text        string
+	Attachment  *Attachment
+	Location    *Location
+	Contacts    []ContactCard
+	PollOptions []string
+
+	// Raw é o evento original, para quem precisar de campos não mapeados
+	Raw *events.Message
+}
+
+// Reaction é uma reação (emoji) a uma mensagem da conversa
+type Reaction struct {
+	ChatJID   string
+	SenderJID string
+	// TargetID é o ID da mensagem que recebeu a reação
+	TargetID string
+	// Emoji vazio indica que a reação foi removida
+	Emoji     string
+	FromMe    bool
+	Timestamp time.Time
+}
+
+// MessageUpdateType identifica a alteração feita em uma mensagem já enviada
+type MessageUpdateType string
+
+// Alterações reconhecidas
+const (
+	MessageEdited  MessageUpdateType = "edited"
+	MessageRevoked MessageUpdateType = "revoked" // apagada para todos
+)
+
+// MessageUpdate é a edição ou a exclusão de uma mensagem já enviada na conversa
+type MessageUpdate struct {
+	Type      MessageUpdateType
+	ChatJID   string
+	SenderJID string
+	// TargetID é o ID da mensagem alterada
+	TargetID string
+	// Message é a nova versão da mensagem editada, com o ID da original; nil nas exclusões
+	Message   *IncomingMessage
+	FromMe    bool
+	Timestamp time.Time
+}
+
+// Summary retorna uma descrição em texto da mensagem, usada no histórico e nos prompts.
+// Mensagens de texto retornam o próprio texto; as demais, o tipo entre colchetes e a legenda
+func (m *IncomingMessage) Summary() string {
+	var label string
+	switch m.Type {
+	case MessageTypeText:
+		return m.Text
+	case MessageTypeImage:
+		label = "[imagem]"
+	case MessageTypeAudio:
+		label = "[áudio"
+		if m.Attachment != nil && m.Attachment.VoiceNote {
+			label = "[mensagem de voz"
+		}
+		if m.Attachment != nil && m.Attachment.Seconds > 0 {
+			label += fmt.Sprintf(" %d:%02d", m.Attachment.Seconds/60, m.Attachment.Seconds%60)
+		}
+		label += "]"
+	case MessageTypeVideo:
+		label = "[vídeo]"
+	case MessageTypeDocument:
+		label = "[documento]"
+		if m.Attachment != nil && m.Attachment.FileName != "" {
+			label = "[documento: " + m.Attachment.FileName + "]"
+		}
+	case MessageTypeSticker:
+		label = "[figurinha]"
+	case MessageTypeLocation:
+		label = "[localização"
+		if m.Location != nil {
+			if m.Location.Live {
+				label = "[localização em tempo real"
+			}
+			var parts []string
+			for _, part := range []string{m.Location.Name, m.Location.Address} {
+				if part != "" {
+					parts = append(parts, part)
+				}
+			}
+			parts = append(parts, fmt.Sprintf("%.6f, %.6f", m.Location.Latitude, m.Location.Longitude))
+			label += ": " + strings.Join(parts, " - ")
+		}
+		label += "]"
+	case MessageTypeContact:
+		names := make([]string, 0, len(m.Contacts))
+		for _, contact := range m.Contacts {
+			names = append(names, contact.DisplayName)
+		}
+		label = "[contato: " + strings.Join(names, ", ") + "]"
+	case MessageTypePoll:
+		label = "[enquete]"
+		if len(m.PollOptions) > 0 {
+			label = "[enquete - opções: " + strings.Join(m.PollOptions, ", ") + "]"
+		}
+	default:
+		label = "[mensagem não suportada]"
+	}
+
+	if m.Text == "" {
+		return label
+	}
+	return label + " " + m.Text
+}
+
+// parseMessage converte uma mensagem do whatsmeow no modelo tipado. Retorna nil para mensagens
+// sem conteúdo próprio, como reações, confirmações de protocolo e votos em enquetes
+func parseMessage(v *events.Message) *IncomingMessage {
+	msg := &IncomingMessage{
+		ID:         v.Info.ID,
+		ChatJID:    v.Info.Chat.String(),
+		SenderJID:  v.Info.Sender.String(),
+		SenderName: v.Info.PushName,
+		Timestamp:  v.Info.Timestamp,
+		IsGroup:    v.Info.IsGroup,
+		FromMe:     v.Info.IsFromMe,
+		Raw:        v,
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+
+	if !fillContent(msg, v.Message) {
+		return nil
+	}
+	return msg
+}
+
+// parseReaction retorna a reação contida na mensagem, ou nil se ela não for uma reação.
+// Reações criptografadas (enviadas em comunidades) não são decodificadas
+func parseReaction(v *events.Message) *Reaction {
+	reaction := v.Message.GetReactionMessage()
+	if reaction == nil || reaction.GetKey().GetID() == "" {
+		return nil
+	}
+
+	r := &Reaction{
+		ChatJID:   v.Info.Chat.String(),
+		SenderJID: v.Info.Sender.String(),
+		TargetID:  reaction.GetKey().GetID(),
+		Emoji:     reaction.GetText(),
+		FromMe:    v.Info.IsFromMe,
+		Timestamp: v.Info.Timestamp,
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+	return r
+}
+
+// parseMessageUpdate retorna a edição ou a exclusão contida na mensagem de protocolo,
+// ou nil se a mensagem não alterar outra
+func parseMessageUpdate(v *events.Message) *MessageUpdate {
+	protocol := v.Message.GetProtocolMessage()
+	if protocol == nil || protocol.GetKey().GetID() == "" {
+		return nil
+	}
+
+	update := &MessageUpdate{
+		ChatJID:   v.Info.Chat.String(),
+		SenderJID: v.Info.Sender.String(),
+		TargetID:  protocol.GetKey().GetID(),
+		FromMe:    v.Info.IsFromMe,
+		Timestamp: v.Info.Timestamp,
+	}
+	if update.Timestamp.IsZero() {
+		update.Timestamp = time.Now()
+	}
+
+	switch protocol.GetType() {
+	case waProto.ProtocolMessage_REVOKE:
+		update.Type = MessageRevoked
+	case waProto.ProtocolMessage_MESSAGE_EDIT:
+		update.Type = MessageEdited
+		// A nova versão mantém o ID da original, para ser citada e associada ao histórico.
+		// Raw fica vazio porque o evento recebido é a mensagem de protocolo, não a mensagem editada
+		edited := &IncomingMessage{
+			ID:         update.TargetID,
+			ChatJID:    update.ChatJID,
+			SenderJID:  update.SenderJID,
+			SenderName: v.Info.PushName,
+			Timestamp:  update.Timestamp,
+			IsGroup:    v.Info.IsGroup,
+			FromMe:     update.FromMe,
+		}
+		if !fillContent(edited, protocol.GetEditedMessage()) {
+			return nil
+		}
+		// Edições alteram apenas o texto ou a legenda; a mídia não é enviada de novo
+		edited.Attachment = nil
+		update.Message = edited
+	default:
+		return nil
+	}
+	return update
+}
+
+// fillContent preenche o tipo e o conteúdo da mensagem. Retorna falso se não houver conteúdo
+func fillContent(msg *IncomingMessage, m *waProto.Message) bool {
+	if m == nil {
+		return false
+	}
+
+	switch {
+	case m.Conversation != nil:
+		msg.Type = MessageTypeText
+		msg.Text = m.GetConversation()
+
+	case m.ExtendedTextMessage != nil:
+		msg.Type = MessageTypeText
+		msg.Text = m.GetExtendedTextMessage().GetText()
+
+	case m.ImageMessage != nil:
+		img := m.GetImageMessage()
+		msg.Type = MessageTypeImage
+		msg.Text = img.GetCaption()
+		msg.Attachment = &Attachment{
+			MimeType: img.GetMimetype(),
+			Size:     int64(img.GetFileLength()),
+			Width:    img.GetWidth(),
+			Height:   img.GetHeight(),
+			media:    img,
+		}
+
+	case m.AudioMessage != nil:
+		audio := m.GetAudioMessage()
+		msg.Type = MessageTypeAudio
+		msg.Attachment = &Attachment{
+			MimeType:  audio.GetMimetype(),
+			Size:      int64(audio.GetFileLength()),
+			Seconds:   audio.GetSeconds(),
+			VoiceNote: audio.GetPTT(),
+			media:     audio,
+		}
+
+	case m.VideoMessage != nil || m.PtvMessage != nil:
+		video := m.GetVideoMessage()
+		if video == nil {
+			video = m.GetPtvMessage()
+		}
+		msg.Type = MessageTypeVideo
+		msg.Text = video.GetCaption()
+		msg.Attachment = &Attachment{
+			MimeType: video.GetMimetype(),
+			Size:     int64(video.GetFileLength()),
+			Seconds:  video.GetSeconds(),
+			Width:    video.GetWidth(),
+			Height:   video.GetHeight(),
+			media:    video,
+		}
+
+	case m.DocumentMessage != nil:
+		doc := m.GetDocumentMessage()
+		msg.Type = MessageTypeDocument
+		msg.Text = doc.GetCaption()
+		fileName := doc.GetFileName()
+		if fileName == "" {
+			fileName = doc.GetTitle()
+		}
+		msg.Attachment = &Attachment{
+			MimeType: doc.GetMimetype(),
+			FileName: fileName,
+			Size:     int64(doc.GetFileLength()),
+			media:    doc,
+		}
+
+	case m.StickerMessage != nil:
+		sticker := m.GetStickerMessage()
+		msg.Type = MessageTypeSticker
+		msg.Attachment = &Attachment{
+			MimeType: sticker.GetMimetype(),
+			Size:     int64(sticker.GetFileLength()),
+			Width:    sticker.GetWidth(),
+			Height:   sticker.GetHeight(),
+			media:    sticker,
+		}
+
+	case m.LocationMessage != nil:
+		loc := m.GetLocationMessage()
+		msg.Type = MessageTypeLocation
+		msg.Text = loc.GetComment()
+		msg.Location = &Location{
+			Latitude:  loc.GetDegreesLatitude(),
+			Longitude: loc.GetDegreesLongitude(),
+			Name:      loc.GetName(),
+			Address:   loc.GetAddress(),
+			Live:      loc.GetIsLive(),
+		}
+
+	case m.LiveLocationMessage != nil:
+		loc := m.GetLiveLocationMessage()
+		msg.Type = MessageTypeLocation
+		msg.Text = loc.GetCaption()
+		msg.Location = &Location{
+			Latitude:  loc.GetDegreesLatitude(),
+			Longitude: loc.GetDegreesLongitude(),
+			Live:      true,
+		}
+
+	case m.ContactMessage != nil:
+		contact := m.GetContactMessage()
+		msg.Type = MessageTypeContact
+		msg.Contacts = []ContactCard{{DisplayName: contact.GetDisplayName(), VCard: contact.GetVcard()}}
+
+	case m.ContactsArrayMessage != nil:
+		msg.Type = MessageTypeContact
+		for _, contact := range m.GetContactsArrayMessage().GetContacts() {
+			msg.Contacts = append(msg.Contacts, ContactCard{DisplayName: contact.GetDisplayName(), VCard: contact.GetVcard()})
+		}
+
+	case m.PollCreationMessage != nil || m.PollCreationMessageV2 != nil || m.PollCreationMessageV3 != nil:
+		poll := m.GetPollCreationMessage()
+		if poll == nil {
+			poll = m.GetPollCreationMessageV2()
+		}
+		if poll == nil {
+			poll = m.GetPollCreationMessageV3()
+		}
+		msg.Type = MessageTypePoll
+		msg.Text = poll.GetName()
+		for _, option := range poll.GetOptions() {
+			msg.PollOptions = append(msg.PollOptions, option.GetOptionName())
+		}
+
+	case m.ProtocolMessage != nil, m.ReactionMessage != nil, m.EncReactionMessage != nil,
+		m.PollUpdateMessage != nil, m.KeepInChatMessage != nil, m.PinInChatMessage != nil:
+		// Eventos sobre outras mensagens, não conteúdo novo
+		return false
+
+	default:
+		// Chaves de criptografia de grupo podem chegar sozinhas, antes da primeira mensagem
+		if isOnlyMetadata(m) {
+			return false
+		}
+		msg.Type = MessageTypeUnknown
+	}
+
+	return true
+}
+
+// isOnlyMetadata verifica se a mensagem traz apenas chaves de criptografia e metadados
+func isOnlyMetadata(m *waProto.Message) bool {
+	clone := proto.Clone(m).(*waProto.Message)
+	clone.SenderKeyDistributionMessage = nil
+	clone.FastRatchetKeySenderKeyDistributionMessage = nil
+	clone.MessageContextInfo = nil
+	return proto.Size(clone) == 0
+}
@@ -0,0 +1,373 @@
+package whatsapp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseMessage(t *testing.T) {
+	sender := types.NewJID("5511999999999", types.DefaultUserServer)
+
+	tests := []struct {
+		name     string
+		message  *waProto.Message
+		ignorada bool
+		tipo     MessageType
+		resumo   string
+	}{
+		{
+			name:    "Texto",
+			message: &waProto.Message{Conversation: proto.String("Olá")},
+			tipo:    MessageTypeText,
+			resumo:  "Olá",
+		},
+		{
+			name: "TextoEstendido",
+			message: &waProto.Message{ExtendedTextMessage: &waProto.ExtendedTextMessage{
+				Text: proto.String("Veja https://exemplo.com"),
+			}},
+			tipo:   MessageTypeText,
+			resumo: "Veja https://exemplo.com",
+		},
+		{
+			name: "ImagemComLegenda",
+			message: &waProto.Message{ImageMessage: &waProto.ImageMessage{
+				Caption:  proto.String("meu cachorro"),
+				Mimetype: proto.String("image/jpeg"),
+			}},
+			tipo:   MessageTypeImage,
+			resumo: "[imagem] meu cachorro",
+		},
+		{
+			name: "MensagemDeVoz",
+			message: &waProto.Message{AudioMessage: &waProto.AudioMessage{
+				Mimetype: proto.String("audio/ogg; codecs=opus"),
+				Seconds:  proto.Uint32(75),
+				PTT:      proto.Bool(true),
+			}},
+			tipo:   MessageTypeAudio,
+			resumo: "[mensagem de voz 1:15]",
+		},
+		{
+			name: "Documento",
+			message: &waProto.Message{DocumentMessage: &waProto.DocumentMessage{
+				FileName: proto.String("orcamento.pdf"),
+				Mimetype: proto.String("application/pdf"),
+			}},
+			tipo:   MessageTypeDocument,
+			resumo: "[documento: orcamento.pdf]",
+		},
+		{
+			name:    "Figurinha",
+			message: &waProto.Message{StickerMessage: &waProto.StickerMessage{Mimetype: proto.String("image/webp")}},
+			tipo:    MessageTypeSticker,
+			resumo:  "[figurinha]",
+		},
+		{
+			name: "Localizacao",
+			message: &waProto.Message{LocationMessage: &waProto.LocationMessage{
+				DegreesLatitude:  proto.Float64(-23.5505),
+				DegreesLongitude: proto.Float64(-46.6333),
+				Name:             proto.String("Praça da Sé"),
+			}},
+			tipo:   MessageTypeLocation,
+			resumo: "[localização: Praça da Sé - -23.550500, -46.633300]",
+		},
+		{
+			name: "Contatos",
+			message: &waProto.Message{ContactsArrayMessage: &waProto.ContactsArrayMessage{
+				Contacts: []*waProto.ContactMessage{
+					{DisplayName: proto.String("Ana")},
+					{DisplayName: proto.String("Bruno")},
+				},
+			}},
+			tipo:   MessageTypeContact,
+			resumo: "[contato: Ana, Bruno]",
+		},
+		{
+			name: "Reacao",
+			message: &waProto.Message{ReactionMessage: &waProto.ReactionMessage{
+				Text: proto.String("👍"),
+			}},
+			ignorada: true,
+		},
+		{
+			name: "ApenasChaves",
+			message: &waProto.Message{SenderKeyDistributionMessage: &waProto.SenderKeyDistributionMessage{
+				GroupID: proto.String("grupo"),
+			}},
+			ignorada: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := &events.Message{
+				Info: types.MessageInfo{
+					MessageSource: types.MessageSource{Chat: sender, Sender: sender},
+					ID:            "ABC123",
+					PushName:      "Contato Teste",
+				},
+				Message: tt.message,
+			}
+
+			msg := parseMessage(evt)
+			if tt.ignorada {
+				if msg != nil {
+					t.Errorf("Mensagem deveria ser ignorada, obteve tipo %s", msg.Type)
+				}
+				return
+			}
+			if msg == nil {
+				t.Fatalf("Mensagem não deveria ser ignorada")
+			}
+			if msg.Type != tt.tipo {
+				t.Errorf("Tipo incorreto: %s, esperado: %s", msg.Type, tt.tipo)
+			}
+			if resumo := msg.Summary(); resumo != tt.resumo {
+				t.Errorf("Resumo incorreto: %q, esperado: %q", resumo, tt.resumo)
+			}
+			if msg.SenderJID != sender.String() || msg.SenderName != "Contato Teste" || msg.ID != "ABC123" {
+				t.Errorf("Remetente incorreto: %+v", msg)
+			}
+		})
+	}
+}
+
+func TestParseReaction(t *testing.T) {
+	sender := types.NewJID("5511999999999", types.DefaultUserServer)
+	evento := func(m *waProto.Message) *events.Message {
+		return &events.Message{
+			Info: types.MessageInfo{
+				MessageSource: types.MessageSource{Chat: sender, Sender: sender},
+				ID:            "REACAO1",
+			},
+			Message: m,
+		}
+	}
+
+	reaction := parseReaction(evento(&waProto.Message{ReactionMessage: &waProto.ReactionMessage{
+		Key:  &waProto.MessageKey{ID: proto.String("RESPOSTA1"), FromMe: proto.Bool(true)},
+		Text: proto.String("👍"),
+	}}))
+	if reaction == nil {
+		t.Fatalf("Reação não reconhecida")
+	}
+	if reaction.TargetID != "RESPOSTA1" || reaction.Emoji != "👍" || reaction.SenderJID != sender.String() {
+		t.Errorf("Reação incorreta: %+v", reaction)
+	}
+
+	removida := parseReaction(evento(&waProto.Message{ReactionMessage: &waProto.ReactionMessage{
+		Key: &waProto.MessageKey{ID: proto.String("RESPOSTA1")},
+	}}))
+	if removida == nil || removida.Emoji != "" {
+		t.Errorf("Remoção da reação incorreta: %+v", removida)
+	}
+
+	if parseReaction(evento(&waProto.Message{Conversation: proto.String("Olá")})) != nil {
+		t.Errorf("Mensagem de texto não é uma reação")
+	}
+}
+
+func TestParseMessageUpdate(t *testing.T) {
+	sender := types.NewJID("5511999999999", types.DefaultUserServer)
+	evento := func(protocol *waProto.ProtocolMessage) *events.Message {
+		return &events.Message{
+			Info: types.MessageInfo{
+				MessageSource: types.MessageSource{Chat: sender, Sender: sender},
+				ID:            "PROTOCOLO1",
+			},
+			Message: &waProto.Message{ProtocolMessage: protocol},
+		}
+	}
+	chave := &waProto.MessageKey{ID: proto.String("PERGUNTA1")}
+
+	editada := parseMessageUpdate(evento(&waProto.ProtocolMessage{
+		Key:           chave,
+		Type:          waProto.ProtocolMessage_MESSAGE_EDIT.Enum(),
+		EditedMessage: &waProto.Message{Conversation: proto.String("Qual o horário de sábado?")},
+	}))
+	if editada == nil || editada.Type != MessageEdited || editada.TargetID != "PERGUNTA1" {
+		t.Fatalf("Edição incorreta: %+v", editada)
+	}
+	if editada.Message == nil || editada.Message.ID != "PERGUNTA1" || editada.Message.Text != "Qual o horário de sábado?" {
+		t.Errorf("Nova versão incorreta: %+v", editada.Message)
+	}
+
+	apagada := parseMessageUpdate(evento(&waProto.ProtocolMessage{
+		Key:  chave,
+		Type: waProto.ProtocolMessage_REVOKE.Enum(),
+	}))
+	if apagada == nil || apagada.Type != MessageRevoked || apagada.TargetID != "PERGUNTA1" || apagada.Message != nil {
+		t.Errorf("Exclusão incorreta: %+v", apagada)
+	}
+
+	outras := []*events.Message{
+		evento(&waProto.ProtocolMessage{Key: chave, Type: waProto.ProtocolMessage_EPHEMERAL_SETTING.Enum()}),
+		{Info: types.MessageInfo{MessageSource: types.MessageSource{Chat: sender, Sender: sender}}, Message: &waProto.Message{Conversation: proto.String("Olá")}},
+	}
+	for _, evt := range outras {
+		if update := parseMessageUpdate(evt); update != nil {
+			t.Errorf("Mensagem não deveria ser uma alteração: %+v", update)
+		}
+	}
+}
+
+func TestParseHistorySync(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "whatszapme-history-test")
+	if err != nil {
+		t.Fatalf("Erro ao criar diretório temporário: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client, err := NewClient(&ClientConfig{DBPath: filepath.Join(tempDir, "store.db"), LogLevel: "ERROR"})
+	if err != nil {
+		t.Fatalf("Erro ao criar cliente WhatsApp: %v", err)
+	}
+
+	contato := "5511999999999@s.whatsapp.net"
+	mensagem := func(id, texto string) *waProto.HistorySyncMsg {
+		return &waProto.HistorySyncMsg{Message: &waProto.WebMessageInfo{
+			Key:              &waProto.MessageKey{RemoteJID: proto.String(contato), ID: proto.String(id)},
+			Message:          &waProto.Message{Conversation: proto.String(texto)},
+			MessageTimestamp: proto.Uint64(1700000000),
+		}}
+	}
+
+	batch := client.parseHistorySync(&events.HistorySync{Data: &waProto.HistorySync{
+		SyncType: waProto.HistorySync_RECENT.Enum(),
+		Progress: proto.Uint32(40),
+		Pushnames: []*waProto.Pushname{
+			{ID: proto.String(contato), Pushname: proto.String("Maria")},
+		},
+		Conversations: []*waProto.Conversation{
+			{ID: proto.String(contato), Messages: []*waProto.HistorySyncMsg{
+				mensagem("ANTIGA1", "Vocês entregam no sábado?"),
+				{Message: &waProto.WebMessageInfo{
+					Key:     &waProto.MessageKey{RemoteJID: proto.String(contato), ID: proto.String("REACAO1")},
+					Message: &waProto.Message{ReactionMessage: &waProto.ReactionMessage{Key: &waProto.MessageKey{ID: proto.String("ANTIGA1")}, Text: proto.String("👍")}},
+				}},
+			}},
+			// Mensagem de grupo sem o participante que a enviou
+			{ID: proto.String("120363000000000000@g.us"), Messages: []*waProto.HistorySyncMsg{mensagem("ANTIGA2", "Olá")}},
+		},
+	}})
+
+	if batch.Progress != 40 || batch.Names[contato] != "Maria" || batch.Skipped != 1 {
+		t.Errorf("Lote incorreto: %+v", batch)
+	}
+	if len(batch.Messages) != 1 {
+		t.Fatalf("Esperava 1 mensagem, obteve %d", len(batch.Messages))
+	}
+	msg := batch.Messages[0]
+	if msg.ID != "ANTIGA1" || msg.ChatJID != contato || msg.SenderName != "Maria" || msg.Text != "Vocês entregam no sábado?" || !msg.Timestamp.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Mensagem incorreta: %+v", msg)
+	}
+}
+
+func TestParseReceipt(t *testing.T) {
+	contato := types.NewJID("5511999999999", types.DefaultUserServer)
+	recibo := func(tipo types.ReceiptType, fromMe bool) *events.Receipt {
+		return &events.Receipt{
+			MessageSource: types.MessageSource{Chat: contato, Sender: contato, IsFromMe: fromMe},
+			MessageIDs:    []types.MessageID{"RESPOSTA1", "RESPOSTA2"},
+			Type:          tipo,
+		}
+	}
+
+	tests := []struct {
+		tipo   types.ReceiptType
+		fromMe bool
+		status ReceiptStatus
+	}{
+		{types.ReceiptTypeDelivered, false, ReceiptDelivered},
+		{types.ReceiptTypeRead, false, ReceiptRead},
+		{types.ReceiptTypePlayed, false, ReceiptRead},
+		{types.ReceiptTypeServerError, false, ReceiptFailed},
+		{types.ReceiptTypeRetry, false, ""},
+		{types.ReceiptTypeReadSelf, true, ""},
+		{types.ReceiptTypeRead, true, ""},
+	}
+
+	for _, tt := range tests {
+		receipt := parseReceipt(recibo(tt.tipo, tt.fromMe))
+		if tt.status == "" {
+			if receipt != nil {
+				t.Errorf("Recibo %q (fromMe=%v) deveria ser ignorado: %+v", tt.tipo, tt.fromMe, receipt)
+			}
+			continue
+		}
+		if receipt == nil || receipt.Status != tt.status || len(receipt.MessageIDs) != 2 || receipt.MessageIDs[1] != "RESPOSTA2" {
+			t.Errorf("Recibo %q incorreto: %+v", tt.tipo, receipt)
+		}
+	}
+}
+
+func TestMediaStore(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "whatszapme-media-test")
+	if err != nil {
+		t.Fatalf("Erro ao criar diretório temporário: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	store, err := NewMediaStore(filepath.Join(tempDir, "midia"), 10, 15)
+	if err != nil {
+		t.Fatalf("Erro ao criar pasta de anexos: %v", err)
+	}
+
+	t.Run("EnderecadoPeloConteudo", func(t *testing.T) {
+		path, hash, err := store.Save([]byte("imagem1"), "image/jpeg", "")
+		if err != nil {
+			t.Fatalf("Erro ao salvar anexo: %v", err)
+		}
+		if filepath.Base(path) != hash+".jpg" || filepath.Base(filepath.Dir(path)) != hash[:2] {
+			t.Errorf("Caminho incorreto: %s", path)
+		}
+
+		again, _, err := store.Save([]byte("imagem1"), "image/jpeg", "")
+		if err != nil || again != path {
+			t.Errorf("Conteúdo repetido deveria reaproveitar o arquivo: %s, %v", again, err)
+		}
+		if used, _ := store.Usage(); used != 7 {
+			t.Errorf("Espaço ocupado incorreto: %d", used)
+		}
+	})
+
+	t.Run("ExtensaoDoNome", func(t *testing.T) {
+		path, _, err := store.Save([]byte("doc"), "application/octet-stream", "contrato.PDF")
+		if err != nil {
+			t.Fatalf("Erro ao salvar anexo: %v", err)
+		}
+		if filepath.Ext(path) != ".pdf" {
+			t.Errorf("Extensão incorreta: %s", path)
+		}
+
+		// O mesmo conteúdo com outro nome, ou sem nome, reaproveita o arquivo
+		for _, nome := range []string{"contrato.pdf", ""} {
+			again, _, err := store.Save([]byte("doc"), "application/octet-stream", nome)
+			if err != nil || again != path {
+				t.Errorf("Conteúdo repetido como %q deveria reaproveitar o arquivo: %s, %v", nome, again, err)
+			}
+		}
+		if used, _ := store.Usage(); used != 10 {
+			t.Errorf("Espaço ocupado incorreto: %d", used)
+		}
+	})
+
+	t.Run("Limites", func(t *testing.T) {
+		if _, _, err := store.Save([]byte("grande demais"), "image/png", ""); !errors.Is(err, ErrMediaTooLarge) {
+			t.Errorf("Esperava erro de tamanho, obteve: %v", err)
+		}
+		if _, _, err := store.Save([]byte("imagem2"), "image/png", ""); !errors.Is(err, ErrMediaQuotaExceeded) {
+			t.Errorf("Esperava erro de espaço, obteve: %v", err)
+		}
+	})
+}
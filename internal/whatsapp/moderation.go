@@ -0,0 +1,344 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ModerationAction é o que o assistente faz em um grupo moderado. Os valores são gravados
+// no registro de moderação
+type ModerationAction string
+
+const (
+	// ModerationWarn avisa no grupo quem violou uma regra
+	ModerationWarn ModerationAction = "avisar"
+	// ModerationDelete apaga a mensagem que violou a regra e avisa o autor; exige que a conta seja administradora
+	ModerationDelete ModerationAction = "apagar"
+	// ModerationRemove apaga a mensagem e remove o autor do grupo; exige que a conta seja administradora
+	ModerationRemove ModerationAction = "remover"
+	// ModerationWelcome registra a mensagem de boas-vindas enviada a quem entrou no grupo
+	ModerationWelcome ModerationAction = "boas-vindas"
+	// ModerationGoodbye registra a mensagem de despedida enviada a quem saiu do grupo
+	ModerationGoodbye ModerationAction = "despedida"
+)
+
+// ModerationRules define as mensagens automáticas e as regras de um grupo moderado
+type ModerationRules struct {
+	// WelcomeTemplate e GoodbyeTemplate são templates (text/template) com os campos de GreetingData;
+	// vazios não enviam mensagem
+	WelcomeTemplate string
+	GoodbyeTemplate string
+	// BlockLinks proíbe mensagens com links
+	BlockLinks bool
+	// FloodLimit é o máximo de mensagens de um participante dentro de FloodWindow; 0 desativa o limite
+	FloodLimit  int
+	FloodWindow time.Duration
+	// BannedWords são palavras proibidas no grupo, sem diferenciar maiúsculas
+	BannedWords []string
+	// Action é o que fazer com quem viola as regras; vazio equivale a ModerationWarn
+	Action ModerationAction
+}
+
+// GreetingData são os campos disponíveis nos templates de boas-vindas e despedida
+type GreetingData struct {
+	Name    string // nome do participante
+	Mention string // menção ao participante, como "@5511999999999"
+	Group   string // nome do grupo
+}
+
+// ModerationEvent é uma ação automática tomada em um grupo, entregue ao SyncStore para registro
+type ModerationEvent struct {
+	GroupJID       string
+	ParticipantJID string
+	Action         ModerationAction
+	Reason         string // regra violada, vazio nas boas-vindas e despedidas
+	MessageID      string // mensagem que violou a regra
+	Text           string
+	Err            error // falha ao executar a ação
+}
+
+// Motivos das violações, usados nos avisos e no registro de moderação
+const (
+	reasonLink       = "links não são permitidos"
+	reasonFlood      = "muitas mensagens em pouco tempo"
+	reasonBannedWord = "palavra proibida"
+)
+
+// linkPattern reconhece endereços com protocolo, começando por www ou com os domínios mais comuns
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+(\.[a-z0-9-]+)*\.(com|net|org|br|io|ly|gl|info|biz|xyz|app)(/\S*)?\b`)
+
+// errNotGroupAdmin indica que a ação exige que a conta seja administradora do grupo
+var errNotGroupAdmin = errors.New("a conta não é administradora do grupo")
+
+// violation retorna o motivo pelo qual a mensagem viola as regras, ou vazio. recent é a quantidade
+// de mensagens do autor dentro da janela de flood, incluindo esta
+func (r ModerationRules) violation(text string, recent int) string {
+	switch {
+	case r.FloodLimit > 0 && recent > r.FloodLimit:
+		return reasonFlood
+	case r.BlockLinks && linkPattern.MatchString(text):
+		return reasonLink
+	case matchKeyword(text, r.BannedWords) != "":
+		return reasonBannedWord
+	}
+	return ""
+}
+
+// renderGreeting monta a mensagem de boas-vindas ou despedida a partir do template
+func renderGreeting(templateText string, data GreetingData) (string, error) {
+	tmpl, err := template.New("greeting").Parse(templateText)
+	if err != nil {
+		return "", fmt.Errorf("erro ao analisar template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("erro ao executar template: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// moderationRules retorna as regras do grupo informadas pelo SyncStore, ou nil se ele não é moderado
+func (c *Client) moderationRules(chat types.JID) *ModerationRules {
+	if c.syncStore == nil {
+		return nil
+	}
+	rules, err := c.syncStore.GetModerationRules(chat.String())
+	if err != nil {
+		c.log.Warnf("Erro ao ler a moderação do grupo %s: %v", chat, err)
+		return nil
+	}
+	return rules
+}
+
+// recordModeration entrega ao SyncStore uma ação de moderação
+func (c *Client) recordModeration(event ModerationEvent) {
+	if event.Err != nil {
+		c.log.Warnf("Moderação (%s) no grupo %s falhou: %v", event.Action, event.GroupJID, event.Err)
+	}
+	if err := c.syncStore.RegistrarModeracao(event); err != nil {
+		c.log.Errorf("Erro ao registrar moderação: %v", err)
+	}
+}
+
+// countRecent registra uma mensagem do participante e retorna quantas ele enviou dentro da janela.
+// Os participantes do grupo sem mensagens dentro da janela são descartados, para o mapa não crescer
+func (c *Client) countRecent(chat, sender types.JID, now time.Time, window time.Duration) int {
+	prefix := chat.String() + "|"
+	key := prefix + sender.ToNonAD().String()
+
+	c.groupMutex.Lock()
+	defer c.groupMutex.Unlock()
+
+	for k, times := range c.recentMessages {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		recent := times[:0]
+		for _, t := range times {
+			if now.Sub(t) < window {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) == 0 {
+			delete(c.recentMessages, k)
+		} else {
+			c.recentMessages[k] = recent
+		}
+	}
+
+	recent := append(c.recentMessages[key], now)
+	c.recentMessages[key] = recent
+	return len(recent)
+}
+
+// cacheGroupInfo guarda os participantes do grupo, usados para reconhecer os administradores
+func (c *Client) cacheGroupInfo(info *types.GroupInfo) {
+	c.groupMutex.Lock()
+	defer c.groupMutex.Unlock()
+	c.groupInfo[info.JID] = info
+}
+
+// forgetGroupInfo descarta os participantes guardados do grupo, que mudaram
+func (c *Client) forgetGroupInfo(chat types.JID) {
+	c.groupMutex.Lock()
+	defer c.groupMutex.Unlock()
+	delete(c.groupInfo, chat)
+}
+
+// cachedGroupInfo retorna os participantes guardados do grupo, sem consultar o servidor
+func (c *Client) cachedGroupInfo(chat types.JID) (*types.GroupInfo, bool) {
+	c.groupMutex.Lock()
+	defer c.groupMutex.Unlock()
+	info, ok := c.groupInfo[chat]
+	return info, ok
+}
+
+// moderateMessage aplica as regras do grupo à mensagem. Retorna true se ela violou alguma regra.
+// Roda no handler de eventos e não consulta o servidor: as mensagens de administradores seguem
+// normalmente quando os participantes do grupo já são conhecidos. Sem eles, a mensagem é bloqueada,
+// e a consulta ao grupo é feita em segundo plano junto com a ação, que não é aplicada a administradores
+func (c *Client) moderateMessage(msg *IncomingMessage, v *events.Message) bool {
+	if v.Info.IsFromMe {
+		return false
+	}
+	rules := c.moderationRules(v.Info.Chat)
+	if rules == nil {
+		return false
+	}
+
+	recent := 0
+	if rules.FloodLimit > 0 && rules.FloodWindow > 0 {
+		recent = c.countRecent(v.Info.Chat, v.Info.Sender, time.Now(), rules.FloodWindow)
+	}
+	reason := rules.violation(msg.Text, recent)
+	if reason == "" {
+		return false
+	}
+
+	if info, ok := c.cachedGroupInfo(v.Info.Chat); ok && isGroupAdmin(info, v.Info.Sender.ToNonAD()) {
+		return false
+	}
+
+	// Durante um flood, o aviso é dado só na primeira mensagem acima do limite; apagar e remover valem para todas
+	if reason == reasonFlood && recent > rules.FloodLimit+1 && (rules.Action == "" || rules.Action == ModerationWarn) {
+		return true
+	}
+
+	go c.enforce(rules.Action, msg, v.Info.Chat, v.Info.Sender, reason)
+	return true
+}
+
+// newModerationEvent monta o registro da ação tomada contra a mensagem
+func newModerationEvent(action ModerationAction, msg *IncomingMessage, chat types.JID, reason string) ModerationEvent {
+	if action == "" {
+		action = ModerationWarn
+	}
+	return ModerationEvent{
+		GroupJID:       chat.String(),
+		ParticipantJID: msg.SenderJID,
+		Action:         action,
+		Reason:         reason,
+		MessageID:      msg.ID,
+		Text:           msg.Text,
+	}
+}
+
+// enforce executa a ação definida para o grupo contra o autor da mensagem. Administradores do grupo
+// não são moderados, e sem permissão de administrador a conta apenas avisa
+func (c *Client) enforce(action ModerationAction, msg *IncomingMessage, chat, sender types.JID, reason string) {
+	event := newModerationEvent(action, msg, chat, reason)
+
+	info, ok := c.cachedGroupInfo(chat)
+	if !ok {
+		var err error
+		if info, err = c.client.GetGroupInfo(chat); err != nil {
+			event.Err = fmt.Errorf("erro ao buscar grupo: %w", err)
+			c.recordModeration(event)
+			return
+		}
+		c.cacheGroupInfo(info)
+	}
+	if isGroupAdmin(info, sender.ToNonAD()) {
+		return
+	}
+	if event.Action != ModerationWarn && !isGroupAdmin(info, c.ownJIDs()...) {
+		event.Err = errNotGroupAdmin
+		event.Action = ModerationWarn
+	}
+
+	notice := fmt.Sprintf("⚠️ Sua mensagem viola as regras do grupo: %s.", reason)
+	switch event.Action {
+	case ModerationDelete, ModerationRemove:
+		revoke := c.client.BuildRevoke(chat, sender, msg.ID)
+		if _, err := c.client.SendMessage(context.Background(), chat, revoke); err != nil {
+			event.Err = fmt.Errorf("erro ao apagar mensagem: %w", err)
+			break
+		}
+		notice = fmt.Sprintf("⚠️ Mensagem apagada: %s.", reason)
+		if event.Action == ModerationRemove {
+			if _, err := c.client.UpdateGroupParticipants(chat, []types.JID{sender.ToNonAD()}, whatsmeow.ParticipantChangeRemove); err != nil {
+				event.Err = fmt.Errorf("erro ao remover participante: %w", err)
+				break
+			}
+			notice = fmt.Sprintf("🚫 Participante removido do grupo: %s.", reason)
+		}
+	}
+
+	// O aviso cita a mensagem apenas se ela continua no grupo
+	var quoted *IncomingMessage
+	if event.Action == ModerationWarn || event.Err != nil {
+		quoted = msg
+	}
+	if _, err := c.SendTextMentioning(event.GroupJID, notice, quoted, []string{msg.SenderJID}); err != nil && event.Err == nil {
+		event.Err = fmt.Errorf("erro ao enviar aviso: %w", err)
+	}
+	c.recordModeration(event)
+}
+
+// greetParticipants envia as mensagens de boas-vindas e despedida do grupo. A própria conta não é
+// saudada, e quem ela removeu não recebe despedida
+func (c *Client) greetParticipants(v *events.GroupInfo) {
+	rules := c.moderationRules(v.JID)
+	if rules == nil || (rules.WelcomeTemplate == "" && rules.GoodbyeTemplate == "") {
+		return
+	}
+
+	own := c.ownJIDs()
+	isOwn := func(jid types.JID) bool {
+		for _, o := range own {
+			if !o.IsEmpty() && jid.User == o.User && jid.Server == o.Server {
+				return true
+			}
+		}
+		return false
+	}
+
+	var group string
+	if info, err := c.client.GetGroupInfo(v.JID); err == nil {
+		group = info.Name
+	}
+
+	greet := func(jids []types.JID, templateText string, action ModerationAction) {
+		if templateText == "" {
+			return
+		}
+		for _, jid := range jids {
+			jid = jid.ToNonAD()
+			if isOwn(jid) {
+				continue
+			}
+			event := ModerationEvent{GroupJID: v.JID.String(), ParticipantJID: jid.String(), Action: action}
+			text, err := renderGreeting(templateText, GreetingData{Name: c.contactName(jid), Mention: "@" + jid.User, Group: group})
+			if err == nil {
+				event.Text = text
+				_, err = c.SendTextMentioning(event.GroupJID, text, nil, []string{event.ParticipantJID})
+			}
+			event.Err = err
+			c.recordModeration(event)
+		}
+	}
+
+	greet(v.Join, rules.WelcomeTemplate, ModerationWelcome)
+	if v.Sender == nil || !isOwn(*v.Sender) {
+		greet(v.Leave, rules.GoodbyeTemplate, ModerationGoodbye)
+	}
+}
+
+// contactName retorna o nome conhecido do contato, ou o telefone
+func (c *Client) contactName(jid types.JID) string {
+	info, err := c.client.Store.Contacts.GetContact(context.Background(), jid)
+	if err != nil {
+		return jid.User
+	}
+	return contactFromInfo(jid, info).DisplayName()
+}
@@ -0,0 +1,102 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestModerationViolation(t *testing.T) {
+	regras := ModerationRules{
+		BlockLinks:  true,
+		FloodLimit:  3,
+		FloodWindow: 10 * time.Second,
+		BannedWords: []string{"golpe", " Pix Grátis "},
+	}
+
+	tests := []struct {
+		name    string
+		regras  ModerationRules
+		texto   string
+		recente int
+		motivo  string
+	}{
+		{"Permitida", regras, "Bom dia, pessoal!", 1, ""},
+		{"LinkComProtocolo", regras, "Vejam https://exemplo.com/oferta", 1, reasonLink},
+		{"LinkSemProtocolo", regras, "Entrem em chat.whatsapp.com/AbCdEf", 1, reasonLink},
+		{"Www", regras, "www.exemplo.com.br", 1, reasonLink},
+		{"Dominio", regras, "acessem loja.com.br agora", 1, reasonLink},
+		{"FimDeFrase", regras, "Chego às 9h.Combinado", 1, ""},
+		{"LinksPermitidos", ModerationRules{}, "https://exemplo.com", 1, ""},
+		{"PalavraProibida", regras, "Isso é GOLPE", 1, reasonBannedWord},
+		{"PalavraComEspacos", regras, "Ganhe pix grátis hoje", 1, reasonBannedWord},
+		{"NoLimiteDeFlood", regras, "Oi", 3, ""},
+		{"Flood", regras, "Oi", 4, reasonFlood},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if motivo := tt.regras.violation(tt.texto, tt.recente); motivo != tt.motivo {
+				t.Errorf("Motivo incorreto: %q, esperava %q", motivo, tt.motivo)
+			}
+		})
+	}
+}
+
+func TestCountRecent(t *testing.T) {
+	client := &Client{recentMessages: make(map[string][]time.Time)}
+	grupo := types.NewJID("120363000000000000", types.GroupServer)
+	autor := types.NewADJID("5511999999999", 0, 2)
+	outro := types.NewJID("5511888888888", types.DefaultUserServer)
+	agora := time.Now()
+
+	for i, esperado := range []int{1, 2, 3} {
+		if n := client.countRecent(grupo, autor, agora.Add(time.Duration(i)*time.Second), 10*time.Second); n != esperado {
+			t.Errorf("Contagem incorreta na mensagem %d: %d, esperava %d", i+1, n, esperado)
+		}
+	}
+	if n := client.countRecent(grupo, outro, agora, 10*time.Second); n != 1 {
+		t.Errorf("As mensagens de outro participante não deveriam contar: %d", n)
+	}
+	// Mensagens fora da janela deixam de contar
+	if n := client.countRecent(grupo, autor, agora.Add(11*time.Second), 10*time.Second); n != 2 {
+		t.Errorf("Contagem após a janela incorreta: %d, esperava 2", n)
+	}
+	// Quem não enviou nada dentro da janela é descartado
+	if _, ok := client.recentMessages[grupo.String()+"|"+outro.String()]; ok || len(client.recentMessages) != 1 {
+		t.Errorf("Participante sem mensagens recentes deveria ser descartado: %v", client.recentMessages)
+	}
+}
+
+func TestRenderGreeting(t *testing.T) {
+	dados := GreetingData{Name: "Maria", Mention: "@5511999999999", Group: "Clientes"}
+
+	texto, err := renderGreeting("Bem-vinda ao {{.Group}}, {{.Mention}}! ", dados)
+	if err != nil || texto != "Bem-vinda ao Clientes, @5511999999999!" {
+		t.Errorf("Mensagem incorreta: %q, %v", texto, err)
+	}
+	if _, err := renderGreeting("Olá {{.Nome", dados); err == nil {
+		t.Errorf("Template inválido deveria falhar")
+	}
+}
+
+func TestIsGroupAdmin(t *testing.T) {
+	admin := types.NewJID("123456789", types.HiddenUserServer)
+	telefoneAdmin := types.NewJID("5511999999999", types.DefaultUserServer)
+	membro := types.NewJID("5511888888888", types.DefaultUserServer)
+	info := &types.GroupInfo{Participants: []types.GroupParticipant{
+		{JID: admin, PhoneNumber: telefoneAdmin, IsAdmin: true},
+		{JID: membro},
+	}}
+
+	if !isGroupAdmin(info, admin) || !isGroupAdmin(info, telefoneAdmin) {
+		t.Errorf("Administrador não reconhecido pelo LID ou pelo telefone")
+	}
+	if isGroupAdmin(info, membro) || isGroupAdmin(info, types.EmptyJID) {
+		t.Errorf("Membro comum reconhecido como administrador")
+	}
+	if !isGroupAdmin(info, types.EmptyJID, membro, telefoneAdmin) {
+		t.Errorf("Algum dos JIDs informados é de administrador")
+	}
+}
@@ -0,0 +1,95 @@
+package whatsapp
+
+import (
+	"context"
+	"math/rand"
+	"time"
+	"unicode/utf8"
+)
+
+// Valores padrão do ritmo de envio das respostas
+const (
+	DefaultTypingSpeed = 15.0 // caracteres por segundo, o ritmo de quem digita rápido no celular
+	DefaultMinDelay    = 2 * time.Second
+	DefaultMaxDelay    = 15 * time.Second
+	DefaultJitter      = 0.25
+)
+
+// composingRefresh é o intervalo de renovação do "digitando...", que o WhatsApp encerra sozinho após alguns segundos
+const composingRefresh = 10 * time.Second
+
+// Pacing define o atraso antes de cada envio, proporcional ao tamanho do texto, para que as respostas
+// não cheguem instantaneamente como as de um robô. O valor zero desativa o atraso
+type Pacing struct {
+	// CharsPerSecond é a velocidade de digitação simulada; zero desativa o atraso
+	CharsPerSecond float64
+	// MinDelay e MaxDelay limitam o atraso de cada mensagem (MaxDelay zero = sem limite)
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// Jitter é a variação aleatória aplicada ao atraso, como fração dele (0.25 = até 25% para mais ou para menos)
+	Jitter float64
+}
+
+// DefaultPacing retorna o ritmo de envio padrão
+func DefaultPacing() Pacing {
+	return Pacing{
+		CharsPerSecond: DefaultTypingSpeed,
+		MinDelay:       DefaultMinDelay,
+		MaxDelay:       DefaultMaxDelay,
+		Jitter:         DefaultJitter,
+	}
+}
+
+// Delay calcula o atraso antes do envio do texto, já com a variação aleatória
+func (p Pacing) Delay(text string) time.Duration {
+	return p.delay(text, rand.Float64())
+}
+
+// delay calcula o atraso com a variação definida por r, entre 0 e 1. Os limites valem depois da variação
+func (p Pacing) delay(text string, r float64) time.Duration {
+	if p.CharsPerSecond <= 0 {
+		return 0
+	}
+
+	seconds := float64(utf8.RuneCountInString(text)) / p.CharsPerSecond
+	seconds *= 1 + p.Jitter*(2*r-1)
+	d := time.Duration(seconds * float64(time.Second))
+
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d < p.MinDelay {
+		d = p.MinDelay
+	}
+	return d
+}
+
+// Pace mantém o "digitando..." no chat durante o atraso calculado para o texto, descontado o tempo
+// já decorrido desde a mensagem anterior (elapsed), como o gasto pelo modelo para gerar a resposta.
+// Retorna antes do fim do atraso se ctx for cancelado
+func (c *Client) Pace(ctx context.Context, jid, text string, pacing Pacing, elapsed time.Duration) error {
+	wait := pacing.Delay(text) - elapsed
+	if wait <= 0 {
+		return nil
+	}
+
+	if err := c.SendTyping(jid, true); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(composingRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			c.SendTyping(jid, true)
+		}
+	}
+}
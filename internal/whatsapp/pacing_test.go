@@ -0,0 +1,41 @@
+package whatsapp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacingDelay(t *testing.T) {
+	pacing := Pacing{
+		CharsPerSecond: 10,
+		MinDelay:       time.Second,
+		MaxDelay:       10 * time.Second,
+		Jitter:         0.5,
+	}
+
+	tests := []struct {
+		name   string
+		pacing Pacing
+		text   string
+		r      float64
+		atraso time.Duration
+	}{
+		{"Proporcional", pacing, strings.Repeat("a", 40), 0.5, 4 * time.Second},
+		{"VariacaoParaMenos", pacing, strings.Repeat("a", 40), 0, 2 * time.Second},
+		{"VariacaoParaMais", pacing, strings.Repeat("a", 40), 1, 6 * time.Second},
+		{"Minimo", pacing, "Oi", 0.5, time.Second},
+		{"Maximo", pacing, strings.Repeat("a", 500), 0.5, 10 * time.Second},
+		{"MaximoComVariacao", pacing, strings.Repeat("a", 90), 1, 10 * time.Second},
+		{"ContaCaracteresNaoBytes", pacing, strings.Repeat("ção", 10), 0.5, 3 * time.Second},
+		{"Desativado", Pacing{}, strings.Repeat("a", 40), 0.5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if atraso := tt.pacing.delay(tt.text, tt.r); atraso != tt.atraso {
+				t.Errorf("Atraso incorreto: %v, esperava %v", atraso, tt.atraso)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// pairClientName é o nome do aparelho exibido no celular. O WhatsApp só aceita o formato
+// "Navegador (Sistema)" com navegadores e sistemas conhecidos
+const pairClientName = "Chrome (Linux)"
+
+// PairPhone inicia o login por código de pareamento, alternativa ao QR Code para servidores sem tela.
+// Retorna o código de 8 caracteres (como "ABCD-EFGH") que deve ser digitado no celular em
+// Aparelhos conectados > Conectar com número de telefone. O número deve estar no formato
+// internacional, como +55 11 99999-9999. O andamento (StatePairing, StateLoggedIn ou StateError)
+// é informado pelo callback de estado. Durante um Login por QR Code, o código é gerado na mesma
+// conexão, que só vale até o fim dos QR Codes, e o Login retorna o resultado do pareamento
+func (c *Client) PairPhone(phone string) (string, error) {
+	if c.client == nil {
+		return "", ErrClientNotInitialized
+	}
+	if c.client.Store.ID != nil {
+		return "", ErrAlreadyLoggedIn
+	}
+	phone, err := pairingPhone(phone)
+	if err != nil {
+		return "", err
+	}
+
+	c.connectionMutex.Lock()
+	defer c.connectionMutex.Unlock()
+
+	switch c.qrLogin {
+	case qrLoginConnecting:
+		return "", ErrLoginNotReady
+	case qrLoginShowing:
+		code, err := c.requestPairCode(phone)
+		if err != nil {
+			// O QR Code continua valendo; só o pedido do código falhou
+			return "", fmt.Errorf("erro ao gerar código de pareamento: %w", err)
+		}
+		c.log.Infof("Código de pareamento gerado para %s durante o login por QR Code", phone)
+		c.updateState(StatePairing, nil)
+		return code, nil
+	}
+
+	loginChan, err := c.openLogin()
+	if err != nil {
+		return "", err
+	}
+
+	// O primeiro QR Code indica que a conexão está pronta; os seguintes são ignorados
+	first, ok := <-loginChan
+	if !ok || first.Event != whatsmeow.QRChannelEventCode {
+		err := loginChannelError(first)
+		c.updateState(StateError, err)
+		return "", err
+	}
+
+	code, err := c.requestPairCode(phone)
+	if err != nil {
+		err = fmt.Errorf("erro ao gerar código de pareamento: %w", err)
+		c.updateState(StateError, err)
+		c.client.Disconnect()
+		return "", err
+	}
+
+	c.log.Infof("Código de pareamento gerado para %s", phone)
+	c.updateState(StatePairing, nil)
+	go c.watchLogin(loginChan)
+	return code, nil
+}
+
+// requestPairingCode pede ao servidor o código de pareamento, com a conexão de login já aberta
+func (c *Client) requestPairingCode(phone string) (string, error) {
+	return c.client.PairPhone(context.Background(), phone, true, whatsmeow.PairClientChrome, pairClientName)
+}
+
+// qrLoginStatus indica em que ponto está um Login por QR Code
+type qrLoginStatus int
+
+const (
+	qrLoginIdle       qrLoginStatus = iota // nenhum login por QR Code em andamento
+	qrLoginConnecting                      // conexão de login aberta, aguardando o primeiro código
+	qrLoginShowing                         // códigos sendo exibidos; a conexão aceita o pareamento por código
+)
+
+// setQRLogin atualiza o andamento do Login por QR Code
+func (c *Client) setQRLogin(status qrLoginStatus) {
+	c.connectionMutex.Lock()
+	c.qrLogin = status
+	c.connectionMutex.Unlock()
+}
+
+// pairingPhone mantém apenas os dígitos do telefone e verifica se ele está no formato internacional
+func pairingPhone(phone string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+
+	switch {
+	case digits == "":
+		return "", errors.New("informe o número de telefone")
+	case strings.HasPrefix(digits, "0"):
+		return "", fmt.Errorf("número %q deve estar no formato internacional, com o código do país (ex.: +55 11 99999-9999)", phone)
+	case len(digits) < 8 || len(digits) > 15:
+		return "", fmt.Errorf("número %q inválido: informe o código do país, o DDD e o número", phone)
+	}
+	return digits, nil
+}
+
+// openLoginChannel abre uma conexão nova para login e retorna o canal com os eventos do pareamento.
+// Uma conexão aberta sem sessão é reiniciada, pois o canal precisa ser criado antes dela e
+// o prazo de login conta a partir da conexão
+func (c *Client) openLoginChannel() (<-chan whatsmeow.QRChannelItem, error) {
+	if c.client.IsConnected() {
+		c.client.Disconnect()
+	}
+
+	loginChan, err := c.client.GetQRChannel(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("erro ao preparar login: %w", err)
+	}
+
+	c.updateState(StateConnecting, nil)
+	if err := c.client.Connect(); err != nil {
+		c.updateState(StateError, err)
+		return nil, fmt.Errorf("erro ao conectar para login: %w", err)
+	}
+	return loginChan, nil
+}
+
+// watchLogin acompanha o canal de login até o pareamento terminar, informando o resultado pelo callback de estado
+func (c *Client) watchLogin(loginChan <-chan whatsmeow.QRChannelItem) {
+	for evt := range loginChan {
+		switch evt.Event {
+		case whatsmeow.QRChannelEventCode:
+			continue
+		case whatsmeow.QRChannelSuccess.Event:
+			c.log.Infof("Pareamento concluído")
+			c.updateState(StateLoggedIn, nil)
+		default:
+			c.updateState(StateError, loginChannelError(evt))
+		}
+		return
+	}
+}
+
+// loginChannelError converte em erro um evento de falha do canal de login
+func loginChannelError(evt whatsmeow.QRChannelItem) error {
+	switch evt.Event {
+	case "":
+		return errors.New("conexão encerrada durante o login")
+	case whatsmeow.QRChannelTimeout.Event:
+		return ErrLoginTimeout
+	case whatsmeow.QRChannelEventError:
+		return fmt.Errorf("erro no login: %w", evt.Error)
+	case whatsmeow.QRChannelClientOutdated.Event:
+		return errors.New("versão do cliente desatualizada; atualize o WhatsZapMe")
+	case whatsmeow.QRChannelScannedWithoutMultidevice.Event:
+		return errors.New("ative o recurso de vários aparelhos no celular e tente de novo")
+	}
+	return fmt.Errorf("falha no login: %s", evt.Event)
+}
@@ -0,0 +1,99 @@
+package whatsapp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+func TestPairingPhone(t *testing.T) {
+	tests := []struct {
+		telefone string
+		esperado string
+		valido   bool
+	}{
+		{"+55 (11) 99999-9999", "5511999999999", true},
+		{"5511999999999", "5511999999999", true},
+		{"+1 415-555-0100", "14155550100", true},
+		{"011 99999-9999", "", false},
+		{"99999", "", false},
+		{"+55 11 99999-9999 1234", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.telefone, func(t *testing.T) {
+			telefone, err := pairingPhone(tt.telefone)
+			if (err == nil) != tt.valido || telefone != tt.esperado {
+				t.Errorf("Telefone incorreto: %q, %v", telefone, err)
+			}
+		})
+	}
+}
+
+func TestLoginChannelError(t *testing.T) {
+	if err := loginChannelError(whatsmeow.QRChannelTimeout); !errors.Is(err, ErrLoginTimeout) {
+		t.Errorf("Esperava ErrLoginTimeout, obteve %v", err)
+	}
+
+	falha := errors.New("websocket fechado")
+	if err := loginChannelError(whatsmeow.QRChannelItem{Event: whatsmeow.QRChannelEventError, Error: falha}); !errors.Is(err, falha) {
+		t.Errorf("O erro do canal deveria ser preservado: %v", err)
+	}
+
+	// O canal fechado sem evento também é uma falha
+	if err := loginChannelError(whatsmeow.QRChannelItem{}); err == nil {
+		t.Errorf("Canal fechado deveria retornar erro")
+	}
+}
+
+func TestPairPhoneDuringLogin(t *testing.T) {
+	codigos := make(chan whatsmeow.QRChannelItem, 8)
+	exibidos := make(chan string, 8)
+	conexoes := 0
+	client := &Client{
+		client:         &whatsmeow.Client{Store: &store.Device{}},
+		log:            waLog.Noop,
+		qrCodeCallback: func(code string) { exibidos <- code },
+		openLogin: func() (<-chan whatsmeow.QRChannelItem, error) {
+			conexoes++
+			return codigos, nil
+		},
+		requestPairCode: func(phone string) (string, error) { return "ABCD-EFGH", nil },
+	}
+
+	login := make(chan error, 1)
+	go func() { login <- client.Login() }()
+	codigos <- whatsmeow.QRChannelItem{Event: whatsmeow.QRChannelEventCode, Code: "2@codigo"}
+	<-exibidos
+
+	// O PairPhone não pode esperar o fim do Login, que segura a conexão enquanto exibe os códigos
+	pareamento := make(chan error, 1)
+	var codigo string
+	go func() {
+		var err error
+		codigo, err = client.PairPhone("+55 11 99999-9999")
+		pareamento <- err
+	}()
+	select {
+	case err := <-pareamento:
+		if err != nil || codigo != "ABCD-EFGH" {
+			t.Fatalf("Pareamento incorreto: %q, %v", codigo, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("PairPhone ficou bloqueado pelo Login em andamento")
+	}
+	if conexoes != 1 || client.state != StatePairing {
+		t.Errorf("O pareamento deveria usar a conexão do Login: %d conexões, estado %s", conexoes, client.state)
+	}
+
+	// O resultado do pareamento chega pelo canal do Login
+	codigos <- whatsmeow.QRChannelSuccess
+	if err := <-login; err != nil || client.state != StateLoggedIn {
+		t.Errorf("Login deveria terminar com o pareamento: %v, estado %s", err, client.state)
+	}
+}
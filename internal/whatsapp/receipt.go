@@ -0,0 +1,81 @@
+package whatsapp
+
+import (
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ReceiptStatus é o estado de uma mensagem enviada informado pelo WhatsApp
+type ReceiptStatus string
+
+// Estados confirmados pelos recibos
+const (
+	ReceiptDelivered ReceiptStatus = "delivered" // entregue no aparelho do contato
+	ReceiptRead      ReceiptStatus = "read"      // lida ou, no caso de áudios, reproduzida pelo contato
+	ReceiptFailed    ReceiptStatus = "failed"    // o servidor não conseguiu entregar a mensagem
+)
+
+// Receipt é a confirmação de entrega ou leitura de mensagens enviadas pelo assistente
+type Receipt struct {
+	ChatJID string
+	// SenderJID é o contato que confirmou o recebimento
+	SenderJID  string
+	MessageIDs []string
+	Status     ReceiptStatus
+	Timestamp  time.Time
+}
+
+// parseReceipt converte um recibo do whatsmeow. Retorna nil para recibos que não mudam o estado
+// das mensagens enviadas, como os emitidos pelos outros aparelhos da própria conta
+func parseReceipt(v *events.Receipt) *Receipt {
+	if v.IsFromMe || len(v.MessageIDs) == 0 {
+		return nil
+	}
+
+	var status ReceiptStatus
+	switch v.Type {
+	case types.ReceiptTypeDelivered:
+		status = ReceiptDelivered
+	case types.ReceiptTypeRead, types.ReceiptTypePlayed:
+		status = ReceiptRead
+	case types.ReceiptTypeServerError:
+		status = ReceiptFailed
+	default:
+		return nil
+	}
+
+	r := &Receipt{
+		ChatJID:    v.Chat.String(),
+		SenderJID:  v.Sender.String(),
+		MessageIDs: make([]string, len(v.MessageIDs)),
+		Status:     status,
+		Timestamp:  v.Timestamp,
+	}
+	for i, id := range v.MessageIDs {
+		r.MessageIDs[i] = string(id)
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+	return r
+}
+
+// MarkRead envia a confirmação de leitura (os tiques azuis) de uma mensagem recebida
+func (c *Client) MarkRead(msg *IncomingMessage) error {
+	chat, err := c.recipient(msg.ChatJID)
+	if err != nil {
+		return err
+	}
+	sender, err := types.ParseJID(msg.SenderJID)
+	if err != nil {
+		return fmt.Errorf("JID inválido: %w", err)
+	}
+
+	if err := c.client.MarkRead([]types.MessageID{msg.ID}, time.Now(), chat, sender); err != nil {
+		return fmt.Errorf("erro ao confirmar leitura: %w", err)
+	}
+	return nil
+}
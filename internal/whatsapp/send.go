@@ -0,0 +1,339 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg" // decodifica as dimensões de fotos JPEG
+	_ "image/png"  // decodifica as dimensões de imagens PNG
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// mediaUploadTimeout limita o tempo de envio de um anexo aos servidores do WhatsApp
+const mediaUploadTimeout = 2 * time.Minute
+
+// recipient valida o estado da conexão e converte o JID do destinatário
+func (c *Client) recipient(jid string) (types.JID, error) {
+	if c.client == nil {
+		return types.JID{}, ErrClientNotInitialized
+	}
+
+	if !c.client.IsLoggedIn() {
+		return types.JID{}, ErrNotLoggedIn
+	}
+
+	recipient, err := types.ParseJID(jid)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("JID inválido: %w", err)
+	}
+	return recipient, nil
+}
+
+// upload criptografa e envia o anexo aos servidores do WhatsApp
+func (c *Client) upload(ctx context.Context, data []byte, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
+	if len(data) == 0 {
+		return whatsmeow.UploadResponse{}, fmt.Errorf("anexo vazio")
+	}
+
+	uploaded, err := c.client.Upload(ctx, data, mediaType)
+	if err != nil {
+		return whatsmeow.UploadResponse{}, fmt.Errorf("erro ao enviar anexo: %w", err)
+	}
+	return uploaded, nil
+}
+
+// quoteContext cita a mensagem recebida em uma resposta, para que o contato saiba a que pergunta ela se refere
+func quoteContext(quoted *IncomingMessage) *waProto.ContextInfo {
+	if quoted == nil || quoted.ID == "" {
+		return nil
+	}
+
+	info := &waProto.ContextInfo{
+		StanzaID:    proto.String(quoted.ID),
+		Participant: proto.String(quoted.SenderJID),
+	}
+	if quoted.Raw != nil {
+		info.Participant = proto.String(quoted.Raw.Info.Sender.ToNonAD().String())
+		info.QuotedMessage = quoted.Raw.Message
+	} else if quoted.Text != "" {
+		info.QuotedMessage = &waProto.Message{Conversation: proto.String(quoted.Text)}
+	}
+	return info
+}
+
+// SendText envia uma mensagem de texto e retorna o ID dela no WhatsApp.
+// Se quoted não for nil, a mensagem é enviada como resposta citando a mensagem recebida
+func (c *Client) SendText(jid, text string, quoted *IncomingMessage) (string, error) {
+	return c.SendTextMentioning(jid, text, quoted, nil)
+}
+
+// SendTextMentioning envia uma mensagem de texto mencionando (@) os contatos informados, como nas
+// respostas em grupos. As menções que ainda não estiverem no texto são inseridas no início dele
+func (c *Client) SendTextMentioning(jid, text string, quoted *IncomingMessage, mentions []string) (string, error) {
+	recipient, err := c.recipient(jid)
+	if err != nil {
+		return "", err
+	}
+
+	msg := &waProto.Message{Conversation: proto.String(text)}
+	info := quoteContext(quoted)
+	if len(mentions) > 0 {
+		text = mentionText(text, mentions)
+		if info == nil {
+			info = &waProto.ContextInfo{}
+		}
+		info.MentionedJID = mentions
+	}
+	if info != nil {
+		msg = &waProto.Message{
+			ExtendedTextMessage: &waProto.ExtendedTextMessage{
+				Text:        proto.String(text),
+				ContextInfo: info,
+			},
+		}
+	}
+
+	resp, err := c.client.SendMessage(context.Background(), recipient, msg)
+	if err != nil {
+		return "", fmt.Errorf("erro ao enviar mensagem: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// mentionText insere no início do texto o "@número" de cada JID mencionado que ainda não aparece nele.
+// O WhatsApp exibe o nome do contato no lugar do número
+func mentionText(text string, mentions []string) string {
+	var prefix strings.Builder
+	for _, raw := range mentions {
+		jid, err := types.ParseJID(raw)
+		if err != nil || jid.User == "" {
+			continue
+		}
+		if tag := "@" + jid.User; !strings.Contains(text, tag) {
+			prefix.WriteString(tag + " ")
+		}
+	}
+	return prefix.String() + text
+}
+
+// SendReaction reage com um emoji à mensagem messageID, enviada por senderJID na conversa chatJID.
+// Um emoji vazio remove a reação
+func (c *Client) SendReaction(chatJID, senderJID, messageID, emoji string) error {
+	chat, err := c.recipient(chatJID)
+	if err != nil {
+		return err
+	}
+	sender, err := types.ParseJID(senderJID)
+	if err != nil {
+		return fmt.Errorf("JID inválido: %w", err)
+	}
+
+	_, err = c.client.SendMessage(context.Background(), chat, c.client.BuildReaction(chat, sender, messageID, emoji))
+	if err != nil {
+		return fmt.Errorf("erro ao enviar reação: %w", err)
+	}
+	return nil
+}
+
+// EditText substitui o texto de uma mensagem enviada pela própria conta. O WhatsApp só aceita
+// edições feitas até 15 minutos depois do envio
+func (c *Client) EditText(chatJID, messageID, text string) error {
+	chat, err := c.recipient(chatJID)
+	if err != nil {
+		return err
+	}
+
+	edit := c.client.BuildEdit(chat, messageID, &waProto.Message{Conversation: proto.String(text)})
+	if _, err := c.client.SendMessage(context.Background(), chat, edit); err != nil {
+		return fmt.Errorf("erro ao editar mensagem: %w", err)
+	}
+	return nil
+}
+
+// Revoke apaga para todos uma mensagem enviada pela própria conta
+func (c *Client) Revoke(chatJID, messageID string) error {
+	chat, err := c.recipient(chatJID)
+	if err != nil {
+		return err
+	}
+
+	// O remetente vazio indica uma mensagem da própria conta
+	revoke := c.client.BuildRevoke(chat, types.EmptyJID, messageID)
+	if _, err := c.client.SendMessage(context.Background(), chat, revoke); err != nil {
+		return fmt.Errorf("erro ao apagar mensagem: %w", err)
+	}
+	return nil
+}
+
+// sendMedia envia o anexo e, em seguida, a mensagem montada por build com os dados do upload.
+// Retorna o ID da mensagem no WhatsApp
+func (c *Client) sendMedia(jid string, data []byte, mediaType whatsmeow.MediaType, build func(whatsmeow.UploadResponse) *waProto.Message) (string, error) {
+	recipient, err := c.recipient(jid)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mediaUploadTimeout)
+	defer cancel()
+
+	uploaded, err := c.upload(ctx, data, mediaType)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.client.SendMessage(ctx, recipient, build(uploaded))
+	if err != nil {
+		return "", fmt.Errorf("erro ao enviar mensagem: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// SendImage envia uma imagem com legenda opcional. O tipo é detectado pelo conteúdo quando mimeType é vazio
+func (c *Client) SendImage(jid string, data []byte, mimeType, caption string) (string, error) {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	// As dimensões ajudam o WhatsApp a reservar o espaço da imagem antes do download
+	var width, height uint32
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		width, height = uint32(cfg.Width), uint32(cfg.Height)
+	}
+
+	return c.sendMedia(jid, data, whatsmeow.MediaImage, func(uploaded whatsmeow.UploadResponse) *waProto.Message {
+		msg := &waProto.ImageMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(mimeType),
+		}
+		if caption != "" {
+			msg.Caption = proto.String(caption)
+		}
+		if width > 0 && height > 0 {
+			msg.Width = proto.Uint32(width)
+			msg.Height = proto.Uint32(height)
+		}
+		return &waProto.Message{ImageMessage: msg}
+	})
+}
+
+// SendDocument envia um arquivo, como um PDF, com o nome exibido ao contato e legenda opcional
+func (c *Client) SendDocument(jid string, data []byte, mimeType, fileName, caption string) (string, error) {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	if fileName == "" {
+		fileName = "documento" + mediaExtension(mimeType, "")
+	}
+
+	return c.sendMedia(jid, data, whatsmeow.MediaDocument, func(uploaded whatsmeow.UploadResponse) *waProto.Message {
+		msg := &waProto.DocumentMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(mimeType),
+			FileName:      proto.String(fileName),
+			Title:         proto.String(fileName),
+		}
+		if caption != "" {
+			msg.Caption = proto.String(caption)
+		}
+		return &waProto.Message{DocumentMessage: msg}
+	})
+}
+
+// SendAudio envia um áudio. Com voiceNote, o áudio aparece como mensagem de voz (PTT) e deve estar em OGG/Opus
+func (c *Client) SendAudio(jid string, data []byte, mimeType string, seconds uint32, voiceNote bool) (string, error) {
+	return c.sendAudio(jid, data, mimeType, seconds, voiceNote, nil)
+}
+
+// SendVoiceReply envia uma mensagem de voz em OGG/Opus citando a mensagem recebida, quando quoted não é nil
+func (c *Client) SendVoiceReply(jid string, data []byte, mimeType string, seconds uint32, quoted *IncomingMessage) (string, error) {
+	return c.sendAudio(jid, data, mimeType, seconds, true, quoted)
+}
+
+// sendAudio envia um áudio, opcionalmente citando uma mensagem recebida
+func (c *Client) sendAudio(jid string, data []byte, mimeType string, seconds uint32, voiceNote bool, quoted *IncomingMessage) (string, error) {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	return c.sendMedia(jid, data, whatsmeow.MediaAudio, func(uploaded whatsmeow.UploadResponse) *waProto.Message {
+		msg := &waProto.AudioMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(uploaded.FileLength),
+			Mimetype:      proto.String(mimeType),
+			PTT:           proto.Bool(voiceNote),
+			ContextInfo:   quoteContext(quoted),
+		}
+		if seconds > 0 {
+			msg.Seconds = proto.Uint32(seconds)
+		}
+		return &waProto.Message{AudioMessage: msg}
+	})
+}
+
+// SendLocation envia uma localização fixa, com nome e endereço opcionais
+func (c *Client) SendLocation(jid string, latitude, longitude float64, name, address string) (string, error) {
+	recipient, err := c.recipient(jid)
+	if err != nil {
+		return "", err
+	}
+
+	location := &waProto.LocationMessage{
+		DegreesLatitude:  proto.Float64(latitude),
+		DegreesLongitude: proto.Float64(longitude),
+	}
+	if name != "" {
+		location.Name = proto.String(name)
+	}
+	if address != "" {
+		location.Address = proto.String(address)
+	}
+
+	resp, err := c.client.SendMessage(context.Background(), recipient, &waProto.Message{LocationMessage: location})
+	if err != nil {
+		return "", fmt.Errorf("erro ao enviar localização: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// SendContact envia um cartão de contato no formato vCard
+func (c *Client) SendContact(jid, displayName, vcard string) (string, error) {
+	recipient, err := c.recipient(jid)
+	if err != nil {
+		return "", err
+	}
+
+	msg := &waProto.Message{
+		ContactMessage: &waProto.ContactMessage{
+			DisplayName: proto.String(displayName),
+			Vcard:       proto.String(vcard),
+		},
+	}
+
+	resp, err := c.client.SendMessage(context.Background(), recipient, msg)
+	if err != nil {
+		return "", fmt.Errorf("erro ao enviar contato: %w", err)
+	}
+	return resp.ID, nil
+}
@@ -0,0 +1,53 @@
+package whatsapp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/mdp/qrterminal/v3"
+)
+
+// TerminalQR exibe no terminal o QR Code de login, substituindo o anterior a cada novo código
+// para que apenas o código válido fique na tela
+type TerminalQR struct {
+	w     io.Writer
+	lines int // linhas escritas pelo último QR Code, apagadas antes do próximo
+}
+
+// NewTerminalQR cria um TerminalQR que escreve em w, normalmente os.Stdout
+func NewTerminalQR(w io.Writer) *TerminalQR {
+	return &TerminalQR{w: w}
+}
+
+// Show exibe o QR Code. No macOS e Linux, usa meios-blocos Unicode, que cabem em metade das linhas,
+// e apaga o código anterior com sequências ANSI
+func (t *TerminalQR) Show(code string) {
+	var buf bytes.Buffer
+	if runtime.GOOS == "windows" {
+		// No Windows, use caracteres ASCII em vez de Unicode
+		qrterminal.GenerateWithConfig(code, qrterminal.Config{
+			Level:     qrterminal.M,
+			Writer:    &buf,
+			BlackChar: qrterminal.BLACK,
+			WhiteChar: qrterminal.WHITE,
+			QuietZone: 1,
+		})
+	} else {
+		qrterminal.GenerateHalfBlock(code, qrterminal.L, &buf)
+	}
+	buf.WriteString("Escaneie o QR Code acima com o WhatsApp no seu celular\n")
+
+	if t.lines > 0 {
+		if runtime.GOOS == "windows" {
+			// O console clássico do Windows não interpreta ANSI; o novo código vai abaixo do anterior
+			fmt.Fprintln(t.w, "\nO QR Code anterior expirou. Use o novo código:")
+		} else {
+			// Volta o cursor ao início do código anterior e apaga até o fim da tela
+			fmt.Fprintf(t.w, "\033[%dA\033[J", t.lines)
+		}
+	}
+	t.lines = bytes.Count(buf.Bytes(), []byte("\n"))
+	t.w.Write(buf.Bytes())
+}
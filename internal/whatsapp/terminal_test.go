@@ -0,0 +1,35 @@
+package whatsapp
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestTerminalQR(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("No Windows o código anterior não é apagado")
+	}
+
+	var saida bytes.Buffer
+	terminal := NewTerminalQR(&saida)
+
+	terminal.Show("2@primeiro-codigo")
+	primeiro := saida.String()
+	if !strings.ContainsAny(primeiro, "▀▄█") {
+		t.Fatalf("QR Code não foi desenhado com meios-blocos:\n%s", primeiro)
+	}
+	if strings.Contains(primeiro, "\033[") {
+		t.Errorf("O primeiro código não deveria apagar nada")
+	}
+
+	// O novo código sobe o cursor pelas linhas do anterior e apaga a tela a partir dali
+	saida.Reset()
+	terminal.Show("2@segundo-codigo")
+	apagar := fmt.Sprintf("\033[%dA\033[J", strings.Count(primeiro, "\n"))
+	if !strings.HasPrefix(saida.String(), apagar) {
+		t.Errorf("O código anterior não foi apagado: %q", saida.String()[:20])
+	}
+}